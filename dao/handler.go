@@ -9,12 +9,22 @@ import (
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
 )
 
+// FileInfoHandler fetches the file from Weaviate and renders its structural
+// summary with RenderFileSummary. It returns an empty string if the file cannot
+// be fetched.
 func FileInfoHandler(weaviate *Weaviate, code string, fileName string) string {
     file, err := FileInfoGetter(weaviate, code, fileName)
     if err != nil {
         return ""
     }
+    return RenderFileSummary(file)
+}
 
+// RenderFileSummary renders the structure of the file, its classes,
+// interfaces and standalone functions with their signatures and documentation,
+// as the text FileInfoHandler adds to prompts. The file may come from Weaviate
+// or straight from a parser.
+func RenderFileSummary(file *types.File) string {
     var prompt strings.Builder
     
     prompt.WriteString(fmt.Sprintf("You are analyzing a file named '%s'", file.Path))
@@ -22,12 +32,17 @@ func FileInfoHandler(weaviate *Weaviate, code string, fileName string) string {
         prompt.WriteString(fmt.Sprintf(" from the module '%s'", file.Module))
     }
     prompt.WriteString(".\n\n")
+    if file.Doc != "" {
+        writeDoc(&prompt, "", file.Doc)
+        prompt.WriteString("\n")
+    }
     
     if len(file.Classes) > 0 {
         prompt.WriteString(fmt.Sprintf("The file contains %d classes:\n\n", len(file.Classes)))
         
         for _, class := range file.Classes {
             prompt.WriteString(fmt.Sprintf("- Class '%s':\n", class.Name))
+            writeDoc(&prompt, "  ", class.Doc)
             
 			prompt.WriteString("  Fields:\n")
 			for _, field := range class.Fields {
@@ -35,10 +50,23 @@ func FileInfoHandler(weaviate *Weaviate, code string, fileName string) string {
 			}
 			prompt.WriteString("\n")
             
+            if len(class.Constructors) > 0 {
+                prompt.WriteString("  Constructors:\n")
+                for _, constructor := range class.Constructors {
+                    paramStrs := make([]string, len(constructor.Parameters))
+                    for i, param := range constructor.Parameters {
+                        paramStrs[i] = fmt.Sprintf("%s: %s", param.Name, param.Type)
+                    }
+                    prompt.WriteString(fmt.Sprintf("  - %s(%s)\n", constructor.Name, strings.Join(paramStrs, ", ")))
+                    writeDoc(&prompt, "    ", constructor.Doc)
+                }
+                prompt.WriteString("\n")
+            }
+            
             if len(class.Methods) > 0 {
                 prompt.WriteString("  Methods:\n")
                 for _, method := range class.Methods {
-                    prompt.WriteString(fmt.Sprintf("  - %s(", method.Func.Name))
+                    prompt.WriteString(fmt.Sprintf("  - %s%s(", methodModifiers(method), method.Func.Name))
                     
                     paramStrs := make([]string, len(method.Func.Parameters))
                     for i, param := range method.Func.Parameters {
@@ -52,6 +80,7 @@ func FileInfoHandler(weaviate *Weaviate, code string, fileName string) string {
                         prompt.WriteString(strings.Join(method.Func.ReturnTypes, ", "))
                     }
                     prompt.WriteString("\n")
+                    writeDoc(&prompt, "    ", method.Func.Doc)
                 }
                 prompt.WriteString("\n")
             }
@@ -81,6 +110,7 @@ func FileInfoHandler(weaviate *Weaviate, code string, fileName string) string {
                         prompt.WriteString(strings.Join(method.ReturnTypes, ", "))
                     }
                     prompt.WriteString("\n")
+                    writeDoc(&prompt, "    ", method.Doc)
                 }
                 prompt.WriteString("\n")
             }
@@ -105,6 +135,7 @@ func FileInfoHandler(weaviate *Weaviate, code string, fileName string) string {
                 prompt.WriteString(strings.Join(function.ReturnTypes, ", "))
             }
             prompt.WriteString("\n")    
+            writeDoc(&prompt, "  ", function.Doc)
         }
     }
     
@@ -113,11 +144,35 @@ func FileInfoHandler(weaviate *Weaviate, code string, fileName string) string {
     return prompt.String()
 }
 
+// methodModifiers returns the modifiers of a method that change how it is
+// tested, each followed by a space: static methods are called on the class and
+// abstract methods have no body to test.
+func methodModifiers(method types.Method) string {
+    var modifiers string
+    if method.IsStatic {
+        modifiers += "static "
+    }
+    if method.IsAbstract {
+        modifiers += "abstract "
+    }
+    return modifiers
+}
+
+// writeDoc writes a documentation comment with every line indented by indent.
+// Empty documentation is skipped.
+func writeDoc(prompt *strings.Builder, indent string, doc string) {
+    if doc == "" {
+        return
+    }
+    for _, line := range strings.Split(doc, "\n") {
+        prompt.WriteString(indent + "  | " + line + "\n")
+    }
+}
+
 func FileInfoGetter(weaviate *Weaviate, code string, fileName string) (*types.File, error) {
-    client := weaviate.GetClient()
-    res, err := client.GraphQL().Get().WithClassName("File").WithFields(ToFields(types.File{})...).
-        WithWhere(filters.Where().WithPath([]string{"path"}).WithOperator(filters.Equal).WithValueText(fileName)).
-        Do(weaviate.GetContext())
+    where := filters.Where().WithPath([]string{"path"}).WithOperator(filters.Equal).WithValueText(fileName)
+    className := weaviate.FileClassName()
+    res, err := weaviate.QueryObjects(className, where, 1, ToFields(types.File{})...)
     if err != nil {
         return nil, fmt.Errorf("weaviate query failed: %w", err)
     }
@@ -127,7 +182,7 @@ func FileInfoGetter(weaviate *Weaviate, code string, fileName string) (*types.Fi
         return nil, fmt.Errorf("invalid response format: missing 'Get' key")
     }
 
-    fileArray, ok := getMap["File"].([]any)
+    fileArray, ok := getMap[className].([]any)
     if !ok || len(fileArray) == 0 {
         return nil, fmt.Errorf("no file found with path: %s", fileName)
     }
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+func TestRenderFileSummaryOfClassAndFunction(t *testing.T) {
+	file := &types.File{
+		Path:   "shapes.py",
+		Module: "geometry",
+		Classes: []types.Class{{
+			Name:   "Circle",
+			Doc:    "A circle.",
+			Fields: []types.Field{{Name: "radius", Type: "float"}},
+			Methods: []types.Method{
+				{Func: types.Function{Name: "area", ReturnTypes: []string{"float"}}},
+				{Func: types.Function{Name: "unit", Parameters: []types.Parameter{{Name: "scale", Type: "int"}}}, IsStatic: true},
+			},
+		}},
+		Functions: []types.Function{{
+			Name:        "scale",
+			Parameters:  []types.Parameter{{Name: "c", Type: "Circle"}, {Name: "f", Type: "float"}},
+			ReturnTypes: []string{"Circle"},
+			Doc:         "Scales a circle.\nThe circle is copied.",
+		}},
+	}
+
+	want := "You are analyzing a file named 'shapes.py' from the module 'geometry'.\n\n" +
+		"The file contains 1 classes:\n\n" +
+		"- Class 'Circle':\n" +
+		"    | A circle.\n" +
+		"  Fields:\n" +
+		"  - radius: float\n" +
+		"\n" +
+		"  Methods:\n" +
+		"  - area() -> float\n" +
+		"  - static unit(scale: int)\n" +
+		"\n" +
+		"The file contains 1 standalone functions:\n\n" +
+		"- scale(c: Circle, f: float) -> Circle\n" +
+		"    | Scales a circle.\n" +
+		"    | The circle is copied.\n" +
+		"\nPlease analyze this code structure and provide insights or answer questions about it."
+	if got := RenderFileSummary(file); got != want {
+		t.Errorf("RenderFileSummary() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderFileSummaryListsConstructors(t *testing.T) {
+	file := &types.File{
+		Path: "Point.java",
+		Classes: []types.Class{{
+			Name: "Point",
+			Constructors: []types.Function{
+				{Name: "Point"},
+				{Name: "Point", Parameters: []types.Parameter{{Name: "x", Type: "int"}, {Name: "y", Type: "int"}}},
+			},
+		}},
+	}
+
+	want := "- Class 'Point':\n" +
+		"  Fields:\n" +
+		"\n" +
+		"  Constructors:\n" +
+		"  - Point()\n" +
+		"  - Point(x: int, y: int)\n" +
+		"\n"
+	if got := RenderFileSummary(file); !strings.Contains(got, want) {
+		t.Errorf("RenderFileSummary() =\n%s\nwant it to contain:\n%s", got, want)
+	}
+}
@@ -0,0 +1,309 @@
+package dao
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Marksagittarius/pinguis/scripts/java"
+	"github.com/Marksagittarius/pinguis/scripts/kotlin"
+	"github.com/Marksagittarius/pinguis/scripts/python"
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// IndexOptions configures IndexProject.
+//
+// Fields:
+//   - StatePath: The file persisting the paths, sizes, modification times and
+//     hashes of the files indexed so far, one JSON entry per line.
+//     A run resumed with the same StatePath skips the files indexed before. An
+//     empty path indexes every file.
+//   - RateLimit: The maximum number of files inserted per second (0 for no limit).
+//   - Progress: Called after every file with the progress of the run (optional).
+//   - Parse: Parses a source file (nil parses Python, Java and Kotlin files by
+//     their extension and skips any other file).
+type IndexOptions struct {
+	StatePath string
+	RateLimit float64
+	Progress  func(IndexProgress)
+	Parse     func(path string) (*types.File, error)
+}
+
+// IndexProgress reports the progress of an IndexProject run.
+type IndexProgress struct {
+	Path    string
+	Indexed int
+	Skipped int
+	Total   int
+}
+
+// indexedFile is an entry of the index state recording a file indexed before.
+// The size and modification time let a resumed run skip unchanged files without
+// hashing them again.
+type indexedFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+// unchanged reports whether the file has the size and modification time it had
+// when it was indexed.
+func (f indexedFile) unchanged(info os.FileInfo) bool {
+	return f.Size == info.Size() && f.ModTime.Equal(info.ModTime())
+}
+
+// IndexProject parses the source files below root and stores them as objects of
+// the file class (see SetFileClassName), creating the class if needed and adding
+// the properties it lacks otherwise (see MigrateClass). Files that cannot be
+// parsed are logged and skipped. Every inserted file is appended to
+// options.StatePath right away, so an interrupted run can be resumed without
+// inserting its files twice. Files whose size and modification time are those
+// recorded are skipped without being read; the others are hashed and skipped
+// only if their content is the one indexed before.
+//
+// Returns:
+//   - int: The number of files inserted by this run.
+//   - error: An error if the class cannot be created, a file cannot be inserted,
+//     or the state cannot be read or written.
+func (w *Weaviate) IndexProject(root string, options IndexOptions) (int, error) {
+	className := w.FileClassName()
+	if err := w.MigrateClass(ToNamedClass(types.File{}, className)); err != nil {
+		return 0, fmt.Errorf("failed to create or migrate %s class: %w", className, err)
+	}
+
+	return indexProject(root, options, func(file *types.File) error {
+		if err := w.context.Err(); err != nil {
+			return err
+		}
+		_, err := w.CreateObject(className, ToProperties(*file))
+		return err
+	})
+}
+
+func indexProject(root string, options IndexOptions, insert func(*types.File) error) (int, error) {
+	parse := options.Parse
+	if parse == nil {
+		parse = parseSourceFile
+	}
+
+	indexed, err := loadIndexState(options.StatePath)
+	if err != nil {
+		return 0, err
+	}
+
+	var paths []string
+	infos := make(map[string]os.FileInfo)
+	err = filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		infos[path] = info
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+
+	var interval time.Duration
+	if options.RateLimit > 0 {
+		interval = time.Duration(float64(time.Second) / options.RateLimit)
+	}
+
+	progress := IndexProgress{Total: len(paths)}
+	var lastInsert time.Time
+	for _, path := range paths {
+		progress.Path = path
+
+		info := infos[path]
+		previous, seen := indexed[path]
+		if seen && previous.unchanged(info) {
+			progress.Skipped++
+			if options.Progress != nil {
+				options.Progress(progress)
+			}
+			continue
+		}
+
+		// A path never indexed cannot match a recorded hash, so it is hashed only
+		// once it has been inserted
+		var hash string
+		if seen {
+			if hash, err = hashSourceFile(path); err != nil {
+				return progress.Indexed, err
+			}
+		}
+
+		if seen && hash == previous.Hash {
+			// Touched but not edited, record the new modification time
+			progress.Skipped++
+			if err := recordIndexedFile(options.StatePath, indexed, path, info, hash); err != nil {
+				return progress.Indexed, err
+			}
+		} else if file, err := parse(path); err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			progress.Skipped++
+		} else if file == nil {
+			progress.Skipped++
+		} else {
+			if wait := interval - time.Since(lastInsert); wait > 0 {
+				time.Sleep(wait)
+			}
+			lastInsert = time.Now()
+
+			file.Path = path
+			if err := insert(file); err != nil {
+				return progress.Indexed, fmt.Errorf("failed to index %s: %w", path, err)
+			}
+			progress.Indexed++
+
+			if hash == "" {
+				if hash, err = hashSourceFile(path); err != nil {
+					return progress.Indexed, err
+				}
+			}
+			if err := recordIndexedFile(options.StatePath, indexed, path, info, hash); err != nil {
+				return progress.Indexed, err
+			}
+		}
+
+		if options.Progress != nil {
+			options.Progress(progress)
+		}
+	}
+
+	return progress.Indexed, nil
+}
+
+// parseSourceFile parses Python, Java and Kotlin files by their extension. Other
+// files are skipped by returning a nil file. Python and Java files with syntax
+// errors are indexed with the declarations that could be parsed, logging a
+// warning.
+func parseSourceFile(path string) (*types.File, error) {
+	switch filepath.Ext(path) {
+	case ".py":
+		file, err := python.GetFileMetaData(path)
+		if errors.Is(err, types.ErrIncompleteParse) {
+			log.Printf("Warning: indexing %s partially: %v", path, err)
+			err = nil
+		}
+		return file, err
+	case ".java":
+		file, err := java.NewTreeSitterJavaParser().ParseFile(path)
+		if errors.Is(err, java.ErrIncompleteParse) {
+			log.Printf("Warning: indexing %s partially: %v", path, err)
+			err = nil
+		}
+		return file, err
+	case ".kt":
+		return kotlin.NewTreeSitterKotlinParser().ParseFile(path)
+	default:
+		return nil, nil
+	}
+}
+
+// hashSourceFile hashes the path and contents of a file, so renamed and edited
+// files are indexed again.
+func hashSourceFile(path string) (string, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(filepath.ToSlash(path)))
+	hash.Write([]byte{0})
+	hash.Write(code)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// recordIndexedFile records a file in the lookup of indexed files and appends it
+// to the state.
+func recordIndexedFile(statePath string, indexed map[string]indexedFile, path string, info os.FileInfo, hash string) error {
+	file := indexedFile{Path: path, Size: info.Size(), ModTime: info.ModTime(), Hash: hash}
+	indexed[path] = file
+	return saveIndexState(statePath, file)
+}
+
+// loadIndexState reads the state as a lookup of the indexed files by path. The
+// state holds one JSON entry per line, a later entry of a path replacing the
+// earlier ones. A last line cut off by an interruption is dropped.
+func loadIndexState(statePath string) (map[string]indexedFile, error) {
+	indexed := make(map[string]indexedFile)
+	if statePath == "" {
+		return indexed, nil
+	}
+
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return indexed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index state: %w", err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var file indexedFile
+		if err := json.Unmarshal([]byte(line), &file); err != nil {
+			if i == len(lines)-1 {
+				// Drop it, so the next entry is not appended to it
+				log.Printf("Warning: ignoring incomplete entry of index state %s", statePath)
+				if err := os.Truncate(statePath, int64(len(data)-len(line))); err != nil {
+					return nil, fmt.Errorf("failed to repair index state: %w", err)
+				}
+				break
+			}
+			return nil, fmt.Errorf("failed to decode index state %s: %w", statePath, err)
+		}
+		indexed[file.Path] = file
+	}
+	return indexed, nil
+}
+
+// saveIndexState appends an indexed file to the state, so saving costs the same
+// however many files were indexed before.
+func saveIndexState(statePath string, file indexedFile) error {
+	if statePath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode index state: %w", err)
+	}
+
+	state, err := os.OpenFile(statePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write index state: %w", err)
+	}
+	if _, err := state.Write(append(data, '\n')); err != nil {
+		state.Close()
+		return fmt.Errorf("failed to write index state: %w", err)
+	}
+	if err := state.Close(); err != nil {
+		return fmt.Errorf("failed to write index state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// writeProject writes count Python files below a new directory and returns it.
+func writeProject(t *testing.T, count int) string {
+	t.Helper()
+	root := t.TempDir()
+	for i := 0; i < count; i++ {
+		code := fmt.Sprintf("def f%d():\n    return %d\n", i, i)
+		if err := os.WriteFile(filepath.Join(root, fmt.Sprintf("m%d.py", i)), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func parseAny(path string) (*types.File, error) {
+	return &types.File{}, nil
+}
+
+// indexInto runs indexProject recording the names of the inserted files, failing
+// the insert after limit files (no limit if negative).
+func indexInto(root, statePath string, limit int) ([]string, error) {
+	var inserted []string
+	_, err := indexProject(root, IndexOptions{StatePath: statePath, Parse: parseAny}, func(file *types.File) error {
+		if limit >= 0 && len(inserted) == limit {
+			return errors.New("interrupted")
+		}
+		inserted = append(inserted, filepath.Base(file.Path))
+		return nil
+	})
+	sort.Strings(inserted)
+	return inserted, err
+}
+
+func TestIndexProjectResumesAfterInterruption(t *testing.T) {
+	root := writeProject(t, 5)
+	statePath := filepath.Join(t.TempDir(), "state.jsonl")
+
+	first, err := indexInto(root, statePath, 2)
+	if err == nil {
+		t.Fatal("interrupted run did not fail")
+	}
+	if len(first) != 2 {
+		t.Fatalf("interrupted run inserted %v, want 2 files", first)
+	}
+
+	second, err := indexInto(root, statePath, -1)
+	if err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+	all := append(append([]string{}, first...), second...)
+	sort.Strings(all)
+	if want := []string{"m0.py", "m1.py", "m2.py", "m3.py", "m4.py"}; !reflect.DeepEqual(all, want) {
+		t.Errorf("runs inserted %v and %v, want every file once", first, second)
+	}
+}
+
+func TestIndexProjectReindexesOnlyEditedFiles(t *testing.T) {
+	root := writeProject(t, 3)
+	statePath := filepath.Join(t.TempDir(), "state.jsonl")
+	if _, err := indexInto(root, statePath, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	// m0.py is touched without being edited, m1.py is edited
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "m0.py"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "m1.py"), []byte("def f1():\n    return -1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inserted, err := indexInto(root, statePath, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"m1.py"}; !reflect.DeepEqual(inserted, want) {
+		t.Errorf("run after edits inserted %v, want %v", inserted, want)
+	}
+
+	indexed, err := loadIndexState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !indexed[filepath.Join(root, "m0.py")].ModTime.Equal(later) {
+		t.Error("state does not record the new modification time of the touched file")
+	}
+}
+
+func TestLoadIndexStateDropsIncompleteEntry(t *testing.T) {
+	root := writeProject(t, 2)
+	statePath := filepath.Join(t.TempDir(), "state.jsonl")
+	if _, err := indexInto(root, statePath, 1); err == nil {
+		t.Fatal("interrupted run did not fail")
+	}
+
+	// An interruption while appending leaves half an entry behind
+	state, err := os.OpenFile(statePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.WriteString(`{"path":"`)
+	state.Close()
+
+	if _, err := indexInto(root, statePath, -1); err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+	indexed, err := loadIndexState(statePath)
+	if err != nil {
+		t.Fatalf("state after resuming: %v", err)
+	}
+	if len(indexed) != 2 {
+		t.Errorf("state holds %d files, want 2", len(indexed))
+	}
+}
@@ -0,0 +1,101 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/fault"
+)
+
+// RetryConfig controls how the operations of a Weaviate instance are retried
+// when they fail transiently, on a 5xx status or a network error such as a
+// reset connection. Requests Weaviate rejects with a 4xx status are never
+// retried. The zero value makes a single attempt without a timeout.
+//
+// Fields:
+//   - MaxAttempts: The most attempts made for an operation, including the first one.
+//   - Backoff: The delay before the first retry, doubled before every further retry.
+//   - Timeout: The time limit of every single attempt; zero means no limit.
+//
+// Retrying CreateObject and AddObjects may create an object twice if Weaviate
+// stored it but the response was lost, unless the objects carry their own IDs.
+type RetryConfig struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	Timeout     time.Duration
+}
+
+// SetRetryConfig sets how the operations of the Weaviate instance are retried.
+func (w *Weaviate) SetRetryConfig(config RetryConfig) {
+	w.retry = config
+}
+
+// withRetry runs the operation with the context of the Weaviate instance until
+// it succeeds, fails with an error that is not transient or runs out of
+// attempts, waiting with exponential backoff between the attempts. Every
+// attempt gets its own context bounded by the timeout of the retry config.
+func withRetry[T any](w *Weaviate, operation func(ctx context.Context) (T, error)) (T, error) {
+	backoff := w.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		result, err := attemptOperation(w.context, w.retry.Timeout, operation)
+		if err == nil || attempt >= w.retry.MaxAttempts || !isTransient(err) || w.context.Err() != nil {
+			return result, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-w.context.Done():
+			return result, err
+		}
+		backoff *= 2
+	}
+}
+
+// withRetryErr is withRetry for operations that return only an error.
+func withRetryErr(w *Weaviate, operation func(ctx context.Context) error) error {
+	_, err := withRetry(w, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, operation(ctx)
+	})
+	return err
+}
+
+// attemptOperation runs a single attempt of the operation, bounded by the
+// timeout if it is positive.
+func attemptOperation[T any](ctx context.Context, timeout time.Duration, operation func(ctx context.Context) (T, error)) (T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return operation(ctx)
+}
+
+// isTransient reports whether the operation that failed with the error may
+// succeed if it is tried again: Weaviate answered with a 5xx status, the
+// connection failed or the attempt ran into its timeout.
+func isTransient(err error) bool {
+	var clientErr *fault.WeaviateClientError
+	if errors.As(err, &clientErr) {
+		if clientErr.IsUnexpectedStatusCode {
+			return clientErr.StatusCode >= 500
+		}
+		if clientErr.DerivedFromError == nil {
+			return false
+		}
+		err = clientErr.DerivedFromError
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,78 @@
+package dao
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// objectServer is a stub of the object creation endpoint of Weaviate answering
+// the first requests with the statuses in failures, and every later one by
+// creating the object with the ID id.
+type objectServer struct {
+	mu       sync.Mutex
+	failures []int
+	id       string
+	requests int
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/objects" {
+		http.NotFound(w, r)
+		return
+	}
+	s.requests++
+	if s.requests <= len(s.failures) {
+		status := s.failures[s.requests-1]
+		http.Error(w, `{"error":[{"message":"`+http.StatusText(status)+`"}]}`, status)
+		return
+	}
+	var object map[string]any
+	json.NewDecoder(r.Body).Decode(&object)
+	object["id"] = s.id
+	json.NewEncoder(w).Encode(object)
+}
+
+func TestCreateObjectRetriesTransientFailures(t *testing.T) {
+	stub := &objectServer{failures: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	w := newStubWeaviate(t, stub)
+	w.SetRetryConfig(RetryConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+
+	if _, err := w.CreateObject("File", map[string]any{"path": "a.py"}); err != nil {
+		t.Fatalf("CreateObject: %v", err)
+	}
+	if stub.requests != 3 {
+		t.Errorf("made %d requests, want 3", stub.requests)
+	}
+}
+
+func TestCreateObjectDoesNotRetryRejectedRequest(t *testing.T) {
+	stub := &objectServer{failures: []int{http.StatusBadRequest}}
+	w := newStubWeaviate(t, stub)
+	w.SetRetryConfig(RetryConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+
+	if _, err := w.CreateObject("File", map[string]any{"path": "a.py"}); err == nil {
+		t.Fatal("CreateObject succeeded, want the error of the rejected request")
+	}
+	if stub.requests != 1 {
+		t.Errorf("made %d requests, want 1", stub.requests)
+	}
+}
+
+func TestCreateObjectGivesUpAfterMaxAttempts(t *testing.T) {
+	stub := &objectServer{failures: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}}
+	w := newStubWeaviate(t, stub)
+	w.SetRetryConfig(RetryConfig{MaxAttempts: 2, Backoff: time.Millisecond})
+
+	if _, err := w.CreateObject("File", map[string]any{"path": "a.py"}); err == nil {
+		t.Fatal("CreateObject succeeded, want the error of the last attempt")
+	}
+	if stub.requests != 2 {
+		t.Errorf("made %d requests, want 2", stub.requests)
+	}
+}
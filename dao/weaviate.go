@@ -9,27 +9,44 @@ package dao
 // - context: For managing request-scoped values and deadlines.
 // - weaviate: The main Weaviate Go client package.
 // - weaviate/data: For data-related operations such as CRUD operations on objects.
+// - weaviate/filters: For building the where filters of GraphQL queries.
 // - weaviate/graphql: For executing GraphQL queries against the Weaviate instance.
 // - weaviate/schema: For managing the schema of the Weaviate instance.
 // - models: For working with Weaviate's data models.
 import (
 	"context"
 	"fmt"
+	"log"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/auth"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/data"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/schema"
 	"github.com/weaviate/weaviate/entities/models"
 )
 
 type Weaviate struct {
-	client  *weaviate.Client
-	context context.Context
+	client    *weaviate.Client
+	context   context.Context
+	retry     RetryConfig
+	fileClass string
 }
 
+// DefaultFileClassName is the Weaviate class indexed files are stored as unless
+// SetFileClassName chooses another one.
+const DefaultFileClassName = "File"
+
+// classNamePattern matches the class names Weaviate accepts.
+var classNamePattern = regexp.MustCompile(`^[A-Z][_0-9A-Za-z]*$`)
+
+// newClient creates the Weaviate client of New and NewWithAuth.
+var newClient = weaviate.NewClient
+
 // New creates a new instance of the Weaviate struct with the provided configuration
 // and context. It initializes a Weaviate client using the given configuration.
 //
@@ -41,7 +58,7 @@ type Weaviate struct {
 //   - *Weaviate: A pointer to the newly created Weaviate instance.
 //   - error: An error if the client initialization fails.
 func New(config weaviate.Config, context context.Context) (*Weaviate, error) {
-	client, err := weaviate.NewClient(config)
+	client, err := newClient(config)
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +66,52 @@ func New(config weaviate.Config, context context.Context) (*Weaviate, error) {
 	return &Weaviate{client: client, context: context}, nil
 }
 
+// NewWithAuth creates a new instance of the Weaviate struct like New, with the
+// client authenticating by the given scheme, as hosted instances such as
+// Weaviate Cloud require. The auth config replaces the AuthConfig of config.
+//
+// Parameters:
+//   - config: The configuration settings required to initialize the Weaviate client.
+//   - authConfig: The authentication scheme, e.g. auth.ApiKey{Value: key} for an API key,
+//     auth.BearerToken for an OIDC token or auth.ClientCredentials for an OIDC client secret.
+//   - context: The context to be used for the Weaviate instance.
+//
+// Returns:
+//   - *Weaviate: A pointer to the newly created Weaviate instance.
+//   - error: An error if the client initialization fails, e.g. because config
+//     also sets a ConnectionClient or the OIDC configuration cannot be fetched.
+func NewWithAuth(config weaviate.Config, authConfig auth.Config, context context.Context) (*Weaviate, error) {
+	config.AuthConfig = authConfig
+	return New(config, context)
+}
+
+// SetFileClassName sets the class indexed files are stored as and looked up in
+// by IndexProject and FileInfoGetter, so that several projects can be indexed
+// into one Weaviate instance, e.g. as "PinguisFile" and "OtherFile". An empty
+// name restores DefaultFileClassName.
+//
+// Parameters:
+//   - className: The name of the class, starting with an uppercase letter and
+//     consisting of letters, digits and underscores, as Weaviate requires.
+//
+// Returns:
+//   - error: An error if the name is not a valid class name.
+func (w *Weaviate) SetFileClassName(className string) error {
+	if className != "" && !classNamePattern.MatchString(className) {
+		return fmt.Errorf("invalid class name %q: must start with an uppercase letter followed by letters, digits or underscores", className)
+	}
+	w.fileClass = className
+	return nil
+}
+
+// FileClassName returns the class indexed files are stored as.
+func (w *Weaviate) FileClassName() string {
+	if w.fileClass == "" {
+		return DefaultFileClassName
+	}
+	return w.fileClass
+}
+
 // GetClient returns the Weaviate client instance associated with the Weaviate object.
 // This client can be used to interact with the Weaviate API.
 func (w *Weaviate) GetClient() *weaviate.Client {
@@ -71,7 +134,9 @@ func (w *Weaviate) GetContext() context.Context {
 // Returns:
 //   - error: An error if the operation fails, or nil if the class is successfully added.
 func (w *Weaviate) AddClass(class *models.Class) error {
-	return w.client.Schema().ClassCreator().WithClass(class).Do(w.context)
+	return withRetryErr(w, func(ctx context.Context) error {
+		return w.client.Schema().ClassCreator().WithClass(class).Do(ctx)
+	})
 }
 
 // GetClassByName retrieves a class definition from the Weaviate schema by its name.
@@ -83,14 +148,18 @@ func (w *Weaviate) AddClass(class *models.Class) error {
 //   - A pointer to the models.Class object representing the class definition.
 //   - An error if the class cannot be retrieved or if an issue occurs during the request.
 func (w *Weaviate) GetClassByName(className string) (*models.Class, error) {
-	return w.client.Schema().ClassGetter().WithClassName(className).Do(w.context)
+	return withRetry(w, func(ctx context.Context) (*models.Class, error) {
+		return w.client.Schema().ClassGetter().WithClassName(className).Do(ctx)
+	})
 }
 
 // GetSchema retrieves the schema from the Weaviate client.
 // It returns a pointer to a schema.Dump object containing the schema
 // and an error if the operation fails.
 func (w *Weaviate) GetSchema() (*schema.Dump, error) {
-	return w.client.Schema().Getter().Do(w.context)
+	return withRetry(w, func(ctx context.Context) (*schema.Dump, error) {
+		return w.client.Schema().Getter().Do(ctx)
+	})
 }
 
 // DeleteClass deletes a class from the Weaviate schema.
@@ -101,7 +170,68 @@ func (w *Weaviate) GetSchema() (*schema.Dump, error) {
 // Returns:
 //   - An error if the deletion fails, or nil if the operation is successful.
 func (w *Weaviate) AddProperties(className string, property *models.Property) error {
-	return w.client.Schema().PropertyCreator().WithClassName(className).WithProperty(property).Do(w.context)
+	return withRetryErr(w, func(ctx context.Context) error {
+		return w.client.Schema().PropertyCreator().WithClassName(className).WithProperty(property).Do(ctx)
+	})
+}
+
+// MigrateClass brings the schema of a class up to date with the given class
+// definition: it creates the class if it does not exist yet, and otherwise adds
+// the properties of the definition the existing class lacks, such as the fields
+// added to types.File after the class was created. Weaviate does not allow
+// adding nested properties to an existing object property through the schema, so
+// those are left to its auto-schema, which adds them when the first object
+// holding them is inserted; they are logged so a schema without auto-schema can
+// be migrated by hand.
+//
+// Parameters:
+//   - class: The class definition, e.g. created by ToNamedClass.
+//
+// Returns:
+//   - error: An error if the class cannot be created or a property cannot be added.
+func (w *Weaviate) MigrateClass(class *models.Class) error {
+	existing, err := w.GetClassByName(class.Class)
+	if err != nil || existing == nil {
+		return w.AddClass(class)
+	}
+
+	existingProperties := make(map[string]*models.Property, len(existing.Properties))
+	for _, property := range existing.Properties {
+		existingProperties[strings.ToLower(property.Name)] = property
+	}
+	for _, property := range class.Properties {
+		current, ok := existingProperties[strings.ToLower(property.Name)]
+		if !ok {
+			if err := w.AddProperties(class.Class, property); err != nil {
+				return fmt.Errorf("failed to add property %s to class %s: %w", property.Name, class.Class, err)
+			}
+			continue
+		}
+		if missing := missingNestedProperties(current.NestedProperties, property.NestedProperties, property.Name); len(missing) > 0 {
+			log.Printf("class %s lacks the nested properties %s, they are added when objects holding them are inserted if auto-schema is enabled",
+				class.Class, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// missingNestedProperties returns the dotted paths, below prefix, of the nested
+// properties of wanted that existing lacks.
+func missingNestedProperties(existing, wanted []*models.NestedProperty, prefix string) []string {
+	existingProperties := make(map[string]*models.NestedProperty, len(existing))
+	for _, property := range existing {
+		existingProperties[strings.ToLower(property.Name)] = property
+	}
+	var missing []string
+	for _, property := range wanted {
+		path := prefix + "." + property.Name
+		if current, ok := existingProperties[strings.ToLower(property.Name)]; ok {
+			missing = append(missing, missingNestedProperties(current.NestedProperties, property.NestedProperties, path)...)
+		} else {
+			missing = append(missing, path)
+		}
+	}
+	return missing
 }
 
 // AddObjects adds multiple objects to the Weaviate database in a single batch operation.
@@ -115,7 +245,9 @@ func (w *Weaviate) AddProperties(className string, property *models.Property) er
 //   - []models.ObjectsGetResponse: A slice containing the responses for the added objects.
 //   - error: An error if the batch operation fails, otherwise nil.
 func (w *Weaviate) AddObjects(objects ...*models.Object) ([]models.ObjectsGetResponse, error) {
-	return w.client.Batch().ObjectsBatcher().WithObjects(objects...).Do(w.context)
+	return withRetry(w, func(ctx context.Context) ([]models.ObjectsGetResponse, error) {
+		return w.client.Batch().ObjectsBatcher().WithObjects(objects...).Do(ctx)
+	})
 }
 
 // CreateObject creates a new object in Weaviate with the specified class name and properties.
@@ -128,7 +260,31 @@ func (w *Weaviate) AddObjects(objects ...*models.Object) ([]models.ObjectsGetRes
 //   - *data.ObjectWrapper: A wrapper containing the created object data.
 //   - error: An error if the object creation fails.
 func (w *Weaviate) CreateObject(className string, properties map[string]any) (*data.ObjectWrapper, error) {
-	return w.client.Data().Creator().WithClassName(className).WithProperties(properties).Do(w.context)
+	return withRetry(w, func(ctx context.Context) (*data.ObjectWrapper, error) {
+		return w.client.Data().Creator().WithClassName(className).WithProperties(properties).Do(ctx)
+	})
+}
+
+// CreateObjectReturningID creates a new object in Weaviate like CreateObject and
+// returns just the UUID Weaviate assigned to it, as needed to update or delete
+// the object later.
+//
+// Parameters:
+//   - className: The name of the class to which the object belongs.
+//   - properties: A map containing the properties of the object to be created.
+//
+// Returns:
+//   - string: The UUID of the created object.
+//   - error: An error if the object creation fails or the response carries no object ID.
+func (w *Weaviate) CreateObjectReturningID(className string, properties map[string]any) (string, error) {
+	wrapper, err := w.CreateObject(className, properties)
+	if err != nil {
+		return "", err
+	}
+	if wrapper == nil || wrapper.Object == nil || wrapper.Object.ID == "" {
+		return "", fmt.Errorf("created %s object has no ID", className)
+	}
+	return wrapper.Object.ID.String(), nil
 }
 
 // GetObjectsByClass retrieves objects from the Weaviate database based on the specified class name.
@@ -142,7 +298,37 @@ func (w *Weaviate) CreateObject(className string, properties map[string]any) (*d
 //   - *models.GraphQLResponse: The response containing the queried objects.
 //   - error: An error if the query fails or encounters an issue.
 func (w *Weaviate) GetObjectsByClass(className string, fields ...graphql.Field) (*models.GraphQLResponse, error) {
-	return w.client.GraphQL().Get().WithClassName(className).WithFields(fields...).Do(w.context)
+	return withRetry(w, func(ctx context.Context) (*models.GraphQLResponse, error) {
+		return w.client.GraphQL().Get().WithClassName(className).WithFields(fields...).Do(ctx)
+	})
+}
+
+// QueryObjects retrieves the objects of the specified class matching the where
+// filter. Unlike an exact match on a single property, the filter can use any
+// operator Weaviate supports, e.g. filters.Like with "pinguis/worker/*" for all
+// files of a module or filters.GreaterThan for a range, and can combine several
+// conditions with filters.And and filters.Or.
+//
+// Parameters:
+//   - className: The name of the class to query objects from.
+//   - where: The filter the objects must match (all objects if nil).
+//   - limit: The maximum number of objects returned (Weaviate's default limit if not positive).
+//   - fields: The GraphQL fields to include in the result.
+//
+// Returns:
+//   - *models.GraphQLResponse: The response containing the matching objects.
+//   - error: An error if the query fails or encounters an issue.
+func (w *Weaviate) QueryObjects(className string, where *filters.WhereBuilder, limit int, fields ...graphql.Field) (*models.GraphQLResponse, error) {
+	return withRetry(w, func(ctx context.Context) (*models.GraphQLResponse, error) {
+		query := w.client.GraphQL().Get().WithClassName(className).WithFields(fields...)
+		if where != nil {
+			query = query.WithWhere(where)
+		}
+		if limit > 0 {
+			query = query.WithLimit(limit)
+		}
+		return query.Do(ctx)
+	})
 }
 
 // GetObjectByID retrieves objects of a specified class by their unique ID from the Weaviate database.
@@ -155,7 +341,9 @@ func (w *Weaviate) GetObjectsByClass(className string, fields ...graphql.Field)
 //   - []*models.Object: A slice of objects matching the specified class and ID.
 //   - error: An error if the retrieval operation fails.
 func (w *Weaviate) GetObjectByID(className string, id string) ([]*models.Object, error) {
-	return w.client.Data().ObjectsGetter().WithClassName(className).WithID(id).Do(w.context)
+	return withRetry(w, func(ctx context.Context) ([]*models.Object, error) {
+		return w.client.Data().ObjectsGetter().WithClassName(className).WithID(id).Do(ctx)
+	})
 }
 
 // UpdateObject updates an object in Weaviate with the specified class name, ID, and properties.
@@ -169,7 +357,9 @@ func (w *Weaviate) GetObjectByID(className string, id string) ([]*models.Object,
 // Returns:
 //   - error: An error if the update operation fails, otherwise nil.
 func (w *Weaviate) UpdateObject(className string, id string, properties map[string]any) error {
-	return w.client.Data().Updater().WithMerge().WithID(id).WithClassName(className).WithProperties(properties).Do(w.context)
+	return withRetryErr(w, func(ctx context.Context) error {
+		return w.client.Data().Updater().WithMerge().WithID(id).WithClassName(className).WithProperties(properties).Do(ctx)
+	})
 }
 
 // ReplaceObject replaces an existing object in Weaviate with the specified class name, ID, and properties.
@@ -182,7 +372,9 @@ func (w *Weaviate) UpdateObject(className string, id string, properties map[stri
 // Returns:
 //   - error: An error if the operation fails, or nil if the replacement is successful.
 func (w *Weaviate) ReplaceObject(className string, id string, properties map[string]any) error {
-	return w.client.Data().Updater().WithID(id).WithClassName(className).WithProperties(properties).Do(w.context)
+	return withRetryErr(w, func(ctx context.Context) error {
+		return w.client.Data().Updater().WithID(id).WithClassName(className).WithProperties(properties).Do(ctx)
+	})
 }
 
 // DeleteObject deletes an object from the Weaviate database based on the specified class name and ID.
@@ -194,7 +386,33 @@ func (w *Weaviate) ReplaceObject(className string, id string, properties map[str
 // Returns:
 //   - error: An error if the deletion fails, or nil if the operation is successful.
 func (w *Weaviate) DeleteObject(className string, id string) error {
-	return w.client.Data().Deleter().WithID(id).WithClassName(className).Do(w.context)
+	return withRetryErr(w, func(ctx context.Context) error {
+		return w.client.Data().Deleter().WithID(id).WithClassName(className).Do(ctx)
+	})
+}
+
+// DeleteClass deletes a class from the Weaviate database together with all of its objects.
+//
+// Parameters:
+//   - className: The name of the class to be deleted.
+//
+// Returns:
+//   - error: An error if the deletion fails, or nil if the operation is successful.
+func (w *Weaviate) DeleteClass(className string) error {
+	return withRetryErr(w, func(ctx context.Context) error {
+		return w.client.Schema().ClassDeleter().WithClassName(className).Do(ctx)
+	})
+}
+
+// ToNamedClass converts a given object to a *models.Class representation like
+// ToClass, named className instead of after the struct, e.g. to store the same
+// struct as a class per project. An empty className keeps the struct name.
+func ToNamedClass(object any, className string) *models.Class {
+	class := ToClass(object)
+	if class != nil && className != "" {
+		class.Class = className
+	}
+	return class
 }
 
 // ToClass converts a given object to a *models.Class representation.
@@ -216,6 +434,7 @@ func (w *Weaviate) DeleteObject(className string, id string) error {
 //   - string -> "string"
 //   - int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64 -> "int"
 //   - float32, float64 -> "number"
+//   - bool -> "boolean"
 //   - Fields with unsupported types or unexported fields are ignored.
 func ToClass(object any) *models.Class {
     t := reflect.TypeOf(object)
@@ -286,6 +505,8 @@ func analyzeFieldType(fieldType reflect.Type, propName string) *models.Property
         dataType = "int"
     case reflect.Float32, reflect.Float64:
         dataType = "number"
+    case reflect.Bool:
+        dataType = "boolean"
     case reflect.Struct:
         dataType = "object"
         
@@ -356,6 +577,8 @@ func createNestedProperty(fieldType reflect.Type, propName string) *models.Neste
         dataType = "int"
     case reflect.Float32, reflect.Float64:
         dataType = "number"
+    case reflect.Bool:
+        dataType = "boolean"
     case reflect.Struct:
         dataType = "object"
         
@@ -0,0 +1,439 @@
+package dao
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/types"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/auth"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// schemaServer is a stub of the schema endpoints of Weaviate holding a single
+// class, recording the properties added to it.
+type schemaServer struct {
+	mu    sync.Mutex
+	class *models.Class
+	added []string
+}
+
+func (s *schemaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/schema/"):
+		if s.class == nil {
+			http.Error(w, `{"error":[{"message":"not found"}]}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(s.class)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/schema":
+		var class models.Class
+		json.NewDecoder(r.Body).Decode(&class)
+		s.class = &class
+		json.NewEncoder(w).Encode(class)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/properties"):
+		var property models.Property
+		json.NewDecoder(r.Body).Decode(&property)
+		s.added = append(s.added, property.Name)
+		s.class.Properties = append(s.class.Properties, &property)
+		json.NewEncoder(w).Encode(property)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newStubWeaviate(t *testing.T, handler http.Handler) *Weaviate {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	w, err := New(weaviate.Config{Host: strings.TrimPrefix(server.URL, "http://"), Scheme: "http"}, context.Background())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return w
+}
+
+type migratedFile struct {
+	Path    string   `json:"path"`
+	Imports []string `json:"imports"`
+	Doc     string   `json:"doc"`
+}
+
+func TestMigrateClassAddsMissingProperties(t *testing.T) {
+	stub := &schemaServer{class: &models.Class{
+		Class: "File",
+		Properties: []*models.Property{
+			{Name: "path", DataType: []string{"text"}},
+		},
+	}}
+	w := newStubWeaviate(t, stub)
+
+	if err := w.MigrateClass(ToNamedClass(migratedFile{}, "File")); err != nil {
+		t.Fatalf("MigrateClass: %v", err)
+	}
+
+	if got := strings.Join(stub.added, ","); got != "imports,doc" {
+		t.Errorf("added properties = %q, want %q", got, "imports,doc")
+	}
+}
+
+func TestMigrateClassCreatesMissingClass(t *testing.T) {
+	stub := &schemaServer{}
+	w := newStubWeaviate(t, stub)
+
+	if err := w.MigrateClass(ToNamedClass(migratedFile{}, "PinguisFile")); err != nil {
+		t.Fatalf("MigrateClass: %v", err)
+	}
+
+	if stub.class == nil || stub.class.Class != "PinguisFile" {
+		t.Fatalf("class = %+v, want PinguisFile to be created", stub.class)
+	}
+	if len(stub.added) != 0 {
+		t.Errorf("added properties = %v, want none", stub.added)
+	}
+}
+
+func TestToClassMapsBoolFieldsToBoolean(t *testing.T) {
+	class := ToClass(types.File{})
+
+	var classes *models.Property
+	for _, property := range class.Properties {
+		if property.Name == "classes" {
+			classes = property
+		}
+	}
+	if classes == nil {
+		t.Fatal("File class has no classes property")
+	}
+	var methods *models.NestedProperty
+	for _, property := range classes.NestedProperties {
+		if property.Name == "methods" {
+			methods = property
+		}
+	}
+	if methods == nil {
+		t.Fatal("classes property has no methods")
+	}
+
+	dataTypes := map[string]string{}
+	for _, property := range methods.NestedProperties {
+		dataTypes[property.Name] = strings.Join(property.DataType, ",")
+	}
+	for _, name := range []string{"is_static", "is_abstract"} {
+		if dataTypes[name] != "boolean" {
+			t.Errorf("methods.%s data type = %q, want boolean", name, dataTypes[name])
+		}
+	}
+}
+
+func TestNewWithAuthPassesAuthConfigToClient(t *testing.T) {
+	var passed weaviate.Config
+	newClient = func(config weaviate.Config) (*weaviate.Client, error) {
+		passed = config
+		return &weaviate.Client{}, nil
+	}
+	t.Cleanup(func() { newClient = weaviate.NewClient })
+
+	key := auth.ApiKey{Value: "secret"}
+	config := weaviate.Config{Host: "example.weaviate.cloud", Scheme: "https", AuthConfig: auth.BearerToken{AccessToken: "old"}}
+	if _, err := NewWithAuth(config, key, context.Background()); err != nil {
+		t.Fatalf("NewWithAuth: %v", err)
+	}
+
+	if passed.AuthConfig != key {
+		t.Errorf("client auth config = %#v, want %#v", passed.AuthConfig, key)
+	}
+	if passed.Host != config.Host || passed.Scheme != config.Scheme {
+		t.Errorf("client config = %+v, want the host and scheme of %+v", passed, config)
+	}
+}
+
+func TestCreateObjectReturningIDReturnsObjectID(t *testing.T) {
+	const id = "5f0c1a5e-8d4b-4c7e-9a41-3f0e2b6d7c11"
+	w := newStubWeaviate(t, &objectServer{id: id})
+
+	wrapper, err := w.CreateObject("File", map[string]any{"path": "a.py"})
+	if err != nil {
+		t.Fatalf("CreateObject: %v", err)
+	}
+	got, err := w.CreateObjectReturningID("File", map[string]any{"path": "a.py"})
+	if err != nil {
+		t.Fatalf("CreateObjectReturningID: %v", err)
+	}
+	if got != id || got != wrapper.Object.ID.String() {
+		t.Errorf("CreateObjectReturningID() = %q, want the ID %q of the object", got, wrapper.Object.ID)
+	}
+}
+
+func TestCreateObjectReturningIDFailsWithoutID(t *testing.T) {
+	w := newStubWeaviate(t, &objectServer{})
+	if id, err := w.CreateObjectReturningID("File", map[string]any{"path": "a.py"}); err == nil {
+		t.Errorf("CreateObjectReturningID() = %q, want an error for the object without ID", id)
+	}
+}
+
+// queryServer is a stub of the GraphQL endpoint of Weaviate holding objects of
+// a single class, filtering them by a single Equal or Like condition on a text
+// property like Weaviate does.
+type queryServer struct {
+	className string
+	objects   []map[string]any
+}
+
+var (
+	whereClause = regexp.MustCompile(`operator: (\w+) path: \["(\w+)"\] valueText: "([^"]*)"`)
+	limitClause = regexp.MustCompile(`limit: (\d+)`)
+)
+
+func (s *queryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/graphql" {
+		http.NotFound(w, r)
+		return
+	}
+	var body struct {
+		Query string `json:"query"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	matches := func(object map[string]any) bool { return true }
+	if where := whereClause.FindStringSubmatch(body.Query); where != nil {
+		value := where[3]
+		if where[1] == "Like" {
+			value = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(value))
+		} else {
+			value = regexp.QuoteMeta(value)
+		}
+		pattern := regexp.MustCompile("^" + value + "$")
+		matches = func(object map[string]any) bool {
+			text, _ := object[where[2]].(string)
+			return pattern.MatchString(text)
+		}
+	}
+	limit := len(s.objects)
+	if match := limitClause.FindStringSubmatch(body.Query); match != nil {
+		limit, _ = strconv.Atoi(match[1])
+	}
+
+	found := []map[string]any{}
+	for _, object := range s.objects {
+		if len(found) < limit && matches(object) {
+			found = append(found, object)
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"Get": map[string]any{s.className: found}}})
+}
+
+// queriedPaths returns the paths of the objects of the class in the response.
+func queriedPaths(t *testing.T, res *models.GraphQLResponse, className string) []string {
+	t.Helper()
+	if len(res.Errors) > 0 {
+		t.Fatalf("query failed: %v", res.Errors[0].Message)
+	}
+	objects, _ := res.Data["Get"].(map[string]any)[className].([]any)
+	var paths []string
+	for _, object := range objects {
+		paths = append(paths, object.(map[string]any)["path"].(string))
+	}
+	return paths
+}
+
+func TestQueryObjectsWithLikeFilter(t *testing.T) {
+	stub := &queryServer{className: "File", objects: []map[string]any{
+		{"path": "pinguis/worker/deep_worker.go"},
+		{"path": "pinguis/dao/weaviate.go"},
+		{"path": "pinguis/worker/flaky.go"},
+		{"path": "pinguis/worker/sub/helper.go"},
+		{"path": "other/worker/main.go"},
+	}}
+	w := newStubWeaviate(t, stub)
+	where := filters.Where().WithPath([]string{"path"}).WithOperator(filters.Like).WithValueText("pinguis/worker/*")
+
+	res, err := w.QueryObjects("File", where, 0, graphql.Field{Name: "path"})
+	if err != nil {
+		t.Fatalf("QueryObjects: %v", err)
+	}
+	want := []string{"pinguis/worker/deep_worker.go", "pinguis/worker/flaky.go", "pinguis/worker/sub/helper.go"}
+	if got := queriedPaths(t, res, "File"); !reflect.DeepEqual(got, want) {
+		t.Errorf("queried %v, want %v", got, want)
+	}
+
+	res, err = w.QueryObjects("File", where, 2, graphql.Field{Name: "path"})
+	if err != nil {
+		t.Fatalf("QueryObjects: %v", err)
+	}
+	if got := queriedPaths(t, res, "File"); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("queried %v with a limit of 2, want %v", got, want[:2])
+	}
+}
+
+// indexServer is a stub of the schema, object and GraphQL endpoints of Weaviate
+// IndexProject and FileInfoGetter use, recording the class every request names.
+type indexServer struct {
+	mu      sync.Mutex
+	queries queryServer
+	classes map[string][]string // Class names by endpoint
+}
+
+var queriedClass = regexp.MustCompile(`\{Get \{(\w+)`)
+
+func (s *indexServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/schema/"):
+		s.classes["schema get"] = append(s.classes["schema get"], strings.TrimPrefix(r.URL.Path, "/v1/schema/"))
+		http.Error(w, `{"error":[{"message":"not found"}]}`, http.StatusNotFound)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/schema":
+		var class models.Class
+		json.NewDecoder(r.Body).Decode(&class)
+		s.classes["schema create"] = append(s.classes["schema create"], class.Class)
+		json.NewEncoder(w).Encode(class)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/objects":
+		var object struct {
+			Class      string         `json:"class"`
+			Properties map[string]any `json:"properties"`
+		}
+		json.NewDecoder(r.Body).Decode(&object)
+		s.classes["insert"] = append(s.classes["insert"], object.Class)
+		s.queries.objects = append(s.queries.objects, object.Properties)
+		json.NewEncoder(w).Encode(object)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/graphql":
+		body, _ := io.ReadAll(r.Body)
+		var query struct {
+			Query string `json:"query"`
+		}
+		json.Unmarshal(body, &query)
+		if match := queriedClass.FindStringSubmatch(query.Query); match != nil {
+			s.classes["query"] = append(s.classes["query"], match[1])
+			s.queries.className = match[1]
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		s.queries.ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestCustomFileClassNameIsUsedThroughout(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "calc.py")
+	if err := os.WriteFile(source, []byte("def add(a, b):\n    return a + b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stub := &indexServer{classes: map[string][]string{}}
+	w := newStubWeaviate(t, stub)
+	if err := w.SetFileClassName("PinguisFile"); err != nil {
+		t.Fatalf("SetFileClassName: %v", err)
+	}
+
+	parse := func(path string) (*types.File, error) {
+		return &types.File{Functions: []types.Function{{Name: "add"}}}, nil
+	}
+	if _, err := w.IndexProject(root, IndexOptions{Parse: parse}); err != nil {
+		t.Fatalf("IndexProject: %v", err)
+	}
+	file, err := FileInfoGetter(w, "", source)
+	if err != nil {
+		t.Fatalf("FileInfoGetter: %v", err)
+	}
+	if file.Path != source || len(file.Functions) != 1 || file.Functions[0].Name != "add" {
+		t.Errorf("FileInfoGetter() = %+v, want the indexed file", file)
+	}
+
+	for _, endpoint := range []string{"schema get", "schema create", "insert", "query"} {
+		classes := stub.classes[endpoint]
+		if len(classes) == 0 {
+			t.Errorf("no %s request", endpoint)
+		}
+		for _, class := range classes {
+			if class != "PinguisFile" {
+				t.Errorf("%s request for class %s, want PinguisFile", endpoint, class)
+			}
+		}
+	}
+}
+
+func TestSetFileClassNameRejectsInvalidName(t *testing.T) {
+	w := &Weaviate{}
+	for _, name := range []string{"file", "My-File", "1File"} {
+		if err := w.SetFileClassName(name); err == nil {
+			t.Errorf("SetFileClassName(%q) succeeded, want an error", name)
+		}
+	}
+	if w.FileClassName() != DefaultFileClassName {
+		t.Errorf("FileClassName() = %s after invalid names, want %s", w.FileClassName(), DefaultFileClassName)
+	}
+}
+
+// propertyNamePattern matches the property names Weaviate accepts.
+var propertyNamePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// illegalPropertyNames returns the names of the properties, nested ones
+// included, that Weaviate does not accept.
+func illegalPropertyNames(names []string, nested []*models.NestedProperty) []string {
+	var illegal []string
+	for _, name := range names {
+		if !propertyNamePattern.MatchString(name) {
+			illegal = append(illegal, name)
+		}
+	}
+	for _, property := range nested {
+		illegal = append(illegal, illegalPropertyNames([]string{property.Name}, property.NestedProperties)...)
+	}
+	return illegal
+}
+
+func TestFilePropertyNamesAreLegal(t *testing.T) {
+	class := ToClass(types.File{})
+	for _, property := range class.Properties {
+		if illegal := illegalPropertyNames([]string{property.Name}, property.NestedProperties); len(illegal) > 0 {
+			t.Errorf("property %s has names Weaviate rejects: %v", property.Name, illegal)
+		}
+	}
+}
+
+func TestFunctionRoundTripsThroughProperties(t *testing.T) {
+	function := types.Function{
+		Name:        "divide",
+		Parameters:  []types.Parameter{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+		ReturnTypes: []string{"int", "error"},
+		Body:        "return a / b, nil",
+		Doc:         "Divides a by b.",
+	}
+
+	properties := ToProperties(function)
+	if _, ok := properties["return_types"]; !ok {
+		t.Fatalf("properties = %v, want the return types as return_types", properties)
+	}
+	// Weaviate returns the properties as JSON
+	data, err := json.Marshal(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read types.Function
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, function) {
+		t.Errorf("read function %+v, want %+v", read, function)
+	}
+}
@@ -1,15 +1,20 @@
 package dependency
 
 import (
+	"container/list"
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 
 	"github.com/Marksagittarius/pinguis/dao"
 	"github.com/Marksagittarius/pinguis/scripts/java"
+	"github.com/Marksagittarius/pinguis/scripts/kotlin"
 	"github.com/Marksagittarius/pinguis/scripts/python"
 	"github.com/Marksagittarius/pinguis/types"
 
@@ -44,12 +49,14 @@ type DependencyGraph struct {
 	FileNodes    map[string]*FileNode `json:"file_nodes"`
 }
 
-// DependencyAnalyzer is the interface that defines dependency analysis operations
+// DependencyAnalyzer is the interface that defines dependency analysis operations.
+// An analyzer sees a single file at a time; which files depend on a file takes
+// the analysis of the whole project and is answered by
+// DependencyAnalysisManager.GetFileDependents.
 type DependencyAnalyzer interface {
 	AnalyzeFile(filePath string) ([]Dependency, error)
 	AnalyzeDirectory(dirPath string) (*DependencyGraph, error)
 	GetDependencies(filePath string) ([]Dependency, error)
-	GetDependents(filePath string) ([]Dependency, error)
 }
 
 // AnalyzerFactory creates appropriate analyzers based on file type
@@ -57,17 +64,72 @@ type AnalyzerFactory interface {
 	CreateAnalyzer(filePath string) (DependencyAnalyzer, error)
 }
 
-// LanguageSpecificAnalyzer provides common functionality for language-specific analyzers
+// DependencyWeights holds the weight assigned to each type of dependency.
+// The weights rank dependencies, e.g. when selecting prompt context.
+type DependencyWeights struct {
+	Import     float64 `json:"import"`
+	Uses       float64 `json:"uses"`
+	Extends    float64 `json:"extends"`
+	Implements float64 `json:"implements"`
+	References float64 `json:"references"`
+	Stdlib     float64 `json:"stdlib"`
+}
+
+// DefaultDependencyWeights returns the weights used when none are configured
+func DefaultDependencyWeights() DependencyWeights {
+	return DependencyWeights{
+		Import:     1.0,
+		Uses:       0.7, // Lower weight for usage vs import
+		Extends:    0.9, // High weight for inheritance
+		Implements: 0.9,
+		References: 0.5,
+		Stdlib:     0.3, // Low weight for imports of the standard library
+	}
+}
+
+// LanguageSpecificAnalyzer provides common functionality for language-specific analyzers.
+// With IgnoreStdlib set, imports of the language's standard library produce no
+// dependencies; it applies to Python and Go, the languages whose imports are analyzed.
 type LanguageSpecificAnalyzer struct {
-	Cache    *DependencyCache
-	FileTree *FileTree
+	Cache        *DependencyCache
+	FileTree     *FileTree
+	Weights      *DependencyWeights
+	IgnoreStdlib bool
+}
+
+// weights returns the configured dependency weights, or the defaults if none are set
+func (a *LanguageSpecificAnalyzer) weights() DependencyWeights {
+	if a.Weights == nil {
+		return DefaultDependencyWeights()
+	}
+	return *a.Weights
 }
 
-// DependencyCache caches the results of dependency analysis
+// DependencyCache caches the results of dependency analysis. With a maximum
+// number of entries set, it keeps the dependencies of at most that many files
+// and evicts the least recently used file beyond that; by default it is
+// unbounded. The dependencies are also persisted as Dependency objects in
+// Weaviate, so files evicted from memory or analyzed by an earlier run are
+// loaded from there instead of being analyzed again.
 type DependencyCache struct {
 	weaviateClient *dao.Weaviate
-	cachedDeps     map[string][]Dependency
-	mutex          sync.RWMutex
+	cachedDeps     map[string]*list.Element
+	order          *list.List // Least recently used file at the back
+	maxEntries     int
+	mutex          sync.Mutex
+
+	pending      map[string]*dependencyCacheEntry // Stored but not yet persisted
+	schemaReady  bool
+	persistMutex sync.Mutex // Serializes the writes to Weaviate
+}
+
+// dependencyCacheEntry is the cached dependencies of a file. Entries to be
+// persisted also hold the hash of the content of the file they were analyzed
+// from, so that they are not loaded again once the file has changed.
+type dependencyCacheEntry struct {
+	filePath    string
+	deps        []Dependency
+	contentHash string
 }
 
 // NewDependencyCache creates a new dependency cache
@@ -79,31 +141,154 @@ func NewDependencyCache(weaviateConfig weaviate.Config) (*DependencyCache, error
 
 	return &DependencyCache{
 		weaviateClient: weaviateClient,
-		cachedDeps:     make(map[string][]Dependency),
+		cachedDeps:     make(map[string]*list.Element),
+		order:          list.New(),
+		pending:        make(map[string]*dependencyCacheEntry),
 	}, nil
 }
 
-// Get returns cached dependencies for a file
+// SetMaxEntries bounds the number of files the cache keeps, evicting the least
+// recently used files beyond it right away. A bound of 0 or less removes it.
+func (dc *DependencyCache) SetMaxEntries(maxEntries int) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	dc.maxEntries = maxEntries
+	dc.evict()
+}
+
+// Get returns cached dependencies for a file and marks it as recently used.
+// Files missing from memory are looked up in Weaviate and kept in memory if
+// found there with the hash of the file's current content.
 func (dc *DependencyCache) Get(filePath string) ([]Dependency, bool) {
-	dc.mutex.RLock()
-	defer dc.mutex.RUnlock()
+	dc.mutex.Lock()
+	if element, ok := dc.cachedDeps[filePath]; ok {
+		dc.order.MoveToFront(element)
+		dc.mutex.Unlock()
+		return element.Value.(*dependencyCacheEntry).deps, true
+	}
+	dc.mutex.Unlock()
+
+	deps, err := dc.load(filePath)
+	if err != nil {
+		log.Printf("Failed to load the dependencies of %s from Weaviate: %v", filePath, err)
+		return nil, false
+	}
+	if len(deps) == 0 {
+		return nil, false
+	}
 
-	deps, ok := dc.cachedDeps[filePath]
-	return deps, ok
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	if element, ok := dc.cachedDeps[filePath]; ok {
+		// Stored while loading, the stored dependencies are newer
+		dc.order.MoveToFront(element)
+		return element.Value.(*dependencyCacheEntry).deps, true
+	}
+	dc.insert(filePath, deps)
+	return deps, true
 }
 
-// Store caches dependencies for a file
+// Store caches dependencies for a file as the most recently used one and
+// upserts them into Weaviate, together with the hash of the file's current
+// content. If they cannot be persisted, the failure is logged and they are kept
+// pending for the next Flush. Dependencies of a file that cannot be read are
+// only cached in memory.
 func (dc *DependencyCache) Store(filePath string, deps []Dependency) {
+	contentHash, hashErr := fileContentHash(filePath)
+
 	dc.mutex.Lock()
-	defer dc.mutex.Unlock()
+	dc.insert(filePath, deps)
+	if dc.weaviateClient == nil || hashErr != nil {
+		dc.mutex.Unlock()
+		return
+	}
+	entry := &dependencyCacheEntry{filePath: filePath, deps: deps, contentHash: contentHash}
+	dc.pending[filePath] = entry
+	dc.mutex.Unlock()
+
+	dc.persistMutex.Lock()
+	defer dc.persistMutex.Unlock()
 
-	dc.cachedDeps[filePath] = deps
+	if err := dc.persist([]*dependencyCacheEntry{entry}); err != nil {
+		log.Printf("Failed to persist the dependencies of %s to Weaviate: %v", filePath, err)
+		return
+	}
+	dc.settle([]*dependencyCacheEntry{entry})
+}
+
+// insert caches dependencies for a file as the most recently used one. The
+// caller must hold the mutex.
+func (dc *DependencyCache) insert(filePath string, deps []Dependency) {
+	if element, ok := dc.cachedDeps[filePath]; ok {
+		element.Value.(*dependencyCacheEntry).deps = deps
+		dc.order.MoveToFront(element)
+		return
+	}
+	dc.cachedDeps[filePath] = dc.order.PushFront(&dependencyCacheEntry{filePath: filePath, deps: deps})
+	dc.evict()
+}
+
+// evict removes the least recently used files beyond the maximum number of
+// entries. The caller must hold the mutex.
+func (dc *DependencyCache) evict() {
+	if dc.maxEntries <= 0 {
+		return
+	}
+	for dc.order.Len() > dc.maxEntries {
+		oldest := dc.order.Back()
+		dc.order.Remove(oldest)
+		delete(dc.cachedDeps, oldest.Value.(*dependencyCacheEntry).filePath)
+	}
+}
+
+// Invalidate removes the cached dependencies of a file, from memory and from
+// Weaviate, so that the next analysis of the file runs again
+func (dc *DependencyCache) Invalidate(filePath string) {
+	dc.mutex.Lock()
+	if element, ok := dc.cachedDeps[filePath]; ok {
+		dc.order.Remove(element)
+		delete(dc.cachedDeps, filePath)
+	}
+	delete(dc.pending, filePath)
+	dc.mutex.Unlock()
+
+	dc.persistMutex.Lock()
+	defer dc.persistMutex.Unlock()
+	if err := dc.deletePersisted(filePath); err != nil {
+		log.Printf("Failed to delete the dependencies of %s from Weaviate: %v", filePath, err)
+	}
 }
 
-// DefaultAnalyzerFactory creates language-specific analyzers based on file extension
+// Clear removes the cached dependencies of every file, from memory and from
+// Weaviate
+func (dc *DependencyCache) Clear() {
+	dc.mutex.Lock()
+	dc.cachedDeps = make(map[string]*list.Element)
+	dc.order = list.New()
+	dc.pending = make(map[string]*dependencyCacheEntry)
+	dc.mutex.Unlock()
+
+	dc.persistMutex.Lock()
+	defer dc.persistMutex.Unlock()
+	if err := dc.deleteAllPersisted(); err != nil {
+		log.Printf("Failed to delete the persisted dependencies from Weaviate: %v", err)
+	}
+}
+
+// DefaultAnalyzerFactory creates language-specific analyzers based on file extension.
+// Symbols, if set, is shared with the analyzers resolving symbol references,
+// Weights, if set, overrides the default dependency weights of every analyzer, and
+// IgnoreStdlib is passed on to every analyzer. NewJavaParser, if set, creates
+// the parser of every Java analyzer in place of the tree-sitter parser, e.g. a
+// fake parser returning canned files in tests.
 type DefaultAnalyzerFactory struct {
-	Cache    *DependencyCache
-	FileTree *FileTree
+	Cache         *DependencyCache
+	FileTree      *FileTree
+	Symbols       *SymbolIndex
+	Weights       *DependencyWeights
+	IgnoreStdlib  bool
+	NewJavaParser func() java.JavaParser
 }
 
 // NewDefaultAnalyzerFactory creates a new analyzer factory
@@ -122,39 +307,81 @@ func (f *DefaultAnalyzerFactory) CreateAnalyzer(filePath string) (DependencyAnal
 	case ".java":
 		return &JavaDependencyAnalyzer{
 			LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{
-				Cache:    f.Cache,
-				FileTree: f.FileTree,
+				Cache:        f.Cache,
+				FileTree:     f.FileTree,
+				Weights:      f.Weights,
+				IgnoreStdlib: f.IgnoreStdlib,
+			},
+			Parser: f.javaParser(),
+		}, nil
+	case ".kt":
+		return &KotlinDependencyAnalyzer{
+			LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{
+				Cache:        f.Cache,
+				FileTree:     f.FileTree,
+				Weights:      f.Weights,
+				IgnoreStdlib: f.IgnoreStdlib,
 			},
-			Parser: java.NewTreeSitterJavaParser(),
+			Parser: kotlin.NewTreeSitterKotlinParser(),
 		}, nil
 	case ".py":
 		return &PythonDependencyAnalyzer{
 			LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{
-				Cache:    f.Cache,
-				FileTree: f.FileTree,
+				Cache:        f.Cache,
+				FileTree:     f.FileTree,
+				Weights:      f.Weights,
+				IgnoreStdlib: f.IgnoreStdlib,
 			},
+			Symbols: f.Symbols,
 		}, nil
 	case ".go":
 		return &GoDependencyAnalyzer{
 			LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{
-				Cache:    f.Cache,
-				FileTree: f.FileTree,
+				Cache:        f.Cache,
+				FileTree:     f.FileTree,
+				Weights:      f.Weights,
+				IgnoreStdlib: f.IgnoreStdlib,
 			},
 		}, nil
 	default:
 		return &GenericDependencyAnalyzer{
 			LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{
-				Cache:    f.Cache,
-				FileTree: f.FileTree,
+				Cache:        f.Cache,
+				FileTree:     f.FileTree,
+				Weights:      f.Weights,
+				IgnoreStdlib: f.IgnoreStdlib,
 			},
 		}, nil
 	}
 }
 
+// HasLanguageAnalyzer reports whether CreateAnalyzer returns a language-specific
+// analyzer for files with the given extension.
+func HasLanguageAnalyzer(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".java", ".kt", ".py", ".go":
+		return true
+	default:
+		return false
+	}
+}
+
+// javaParser creates the parser of a Java analyzer, the tree-sitter parser
+// unless NewJavaParser is set
+func (f *DefaultAnalyzerFactory) javaParser() java.JavaParser {
+	if f.NewJavaParser != nil {
+		return f.NewJavaParser()
+	}
+	return java.NewTreeSitterJavaParser()
+}
+
 // JavaDependencyAnalyzer analyzes dependencies in Java files
 type JavaDependencyAnalyzer struct {
 	LanguageSpecificAnalyzer
 	Parser java.JavaParser
+
+	packagesMutex sync.Mutex
+	packages      map[string]map[string]string // Files by declared type name, per package directory
 }
 
 // AnalyzeFile analyzes dependencies in a Java file
@@ -164,8 +391,9 @@ func (a *JavaDependencyAnalyzer) AnalyzeFile(filePath string) ([]Dependency, err
 		return deps, nil
 	}
 
+	// The dependencies of the declarations that could be parsed are still valid
 	file, err := a.Parser.ParseFile(filePath)
-	if err != nil {
+	if err != nil && !errors.Is(err, java.ErrIncompleteParse) {
 		return nil, fmt.Errorf("failed to parse Java file %s: %v", filePath, err)
 	}
 
@@ -181,9 +409,10 @@ func (a *JavaDependencyAnalyzer) AnalyzeFile(filePath string) ([]Dependency, err
 func (a *JavaDependencyAnalyzer) extractJavaDependencies(file *types.File) []Dependency {
 	var dependencies []Dependency
 
-	// Extract import dependencies
-	// Implementation would analyze imports, extends, implements relationships
-	// And track which classes/methods are used within the file
+	// Extract inheritance dependencies
+	dependencies = append(dependencies, a.extractJavaTypeDependencies(file)...)
+
+	// Imports and the classes/methods used within the file are not tracked yet
 
 	return dependencies
 }
@@ -198,16 +427,15 @@ func (a *JavaDependencyAnalyzer) GetDependencies(filePath string) ([]Dependency,
 	return a.AnalyzeFile(filePath)
 }
 
-// GetDependents returns files that depend on the given file
-func (a *JavaDependencyAnalyzer) GetDependents(filePath string) ([]Dependency, error) {
-	// This would require having analyzed the entire project first
-	// Then filtering dependencies where TargetFile matches filePath
-	return nil, fmt.Errorf("not implemented")
-}
-
-// PythonDependencyAnalyzer analyzes dependencies in Python files
+// PythonDependencyAnalyzer analyzes dependencies in Python files.
+// Function calls are resolved through Symbols if set, and otherwise against the
+// Python files next to the analyzed file.
 type PythonDependencyAnalyzer struct {
 	LanguageSpecificAnalyzer
+	Symbols *SymbolIndex
+
+	classesMutex sync.Mutex
+	classes      map[string]map[string]bool // Names of the top-level classes, per file
 }
 
 // AnalyzeFile analyzes dependencies in a Python file
@@ -217,8 +445,9 @@ func (a *PythonDependencyAnalyzer) AnalyzeFile(filePath string) ([]Dependency, e
 		return deps, nil
 	}
 
+	// The dependencies of the declarations that could be parsed are still valid
 	file, err := python.GetFileMetaData(filePath)
-	if err != nil {
+	if err != nil && !errors.Is(err, types.ErrIncompleteParse) {
 		return nil, fmt.Errorf("failed to parse Python file %s: %v", filePath, err)
 	}
 
@@ -279,6 +508,9 @@ func (a *PythonDependencyAnalyzer) extractImportsFromBody(sourceFilePath string,
 				moduleName := parts[1]
 				// Create dependency for the imported module
 				targetFilePath := a.resolveModulePath(sourceFilePath, moduleName)
+				if a.ignoresImport(moduleName, targetFilePath) {
+					continue
+				}
 
 				// Extract imported elements
 				importedElements := strings.Join(parts[3:], " ")
@@ -295,8 +527,23 @@ func (a *PythonDependencyAnalyzer) extractImportsFromBody(sourceFilePath string,
 						Type:          DependencyType(ImportDependency),
 						SourceElement: sourceElement,
 						TargetElement: element,
-						Weight:        1.0,
+						Weight:        a.weights().Import,
 					})
+
+					// Names re-exported by a package also reference their defining module
+					if filepath.Base(targetFilePath) != "__init__.py" {
+						continue
+					}
+					if definedIn := a.resolveReExport(targetFilePath, element); definedIn != "" && definedIn != targetFilePath {
+						dependencies = append(dependencies, Dependency{
+							SourceFile:    sourceFilePath,
+							TargetFile:    definedIn,
+							Type:          DependencyType(ReferencesDependency),
+							SourceElement: sourceElement,
+							TargetElement: element,
+							Weight:        a.weights().References,
+						})
+					}
 				}
 			}
 		}
@@ -308,13 +555,16 @@ func (a *PythonDependencyAnalyzer) extractImportsFromBody(sourceFilePath string,
 				for i := 1; i < len(parts); i++ {
 					moduleName := strings.TrimRight(parts[i], ",")
 					targetFilePath := a.resolveModulePath(sourceFilePath, moduleName)
+					if a.ignoresImport(moduleName, targetFilePath) {
+						continue
+					}
 
 					dependencies = append(dependencies, Dependency{
 						SourceFile:    sourceFilePath,
 						TargetFile:    targetFilePath,
 						Type:          DependencyType(ImportDependency),
 						SourceElement: sourceElement,
-						Weight:        1.0,
+						Weight:        a.weights().Import,
 					})
 				}
 			}
@@ -324,141 +574,215 @@ func (a *PythonDependencyAnalyzer) extractImportsFromBody(sourceFilePath string,
 	return dependencies
 }
 
+// ignoresImport reports whether the import of the module, resolved to the given
+// path, is left out because it refers to the standard library. Project modules
+// shadowing a standard library module are kept.
+func (a *PythonDependencyAnalyzer) ignoresImport(moduleName string, targetFilePath string) bool {
+	if !a.IgnoreStdlib || !isPythonStdlib(moduleName) {
+		return false
+	}
+	_, err := os.Stat(targetFilePath)
+	return err != nil
+}
+
 // extractFunctionCallsFromBody extracts function calls from a function or method body
 func (a *PythonDependencyAnalyzer) extractFunctionCallsFromBody(sourceFilePath string, body string, sourceElement string) []Dependency {
 	var dependencies []Dependency
 
-	// Get directory containing the source file
-	sourceDir := filepath.Dir(sourceFilePath)
-
-	// Get all Python files in the same directory to check for function calls
-	files, err := filepath.Glob(filepath.Join(sourceDir, "*.py"))
-	if err != nil {
-		return dependencies
+	symbols := a.Symbols
+	if symbols == nil {
+		// Get all Python files in the same directory to check for function calls
+		files, err := filepath.Glob(filepath.Join(filepath.Dir(sourceFilePath), "*.py"))
+		if err != nil {
+			return dependencies
+		}
+		symbols = NewPythonSymbolIndex(files)
 	}
 
-	for _, targetFilePath := range files {
+	for _, symbol := range symbols.Symbols() {
 		// Skip self-references
-		if targetFilePath == sourceFilePath {
+		if symbol.File == sourceFilePath {
 			continue
 		}
 
-		// Parse the target file to get its functions and classes
-		targetFile, err := python.GetFileMetaData(targetFilePath)
-		if err != nil {
-			continue
+		called := false
+		switch symbol.Kind {
+		case FunctionSymbol:
+			// Simple detection: look for function_name( pattern
+			called = strings.Contains(body, symbol.Name+"(")
+		case MethodSymbol:
+			// Two patterns: either direct call to method or through an instance
+			methodName := symbol.Name[strings.LastIndex(symbol.Name, ".")+1:]
+			called = strings.Contains(body, symbol.Name+"(") || strings.Contains(body, "."+methodName+"(")
 		}
 
-		// Check for calls to functions from the target file
-		for _, function := range targetFile.Functions {
-			// Simple detection: look for function_name( pattern
-			pattern := function.Name + "("
-			if strings.Contains(body, pattern) {
-				dependencies = append(dependencies, Dependency{
-					SourceFile:    sourceFilePath,
-					TargetFile:    targetFilePath,
-					Type:          DependencyType(UsesDependency),
-					SourceElement: sourceElement,
-					TargetElement: function.Name,
-					Weight:        0.7, // Lower weight for usage vs import
-				})
-			}
+		if called {
+			dependencies = append(dependencies, Dependency{
+				SourceFile:    sourceFilePath,
+				TargetFile:    symbol.File,
+				Type:          DependencyType(UsesDependency),
+				SourceElement: sourceElement,
+				TargetElement: symbol.Name,
+				Weight:        a.weights().Uses,
+			})
 		}
+	}
 
-		// Check for calls to class methods from the target file
-		for _, class := range targetFile.Classes {
-			for _, method := range class.Methods {
-				// Two patterns: either direct call to method or through an instance
-				classMethodPattern := class.Name + "." + method.Func.Name + "("
-				instanceMethodPattern := "." + method.Func.Name + "("
+	return dependencies
+}
 
-				if strings.Contains(body, classMethodPattern) || strings.Contains(body, instanceMethodPattern) {
-					dependencies = append(dependencies, Dependency{
-						SourceFile:    sourceFilePath,
-						TargetFile:    targetFilePath,
-						Type:          DependencyType(UsesDependency),
-						SourceElement: sourceElement,
-						TargetElement: class.Name + "." + method.Func.Name,
-						Weight:        0.7, // Lower weight for usage vs import
-					})
-				}
-			}
+// resolveModulePath resolves a Python module name to a file path. Dotted names
+// resolve through packages and packages resolve to their __init__.py; names with
+// leading dots are resolved relative to the importing file's package.
+func (a *PythonDependencyAnalyzer) resolveModulePath(sourceFilePath string, moduleName string) string {
+	sourceDir := filepath.Dir(sourceFilePath)
+
+	if strings.HasPrefix(moduleName, ".") {
+		// One dot is the importing file's package, every further dot its parent
+		relativeName := strings.TrimLeft(moduleName, ".")
+		packageDir := sourceDir
+		for i := 1; i < len(moduleName)-len(relativeName); i++ {
+			packageDir = filepath.Dir(packageDir)
 		}
+		if modulePath, found := findPythonModule(packageDir, relativeName); found {
+			return modulePath
+		}
+		return moduleName + ".py"
 	}
 
-	return dependencies
+	// First, check if the module is in the same directory
+	if modulePath, found := findPythonModule(sourceDir, moduleName); found {
+		return modulePath
+	}
+
+	// If not found, look for modules in the root of the project
+	if modulePath, found := findPythonModule(filepath.Dir(sourceDir), moduleName); found {
+		return modulePath
+	}
+
+	// If we still can't find it, just use the module name as is for reference
+	// In a real implementation, we might want to handle Python's import system more thoroughly
+	return moduleName + ".py"
 }
 
-// extractClassInheritance extracts class inheritance dependencies
-func (a *PythonDependencyAnalyzer) extractClassInheritance(sourceFilePath string, class types.Class) []Dependency {
-	var dependencies []Dependency
+// findPythonModule looks up a dotted module name inside dir, either as a module
+// file or as a package. An empty name refers to the package at dir.
+func findPythonModule(dir string, moduleName string) (string, bool) {
+	base := dir
+	if moduleName != "" {
+		base = filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(moduleName, ".", "/")))
+	}
 
-	// In a real implementation, we would need to parse the class definition to extract base classes
-	// For simplicity, we'll just search for potential inheritance references in the source file
+	candidates := []string{filepath.Join(base, "__init__.py")}
+	if moduleName != "" {
+		candidates = append([]string{base + ".py"}, candidates...)
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
 
-	sourceDir := filepath.Dir(sourceFilePath)
+// maxReExportDepth bounds how many packages a re-exported name is followed
+// through, guarding against import cycles.
+const maxReExportDepth = 8
 
-	// Get all Python files in the same directory
-	files, err := filepath.Glob(filepath.Join(sourceDir, "*.py"))
+var (
+	pythonAllPattern    = regexp.MustCompile(`(?ms)^__all__\s*=\s*[\[(](.*?)[\])]`)
+	pythonQuotedPattern = regexp.MustCompile(`["']([^"']+)["']`)
+)
+
+// resolveReExport follows a name imported from a package to the file defining
+// it, through the "from .x import y" and "from .x import *" statements of the
+// package's __init__.py. It returns an empty string if the package does not
+// re-export the name.
+func (a *PythonDependencyAnalyzer) resolveReExport(initPath string, name string) string {
+	return a.resolveReExportDepth(initPath, name, 0)
+}
+
+func (a *PythonDependencyAnalyzer) resolveReExportDepth(initPath string, name string, depth int) string {
+	if depth >= maxReExportDepth {
+		return ""
+	}
+	code, err := os.ReadFile(initPath)
 	if err != nil {
-		return dependencies
+		return ""
 	}
 
-	for _, targetFilePath := range files {
-		// Skip self-references
-		if targetFilePath == sourceFilePath {
+	for _, statement := range parsePythonFromImports(string(code)) {
+		targetPath := a.resolveModulePath(initPath, statement.Module)
+		if _, err := os.Stat(targetPath); err != nil {
 			continue
 		}
 
-		// Parse the target file to get its classes
-		targetFile, err := python.GetFileMetaData(targetFilePath)
-		if err != nil {
-			continue
-		}
+		for _, element := range statement.Elements {
+			fields := strings.Fields(element)
+			if len(fields) == 0 {
+				continue
+			}
 
-		// Check if any classes in the target file might be base classes
-		for _, targetClass := range targetFile.Classes {
-			// In real code, we'd check class definition for parent classes
-			// Here we use a simplified approach to check for potential parent classes
-
-			// Check for class inheritance patterns like "class MyClass(ParentClass):"
-			if class.Name != targetClass.Name && strings.Contains(sourceFilePath, targetClass.Name) {
-				dependencies = append(dependencies, Dependency{
-					SourceFile:    sourceFilePath,
-					TargetFile:    targetFilePath,
-					Type:          DependencyType(ExtendsDependency),
-					SourceElement: class.Name,
-					TargetElement: targetClass.Name,
-					Weight:        0.9, // High weight for inheritance
-				})
+			switch {
+			case fields[0] == "*":
+				// Star imports only re-export the names the module exports
+				if pythonExports(targetPath, name) {
+					return a.definingFile(targetPath, name, depth)
+				}
+			case fields[len(fields)-1] == name:
+				// "y" or "x as y"
+				return a.definingFile(targetPath, fields[0], depth)
 			}
 		}
 	}
-
-	return dependencies
+	return ""
 }
 
-// resolveModulePath resolves a Python module name to a file path
-func (a *PythonDependencyAnalyzer) resolveModulePath(sourceFilePath string, moduleName string) string {
-	// First, check if the module is in the same directory
-	sourceDir := filepath.Dir(sourceFilePath)
-	candidatePath := filepath.Join(sourceDir, moduleName+".py")
-
-	if _, err := os.Stat(candidatePath); err == nil {
-		return candidatePath
+// definingFile returns the file defining the name exported by the module at
+// modulePath, following the module's own re-exports if it is a package.
+func (a *PythonDependencyAnalyzer) definingFile(modulePath string, name string, depth int) string {
+	if filepath.Base(modulePath) == "__init__.py" {
+		if definedIn := a.resolveReExportDepth(modulePath, name, depth+1); definedIn != "" {
+			return definedIn
+		}
 	}
+	return modulePath
+}
 
-	// If not found, look for modules in the root of the project
-	projectRoot := filepath.Dir(sourceDir)
-	candidatePath = filepath.Join(projectRoot, moduleName+".py")
+// pythonExports reports whether a star import of the module at modulePath brings
+// the name into scope: the name is listed in the module's __all__, or, without
+// __all__, it is a public name the module defines or re-exports.
+func pythonExports(modulePath string, name string) bool {
+	code, err := os.ReadFile(modulePath)
+	if err != nil {
+		return false
+	}
 
-	if _, err := os.Stat(candidatePath); err == nil {
-		return candidatePath
+	if match := pythonAllPattern.FindSubmatch(code); match != nil {
+		for _, listed := range pythonQuotedPattern.FindAllSubmatch(match[1], -1) {
+			if string(listed[1]) == name {
+				return true
+			}
+		}
+		return false
 	}
 
-	// If we still can't find it, just use the module name as is for reference
-	// In a real implementation, we might want to handle Python's import system more thoroughly
-	return moduleName + ".py"
+	if strings.HasPrefix(name, "_") {
+		return false
+	}
+	for _, match := range pythonDefinitionPattern.FindAllSubmatch(code, -1) {
+		if string(match[1]) == name || string(match[2]) == name {
+			return true
+		}
+	}
+	for _, statement := range parsePythonFromImports(string(code)) {
+		for _, element := range statement.Elements {
+			if fields := strings.Fields(element); fields[len(fields)-1] == name {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // AnalyzeDirectory analyzes dependencies in a directory
@@ -471,23 +795,86 @@ func (a *PythonDependencyAnalyzer) GetDependencies(filePath string) ([]Dependenc
 	return a.AnalyzeFile(filePath)
 }
 
-// GetDependents returns files that depend on the given file
-func (a *PythonDependencyAnalyzer) GetDependents(filePath string) ([]Dependency, error) {
-	// This would require having analyzed the entire project first
-	// Then filtering dependencies where TargetFile matches filePath
-	return nil, fmt.Errorf("not implemented")
-}
-
-// GoDependencyAnalyzer analyzes dependencies in Go files
+// GoDependencyAnalyzer analyzes the imports of Go files. Files excluded by their
+// build constraints (//go:build lines and _GOOS/_GOARCH file name suffixes) for the
+// target platform have no dependencies. GOOS and GOARCH select the target platform
+// and default to the platform pinguis runs on.
 type GoDependencyAnalyzer struct {
 	LanguageSpecificAnalyzer
+	GOOS   string
+	GOARCH string
 }
 
-// AnalyzeFile analyzes dependencies in a Go file
+// AnalyzeFile analyzes dependencies in a Go file. Every import becomes an import
+// dependency on the directory of the imported package if it belongs to the module
+// of the file, and on the import path otherwise. Imports of the standard library
+// get the Stdlib weight so callers can filter them. Every function of a package
+// of the module called by the file becomes a uses dependency on the package's
+// directory, with the calling function as source element.
 func (a *GoDependencyAnalyzer) AnalyzeFile(filePath string) ([]Dependency, error) {
-	// Go-specific dependency analysis would go here
-	// This would parse imports and track which packages/types are used
-	return nil, fmt.Errorf("not implemented")
+	if filepath.Ext(filePath) != ".go" {
+		return nil, nil
+	}
+
+	// Check cache first
+	if deps, found := a.Cache.Get(filePath); found {
+		return deps, nil
+	}
+
+	matches, err := goFileMatches(filePath, a.GOOS, a.GOARCH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build constraints of Go file %s: %v", filePath, err)
+	}
+
+	var dependencies []Dependency
+	if matches {
+		file, err := parseGoFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Go file %s: %v", filePath, err)
+		}
+
+		modulePath, moduleRoot := goModule(filepath.Dir(filePath))
+		imports, err := goImports(file, modulePath, moduleRoot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Go file %s: %v", filePath, err)
+		}
+
+		for _, imported := range imports {
+			weight := a.weights().Import
+			if isGoStdlib(imported.Path, modulePath) {
+				if a.IgnoreStdlib {
+					continue
+				}
+				weight = a.weights().Stdlib
+			}
+			dependencies = append(dependencies, Dependency{
+				SourceFile:    filePath,
+				TargetFile:    goImportTarget(imported.Path, modulePath, moduleRoot),
+				Type:          DependencyType(ImportDependency),
+				TargetElement: imported.Path,
+				Weight:        weight,
+			})
+		}
+
+		for _, call := range goCalls(file, imports) {
+			if !inGoModule(call.Import.Path, modulePath) {
+				continue
+			}
+			dependencies = append(dependencies, Dependency{
+				SourceFile:    filePath,
+				TargetFile:    goImportTarget(call.Import.Path, modulePath, moduleRoot),
+				Type:          DependencyType(UsesDependency),
+				SourceElement: call.Caller,
+				TargetElement: call.Import.Name + "." + call.Callee,
+				Weight:        a.weights().Uses,
+			})
+		}
+	}
+
+	// Cache the results
+	a.Cache.Store(filePath, dependencies)
+
+	return dependencies, nil
 }
 
 // AnalyzeDirectory analyzes dependencies in a directory
@@ -500,11 +887,6 @@ func (a *GoDependencyAnalyzer) GetDependencies(filePath string) ([]Dependency, e
 	return a.AnalyzeFile(filePath)
 }
 
-// GetDependents returns files that depend on the given file
-func (a *GoDependencyAnalyzer) GetDependents(filePath string) ([]Dependency, error) {
-	return nil, fmt.Errorf("not implemented")
-}
-
 // GenericDependencyAnalyzer provides basic dependency analysis for unsupported file types
 type GenericDependencyAnalyzer struct {
 	LanguageSpecificAnalyzer
@@ -526,13 +908,14 @@ func (a *GenericDependencyAnalyzer) GetDependencies(filePath string) ([]Dependen
 	return a.AnalyzeFile(filePath)
 }
 
-// GetDependents returns files that depend on the given file
-func (a *GenericDependencyAnalyzer) GetDependents(filePath string) ([]Dependency, error) {
-	return nil, nil
+// fileAnalyzer analyzes the dependencies of a single file. Both the analyzers
+// and the DependencyAnalysisManager, which picks an analyzer per file, are one.
+type fileAnalyzer interface {
+	AnalyzeFile(filePath string) ([]Dependency, error)
 }
 
 // analyzeDirectory is a helper function to analyze all files in a directory
-func analyzeDirectory(dirPath string, analyzer DependencyAnalyzer) (*DependencyGraph, error) {
+func analyzeDirectory(dirPath string, analyzer fileAnalyzer) (*DependencyGraph, error) {
 	treeBuilder := &FileTreeBuilder{}
 	tree, err := treeBuilder.BuildTree(dirPath)
 	if err != nil {
@@ -546,7 +929,7 @@ func analyzeDirectory(dirPath string, analyzer DependencyAnalyzer) (*DependencyG
 
 	// Collect all files
 	var filePaths []string
-	collectFiles(tree.Root, dirPath, &filePaths, graph.FileNodes)
+	collectFiles(tree, dirPath, &filePaths, graph.FileNodes)
 
 	// Analyze each file
 	var allDeps []Dependency
@@ -554,7 +937,7 @@ func analyzeDirectory(dirPath string, analyzer DependencyAnalyzer) (*DependencyG
 		deps, err := analyzer.AnalyzeFile(filePath)
 		if err != nil {
 			// Log the error but continue with other files
-			fmt.Printf("Error analyzing %s: %v\n", filePath, err)
+			log.Printf("Failed to analyze %s: %v", filePath, err)
 			continue
 		}
 		allDeps = append(allDeps, deps...)
@@ -574,27 +957,41 @@ func analyzeDirectory(dirPath string, analyzer DependencyAnalyzer) (*DependencyG
 	return graph, nil
 }
 
-// collectFiles recursively collects file paths from a file tree
-func collectFiles(node *FileNode, basePath string, filePaths *[]string, nodeMap map[string]*FileNode) {
-	path := filepath.Join(basePath, node.FileName)
-
-	if node.FileType != "dir" {
-		*filePaths = append(*filePaths, path)
-	}
+// collectFiles collects the file paths of a file tree built from basePath and
+// maps the path of every node to the node. The root of the tree is basePath
+// itself, so the paths of its nodes are basePath joined with their path below
+// the root, the paths the files are read and analyzed at.
+func collectFiles(tree *FileTree, basePath string, filePaths *[]string, nodeMap map[string]*FileNode) {
+	tree.Walk(func(node *FileNode, path string) error {
+		relPath, err := filepath.Rel(tree.Root.FileName, path)
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(basePath, relPath)
 
-	nodeMap[path] = node
+		if node.FileType != "dir" {
+			*filePaths = append(*filePaths, path)
+		}
 
-	for _, child := range node.Children {
-		childPath := filepath.Join(basePath, node.FileName)
-		collectFiles(child, childPath, filePaths, nodeMap)
-	}
+		nodeMap[path] = node
+		return nil
+	})
 }
 
-// DependencyAnalysisManager manages the dependency analysis process
+// DependencyAnalysisManager manages the dependency analysis process.
+// The dependencies of the whole project are kept after the first project
+// analysis, together with the reverse index built from them, to answer which
+// files depend on a file.
 type DependencyAnalysisManager struct {
 	AnalyzerFactory AnalyzerFactory
 	Cache           *DependencyCache
 	FileTree        *FileTree
+
+	rootPath    string
+	mutex       sync.Mutex
+	projectDeps []Dependency
+	analyzed    bool
+	dependents  map[string][]Dependency
 }
 
 // NewDependencyAnalysisManager creates a new dependency analysis manager
@@ -616,6 +1013,7 @@ func NewDependencyAnalysisManager(weaviateConfig weaviate.Config, rootPath strin
 		AnalyzerFactory: factory,
 		Cache:           cache,
 		FileTree:        tree,
+		rootPath:        rootPath,
 	}, nil
 }
 
@@ -629,15 +1027,22 @@ func (m *DependencyAnalysisManager) AnalyzeFile(filePath string) ([]Dependency,
 	return analyzer.AnalyzeFile(filePath)
 }
 
-// AnalyzeProject analyzes dependencies for an entire project
+// AnalyzeProject analyzes dependencies for an entire project, every file with
+// the analyzer of its language. The resulting dependencies replace those kept
+// for answering GetFileDependents.
 func (m *DependencyAnalysisManager) AnalyzeProject(projectPath string) (*DependencyGraph, error) {
-	// Use a generic analyzer for the project directory
-	analyzer, err := m.AnalyzerFactory.CreateAnalyzer("")
+	graph, err := analyzeDirectory(projectPath, m)
 	if err != nil {
 		return nil, err
 	}
 
-	return analyzer.AnalyzeDirectory(projectPath)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.projectDeps = graph.Dependencies
+	m.analyzed = true
+	m.dependents = nil
+
+	return graph, nil
 }
 
 // GetFileDependencies gets all dependencies for a specific file
@@ -650,12 +1055,104 @@ func (m *DependencyAnalysisManager) GetFileDependencies(filePath string) ([]Depe
 	return analyzer.GetDependencies(filePath)
 }
 
-// GetFileDependents gets all files that depend on a specific file
+// GetFunctionDependencies gets the dependencies of a single function in a file,
+// i.e. the dependencies of the file whose SourceElement is the function
+func (m *DependencyAnalysisManager) GetFunctionDependencies(filePath string, functionName string) ([]Dependency, error) {
+	deps, err := m.GetFileDependencies(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var functionDeps []Dependency
+	for _, dep := range deps {
+		if dep.SourceElement == functionName {
+			functionDeps = append(functionDeps, dep)
+		}
+	}
+
+	return functionDeps, nil
+}
+
+// InvalidateFile drops the cached analysis of a file, e.g. after it changed.
+// The project dependencies are dropped too, so the next GetFileDependents
+// analyzes the project again.
+func (m *DependencyAnalysisManager) InvalidateFile(filePath string) {
+	m.Cache.Invalidate(filePath)
+	m.resetProject()
+}
+
+// ClearCache drops the cached analysis of every file and the project dependencies
+func (m *DependencyAnalysisManager) ClearCache() {
+	m.Cache.Clear()
+	m.resetProject()
+}
+
+// resetProject drops the project dependencies and their reverse index
+func (m *DependencyAnalysisManager) resetProject() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.projectDeps = nil
+	m.analyzed = false
+	m.dependents = nil
+}
+
+// BuildReverseIndex returns the dependencies of the project grouped by the
+// absolute path of their target file, analyzing the project at the manager's
+// root path first if it has not been analyzed yet. The index is kept and
+// reused by GetFileDependents until the project is analyzed again or the cache
+// is invalidated; callers must not modify it.
+func (m *DependencyAnalysisManager) BuildReverseIndex() (map[string][]Dependency, error) {
+	m.mutex.Lock()
+	analyzed := m.analyzed
+	m.mutex.Unlock()
+
+	if !analyzed {
+		if m.rootPath == "" {
+			return nil, fmt.Errorf("no project root to analyze")
+		}
+		if _, err := m.AnalyzeProject(m.rootPath); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.dependents != nil {
+		return m.dependents, nil
+	}
+
+	index := make(map[string][]Dependency)
+	for _, dep := range m.projectDeps {
+		target := dep.TargetFile
+		if absPath, err := filepath.Abs(target); err == nil {
+			target = absPath
+		}
+		index[target] = append(index[target], dep)
+	}
+	m.dependents = index
+
+	return index, nil
+}
+
+// GetFileDependents gets all dependencies of the project whose target is a
+// specific file, i.e. what depends on the file. A file nothing depends on has
+// an empty slice of dependents.
 func (m *DependencyAnalysisManager) GetFileDependents(filePath string) ([]Dependency, error) {
-	analyzer, err := m.AnalyzerFactory.CreateAnalyzer(filePath)
+	index, err := m.BuildReverseIndex()
 	if err != nil {
 		return nil, err
 	}
 
-	return analyzer.GetDependents(filePath)
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", filePath, err)
+	}
+
+	dependents, ok := index[absPath]
+	if !ok {
+		return []Dependency{}, nil
+	}
+	return dependents, nil
 }
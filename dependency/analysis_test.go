@@ -0,0 +1,305 @@
+package dependency
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/scripts/java"
+)
+
+func TestDependencyCacheEvictsLeastRecentlyUsedFile(t *testing.T) {
+	cache := newMemoryCache()
+	cache.SetMaxEntries(2)
+
+	cache.Store("a.py", []Dependency{{SourceFile: "a.py", TargetFile: "b.py"}})
+	cache.Store("b.py", nil)
+	// Using a.py makes b.py the least recently used file
+	if _, ok := cache.Get("a.py"); !ok {
+		t.Fatal("a.py is not cached")
+	}
+	cache.Store("c.py", nil)
+
+	if _, ok := cache.Get("b.py"); ok {
+		t.Error("least recently used b.py was not evicted")
+	}
+	for _, path := range []string{"a.py", "c.py"} {
+		if _, ok := cache.Get(path); !ok {
+			t.Errorf("recently used %s was evicted", path)
+		}
+	}
+	if deps, _ := cache.Get("a.py"); len(deps) != 1 || deps[0].TargetFile != "b.py" {
+		t.Errorf("dependencies of a.py = %+v, want the stored ones", deps)
+	}
+}
+
+func TestDependencyCacheSetMaxEntriesEvictsRightAway(t *testing.T) {
+	cache := newMemoryCache()
+	for i := 0; i < 5; i++ {
+		cache.Store(fmt.Sprintf("%d.py", i), nil)
+	}
+
+	cache.SetMaxEntries(2)
+	if cache.order.Len() != 2 || len(cache.cachedDeps) != 2 {
+		t.Fatalf("cache holds %d files, want 2", len(cache.cachedDeps))
+	}
+	for _, path := range []string{"3.py", "4.py"} {
+		if _, ok := cache.Get(path); !ok {
+			t.Errorf("most recently stored %s was evicted", path)
+		}
+	}
+}
+
+func TestDependencyCacheInvalidateAndClear(t *testing.T) {
+	cache := newMemoryCache()
+	cache.SetMaxEntries(2)
+	cache.Store("a.py", nil)
+	cache.Store("b.py", nil)
+
+	cache.Invalidate("a.py")
+	if _, ok := cache.Get("a.py"); ok {
+		t.Error("invalidated a.py is still cached")
+	}
+	// The invalidated file no longer takes up a place in the cache
+	cache.Store("c.py", nil)
+	for _, path := range []string{"b.py", "c.py"} {
+		if _, ok := cache.Get(path); !ok {
+			t.Errorf("%s is not cached after a.py was invalidated", path)
+		}
+	}
+
+	cache.Clear()
+	for _, path := range []string{"b.py", "c.py"} {
+		if _, ok := cache.Get(path); ok {
+			t.Errorf("%s is still cached after Clear", path)
+		}
+	}
+	if cache.order.Len() != 0 {
+		t.Errorf("cache holds %d files after Clear, want none", cache.order.Len())
+	}
+}
+
+func TestInvalidateFileAnalyzesFileAgain(t *testing.T) {
+	dir := t.TempDir()
+	circle, square := filepath.Join(dir, "Circle.java"), filepath.Join(dir, "Square.java")
+	writeTree(t, dir, "Circle.java", "Square.java")
+	parser := &countingJavaParser{parsed: map[string]int{}}
+	cache := newMemoryCache()
+	manager := &DependencyAnalysisManager{
+		AnalyzerFactory: &DefaultAnalyzerFactory{Cache: cache, NewJavaParser: func() java.JavaParser { return parser }},
+		Cache:           cache,
+		rootPath:        dir,
+	}
+
+	analyze := func() {
+		t.Helper()
+		for _, path := range []string{circle, square} {
+			if _, err := manager.GetFileDependencies(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	analyze()
+	analyze()
+	manager.InvalidateFile(circle)
+	analyze()
+
+	if parser.parsed[circle] != 2 || parser.parsed[square] != 1 {
+		t.Errorf("Circle.java parsed %d times and Square.java %d times, want 2 and 1",
+			parser.parsed[circle], parser.parsed[square])
+	}
+
+	manager.ClearCache()
+	analyze()
+	if parser.parsed[circle] != 3 || parser.parsed[square] != 2 {
+		t.Errorf("after ClearCache Circle.java parsed %d times and Square.java %d times, want 3 and 2",
+			parser.parsed[circle], parser.parsed[square])
+	}
+}
+
+// fakeAnalyzerFactory creates analyzers returning canned dependencies or
+// errors by file path, counting the files analyzed.
+type fakeAnalyzerFactory struct {
+	deps     map[string][]Dependency
+	errs     map[string]error
+	analyzed int
+}
+
+func (f *fakeAnalyzerFactory) CreateAnalyzer(filePath string) (DependencyAnalyzer, error) {
+	return &fakeAnalyzer{factory: f}, nil
+}
+
+type fakeAnalyzer struct {
+	factory *fakeAnalyzerFactory
+}
+
+func (a *fakeAnalyzer) AnalyzeFile(filePath string) ([]Dependency, error) {
+	a.factory.analyzed++
+	if err := a.factory.errs[filePath]; err != nil {
+		return nil, err
+	}
+	return a.factory.deps[filePath], nil
+}
+
+func (a *fakeAnalyzer) AnalyzeDirectory(dirPath string) (*DependencyGraph, error) {
+	return analyzeDirectory(dirPath, a)
+}
+
+func (a *fakeAnalyzer) GetDependencies(filePath string) ([]Dependency, error) {
+	return a.AnalyzeFile(filePath)
+}
+
+// newFakeManager returns a manager of a project holding a.py, which imports
+// b.py, b.py and c.py, which nothing imports.
+func newFakeManager(t *testing.T) (*DependencyAnalysisManager, *fakeAnalyzerFactory, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeTree(t, dir, "a.py", "b.py", "c.py")
+	a, b := filepath.Join(dir, "a.py"), filepath.Join(dir, "b.py")
+	factory := &fakeAnalyzerFactory{deps: map[string][]Dependency{
+		a: {{SourceFile: a, TargetFile: b, Type: ImportDependency}},
+	}}
+	manager := &DependencyAnalysisManager{AnalyzerFactory: factory, Cache: newMemoryCache(), rootPath: dir}
+	return manager, factory, dir
+}
+
+func TestGetFileDependentsAnalyzesProjectOnce(t *testing.T) {
+	manager, factory, dir := newFakeManager(t)
+	if factory.analyzed != 0 {
+		t.Fatalf("%d files analyzed before the first query", factory.analyzed)
+	}
+
+	dependents, err := manager.GetFileDependents(filepath.Join(dir, "b.py"))
+	if err != nil {
+		t.Fatalf("GetFileDependents: %v", err)
+	}
+	if len(dependents) != 1 || dependents[0].SourceFile != filepath.Join(dir, "a.py") {
+		t.Errorf("dependents of b.py = %+v, want a.py", dependents)
+	}
+	if factory.analyzed != 3 {
+		t.Errorf("%d files analyzed, want the 3 files of the project", factory.analyzed)
+	}
+
+	if _, err := manager.GetFileDependents(filepath.Join(dir, "a.py")); err != nil {
+		t.Fatalf("GetFileDependents: %v", err)
+	}
+	if factory.analyzed != 3 {
+		t.Errorf("%d files analyzed after a second query, want the project analyzed once", factory.analyzed)
+	}
+
+	manager.InvalidateFile(filepath.Join(dir, "a.py"))
+	if _, err := manager.GetFileDependents(filepath.Join(dir, "b.py")); err != nil {
+		t.Fatalf("GetFileDependents: %v", err)
+	}
+	if factory.analyzed != 6 {
+		t.Errorf("%d files analyzed after invalidating a file, want the project analyzed again", factory.analyzed)
+	}
+}
+
+func TestGetFileDependentsOfFileWithoutDependents(t *testing.T) {
+	manager, _, dir := newFakeManager(t)
+
+	dependents, err := manager.GetFileDependents(filepath.Join(dir, "c.py"))
+	if err != nil {
+		t.Fatalf("GetFileDependents: %v", err)
+	}
+	if dependents == nil || len(dependents) != 0 {
+		t.Errorf("dependents of c.py = %#v, want an empty slice", dependents)
+	}
+}
+
+func TestGetFileDependentsNormalizesPath(t *testing.T) {
+	manager, _, dir := newFakeManager(t)
+	t.Chdir(dir)
+
+	for _, path := range []string{"b.py", "./b.py", filepath.Join("..", filepath.Base(dir), "b.py")} {
+		dependents, err := manager.GetFileDependents(path)
+		if err != nil {
+			t.Fatalf("GetFileDependents(%s): %v", path, err)
+		}
+		if len(dependents) != 1 {
+			t.Errorf("dependents of %s = %+v, want a.py", path, dependents)
+		}
+	}
+}
+
+func TestBuildReverseIndexGroupsByTarget(t *testing.T) {
+	manager, _, dir := newFakeManager(t)
+
+	index, err := manager.BuildReverseIndex()
+	if err != nil {
+		t.Fatalf("BuildReverseIndex: %v", err)
+	}
+	b := filepath.Join(dir, "b.py")
+	if len(index) != 1 || len(index[b]) != 1 {
+		t.Errorf("index = %+v, want the dependency of a.py on b.py only", index)
+	}
+
+	again, err := manager.BuildReverseIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(again).Pointer() != reflect.ValueOf(index).Pointer() {
+		t.Error("BuildReverseIndex built a new index, want the kept one")
+	}
+}
+
+func TestBuildReverseIndexWithoutProjectRoot(t *testing.T) {
+	manager := &DependencyAnalysisManager{AnalyzerFactory: &fakeAnalyzerFactory{}, Cache: newMemoryCache()}
+	if _, err := manager.BuildReverseIndex(); err == nil {
+		t.Error("BuildReverseIndex succeeded without a project to analyze")
+	}
+}
+
+func TestAnalyzeDirectoryLogsFilesThatFail(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	_, factory, dir := newFakeManager(t)
+	c := filepath.Join(dir, "c.py")
+	factory.errs = map[string]error{c: errors.New("unreadable")}
+
+	graph, err := analyzeDirectory(dir, &fakeAnalyzer{factory: factory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Dependencies) != 1 {
+		t.Errorf("dependencies = %+v, want the import of a.py", graph.Dependencies)
+	}
+	if want := "Failed to analyze " + c + ": unreadable"; !strings.Contains(logged.String(), want) {
+		t.Errorf("log = %q, want it to contain %q", logged.String(), want)
+	}
+}
+
+func TestGetFunctionDependenciesKeepsOnlyTheFunction(t *testing.T) {
+	manager, factory, dir := newFakeManager(t)
+	a, b, c := filepath.Join(dir, "a.py"), filepath.Join(dir, "b.py"), filepath.Join(dir, "c.py")
+	load := Dependency{SourceFile: a, TargetFile: b, Type: ImportDependency, SourceElement: "load", TargetElement: "read"}
+	save := Dependency{SourceFile: a, TargetFile: c, Type: ImportDependency, SourceElement: "save", TargetElement: "write"}
+	factory.deps[a] = []Dependency{load, save}
+
+	tests := []struct {
+		function string
+		want     []Dependency
+	}{
+		{"load", []Dependency{load}},
+		{"save", []Dependency{save}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		got, err := manager.GetFunctionDependencies(a, tt.function)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetFunctionDependencies(%s) = %+v, want %+v", tt.function, got, tt.want)
+		}
+	}
+}
@@ -5,21 +5,45 @@ import (
     "path/filepath"
 )
 
-type FileTreeBuilder struct{}
+// FileTreeBuilder builds the file tree of a directory. Ignore lists
+// gitignore-style patterns of the files and directories left out of the tree,
+// relative to its root; the patterns of the .pinguisignore file at the root
+// are added after them, so they take precedence.
+type FileTreeBuilder struct {
+	Ignore []string
+
+	matcher *ignoreMatcher
+	root    string
+}
+
+// WithIgnorePatterns adds patterns to the Ignore patterns of the builder and
+// returns the builder.
+func (b *FileTreeBuilder) WithIgnorePatterns(patterns ...string) *FileTreeBuilder {
+	b.Ignore = append(b.Ignore, patterns...)
+	return b
+}
 
 func (b *FileTreeBuilder) BuildTree(path string) (*FileTree, error) {
 	rootName := filepath.Base(path)
 	root := NewFileNode(rootName, "dir")
 	tree := NewFileTree(root)
 
-	err := b.buildTreeRecursive(path, root)
+	ignoreFile, err := readIgnoreFile(path)
+	if err != nil {
+		return tree, err
+	}
+	b.matcher = newIgnoreMatcher(append(append([]string{}, b.Ignore...), ignoreFile...))
+	b.root = path
+
+	err = b.buildTreeRecursive(path, root)
 	return tree, err
 }
 
 // buildTreeRecursive is a recursive function that builds a file tree structure starting from the given path.
 // It reads the directory entries at the specified path, creates corresponding FileNode objects, and adds them
 // as children to the provided parentNode. If an entry is a directory, the function calls itself recursively
-// to process the directory's contents.
+// to process the directory's contents. Entries matching the ignore patterns are skipped, together with
+// everything below an ignored directory.
 //
 // Parameters:
 //   - path: The file system path to read and build the tree from.
@@ -37,6 +61,10 @@ func (b *FileTreeBuilder) buildTreeRecursive(path string, parentNode *FileNode)
 		name := entry.Name()
 		entryPath := filepath.Join(path, name)
 
+		if b.ignores(entryPath, entry.IsDir()) {
+			continue
+		}
+
 		var nodeType string
 		if entry.IsDir() {
 			nodeType = "dir"
@@ -62,3 +90,15 @@ func (b *FileTreeBuilder) buildTreeRecursive(path string, parentNode *FileNode)
 
 	return nil
 }
+
+// ignores reports whether the entry at the path is left out of the tree.
+func (b *FileTreeBuilder) ignores(entryPath string, isDir bool) bool {
+	if b.matcher == nil {
+		return false
+	}
+	relPath, err := filepath.Rel(b.root, entryPath)
+	if err != nil {
+		return false
+	}
+	return b.matcher.ignores(filepath.ToSlash(relPath), isDir)
+}
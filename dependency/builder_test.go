@@ -0,0 +1,119 @@
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeTree creates the files below dir, with the directories they are in.
+func writeTree(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// treePaths returns the sorted slash-separated paths of the nodes below the root
+// of the tree.
+func treePaths(t *testing.T, tree *FileTree) []string {
+	t.Helper()
+	var paths []string
+	tree.Walk(func(node *FileNode, path string) error {
+		if node != tree.Root {
+			rel, err := filepath.Rel(tree.Root.FileName, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths
+}
+
+func TestBuildTreeSkipsIgnoredEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir,
+		"src/app.py",
+		"src/dist",
+		"dist/bundle.js",
+		"node_modules/left-pad/index.js",
+		"logs/build.log",
+		"logs/keep.log",
+	)
+	if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte("# generated\ndist/\n*.log\n!keep.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	builder := (&FileTreeBuilder{}).WithIgnorePatterns("node_modules/", IgnoreFileName)
+	tree, err := builder.BuildTree(dir)
+	if err != nil {
+		t.Fatalf("BuildTree: %v", err)
+	}
+
+	want := []string{"logs", "logs/keep.log", "src", "src/app.py", "src/dist"}
+	if got := treePaths(t, tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("tree holds %v, want %v", got, want)
+	}
+}
+
+func TestIgnoreFilePatternsTakePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "gen/api.py", "gen/cache.py")
+	if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte("gen/cache.py\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ignore file excludes again what the builder patterns include
+	builder := (&FileTreeBuilder{}).WithIgnorePatterns(IgnoreFileName, "gen/", "!gen/")
+	tree, err := builder.BuildTree(dir)
+	if err != nil {
+		t.Fatalf("BuildTree: %v", err)
+	}
+
+	want := []string{"gen", "gen/api.py"}
+	if got := treePaths(t, tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("tree holds %v, want %v", got, want)
+	}
+}
+
+func TestCollectFilesJoinsNestedPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "top.py", "pkg/mid.py", "pkg/sub/leaf.py")
+	tree, err := (&FileTreeBuilder{}).BuildTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var filePaths []string
+	nodes := make(map[string]*FileNode)
+	collectFiles(tree, dir, &filePaths, nodes)
+
+	sort.Strings(filePaths)
+	want := []string{
+		filepath.Join(dir, "pkg", "mid.py"),
+		filepath.Join(dir, "pkg", "sub", "leaf.py"),
+		filepath.Join(dir, "top.py"),
+	}
+	if !reflect.DeepEqual(filePaths, want) {
+		t.Errorf("file paths = %v, want %v", filePaths, want)
+	}
+	for _, path := range append(want, dir, filepath.Join(dir, "pkg"), filepath.Join(dir, "pkg", "sub")) {
+		node, ok := nodes[path]
+		if !ok {
+			t.Errorf("no node for %s", path)
+		} else if node.FileName != filepath.Base(path) {
+			t.Errorf("node for %s is %s", path, node.FileName)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package dependency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/dao"
+
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// dependencyClassName is the Weaviate class the cached dependencies are
+// persisted as, one object per dependency.
+const dependencyClassName = "Dependency"
+
+// contentHashProperty is the property of the persisted dependencies holding the
+// hash of the content of the file they were analyzed from.
+const contentHashProperty = "content_hash"
+
+// maxPersistedDependencies is the most dependencies loaded for a single file,
+// the default maximum number of results of a Weaviate query.
+const maxPersistedDependencies = 10000
+
+// Flush persists every dependency stored in the cache that is not in Weaviate
+// yet, those whose Store failed to persist them, in a single batch.
+func (dc *DependencyCache) Flush() error {
+	dc.persistMutex.Lock()
+	defer dc.persistMutex.Unlock()
+
+	dc.mutex.Lock()
+	entries := make([]*dependencyCacheEntry, 0, len(dc.pending))
+	for _, entry := range dc.pending {
+		entries = append(entries, entry)
+	}
+	dc.mutex.Unlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := dc.persist(entries); err != nil {
+		return fmt.Errorf("failed to flush the dependencies of %d files: %w", len(entries), err)
+	}
+	dc.settle(entries)
+	return nil
+}
+
+// settle removes the persisted entries from the pending ones, unless the
+// dependencies of their file were stored again in the meantime.
+func (dc *DependencyCache) settle(entries []*dependencyCacheEntry) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	for _, entry := range entries {
+		if dc.pending[entry.filePath] == entry {
+			delete(dc.pending, entry.filePath)
+		}
+	}
+}
+
+// persist replaces the persisted dependencies of the files of the entries
+// with theirs, creating the Dependency class first if it does not exist. The
+// caller must hold the persist mutex.
+func (dc *DependencyCache) persist(entries []*dependencyCacheEntry) error {
+	if _, err := dc.schemaExists(true); err != nil {
+		return err
+	}
+
+	var objects []*models.Object
+	for _, entry := range entries {
+		if err := dc.deletePersisted(entry.filePath); err != nil {
+			return err
+		}
+		for _, dep := range entry.deps {
+			properties := dao.ToProperties(dep)
+			properties[contentHashProperty] = entry.contentHash
+			objects = append(objects, &models.Object{
+				Class:      dependencyClassName,
+				Properties: properties,
+			})
+		}
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+
+	responses, err := dc.weaviateClient.AddObjects(objects...)
+	if err != nil {
+		return fmt.Errorf("batch insert failed: %w", err)
+	}
+	for _, response := range responses {
+		if response.Result == nil || response.Result.Errors == nil {
+			continue
+		}
+		var messages []string
+		for _, item := range response.Result.Errors.Error {
+			messages = append(messages, item.Message)
+		}
+		return fmt.Errorf("batch insert failed: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// load returns the persisted dependencies of the file that were analyzed from
+// its current content, or none if it has none, cannot be read or the cache has
+// no Weaviate client.
+func (dc *DependencyCache) load(filePath string) ([]Dependency, error) {
+	if dc.weaviateClient == nil {
+		return nil, nil
+	}
+	contentHash, err := fileContentHash(filePath)
+	if err != nil {
+		return nil, nil
+	}
+	if exists, err := dc.schemaExists(false); err != nil || !exists {
+		return nil, err
+	}
+
+	where := filters.Where().WithOperator(filters.And).WithOperands([]*filters.WhereBuilder{
+		sourceFileFilter(filePath),
+		filters.Where().WithPath([]string{contentHashProperty}).WithOperator(filters.Equal).WithValueText(contentHash),
+	})
+	depArray, err := dc.queryPersisted(where, dao.ToFields(Dependency{})...)
+	if err != nil || len(depArray) == 0 {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(depArray)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	var persisted []Dependency
+	if err := json.Unmarshal(jsonData, &persisted); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data to Dependency structs: %w", err)
+	}
+
+	// The filter may also match files whose path contains the same words
+	var deps []Dependency
+	for _, dep := range persisted {
+		if dep.SourceFile == filePath {
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// deletePersisted deletes the persisted dependencies of the file.
+func (dc *DependencyCache) deletePersisted(filePath string) error {
+	if dc.weaviateClient == nil {
+		return nil
+	}
+	if exists, err := dc.schemaExists(false); err != nil || !exists {
+		return err
+	}
+
+	objects, err := dc.queryPersisted(sourceFileFilter(filePath),
+		graphql.Field{Name: "source_file"},
+		graphql.Field{Name: "_additional", Fields: []graphql.Field{{Name: "id"}}})
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		properties, _ := object.(map[string]any)
+		if sourceFile, _ := properties["source_file"].(string); sourceFile != filePath {
+			continue
+		}
+		additional, _ := properties["_additional"].(map[string]any)
+		id, _ := additional["id"].(string)
+		if id == "" {
+			continue
+		}
+		if err := dc.weaviateClient.DeleteObject(dependencyClassName, id); err != nil {
+			return fmt.Errorf("failed to delete dependency %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// queryPersisted returns the fields of the persisted dependencies matching the
+// filter.
+func (dc *DependencyCache) queryPersisted(where *filters.WhereBuilder, fields ...graphql.Field) ([]any, error) {
+	res, err := dc.weaviateClient.QueryObjects(dependencyClassName, where, maxPersistedDependencies, fields...)
+	if err != nil {
+		return nil, fmt.Errorf("weaviate query failed: %w", err)
+	}
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("weaviate query failed: %s", res.Errors[0].Message)
+	}
+
+	getMap, ok := res.Data["Get"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid response format: missing 'Get' key")
+	}
+	objects, _ := getMap[dependencyClassName].([]any)
+	return objects, nil
+}
+
+// deleteAllPersisted deletes the Dependency class together with every
+// persisted dependency. The class is created again by the next Store.
+func (dc *DependencyCache) deleteAllPersisted() error {
+	if dc.weaviateClient == nil {
+		return nil
+	}
+	if exists, err := dc.schemaExists(false); err != nil || !exists {
+		return err
+	}
+
+	if err := dc.weaviateClient.DeleteClass(dependencyClassName); err != nil {
+		return fmt.Errorf("failed to delete Dependency class: %w", err)
+	}
+
+	dc.mutex.Lock()
+	dc.schemaReady = false
+	dc.mutex.Unlock()
+	return nil
+}
+
+// schemaExists reports whether the Dependency class exists in Weaviate,
+// creating it if it does not and create is set. An existing class lacking
+// properties, e.g. persisted by an earlier version, is migrated. Once the class
+// is known to exist, Weaviate is not asked again.
+func (dc *DependencyCache) schemaExists(create bool) (bool, error) {
+	dc.mutex.Lock()
+	ready := dc.schemaReady
+	dc.mutex.Unlock()
+	if ready {
+		return true, nil
+	}
+
+	if !create {
+		if _, err := dc.weaviateClient.GetClassByName(dependencyClassName); err != nil {
+			return false, nil
+		}
+	}
+	if err := dc.weaviateClient.MigrateClass(dependencyClass()); err != nil {
+		return false, fmt.Errorf("failed to create Dependency class: %w", err)
+	}
+
+	dc.mutex.Lock()
+	dc.schemaReady = true
+	dc.mutex.Unlock()
+	return true, nil
+}
+
+// dependencyClass returns the class of the persisted dependencies. The paths
+// and the content hash are tokenized as a whole, so that filtering on them
+// matches exactly rather than on any of their words.
+func dependencyClass() *models.Class {
+	class := dao.ToClass(Dependency{})
+	class.Properties = append(class.Properties, &models.Property{Name: contentHashProperty, DataType: []string{"text"}})
+	for _, property := range class.Properties {
+		switch property.Name {
+		case "source_file", "target_file", contentHashProperty:
+			property.Tokenization = models.PropertyTokenizationField
+		}
+	}
+	return class
+}
+
+// sourceFileFilter matches the dependencies of the file.
+func sourceFileFilter(filePath string) *filters.WhereBuilder {
+	return filters.Where().WithPath([]string{"source_file"}).WithOperator(filters.Equal).WithValueText(filePath)
+}
+
+// fileContentHash returns the hex-encoded SHA-256 hash of the content of the
+// file.
+func fileContentHash(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,177 @@
+package dependency
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// weaviateStub is a stub of the Weaviate endpoints the dependency cache uses.
+// Queries return the stored objects whose content hash they name, regardless
+// of their source file, like a filter on a word-tokenized path may. The first
+// failBatches batch inserts fail; batches counts every batch insert.
+type weaviateStub struct {
+	mu          sync.Mutex
+	class       *models.Class
+	objects     []map[string]any
+	failBatches int
+	batches     int
+}
+
+func (s *weaviateStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/schema/"):
+		if s.class == nil {
+			http.Error(w, `{"error":[{"message":"not found"}]}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(s.class)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/schema":
+		var class models.Class
+		json.NewDecoder(r.Body).Decode(&class)
+		s.class = &class
+		json.NewEncoder(w).Encode(class)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/batch/objects":
+		s.batches++
+		if s.batches <= s.failBatches {
+			http.Error(w, `{"error":[{"message":"unavailable"}]}`, http.StatusInternalServerError)
+			return
+		}
+		var batch struct {
+			Objects []*models.Object `json:"objects"`
+		}
+		json.NewDecoder(r.Body).Decode(&batch)
+		for _, object := range batch.Objects {
+			s.objects = append(s.objects, object.Properties.(map[string]any))
+		}
+		json.NewEncoder(w).Encode([]models.ObjectsGetResponse{})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/graphql":
+		body, _ := io.ReadAll(r.Body)
+		matching := []map[string]any{}
+		for _, object := range s.objects {
+			if hash, _ := object[contentHashProperty].(string); strings.Contains(string(body), hash) {
+				matching = append(matching, object)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"Get": map[string]any{dependencyClassName: matching}}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// counts returns the number of batch inserts and of stored objects.
+func (s *weaviateStub) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches, len(s.objects)
+}
+
+func newStubDependencyCache(t *testing.T, stub *weaviateStub) *DependencyCache {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	cache, err := NewDependencyCache(weaviate.Config{Host: strings.TrimPrefix(server.URL, "http://"), Scheme: "http"})
+	if err != nil {
+		t.Fatalf("NewDependencyCache: %v", err)
+	}
+	return cache
+}
+
+func writeSource(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDependencyCacheLoadsOnlyTheExactSourceFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.py")
+	other := filepath.Join(dir, "b", "a.py")
+	writeSource(t, file, "import b\n")
+	writeSource(t, other, "import b\n")
+
+	stub := &weaviateStub{}
+	newStubDependencyCache(t, stub).Store(file, []Dependency{{SourceFile: file, TargetFile: "b.py", Type: ImportDependency}})
+	newStubDependencyCache(t, stub).Store(other, []Dependency{{SourceFile: other, TargetFile: "c.py", Type: ImportDependency}})
+
+	deps, ok := newStubDependencyCache(t, stub).Get(file)
+	if !ok || len(deps) != 1 || deps[0].TargetFile != "b.py" {
+		t.Fatalf("Get(%s) = %v, %v, want only its own dependency on b.py", file, deps, ok)
+	}
+}
+
+func TestDependencyCacheIgnoresDependenciesOfChangedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.py")
+	writeSource(t, file, "import b\n")
+
+	stub := &weaviateStub{}
+	newStubDependencyCache(t, stub).Store(file, []Dependency{{SourceFile: file, TargetFile: "b.py", Type: ImportDependency}})
+
+	writeSource(t, file, "import c\n")
+	if deps, ok := newStubDependencyCache(t, stub).Get(file); ok {
+		t.Fatalf("Get(%s) = %v after the file changed, want a miss", file, deps)
+	}
+}
+
+func TestDependencyClassTokenizesPathsAsAWhole(t *testing.T) {
+	tokenization := map[string]string{}
+	for _, property := range dependencyClass().Properties {
+		tokenization[property.Name] = property.Tokenization
+	}
+	for _, name := range []string{"source_file", "target_file", contentHashProperty} {
+		if tokenization[name] != models.PropertyTokenizationField {
+			t.Errorf("%s tokenization = %q, want %q", name, tokenization[name], models.PropertyTokenizationField)
+		}
+	}
+}
+
+func TestDependencyCacheFlushPersistsPendingDependenciesInOneBatch(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.py"), filepath.Join(dir, "b.py")
+	writeSource(t, a, "import b\n")
+	writeSource(t, b, "import c\n")
+
+	stub := &weaviateStub{failBatches: 2}
+	cache := newStubDependencyCache(t, stub)
+	cache.Store(a, []Dependency{{SourceFile: a, TargetFile: b, Type: ImportDependency}})
+	cache.Store(b, []Dependency{{SourceFile: b, TargetFile: "c.py", Type: ImportDependency}})
+	if _, objects := stub.counts(); objects != 0 || len(cache.pending) != 2 {
+		t.Fatalf("%d objects persisted and %d files pending after failed stores, want 0 and 2", objects, len(cache.pending))
+	}
+
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if batches, objects := stub.counts(); batches != 3 || objects != 2 || len(cache.pending) != 0 {
+		t.Errorf("%d batches, %d objects persisted and %d files pending after Flush, want 3, 2 and 0",
+			batches, objects, len(cache.pending))
+	}
+	for _, path := range []string{a, b} {
+		if deps, ok := newStubDependencyCache(t, stub).Get(path); !ok || len(deps) != 1 {
+			t.Errorf("Get(%s) from a new cache = %v, %v, want the flushed dependency", path, deps, ok)
+		}
+	}
+
+	// Nothing is pending any more, so there is nothing to send
+	err := cache.Flush()
+	if batches, _ := stub.counts(); err != nil || batches != 3 {
+		t.Errorf("second Flush sent %d batches in total, %v, want none more", batches, err)
+	}
+}
@@ -0,0 +1,202 @@
+package dependency
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var goModulePattern = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+
+// goFileMatches reports whether the build constraints of the Go file select it
+// for the target platform. An empty goos or goarch selects the platform pinguis
+// runs on.
+func goFileMatches(filePath string, goos string, goarch string) (bool, error) {
+	buildContext := build.Default
+	if goos != "" {
+		buildContext.GOOS = goos
+	}
+	if goarch != "" {
+		buildContext.GOARCH = goarch
+	}
+	return buildContext.MatchFile(filepath.Dir(filePath), filepath.Base(filePath))
+}
+
+// goImport is an import of a Go file: the imported package's path and the
+// name the file refers to the package by.
+type goImport struct {
+	Path string
+	Name string
+}
+
+// parseGoFile parses the Go file, including its function bodies.
+func parseGoFile(filePath string) (*ast.File, error) {
+	return parser.ParseFile(token.NewFileSet(), filePath, nil, parser.SkipObjectResolution)
+}
+
+// goImports returns the imports of the parsed Go file in source order. Packages
+// imported without a name are referred to by their package name, read from the
+// package's files for packages of the module and guessed from the import path
+// for the others.
+func goImports(file *ast.File, modulePath string, moduleRoot string) ([]goImport, error) {
+	imports := make([]goImport, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		var name string
+		switch {
+		case spec.Name != nil:
+			name = spec.Name.Name
+		case inGoModule(importPath, modulePath):
+			name = goPackageName(goImportTarget(importPath, modulePath, moduleRoot))
+		}
+		if name == "" {
+			name = goImportName(importPath)
+		}
+		imports = append(imports, goImport{Path: importPath, Name: name})
+	}
+	return imports, nil
+}
+
+// goPackageName returns the name of the package in dir, read from the package
+// clause of its first non-test Go file, or an empty string if it has none.
+func goPackageName(dir string) string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return ""
+	}
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly)
+		if err == nil {
+			return file.Name.Name
+		}
+	}
+	return ""
+}
+
+// goImportName guesses the name of a package from its import path: the last
+// element, skipping a major version suffix such as v2.
+func goImportName(importPath string) string {
+	elements := strings.Split(importPath, "/")
+	name := elements[len(elements)-1]
+	if len(elements) > 1 && goMajorVersionPattern.MatchString(name) {
+		name = elements[len(elements)-2]
+	}
+	return name
+}
+
+var goMajorVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// goCall is a call of a function of an imported package, made by the function
+// named Caller, or from a package-level declaration if Caller is empty.
+type goCall struct {
+	Caller string
+	Import goImport
+	Callee string
+}
+
+// goCalls returns the calls the parsed Go file makes into the given imports,
+// each distinct call once, in source order. Calls through a local variable
+// shadowing an import's name are attributed to the import as well.
+func goCalls(file *ast.File, imports []goImport) []goCall {
+	byName := make(map[string]goImport, len(imports))
+	for _, imported := range imports {
+		if imported.Name != "_" && imported.Name != "." {
+			byName[imported.Name] = imported
+		}
+	}
+
+	var calls []goCall
+	seen := make(map[goCall]bool)
+	for _, decl := range file.Decls {
+		caller := ""
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			caller = fn.Name.Name
+		}
+
+		ast.Inspect(decl, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkg, ok := selector.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if imported, ok := byName[pkg.Name]; ok {
+				c := goCall{Caller: caller, Import: imported, Callee: selector.Sel.Name}
+				if !seen[c] {
+					seen[c] = true
+					calls = append(calls, c)
+				}
+			}
+			return true
+		})
+	}
+	return calls
+}
+
+// goModule returns the module path and root directory of the nearest go.mod
+// enclosing dir, or empty strings if there is none.
+func goModule(dir string) (string, string) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			if match := goModulePattern.FindSubmatch(data); match != nil {
+				return string(match[1]), dir
+			}
+			return "", ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// inGoModule reports whether the import path names a package of the module.
+func inGoModule(importPath string, modulePath string) bool {
+	return modulePath != "" && (importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/"))
+}
+
+// goImportTarget returns the directory of an imported package of the module, or
+// the import path itself for packages of other modules.
+func goImportTarget(importPath string, modulePath string, moduleRoot string) string {
+	if !inGoModule(importPath, modulePath) {
+		return importPath
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(importPath, modulePath), "/")
+	return filepath.Join(moduleRoot, filepath.FromSlash(rel))
+}
+
+// isGoStdlib reports whether the import path names a package of the Go standard
+// library. Like the go command, it treats paths whose first element has no dot
+// as standard library, unless they belong to the module.
+func isGoStdlib(importPath string, modulePath string) bool {
+	if inGoModule(importPath, modulePath) {
+		return false
+	}
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,163 @@
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeGoModule writes the files, given by their slash-separated paths, to a
+// new directory holding the go.mod of the module example.com/shop and returns
+// the directory.
+func writeGoModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/shop\n\ngo 1.22\n"
+	for file, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGoDependencyAnalyzerIgnoresStdlib(t *testing.T) {
+	dir := writeGoModule(t, map[string]string{
+		"main.go": "package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\n\t\"example.com/shop/cart\"\n)\n\n" +
+			"func main() {\n\tfmt.Fprintln(io.Discard, cart.New())\n}\n",
+		"cart/cart.go": "package cart\n\nfunc New() int { return 0 }\n",
+	})
+	main, cart := filepath.Join(dir, "main.go"), filepath.Join(dir, "cart")
+	weights := DefaultDependencyWeights()
+	uses := Dependency{
+		SourceFile: main, TargetFile: cart, Type: UsesDependency,
+		SourceElement: "main", TargetElement: "cart.New", Weight: weights.Uses,
+	}
+
+	tests := []struct {
+		name         string
+		ignoreStdlib bool
+		want         []Dependency
+	}{
+		{
+			name: "stdlib kept",
+			want: []Dependency{
+				{SourceFile: main, TargetFile: "fmt", Type: ImportDependency, TargetElement: "fmt", Weight: weights.Stdlib},
+				{SourceFile: main, TargetFile: "io", Type: ImportDependency, TargetElement: "io", Weight: weights.Stdlib},
+				{SourceFile: main, TargetFile: cart, Type: ImportDependency, TargetElement: "example.com/shop/cart", Weight: weights.Import},
+				uses,
+			},
+		},
+		{
+			name:         "stdlib ignored",
+			ignoreStdlib: true,
+			want: []Dependency{
+				{SourceFile: main, TargetFile: cart, Type: ImportDependency, TargetElement: "example.com/shop/cart", Weight: weights.Import},
+				uses,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := &GoDependencyAnalyzer{
+				LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{Cache: newMemoryCache(), IgnoreStdlib: tt.ignoreStdlib},
+			}
+			deps, err := analyzer.AnalyzeFile(main)
+			if err != nil {
+				t.Fatalf("AnalyzeFile: %v", err)
+			}
+			if !reflect.DeepEqual(deps, tt.want) {
+				t.Errorf("dependencies =\n%+v\nwant\n%+v", deps, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoDependencyAnalyzerFollowsBuildConstraints(t *testing.T) {
+	dir := writeGoModule(t, map[string]string{
+		"fs/open_unix.go":  "//go:build linux || darwin\n\npackage fs\n\nimport \"example.com/shop/unix\"\n",
+		"fs/open_other.go": "//go:build !linux && !darwin\n\npackage fs\n\nimport \"example.com/shop/other\"\n",
+		// The file name suffix constrains the file like a //go:build line
+		"fs/path_windows.go": "package fs\n\nimport \"example.com/shop/windows\"\n",
+	})
+	files := []string{"fs/open_unix.go", "fs/open_other.go", "fs/path_windows.go"}
+
+	tests := []struct {
+		goos string
+		want map[string]string
+	}{
+		{goos: "linux", want: map[string]string{"fs/open_unix.go": "example.com/shop/unix"}},
+		{goos: "windows", want: map[string]string{
+			"fs/open_other.go":   "example.com/shop/other",
+			"fs/path_windows.go": "example.com/shop/windows",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			analyzer := &GoDependencyAnalyzer{
+				LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{Cache: newMemoryCache()},
+				GOOS:                     tt.goos,
+				GOARCH:                   "amd64",
+			}
+			for _, file := range files {
+				deps, err := analyzer.AnalyzeFile(filepath.Join(dir, filepath.FromSlash(file)))
+				if err != nil {
+					t.Fatalf("AnalyzeFile(%s): %v", file, err)
+				}
+				want, selected := tt.want[file]
+				switch {
+				case !selected && len(deps) != 0:
+					t.Errorf("dependencies of excluded %s = %+v, want none", file, deps)
+				case selected && (len(deps) != 1 || deps[0].TargetElement != want):
+					t.Errorf("dependencies of %s = %+v, want the import of %s", file, deps, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGoDependencyAnalyzerRecordsCallsIntoModulePackages(t *testing.T) {
+	dir := writeGoModule(t, map[string]string{
+		"shop.go": "package shop\n\nimport (\n\t\"strings\"\n\n" +
+			"\t\"example.com/shop/internal/money\"\n\tstock \"example.com/shop/inventory\"\n\t\"github.com/acme/log\"\n)\n\n" +
+			"var zero = currency.Format(0)\n\n" +
+			"func Price(cents int) string {\n\tlog.Print(cents)\n\treturn strings.TrimSpace(currency.Format(cents))\n}\n\n" +
+			"func Reserve(id string) {\n\tstock.Take(id)\n\tstock.Take(id)\n}\n",
+		// The directory is named differently from the package it holds
+		"internal/money/format.go": "package currency\n\nfunc Format(cents int) string { return \"\" }\n",
+		"inventory/stock.go":       "package inventory\n\nfunc Take(id string) {}\n",
+	})
+	shop := filepath.Join(dir, "shop.go")
+	money, inventory := filepath.Join(dir, "internal", "money"), filepath.Join(dir, "inventory")
+
+	analyzer := &GoDependencyAnalyzer{LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{Cache: newMemoryCache()}}
+	deps, err := analyzer.AnalyzeFile(shop)
+	if err != nil {
+		t.Fatalf("AnalyzeFile: %v", err)
+	}
+
+	var uses []Dependency
+	for _, dep := range deps {
+		if dep.Type == UsesDependency {
+			uses = append(uses, dep)
+		}
+	}
+	weight := DefaultDependencyWeights().Uses
+	// Calls into the standard library and other modules are not recorded, and
+	// repeated calls are recorded once
+	want := []Dependency{
+		{SourceFile: shop, TargetFile: money, Type: UsesDependency, SourceElement: "", TargetElement: "currency.Format", Weight: weight},
+		{SourceFile: shop, TargetFile: money, Type: UsesDependency, SourceElement: "Price", TargetElement: "currency.Format", Weight: weight},
+		{SourceFile: shop, TargetFile: inventory, Type: UsesDependency, SourceElement: "Reserve", TargetElement: "stock.Take", Weight: weight},
+	}
+	if !reflect.DeepEqual(uses, want) {
+		t.Errorf("uses dependencies =\n%+v\nwant\n%+v", uses, want)
+	}
+}
@@ -0,0 +1,82 @@
+package dependency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// FileIdentity returns an identity of the parsed file that does not depend on
+// where the file is located, so a file that was renamed or moved without being
+// changed keeps its identity and results cached under its old path can be
+// carried over. The identity combines a normalized signature of the symbols the
+// file defines with a hash of the raw content of the file at its path, so any
+// change of the content, including edits the parser does not capture such as a
+// function body or a comment, alters the identity. If the file cannot be read,
+// the parsed file without its path and module, which parsers derive from the
+// path for some languages, is hashed instead.
+func FileIdentity(file *types.File) string {
+	data, err := os.ReadFile(file.Path)
+	if err != nil {
+		content := *file
+		content.Path = ""
+		content.Module = ""
+		// Marshaling a struct of slices, strings and structs cannot fail
+		data, _ = json.Marshal(content)
+	}
+	contentHash := sha256.Sum256(data)
+
+	identity := sha256.New()
+	identity.Write([]byte(symbolSignature(file)))
+	identity.Write(contentHash[:])
+	return hex.EncodeToString(identity.Sum(nil))
+}
+
+// symbolSignature returns one line per symbol the file defines, naming its kind
+// and signature, sorted so the order of declarations does not matter.
+func symbolSignature(file *types.File) string {
+	var lines []string
+	for _, function := range file.Functions {
+		lines = append(lines, string(FunctionSymbol)+" "+functionSignatureOf(function.Name, function))
+	}
+	for _, class := range file.Classes {
+		lines = append(lines, string(ClassSymbol)+" "+class.Name)
+		for _, method := range class.Methods {
+			lines = append(lines, string(MethodSymbol)+" "+functionSignatureOf(class.Name+"."+method.Func.Name, method.Func))
+		}
+	}
+	for _, iface := range file.Interfaces {
+		lines = append(lines, string(InterfaceSymbol)+" "+iface.Name)
+		for _, method := range iface.Methods {
+			lines = append(lines, string(MethodSymbol)+" "+functionSignatureOf(iface.Name+"."+method.Name, method))
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// functionSignatureOf formats the function under the given name with its
+// parameter types and return types, e.g. "add(int, int) int".
+func functionSignatureOf(name string, function types.Function) string {
+	params := make([]string, len(function.Parameters))
+	for i, param := range function.Parameters {
+		params[i] = strings.Join(strings.Fields(param.Type), " ")
+	}
+	signature := name + "(" + strings.Join(params, ", ") + ")"
+
+	var returns []string
+	for _, returnType := range function.ReturnTypes {
+		if returnType = strings.Join(strings.Fields(returnType), " "); returnType != "" {
+			returns = append(returns, returnType)
+		}
+	}
+	if len(returns) > 0 {
+		signature += " " + strings.Join(returns, ", ")
+	}
+	return signature
+}
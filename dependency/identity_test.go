@@ -0,0 +1,52 @@
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// writeSourceFile writes the code to a file at the path below dir and returns a
+// parsed form of it defining a single function add.
+func writeSourceFile(t *testing.T, dir, path, code string) *types.File {
+	t.Helper()
+	path = filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &types.File{
+		Path:   path,
+		Module: filepath.Base(filepath.Dir(path)),
+		Functions: []types.Function{{
+			Name:       "add",
+			Parameters: []types.Parameter{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+		}},
+	}
+}
+
+func TestFileIdentitySurvivesRename(t *testing.T) {
+	dir := t.TempDir()
+	code := "def add(a: int, b: int):\n    return a + b\n"
+	original := writeSourceFile(t, dir, "old/calc.py", code)
+	moved := writeSourceFile(t, dir, "new/arith.py", code)
+
+	if FileIdentity(original) != FileIdentity(moved) {
+		t.Error("identical files at different paths have different identities")
+	}
+}
+
+func TestFileIdentityChangesWithContent(t *testing.T) {
+	dir := t.TempDir()
+	original := writeSourceFile(t, dir, "a/calc.py", "def add(a: int, b: int):\n    return a + b\n")
+	// The parsed file is the same, only the body and a comment changed
+	edited := writeSourceFile(t, dir, "b/calc.py", "def add(a: int, b: int):\n    # add the numbers\n    return b + a\n")
+
+	if FileIdentity(original) == FileIdentity(edited) {
+		t.Error("files with different content share an identity")
+	}
+}
@@ -0,0 +1,143 @@
+package dependency
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file listing the patterns of the files
+// and directories FileTreeBuilder leaves out, read from the root of the tree.
+const IgnoreFileName = ".pinguisignore"
+
+// ignoreRule is a single gitignore-style pattern. Segments are the
+// slash-separated parts of the pattern; an anchored pattern matches paths
+// relative to the root, any other pattern matches the name of an entry at any
+// depth. A directory-only pattern, written with a trailing slash, matches only
+// directories, and a negated pattern, written with a leading "!", includes
+// again what an earlier pattern excluded.
+type ignoreRule struct {
+	segments []string
+	anchored bool
+	dirOnly  bool
+	negated  bool
+}
+
+// parseIgnoreRule parses a line of an ignore file, returning false for blank
+// lines and comments. A leading backslash escapes a "#" or "!".
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negated = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash at the start or in the middle ties the pattern to the root
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// matches reports whether the rule matches the entry at the slash-separated
+// path relative to the root.
+func (r ignoreRule) matches(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		return matchIgnoreSegments(r.segments, []string{path.Base(relPath)})
+	}
+	return matchIgnoreSegments(r.segments, strings.Split(relPath, "/"))
+}
+
+// matchIgnoreSegments matches the segments of a path against those of a
+// pattern, where a "**" segment matches any number of path segments and any
+// other segment is a glob matching a single one.
+func matchIgnoreSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchIgnoreSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+		return false
+	}
+	return matchIgnoreSegments(pattern[1:], segments[1:])
+}
+
+// ignoreMatcher decides which entries of a tree are left out, the last rule
+// matching an entry deciding for it as in a .gitignore file.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// newIgnoreMatcher parses the patterns into a matcher.
+func newIgnoreMatcher(patterns []string) *ignoreMatcher {
+	matcher := &ignoreMatcher{}
+	for _, pattern := range patterns {
+		if rule, ok := parseIgnoreRule(pattern); ok {
+			matcher.rules = append(matcher.rules, rule)
+		}
+	}
+	return matcher
+}
+
+// ignores reports whether the entry at the slash-separated path relative to the
+// root is left out.
+func (m *ignoreMatcher) ignores(relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.negated == ignored && rule.matches(relPath, isDir) {
+			ignored = !rule.negated
+		}
+	}
+	return ignored
+}
+
+// readIgnoreFile returns the lines of the ignore file in the directory, or
+// none if it has no ignore file.
+func readIgnoreFile(dir string) ([]string, error) {
+	file, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
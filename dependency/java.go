@@ -0,0 +1,184 @@
+package dependency
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/scripts/java"
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// extractJavaTypeDependencies returns the extends and implements dependencies of
+// the classes of a Java file. Superclasses and interfaces are resolved to the
+// project files declaring them; types declared outside the project, such as
+// those of the JDK, and types declared in the file itself produce no dependency.
+func (a *JavaDependencyAnalyzer) extractJavaTypeDependencies(file *types.File) []Dependency {
+	var dependencies []Dependency
+
+	for _, class := range file.Classes {
+		if class.Extends != "" {
+			if targetFile, found := a.resolveJavaType(file, class.Extends); found {
+				dependencies = append(dependencies, Dependency{
+					SourceFile:    file.Path,
+					TargetFile:    targetFile,
+					Type:          DependencyType(ExtendsDependency),
+					SourceElement: class.Name,
+					TargetElement: javaRawType(class.Extends),
+					Weight:        a.weights().Extends,
+				})
+			}
+		}
+
+		for _, iface := range class.Implements {
+			if targetFile, found := a.resolveJavaType(file, iface); found {
+				dependencies = append(dependencies, Dependency{
+					SourceFile:    file.Path,
+					TargetFile:    targetFile,
+					Type:          DependencyType(ImplementsDependency),
+					SourceElement: class.Name,
+					TargetElement: javaRawType(iface),
+					Weight:        a.weights().Implements,
+				})
+			}
+		}
+	}
+
+	return dependencies
+}
+
+// javaRawType returns a type name without its type arguments, e.g. Comparable
+// for Comparable<Item>.
+func javaRawType(name string) string {
+	if i := strings.Index(name, "<"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
+
+// resolveJavaType resolves a type name used in a Java file to the file declaring
+// the type, the way the Java compiler looks it up: a single-type import of the
+// name wins, then the types of the file's own package, then the on-demand (.*)
+// imports. Qualified names are looked up as fully qualified names, and
+// otherwise as types nested in the type their first part names. It reports false
+// if the type is not declared in the project or is declared in the file itself.
+func (a *JavaDependencyAnalyzer) resolveJavaType(file *types.File, name string) (string, bool) {
+	name = javaRawType(name)
+	if name == "" {
+		return "", false
+	}
+	sourceRoot := javaSourceRoot(file)
+
+	var targetFile string
+	if outer, _, qualified := strings.Cut(name, "."); qualified {
+		targetFile = findJavaTypeFile(sourceRoot, name)
+		if targetFile == "" {
+			targetFile, _ = a.resolveJavaType(file, outer)
+		}
+	} else {
+		targetFile = a.resolveSimpleJavaType(file, sourceRoot, name)
+	}
+
+	if targetFile == "" || targetFile == file.Path {
+		return "", false
+	}
+	return targetFile, true
+}
+
+// resolveSimpleJavaType resolves an unqualified type name used in a Java file.
+func (a *JavaDependencyAnalyzer) resolveSimpleJavaType(file *types.File, sourceRoot string, name string) string {
+	for _, imported := range file.Imports {
+		if imported == name || strings.HasSuffix(imported, "."+name) {
+			return findJavaTypeFile(sourceRoot, imported)
+		}
+	}
+
+	if targetFile := a.findPackageType(filepath.Dir(file.Path), name); targetFile != "" {
+		return targetFile
+	}
+
+	for _, imported := range file.Imports {
+		if pkg, onDemand := strings.CutSuffix(imported, ".*"); onDemand {
+			if targetFile := findJavaTypeFile(sourceRoot, pkg+"."+name); targetFile != "" {
+				return targetFile
+			}
+		}
+	}
+	return ""
+}
+
+// findPackageType returns the file of the package directory declaring the
+// top-level type. Public types live in the file named after them; other types
+// may be declared in any file of the package, so those are parsed as well, once
+// per package and analyzer.
+func (a *JavaDependencyAnalyzer) findPackageType(packageDir string, name string) string {
+	candidate := filepath.Join(packageDir, name+".java")
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	return a.packageTypes(packageDir)[name]
+}
+
+// packageTypes returns the files of the package directory by the names of the
+// top-level types they declare, parsing the package on its first lookup.
+func (a *JavaDependencyAnalyzer) packageTypes(packageDir string) map[string]string {
+	a.packagesMutex.Lock()
+	defer a.packagesMutex.Unlock()
+	if declared, ok := a.packages[packageDir]; ok {
+		return declared
+	}
+
+	declared := make(map[string]string)
+	files, _ := filepath.Glob(filepath.Join(packageDir, "*.java"))
+	for _, path := range files {
+		parsed, err := a.Parser.ParseFile(path)
+		if err != nil && !errors.Is(err, java.ErrIncompleteParse) {
+			continue
+		}
+		for _, class := range parsed.Classes {
+			if _, ok := declared[class.Name]; !ok {
+				declared[class.Name] = path
+			}
+		}
+		for _, iface := range parsed.Interfaces {
+			if _, ok := declared[iface.Name]; !ok {
+				declared[iface.Name] = path
+			}
+		}
+	}
+
+	if a.packages == nil {
+		a.packages = make(map[string]map[string]string)
+	}
+	a.packages[packageDir] = declared
+	return declared
+}
+
+// javaSourceRoot returns the directory the package hierarchy of a Java file
+// starts at, i.e. the file's directory without the directories of its package.
+func javaSourceRoot(file *types.File) string {
+	root := filepath.Dir(file.Path)
+	if file.Module == "" {
+		return root
+	}
+	for range strings.Split(file.Module, ".") {
+		root = filepath.Dir(root)
+	}
+	return root
+}
+
+// findJavaTypeFile returns the file declaring the type with the fully qualified
+// name below the source root. The name may refer to a nested type, which is
+// declared in the file of its outermost enclosing type. It returns an empty
+// string if there is no such file.
+func findJavaTypeFile(sourceRoot string, qualifiedName string) string {
+	parts := strings.Split(qualifiedName, ".")
+	for i := len(parts); i >= 1; i-- {
+		candidate := filepath.Join(sourceRoot, filepath.Join(parts[:i-1]...), parts[i-1]+".java")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
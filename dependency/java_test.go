@@ -0,0 +1,140 @@
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/scripts/java"
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// countingJavaParser returns canned files by path and counts the files parsed.
+type countingJavaParser struct {
+	files  map[string]*types.File
+	parsed map[string]int
+}
+
+func (p *countingJavaParser) ParseFile(filePath string) (*types.File, error) {
+	p.parsed[filePath]++
+	if file, ok := p.files[filePath]; ok {
+		return file, nil
+	}
+	return &types.File{Path: filePath}, nil
+}
+
+func (p *countingJavaParser) ParseModule(modulePath string) (*types.Module, error) {
+	return &types.Module{}, nil
+}
+
+func TestFindPackageTypeParsesPackageOnce(t *testing.T) {
+	dir := t.TempDir()
+	shapes := filepath.Join(dir, "Shapes.java")
+	for _, name := range []string{"Shapes.java", "Other.java", "Main.java"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("class X {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parser := &countingJavaParser{
+		files: map[string]*types.File{
+			shapes: {Path: shapes, Classes: []types.Class{{Name: "Circle"}}, Interfaces: []types.Interface{{Name: "Shape"}}},
+		},
+		parsed: map[string]int{},
+	}
+	analyzer := &JavaDependencyAnalyzer{Parser: parser}
+
+	for _, name := range []string{"Circle", "Shape", "Circle", "Missing"} {
+		want := shapes
+		if name == "Missing" {
+			want = ""
+		}
+		if got := analyzer.findPackageType(dir, name); got != want {
+			t.Errorf("findPackageType(%s) = %q, want %q", name, got, want)
+		}
+	}
+	if got := analyzer.findPackageType(dir, "Main"); got != filepath.Join(dir, "Main.java") {
+		t.Errorf("findPackageType(Main) = %q, want the file named after it", got)
+	}
+	for path, count := range parser.parsed {
+		if count != 1 {
+			t.Errorf("%s parsed %d times, want once", filepath.Base(path), count)
+		}
+	}
+}
+
+func TestJavaAnalyzerUsesInjectedParser(t *testing.T) {
+	dir := t.TempDir()
+	circle := filepath.Join(dir, "Circle.java")
+	shape := filepath.Join(dir, "Shape.java")
+	for _, path := range []string{circle, shape} {
+		// The contents are never parsed, the fake parser answers for them
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parser := &countingJavaParser{
+		files: map[string]*types.File{
+			circle: {Path: circle, Classes: []types.Class{{Name: "Circle", Extends: "Shape"}}},
+		},
+		parsed: map[string]int{},
+	}
+	factory := &DefaultAnalyzerFactory{
+		Cache:         newMemoryCache(),
+		NewJavaParser: func() java.JavaParser { return parser },
+	}
+
+	analyzer, err := factory.CreateAnalyzer(circle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps, err := analyzer.AnalyzeFile(circle)
+	if err != nil {
+		t.Fatalf("AnalyzeFile: %v", err)
+	}
+	if parser.parsed[circle] != 1 {
+		t.Errorf("fake parser parsed Circle.java %d times, want once", parser.parsed[circle])
+	}
+	if len(deps) != 1 || deps[0].TargetFile != shape || deps[0].Type != DependencyType(ExtendsDependency) {
+		t.Errorf("dependencies = %+v, want Circle extending the Shape of the canned file", deps)
+	}
+}
+
+func TestDependencyWeightsFlowToDependencies(t *testing.T) {
+	dir := t.TempDir()
+	circle := filepath.Join(dir, "Circle.java")
+	for _, name := range []string{"Circle.java", "Shape.java", "Drawable.java"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parser := &countingJavaParser{
+		files: map[string]*types.File{
+			circle: {Path: circle, Classes: []types.Class{{Name: "Circle", Extends: "Shape", Implements: []string{"Drawable"}}}},
+		},
+		parsed: map[string]int{},
+	}
+	weights := DependencyWeights{Extends: 0.25, Implements: 0.125}
+	factory := &DefaultAnalyzerFactory{
+		Cache:         newMemoryCache(),
+		Weights:       &weights,
+		NewJavaParser: func() java.JavaParser { return parser },
+	}
+
+	analyzer, err := factory.CreateAnalyzer(circle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps, err := analyzer.AnalyzeFile(circle)
+	if err != nil {
+		t.Fatalf("AnalyzeFile: %v", err)
+	}
+	want := map[DependencyType]float64{ExtendsDependency: 0.25, ImplementsDependency: 0.125}
+	if len(deps) != len(want) {
+		t.Fatalf("dependencies = %+v, want one extends and one implements", deps)
+	}
+	for _, dep := range deps {
+		if dep.Weight != want[dep.Type] {
+			t.Errorf("%s dependency has weight %v, want %v", dep.Type, dep.Weight, want[dep.Type])
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package dependency
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/scripts/kotlin"
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// KotlinDependencyAnalyzer analyzes dependencies in Kotlin files. Imports are
+// resolved to the Kotlin or Java files of the project declaring the imported
+// type or top-level function; imports of libraries, such as the Kotlin standard
+// library, produce no dependency.
+type KotlinDependencyAnalyzer struct {
+	LanguageSpecificAnalyzer
+	Parser kotlin.KotlinParser
+}
+
+// AnalyzeFile analyzes dependencies in a Kotlin file
+func (a *KotlinDependencyAnalyzer) AnalyzeFile(filePath string) ([]Dependency, error) {
+	// Check cache first
+	if deps, found := a.Cache.Get(filePath); found {
+		return deps, nil
+	}
+
+	file, err := a.Parser.ParseFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Kotlin file %s: %v", filePath, err)
+	}
+
+	dependencies := a.extractKotlinImports(file)
+
+	// Cache the results
+	a.Cache.Store(filePath, dependencies)
+
+	return dependencies, nil
+}
+
+// extractKotlinImports returns the import dependencies of a Kotlin file, one
+// per import resolved to another file of the project. On-demand (.*) imports
+// name no single declaration and are not resolved.
+func (a *KotlinDependencyAnalyzer) extractKotlinImports(file *types.File) []Dependency {
+	var dependencies []Dependency
+	sourceRoot := javaSourceRoot(file)
+
+	for _, imported := range file.Imports {
+		if strings.HasSuffix(imported, ".*") {
+			continue
+		}
+		targetFile := a.findKotlinDeclarationFile(sourceRoot, imported)
+		if targetFile == "" || targetFile == file.Path {
+			continue
+		}
+		dependencies = append(dependencies, Dependency{
+			SourceFile:    file.Path,
+			TargetFile:    targetFile,
+			Type:          DependencyType(ImportDependency),
+			TargetElement: imported[strings.LastIndex(imported, ".")+1:],
+			Weight:        a.weights().Import,
+		})
+	}
+
+	return dependencies
+}
+
+// findKotlinDeclarationFile returns the file below the source root declaring
+// the fully qualified name, or an empty string if there is none. The name may
+// refer to a member of a type, which is declared in the file of the type.
+// Kotlin files may declare any number of types and functions, so a file named
+// after the declaration is preferred, and the other Kotlin files of its package
+// directory are parsed otherwise. Java files are found by their name only.
+func (a *KotlinDependencyAnalyzer) findKotlinDeclarationFile(sourceRoot string, qualifiedName string) string {
+	parts := strings.Split(qualifiedName, ".")
+	for i := len(parts); i >= 1; i-- {
+		packageDir := filepath.Join(sourceRoot, filepath.Join(parts[:i-1]...))
+		name := parts[i-1]
+
+		candidate := filepath.Join(packageDir, name+".kt")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if targetFile := a.findPackageDeclaration(packageDir, name); targetFile != "" {
+			return targetFile
+		}
+	}
+	return findJavaTypeFile(sourceRoot, qualifiedName)
+}
+
+// findPackageDeclaration returns the Kotlin file of the package directory
+// declaring a top-level type or function with the name.
+func (a *KotlinDependencyAnalyzer) findPackageDeclaration(packageDir string, name string) string {
+	files, err := filepath.Glob(filepath.Join(packageDir, "*.kt"))
+	if err != nil {
+		return ""
+	}
+	for _, path := range files {
+		parsed, err := a.Parser.ParseFile(path)
+		if err != nil {
+			continue
+		}
+		for _, class := range parsed.Classes {
+			if class.Name == name {
+				return path
+			}
+		}
+		for _, iface := range parsed.Interfaces {
+			if iface.Name == name {
+				return path
+			}
+		}
+		for _, function := range parsed.Functions {
+			if function.Name == name {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// AnalyzeDirectory analyzes dependencies in a directory
+func (a *KotlinDependencyAnalyzer) AnalyzeDirectory(dirPath string) (*DependencyGraph, error) {
+	return analyzeDirectory(dirPath, a)
+}
+
+// GetDependencies returns dependencies for a file
+func (a *KotlinDependencyAnalyzer) GetDependencies(filePath string) ([]Dependency, error) {
+	return a.AnalyzeFile(filePath)
+}
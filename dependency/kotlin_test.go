@@ -0,0 +1,40 @@
+package dependency
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKotlinDependencyAnalyzerResolvesImports(t *testing.T) {
+	root := filepath.Join("testdata", "kotlin", "src")
+	factory := NewDefaultAnalyzerFactory(newMemoryCache(), nil)
+
+	analyzer, err := factory.CreateAnalyzer(filepath.Join(root, "com", "example", "app", "Main.kt"))
+	if err != nil {
+		t.Fatalf("CreateAnalyzer: %v", err)
+	}
+	if _, ok := analyzer.(*KotlinDependencyAnalyzer); !ok {
+		t.Fatalf("CreateAnalyzer returned %T, want *KotlinDependencyAnalyzer", analyzer)
+	}
+
+	deps, err := analyzer.AnalyzeFile(filepath.Join(root, "com", "example", "app", "Main.kt"))
+	if err != nil {
+		t.Fatalf("AnalyzeFile: %v", err)
+	}
+	got := dependencyKeys(t, root, deps)
+	want := []string{
+		"com/example/app/Main.kt: -import-> com/example/model/Orders.kt:Order",
+		"com/example/app/Main.kt: -import-> com/example/model/User.kt:User",
+		"com/example/app/Main.kt: -import-> com/example/util/Strings.kt:formatName",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestHasLanguageAnalyzerIncludesKotlin(t *testing.T) {
+	if !HasLanguageAnalyzer(".kt") {
+		t.Error("HasLanguageAnalyzer(.kt) = false, want true")
+	}
+}
@@ -0,0 +1,81 @@
+package dependency
+
+// MergeGraphs merges dependency graphs, e.g. of the subprojects of a monorepo,
+// into a single graph.
+//
+// File nodes are unioned by path. When several graphs have a node for the same
+// path, the node with more children is kept, and the first one on a tie. The
+// dependencies are concatenated with duplicates removed, and the dependencies of
+// the merged nodes are rewired to the merged nodes, so edges between files of
+// different graphs are linked as well. The merged graph holds copies of the file
+// nodes whose children, parents and dependencies are the merged copies, so the
+// merged graph and the input graphs can be modified independently. Children
+// that are not file nodes of their graph are dropped.
+func MergeGraphs(graphs ...*DependencyGraph) *DependencyGraph {
+	merged := &DependencyGraph{
+		Dependencies: []Dependency{},
+		FileNodes:    make(map[string]*FileNode),
+	}
+
+	// The nodes kept for each path, and the paths of their children in the
+	// graph they were kept from
+	kept := make(map[string]*FileNode)
+	childPaths := make(map[string][]string)
+	seen := make(map[Dependency]bool)
+	for _, graph := range graphs {
+		if graph == nil {
+			continue
+		}
+
+		pathOf := make(map[*FileNode]string, len(graph.FileNodes))
+		for filePath, node := range graph.FileNodes {
+			pathOf[node] = filePath
+		}
+
+		for filePath, node := range graph.FileNodes {
+			if existing, ok := kept[filePath]; ok && len(existing.Children) >= len(node.Children) {
+				continue
+			}
+			kept[filePath] = node
+			childPaths[filePath] = childPaths[filePath][:0]
+			for _, child := range node.Children {
+				if childPath, ok := pathOf[child]; ok {
+					childPaths[filePath] = append(childPaths[filePath], childPath)
+				}
+			}
+		}
+
+		for _, dep := range graph.Dependencies {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			merged.Dependencies = append(merged.Dependencies, dep)
+		}
+	}
+
+	for filePath, node := range kept {
+		merged.FileNodes[filePath] = NewFileNode(node.FileName, node.FileType)
+	}
+	for filePath, node := range merged.FileNodes {
+		for _, childPath := range childPaths[filePath] {
+			if child, ok := merged.FileNodes[childPath]; ok && child.Parent == nil {
+				node.AddChild(child)
+			}
+		}
+	}
+
+	linked := make(map[[2]string]bool)
+	for _, dep := range merged.Dependencies {
+		sourceNode, sourceOk := merged.FileNodes[dep.SourceFile]
+		targetNode, targetOk := merged.FileNodes[dep.TargetFile]
+		edge := [2]string{dep.SourceFile, dep.TargetFile}
+
+		if sourceOk && targetOk && !linked[edge] {
+			linked[edge] = true
+			sourceNode.AddDependency(targetNode)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,67 @@
+package dependency
+
+import "testing"
+
+// newTestGraph returns a graph of a directory dir holding the files, each
+// importing the next one.
+func newTestGraph(dir string, files ...string) *DependencyGraph {
+	graph := &DependencyGraph{FileNodes: map[string]*FileNode{}}
+	root := NewFileNode(dir, "dir")
+	graph.FileNodes[dir] = root
+	for i, file := range files {
+		node := NewFileNode(file, "file")
+		root.AddChild(node)
+		graph.FileNodes[dir+"/"+file] = node
+		if i > 0 {
+			graph.Dependencies = append(graph.Dependencies, Dependency{
+				SourceFile: dir + "/" + files[i-1],
+				TargetFile: dir + "/" + file,
+				Type:       DependencyType(ImportDependency),
+			})
+		}
+	}
+	return graph
+}
+
+func TestMergeGraphsCopiesNodesDeeply(t *testing.T) {
+	first := newTestGraph("p", "a.py", "b.py")
+	second := newTestGraph("p", "a.py", "b.py", "c.py")
+
+	merged := MergeGraphs(first, second)
+
+	root := merged.FileNodes["p"]
+	if len(root.Children) != 3 {
+		t.Fatalf("merged root has %d children, want 3", len(root.Children))
+	}
+	for _, child := range root.Children {
+		if child.Parent != root {
+			t.Errorf("parent of %s is not the merged root", child.FileName)
+		}
+		if child != merged.FileNodes["p/"+child.FileName] {
+			t.Errorf("child %s is not the merged node of its path", child.FileName)
+		}
+	}
+	for path, node := range merged.FileNodes {
+		if node == first.FileNodes[path] || node == second.FileNodes[path] {
+			t.Errorf("merged node %s is a node of an input graph", path)
+		}
+	}
+
+	a := merged.FileNodes["p/a.py"]
+	if len(a.Dependencies) != 1 || a.Dependencies[0] != merged.FileNodes["p/b.py"] {
+		t.Errorf("dependencies of a.py = %v, want the merged b.py", a.Dependencies)
+	}
+
+	// Changing the merged graph leaves the input graphs alone
+	root.AddChild(NewFileNode("d.py", "file"))
+	a.AddDependency(merged.FileNodes["p/c.py"])
+	if len(second.FileNodes["p"].Children) != 3 {
+		t.Errorf("input root has %d children after changing the merged graph, want 3", len(second.FileNodes["p"].Children))
+	}
+	if second.FileNodes["p/a.py"].Parent != second.FileNodes["p"] {
+		t.Error("parent of an input node changed")
+	}
+	if len(second.FileNodes["p/a.py"].Dependencies) != 0 {
+		t.Errorf("input a.py has dependencies %v, want none", second.FileNodes["p/a.py"].Dependencies)
+	}
+}
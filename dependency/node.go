@@ -1,5 +1,10 @@
 package dependency
 
+import (
+	"encoding/json"
+	"path"
+)
+
 // FileNode represents a node in a file tree structure.
 // It contains information about the file name, file type,
 // its children nodes, parent node, and any dependencies.
@@ -9,12 +14,34 @@ package dependency
 // Children: A slice of pointers to FileNode representing the children of this node.
 // Parent: A pointer to the parent FileNode.
 // Dependencies: A slice of pointers to FileNode representing the dependencies of this node.
+//
+// Parent is not serialized and Dependencies are serialized as paths relative to
+// the tree root, so a tree marshals without cycles or duplicated subtrees.
+// Decoding a node restores both links of the whole tree below it.
 type FileNode struct {
 	FileName string  `json:"file_name"`
 	FileType string	 `json:"file_type"`
 	Children []*FileNode `json:"children"`
-	Parent   *FileNode `json:"parent"`
-	Dependencies []*FileNode `json:"dependencies"`
+	Parent   *FileNode `json:"-"`
+	Dependencies []*FileNode `json:"-"`
+}
+
+// fileNodeJSON is the serialized form of a FileNode.
+type fileNodeJSON struct {
+	FileName     string      `json:"file_name"`
+	FileType     string      `json:"file_type"`
+	Children     []*FileNode `json:"children"`
+	Dependencies []string    `json:"dependencies"`
+}
+
+// decodedFileNode is the serialized form of a FileNode as it is decoded, its
+// children decoded the same way, so the links are restored once for the whole
+// tree.
+type decodedFileNode struct {
+	FileName     string             `json:"file_name"`
+	FileType     string             `json:"file_type"`
+	Children     []*decodedFileNode `json:"children"`
+	Dependencies []string           `json:"dependencies"`
 }
 
 func NewFileNode(fileName string, fileType string) *FileNode {
@@ -27,7 +54,17 @@ func NewFileNode(fileName string, fileType string) *FileNode {
 	}
 }
 
+// AddChild appends the child to the children of the node. A child that belongs
+// to another node is moved, so it is never the child of two nodes.
 func (n *FileNode) AddChild(child *FileNode) {
+	if previous := child.Parent; previous != nil {
+		for i, sibling := range previous.Children {
+			if sibling == child {
+				previous.Children = append(previous.Children[:i:i], previous.Children[i+1:]...)
+				break
+			}
+		}
+	}
 	n.Children = append(n.Children, child)
 	child.Parent = n
 }
@@ -35,3 +72,71 @@ func (n *FileNode) AddChild(child *FileNode) {
 func (n *FileNode) AddDependency(dependency *FileNode) {
 	n.Dependencies = append(n.Dependencies, dependency)
 }
+
+// treePath returns the slash-separated path of the node relative to the root
+// of the tree it belongs to. The root itself is represented by ".".
+func (n *FileNode) treePath() string {
+	var names []string
+	for node := n; node.Parent != nil; node = node.Parent {
+		names = append([]string{node.FileName}, names...)
+	}
+	if len(names) == 0 {
+		return "."
+	}
+	return path.Join(names...)
+}
+
+// MarshalJSON encodes the node and its children, replacing dependency pointers
+// with their paths relative to the tree root.
+func (n *FileNode) MarshalJSON() ([]byte, error) {
+	deps := make([]string, 0, len(n.Dependencies))
+	for _, dep := range n.Dependencies {
+		deps = append(deps, dep.treePath())
+	}
+
+	return json.Marshal(fileNodeJSON{
+		FileName:     n.FileName,
+		FileType:     n.FileType,
+		Children:     n.Children,
+		Dependencies: deps,
+	})
+}
+
+// UnmarshalJSON decodes the node and the tree below it, linking every child to
+// its parent and resolving the dependency paths against the decoded tree, so
+// the node is fully linked however it was decoded. Dependency paths that do not
+// match any node in the tree are dropped.
+func (n *FileNode) UnmarshalJSON(data []byte) error {
+	var raw decodedFileNode
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	nodes := make(map[string]*FileNode)
+	pending := make(map[*FileNode][]string)
+	var build func(node *FileNode, raw *decodedFileNode, nodePath string)
+	build = func(node *FileNode, raw *decodedFileNode, nodePath string) {
+		nodes[nodePath] = node
+		pending[node] = raw.Dependencies
+		for _, rawChild := range raw.Children {
+			if rawChild == nil {
+				continue
+			}
+			child := NewFileNode(rawChild.FileName, rawChild.FileType)
+			node.AddChild(child)
+			build(child, rawChild, path.Join(nodePath, rawChild.FileName))
+		}
+	}
+
+	*n = *NewFileNode(raw.FileName, raw.FileType)
+	build(n, &raw, ".")
+
+	for node, depPaths := range pending {
+		for _, depPath := range depPaths {
+			if dep, ok := nodes[depPath]; ok {
+				node.AddDependency(dep)
+			}
+		}
+	}
+	return nil
+}
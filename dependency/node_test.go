@@ -0,0 +1,106 @@
+package dependency
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newLinkedTree returns a tree p holding a.py and a directory sub holding b.py,
+// with a.py depending on b.py.
+func newLinkedTree() (root, a, b *FileNode) {
+	root = NewFileNode("p", "dir")
+	sub := NewFileNode("sub", "dir")
+	a = NewFileNode("a.py", "file")
+	b = NewFileNode("b.py", "file")
+	root.AddChild(a)
+	root.AddChild(sub)
+	sub.AddChild(b)
+	a.AddDependency(b)
+	return root, a, b
+}
+
+// checkLinks fails the test unless every node below root is linked to its parent
+// and a.py depends on sub/b.py of the same tree.
+func checkLinks(t *testing.T, root *FileNode) {
+	t.Helper()
+	if len(root.Children) != 2 {
+		t.Fatalf("root has %d children, want 2", len(root.Children))
+	}
+	a, sub := root.Children[0], root.Children[1]
+	if a.Parent != root || sub.Parent != root || len(sub.Children) != 1 || sub.Children[0].Parent != sub {
+		t.Fatal("decoded nodes are not linked to their parents")
+	}
+	if len(a.Dependencies) != 1 || a.Dependencies[0] != sub.Children[0] {
+		t.Errorf("dependencies of a.py = %v, want the decoded sub/b.py", a.Dependencies)
+	}
+}
+
+func TestFileNodeRoundTripsWithoutFileTree(t *testing.T) {
+	root, _, _ := newLinkedTree()
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var decoded FileNode
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	checkLinks(t, &decoded)
+}
+
+func TestFileTreeRoundTrips(t *testing.T) {
+	root, _, _ := newLinkedTree()
+	data, err := json.Marshal(NewFileTree(root))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var decoded FileTree
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	checkLinks(t, decoded.Root)
+}
+
+func TestDependencyGraphRoundTripsSharingNodes(t *testing.T) {
+	root, a, b := newLinkedTree()
+	graph := &DependencyGraph{
+		Dependencies: []Dependency{{SourceFile: "p/a.py", TargetFile: "p/sub/b.py", Type: DependencyType(ImportDependency)}},
+		FileNodes: map[string]*FileNode{
+			"p":          root,
+			"p/a.py":     a,
+			"p/sub":      root.Children[1],
+			"p/sub/b.py": b,
+		},
+	}
+	data, err := json.Marshal(graph)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var decoded DependencyGraph
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	checkLinks(t, decoded.FileNodes["p"])
+	if decoded.FileNodes["p"].Children[0] != decoded.FileNodes["p/a.py"] {
+		t.Error("child of the decoded root is not the decoded node of its path")
+	}
+	if len(decoded.Dependencies) != 1 {
+		t.Errorf("decoded dependencies = %v, want 1", decoded.Dependencies)
+	}
+}
+
+func TestAddChildMovesChildFromPreviousParent(t *testing.T) {
+	root, a, _ := newLinkedTree()
+	sub := root.Children[1]
+
+	sub.AddChild(a)
+	if a.Parent != sub {
+		t.Error("moved child is not linked to its new parent")
+	}
+	if len(root.Children) != 1 || root.Children[0] != sub {
+		t.Errorf("previous parent still has children %v, want only sub", root.Children)
+	}
+}
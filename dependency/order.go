@@ -0,0 +1,132 @@
+package dependency
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TopologicalOrder returns the files of the graph in dependency order: every
+// file comes after the files it depends on, so files without dependencies come
+// first. The files are the non-directory file nodes of the graph and the source
+// files of its dependencies, including files without any edge. Only import and
+// uses dependencies order the files; a dependency on a directory, such as the
+// package a Go import names, is a dependency on every file directly in it, and
+// dependencies on anything outside the graph, such as the standard library, are
+// ignored. Files that are not ordered relative to each other are sorted by path.
+//
+// The order is computed with Kahn's algorithm. If the files depend on each other
+// in a cycle, an error naming the files of one of the cycles is returned.
+func (g *DependencyGraph) TopologicalOrder() ([]string, error) {
+	files := make(map[string]bool)
+	pathOf := make(map[*FileNode]string, len(g.FileNodes))
+	for path, node := range g.FileNodes {
+		pathOf[node] = path
+		if node.FileType != "dir" {
+			files[path] = true
+		}
+	}
+	for _, dep := range g.Dependencies {
+		files[dep.SourceFile] = true
+	}
+
+	// dependencies[f] are the files f depends on, dependents[f] those depending on f
+	dependencies := make(map[string]map[string]bool, len(files))
+	dependents := make(map[string][]string, len(files))
+	for _, dep := range g.Dependencies {
+		if dep.Type != DependencyType(ImportDependency) && dep.Type != DependencyType(UsesDependency) {
+			continue
+		}
+		for _, target := range g.targetFiles(dep.TargetFile, pathOf) {
+			if !files[target] || target == dep.SourceFile || dependencies[dep.SourceFile][target] {
+				continue
+			}
+			if dependencies[dep.SourceFile] == nil {
+				dependencies[dep.SourceFile] = make(map[string]bool)
+			}
+			dependencies[dep.SourceFile][target] = true
+			dependents[target] = append(dependents[target], dep.SourceFile)
+		}
+	}
+
+	remaining := make(map[string]int, len(files))
+	var ready []string
+	for file := range files {
+		remaining[file] = len(dependencies[file])
+		if remaining[file] == 0 {
+			ready = append(ready, file)
+		}
+	}
+
+	order := make([]string, 0, len(files))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		file := ready[0]
+		ready = ready[1:]
+		order = append(order, file)
+
+		for _, dependent := range dependents[file] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(files) {
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(findCycle(dependencies, remaining), " -> "))
+	}
+	return order, nil
+}
+
+// targetFiles returns the files a dependency on the target refers to: the
+// files directly in the target if it is a directory of the graph, and the target
+// itself otherwise.
+func (g *DependencyGraph) targetFiles(target string, pathOf map[*FileNode]string) []string {
+	node, ok := g.FileNodes[target]
+	if !ok || node.FileType != "dir" {
+		return []string{target}
+	}
+
+	var targets []string
+	for _, child := range node.Children {
+		if path, ok := pathOf[child]; ok && child.FileType != "dir" {
+			targets = append(targets, path)
+		}
+	}
+	return targets
+}
+
+// findCycle returns a cycle among the files Kahn's algorithm could not order,
+// those with dependencies left, starting and ending with the same file. Each of
+// them depends on another one of them, so following those dependencies from any
+// of them runs into a cycle.
+func findCycle(dependencies map[string]map[string]bool, remaining map[string]int) []string {
+	var unordered []string
+	for file, count := range remaining {
+		if count > 0 {
+			unordered = append(unordered, file)
+		}
+	}
+	sort.Strings(unordered)
+
+	visited := make(map[string]int)
+	var path []string
+	file := unordered[0]
+	for {
+		if at, ok := visited[file]; ok {
+			return append(path[at:], file)
+		}
+		visited[file] = len(path)
+		path = append(path, file)
+
+		var next []string
+		for target := range dependencies[file] {
+			if remaining[target] > 0 {
+				next = append(next, target)
+			}
+		}
+		sort.Strings(next)
+		file = next[0]
+	}
+}
@@ -0,0 +1,104 @@
+package dependency
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newOrderGraph returns a graph of the files with the dependencies, each given
+// as its source and target file and its type.
+func newOrderGraph(files []string, deps ...[3]string) *DependencyGraph {
+	graph := &DependencyGraph{FileNodes: make(map[string]*FileNode)}
+	for _, file := range files {
+		graph.FileNodes[file] = &FileNode{FileName: file, FileType: "py"}
+	}
+	for _, dep := range deps {
+		graph.Dependencies = append(graph.Dependencies, Dependency{SourceFile: dep[0], TargetFile: dep[1], Type: DependencyType(dep[2])})
+	}
+	return graph
+}
+
+func TestTopologicalOrderPutsDependenciesFirst(t *testing.T) {
+	graph := newOrderGraph([]string{"app.py", "models.py", "db.py", "notes.py"},
+		[3]string{"app.py", "models.py", ImportDependency},
+		[3]string{"models.py", "db.py", UsesDependency},
+		[3]string{"app.py", "db.py", ImportDependency},
+		// Only imports and uses order files, so this is no cycle
+		[3]string{"db.py", "app.py", ExtendsDependency},
+		// Dependencies outside the graph are ignored
+		[3]string{"db.py", "sqlite3", ImportDependency},
+	)
+
+	order, err := graph.TopologicalOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// notes.py has no edge, it is ordered by its path among the ready files
+	want := []string{"db.py", "models.py", "app.py", "notes.py"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestTopologicalOrderFollowsDirectoryDependencies(t *testing.T) {
+	graph := newOrderGraph([]string{"main.go", "util/a.go", "util/b.go"},
+		[3]string{"main.go", "util", ImportDependency},
+	)
+	util := &FileNode{FileName: "util", FileType: "dir", Children: []*FileNode{graph.FileNodes["util/a.go"], graph.FileNodes["util/b.go"]}}
+	graph.FileNodes["util"] = util
+
+	order, err := graph.TopologicalOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"util/a.go", "util/b.go", "main.go"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestTopologicalOrderNamesCycle(t *testing.T) {
+	tests := []struct {
+		name string
+		deps [][3]string
+		want string
+	}{
+		{
+			name: "two files",
+			deps: [][3]string{{"a.py", "b.py", ImportDependency}, {"b.py", "a.py", UsesDependency}},
+			want: "a.py -> b.py -> a.py",
+		},
+		{
+			// c.py depends on the cycle but is not part of it
+			name: "file depending on a cycle",
+			deps: [][3]string{
+				{"c.py", "a.py", ImportDependency},
+				{"a.py", "b.py", ImportDependency},
+				{"b.py", "a.py", ImportDependency},
+			},
+			want: "a.py -> b.py -> a.py",
+		},
+		{
+			name: "three files",
+			deps: [][3]string{
+				{"a.py", "b.py", ImportDependency},
+				{"b.py", "c.py", ImportDependency},
+				{"c.py", "a.py", ImportDependency},
+			},
+			want: "a.py -> b.py -> c.py -> a.py",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := newOrderGraph([]string{"a.py", "b.py", "c.py"}, tt.deps...)
+			order, err := graph.TopologicalOrder()
+			if err == nil {
+				t.Fatalf("order = %v, want a cycle error", order)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to name the cycle %s", err, tt.want)
+			}
+		})
+	}
+}
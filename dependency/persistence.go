@@ -0,0 +1,177 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// graphSnapshot is the on-disk representation of a DependencyGraph.
+// FileNodes reference each other through Parent, Children and Dependencies
+// pointers, so they are flattened into path-keyed records and relinked on load.
+type graphSnapshot struct {
+	Dependencies []Dependency   `json:"dependencies"`
+	Nodes        []nodeSnapshot `json:"nodes"`
+}
+
+// nodeSnapshot is the on-disk representation of a single FileNode.
+// Children and Dependencies are stored as the paths of the referenced nodes.
+type nodeSnapshot struct {
+	Path         string   `json:"path"`
+	FileName     string   `json:"file_name"`
+	FileType     string   `json:"file_type"`
+	Children     []string `json:"children"`
+	Dependencies []string `json:"dependencies"`
+}
+
+// SaveGraph writes the dependency graph to a JSON file at the specified path.
+//
+// Parameters:
+//   - g: The dependency graph to persist.
+//   - path: The path of the JSON file to write.
+//
+// Returns:
+//   - error: An error if the graph is nil, references a node that is not part of
+//     its FileNodes, or the file cannot be written.
+func SaveGraph(g *DependencyGraph, path string) error {
+	if g == nil {
+		return fmt.Errorf("cannot save nil dependency graph")
+	}
+
+	snapshot, err := g.snapshot()
+	if err != nil {
+		return err
+	}
+	return types.SaveToJSON(path, snapshot)
+}
+
+// MarshalJSON encodes the graph in the form SaveGraph writes, so every node is
+// stored once and the links between nodes as their paths.
+func (g *DependencyGraph) MarshalJSON() ([]byte, error) {
+	snapshot, err := g.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalJSON decodes a graph encoded by MarshalJSON and rebuilds the links
+// between its nodes, as LoadGraph does.
+func (g *DependencyGraph) UnmarshalJSON(data []byte) error {
+	var snapshot graphSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	graph, err := snapshot.graph()
+	if err != nil {
+		return err
+	}
+	*g = *graph
+	return nil
+}
+
+// snapshot flattens the graph into path-keyed records.
+func (g *DependencyGraph) snapshot() (graphSnapshot, error) {
+	nodePaths := make(map[*FileNode]string, len(g.FileNodes))
+	for nodePath, node := range g.FileNodes {
+		nodePaths[node] = nodePath
+	}
+
+	resolve := func(nodes []*FileNode) ([]string, error) {
+		paths := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			nodePath, ok := nodePaths[node]
+			if !ok {
+				return nil, fmt.Errorf("node %s is not part of the graph", node.FileName)
+			}
+			paths = append(paths, nodePath)
+		}
+		return paths, nil
+	}
+
+	snapshot := graphSnapshot{
+		Dependencies: g.Dependencies,
+		Nodes:        make([]nodeSnapshot, 0, len(g.FileNodes)),
+	}
+
+	for nodePath, node := range g.FileNodes {
+		children, err := resolve(node.Children)
+		if err != nil {
+			return graphSnapshot{}, err
+		}
+		dependencies, err := resolve(node.Dependencies)
+		if err != nil {
+			return graphSnapshot{}, err
+		}
+
+		snapshot.Nodes = append(snapshot.Nodes, nodeSnapshot{
+			Path:         nodePath,
+			FileName:     node.FileName,
+			FileType:     node.FileType,
+			Children:     children,
+			Dependencies: dependencies,
+		})
+	}
+
+	// Keep the output stable across runs regardless of map iteration order
+	sort.Slice(snapshot.Nodes, func(i, j int) bool {
+		return snapshot.Nodes[i].Path < snapshot.Nodes[j].Path
+	})
+	return snapshot, nil
+}
+
+// LoadGraph reads a dependency graph previously written by SaveGraph and
+// rebuilds the Parent, Children and Dependencies links between its nodes.
+//
+// Parameters:
+//   - path: The path of the JSON file to read.
+//
+// Returns:
+//   - *DependencyGraph: The restored dependency graph.
+//   - error: An error if the file cannot be read or references unknown nodes.
+func LoadGraph(path string) (*DependencyGraph, error) {
+	snapshot, err := types.LoadFromJSON[graphSnapshot](path)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.graph()
+}
+
+// graph rebuilds the graph the snapshot was taken of.
+func (snapshot *graphSnapshot) graph() (*DependencyGraph, error) {
+	graph := &DependencyGraph{
+		Dependencies: snapshot.Dependencies,
+		FileNodes:    make(map[string]*FileNode, len(snapshot.Nodes)),
+	}
+	if graph.Dependencies == nil {
+		graph.Dependencies = []Dependency{}
+	}
+
+	for _, ns := range snapshot.Nodes {
+		graph.FileNodes[ns.Path] = NewFileNode(ns.FileName, ns.FileType)
+	}
+
+	for _, ns := range snapshot.Nodes {
+		node := graph.FileNodes[ns.Path]
+
+		for _, childPath := range ns.Children {
+			child, ok := graph.FileNodes[childPath]
+			if !ok {
+				return nil, fmt.Errorf("node %s references unknown child %s", ns.Path, childPath)
+			}
+			node.AddChild(child)
+		}
+
+		for _, depPath := range ns.Dependencies {
+			dep, ok := graph.FileNodes[depPath]
+			if !ok {
+				return nil, fmt.Errorf("node %s references unknown dependency %s", ns.Path, depPath)
+			}
+			node.AddDependency(dep)
+		}
+	}
+
+	return graph, nil
+}
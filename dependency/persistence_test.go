@@ -0,0 +1,101 @@
+package dependency
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// nodeShape is a FileNode with its links given as the paths of the linked nodes.
+type nodeShape struct {
+	FileName     string
+	FileType     string
+	Parent       string
+	Children     []string
+	Dependencies []string
+}
+
+// graphShape returns the nodes of the graph by path, with their links as paths,
+// so that graphs with distinct nodes can be compared.
+func graphShape(t *testing.T, g *DependencyGraph) map[string]nodeShape {
+	t.Helper()
+	paths := make(map[*FileNode]string, len(g.FileNodes))
+	for path, node := range g.FileNodes {
+		paths[node] = path
+	}
+	pathOf := func(node *FileNode) string {
+		path, ok := paths[node]
+		if !ok {
+			t.Fatalf("node %s is linked but not among the graph's nodes", node.FileName)
+		}
+		return path
+	}
+
+	shape := make(map[string]nodeShape, len(g.FileNodes))
+	for path, node := range g.FileNodes {
+		s := nodeShape{FileName: node.FileName, FileType: node.FileType}
+		if node.Parent != nil {
+			s.Parent = pathOf(node.Parent)
+		}
+		for _, child := range node.Children {
+			s.Children = append(s.Children, pathOf(child))
+		}
+		for _, dep := range node.Dependencies {
+			s.Dependencies = append(s.Dependencies, pathOf(dep))
+		}
+		shape[path] = s
+	}
+	return shape
+}
+
+func TestSaveGraphRoundTrips(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "main.py", "pkg/util.py", "pkg/sub/leaf.py")
+	main, util, leaf := filepath.Join(dir, "main.py"), filepath.Join(dir, "pkg", "util.py"), filepath.Join(dir, "pkg", "sub", "leaf.py")
+	factory := &fakeAnalyzerFactory{deps: map[string][]Dependency{
+		main: {
+			{SourceFile: main, TargetFile: util, Type: ImportDependency, Weight: 1},
+			{SourceFile: main, TargetFile: leaf, Type: UsesDependency, SourceElement: "run", TargetElement: "Leaf", Weight: 0.7},
+		},
+		util: {{SourceFile: util, TargetFile: leaf, Type: ImportDependency, Weight: 1}},
+	}}
+	graph, err := analyzeDirectory(dir, &fakeAnalyzer{factory: factory})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "graph.json")
+	if err := SaveGraph(graph, path); err != nil {
+		t.Fatalf("SaveGraph: %v", err)
+	}
+	loaded, err := LoadGraph(path)
+	if err != nil {
+		t.Fatalf("LoadGraph: %v", err)
+	}
+
+	want, got := graphShape(t, graph), graphShape(t, loaded)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded nodes:\n%+v\nwant:\n%+v", got, want)
+	}
+	if got[main].Parent != dir || got[leaf].Parent != filepath.Join(dir, "pkg", "sub") {
+		t.Errorf("parents of main.py and leaf.py = %s and %s, want their directories", got[main].Parent, got[leaf].Parent)
+	}
+	if len(got[main].Dependencies) != 2 {
+		t.Errorf("main.py depends on %v, want util.py and leaf.py", got[main].Dependencies)
+	}
+	if !reflect.DeepEqual(loaded.Dependencies, graph.Dependencies) {
+		t.Errorf("loaded dependencies %+v, want %+v", loaded.Dependencies, graph.Dependencies)
+	}
+}
+
+func TestSaveGraphRejectsNil(t *testing.T) {
+	if err := SaveGraph(nil, filepath.Join(t.TempDir(), "graph.json")); err == nil {
+		t.Error("SaveGraph saved a nil graph")
+	}
+}
+
+func TestLoadGraphOfMissingFile(t *testing.T) {
+	if _, err := LoadGraph(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadGraph loaded a missing file")
+	}
+}
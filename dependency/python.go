@@ -0,0 +1,226 @@
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+var (
+	pythonImportPattern = regexp.MustCompile(`(?m)^\s*import\s+([^\n#]+)`)
+	// pythonFromImportPattern matches a "from x import ..." statement, capturing
+	// the module and either the parenthesized list of imported names, which may
+	// span several lines, or the names up to the end of the line, which may be
+	// continued with backslashes.
+	pythonFromImportPattern = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+(\S+)[ \t]+import[ \t]*(?:\(([^)]*)\)|([^\n#\\]*(?:\\\n[^\n#\\]*)*))`)
+	pythonCommentPattern    = regexp.MustCompile(`#[^\n]*`)
+	// pythonDefinitionPattern matches a module-level function, class or
+	// variable definition, capturing the defined name
+	pythonDefinitionPattern = regexp.MustCompile(`(?m)^(?:(?:async[ \t]+)?def|class)[ \t]+(\w+)|^(\w+)[ \t]*=`)
+	// pythonClassPattern matches a module-level class definition, capturing the
+	// class name
+	pythonClassPattern = regexp.MustCompile(`(?m)^class[ \t]+(\w+)`)
+)
+
+// pythonFromImport is a "from x import ..." statement: the module the names are
+// imported from and the imported elements, e.g. "y", "y as z" or "*".
+type pythonFromImport struct {
+	Module   string
+	Elements []string
+}
+
+// parsePythonFromImports returns the "from x import ..." statements of the
+// Python code, including those importing a parenthesized list of names over
+// several lines.
+func parsePythonFromImports(code string) []pythonFromImport {
+	var statements []pythonFromImport
+	for _, match := range pythonFromImportPattern.FindAllStringSubmatch(code, -1) {
+		names := match[2] + match[3]
+		names = pythonCommentPattern.ReplaceAllString(names, "")
+		names = strings.ReplaceAll(names, "\\\n", " ")
+
+		statement := pythonFromImport{Module: match[1]}
+		for _, element := range strings.Split(names, ",") {
+			if element = strings.Join(strings.Fields(element), " "); element != "" {
+				statement.Elements = append(statement.Elements, element)
+			}
+		}
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// pythonImports are the names the module-level import statements of a Python
+// file bring into scope. Modules maps the names bound by "import x" and
+// "import x as y" to the imported module. Names maps the names bound by
+// "from x import y" and "from x import y as z" to the module they are imported
+// from and their name in it. StarModules lists the modules of "from x import *".
+type pythonImports struct {
+	Modules     map[string]string
+	Names       map[string][2]string
+	StarModules []string
+}
+
+// parsePythonImports collects the import statements of the Python code.
+func parsePythonImports(code string) pythonImports {
+	imports := pythonImports{
+		Modules: make(map[string]string),
+		Names:   make(map[string][2]string),
+	}
+
+	for _, match := range pythonImportPattern.FindAllStringSubmatch(code, -1) {
+		for _, element := range strings.Split(match[1], ",") {
+			fields := strings.Fields(element)
+			switch {
+			case len(fields) == 3 && fields[1] == "as":
+				imports.Modules[fields[2]] = fields[0]
+			case len(fields) == 1:
+				imports.Modules[fields[0]] = fields[0]
+			}
+		}
+	}
+
+	for _, statement := range parsePythonFromImports(code) {
+		moduleName := statement.Module
+		for _, element := range statement.Elements {
+			fields := strings.Fields(element)
+			switch {
+			case len(fields) == 1 && fields[0] == "*":
+				imports.StarModules = append(imports.StarModules, moduleName)
+			case len(fields) == 3 && fields[1] == "as":
+				imports.Names[fields[2]] = [2]string{moduleName, fields[0]}
+			case len(fields) == 1:
+				imports.Names[fields[0]] = [2]string{moduleName, fields[0]}
+			}
+		}
+	}
+
+	return imports
+}
+
+// extractClassInheritance returns an extends dependency for every declared base
+// class of the class that is defined in another file of the project. Bases are
+// resolved through the imports of the source file; bases that do not resolve to
+// a class of a project file, such as Exception or the classes of installed
+// packages, and bases defined in the source file itself produce no dependency.
+func (a *PythonDependencyAnalyzer) extractClassInheritance(sourceFilePath string, class types.Class) []Dependency {
+	var dependencies []Dependency
+	if len(class.BaseClasses) == 0 {
+		return dependencies
+	}
+
+	code, err := os.ReadFile(sourceFilePath)
+	if err != nil {
+		return dependencies
+	}
+	imports := parsePythonImports(string(code))
+
+	for _, base := range class.BaseClasses {
+		targetFilePath, targetClass := a.resolveBaseClass(sourceFilePath, imports, base)
+		if targetFilePath == "" || targetFilePath == sourceFilePath {
+			continue
+		}
+
+		dependencies = append(dependencies, Dependency{
+			SourceFile:    sourceFilePath,
+			TargetFile:    targetFilePath,
+			Type:          DependencyType(ExtendsDependency),
+			SourceElement: class.Name,
+			TargetElement: targetClass,
+			Weight:        a.weights().Extends,
+		})
+	}
+
+	return dependencies
+}
+
+// resolveBaseClass returns the file defining the base class and the class's
+// name in it, or an empty path if the base is not a class of a project file.
+// Qualified bases such as models.Base are looked up in the module their
+// qualifier names, plain names through the from-imports of the source file.
+func (a *PythonDependencyAnalyzer) resolveBaseClass(sourceFilePath string, imports pythonImports, base string) (string, string) {
+	if qualifier, name, qualified := cutLast(base, "."); qualified {
+		moduleName := qualifier
+		if imported, ok := imports.Modules[qualifier]; ok {
+			moduleName = imported
+		} else if imported, ok := imports.Names[qualifier]; ok {
+			// "from pkg import module", then module.Base; "from . import module" names .module
+			moduleName = strings.TrimSuffix(imported[0], ".") + "." + imported[1]
+		}
+		return a.findPythonClass(sourceFilePath, moduleName, name)
+	}
+
+	if imported, ok := imports.Names[base]; ok {
+		return a.findPythonClass(sourceFilePath, imported[0], imported[1])
+	}
+
+	for _, moduleName := range imports.StarModules {
+		modulePath := a.resolveModulePath(sourceFilePath, moduleName)
+		if pythonExports(modulePath, base) {
+			if targetFilePath, name := a.findPythonClass(sourceFilePath, moduleName, base); targetFilePath != "" {
+				return targetFilePath, name
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// findPythonClass returns the file defining the class the module imported by
+// the source file exports under the given name, following the re-exports of
+// packages, or an empty path if the module is not part of the project or the
+// name is not a class.
+func (a *PythonDependencyAnalyzer) findPythonClass(sourceFilePath string, moduleName string, name string) (string, string) {
+	modulePath := a.resolveModulePath(sourceFilePath, moduleName)
+	if _, err := os.Stat(modulePath); err != nil {
+		return "", ""
+	}
+
+	targetFilePath := modulePath
+	if filepath.Base(modulePath) == "__init__.py" {
+		if definedIn := a.resolveReExport(modulePath, name); definedIn != "" {
+			targetFilePath = definedIn
+		}
+	}
+
+	if !a.fileClasses(targetFilePath)[name] {
+		return "", ""
+	}
+	return targetFilePath, name
+}
+
+// fileClasses returns the names of the top-level classes of the Python file,
+// reading the file on its first lookup. Classes nested in other classes or
+// functions cannot be imported from the file, so they are left out.
+func (a *PythonDependencyAnalyzer) fileClasses(filePath string) map[string]bool {
+	a.classesMutex.Lock()
+	defer a.classesMutex.Unlock()
+	if classes, ok := a.classes[filePath]; ok {
+		return classes
+	}
+
+	classes := make(map[string]bool)
+	if code, err := os.ReadFile(filePath); err == nil {
+		for _, match := range pythonClassPattern.FindAllSubmatch(code, -1) {
+			classes[string(match[1])] = true
+		}
+	}
+
+	if a.classes == nil {
+		a.classes = make(map[string]map[string]bool)
+	}
+	a.classes[filePath] = classes
+	return classes
+}
+
+// cutLast slices s around the last instance of sep, like strings.Cut does
+// around the first.
+func cutLast(s string, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
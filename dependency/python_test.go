@@ -0,0 +1,206 @@
+package dependency
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newMemoryCache returns a dependency cache that is not backed by Weaviate.
+func newMemoryCache() *DependencyCache {
+	return &DependencyCache{
+		cachedDeps: make(map[string]*list.Element),
+		order:      list.New(),
+		pending:    make(map[string]*dependencyCacheEntry),
+	}
+}
+
+// requirePython skips the test if there is no Python interpreter to extract
+// the metadata of Python files with.
+func requirePython(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("python"); err != nil {
+		t.Skip("python is not installed")
+	}
+}
+
+// dependencyKeys returns the dependencies as sorted strings with paths relative
+// to dir, so that dependency sets can be compared regardless of their order.
+func dependencyKeys(t *testing.T, dir string, deps []Dependency) []string {
+	t.Helper()
+	keys := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		source, err := filepath.Rel(dir, dep.SourceFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target, err := filepath.Rel(dir, dep.TargetFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, fmt.Sprintf("%s:%s -%s-> %s:%s",
+			filepath.ToSlash(source), dep.SourceElement, dep.Type, filepath.ToSlash(target), dep.TargetElement))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestPythonDependencyAnalyzerFixtures(t *testing.T) {
+	requirePython(t)
+
+	tests := []struct {
+		fixture      string
+		file         string
+		ignoreStdlib bool
+		want         []string
+	}{
+		{
+			fixture: "from_import",
+			file:    "app.py",
+			want: []string{
+				"app.py:run -import-> helpers.py:greet",
+				"app.py:run -import-> helpers.py:shout",
+				"app.py:run -uses-> helpers.py:greet",
+				"app.py:run -uses-> helpers.py:shout",
+			},
+		},
+		{
+			fixture: "plain_import",
+			file:    "app.py",
+			want: []string{
+				"app.py:total -import-> mathutils.py:",
+				"app.py:total -uses-> mathutils.py:add_all",
+			},
+		},
+		{
+			fixture: "cross_file_call",
+			file:    "report.py",
+			want: []string{
+				"report.py:render -uses-> formatting.py:format_rows",
+			},
+		},
+		{
+			fixture: "inheritance",
+			file:    "shapes.py",
+			want: []string{
+				"shapes.py:Square -extends-> base.py:Shape",
+			},
+		},
+		{
+			// Inner is nested in a class of models.py and Exception is not
+			// defined in the project, so neither is resolved
+			fixture: "qualified_inheritance",
+			file:    "app.py",
+			want: []string{
+				"app.py:Record -extends-> models.py:Base",
+			},
+		},
+		{
+			fixture: "reexport",
+			file:    "app.py",
+			want: []string{
+				"app.py:main -import-> pkg/__init__.py:parse",
+				"app.py:main -references-> pkg/parser.py:parse",
+			},
+		},
+		{
+			fixture: "reexport_multiline",
+			file:    "app.py",
+			want: []string{
+				"app.py:main -import-> pkg/__init__.py:render",
+				"app.py:main -import-> pkg/__init__.py:split",
+				"app.py:main -references-> pkg/parser.py:split",
+				"app.py:main -references-> pkg/render.py:render",
+			},
+		},
+		{
+			// os and sys are left out, the project module paths is kept
+			fixture:      "stdlib",
+			file:         "app.py",
+			ignoreStdlib: true,
+			want: []string{
+				"app.py:cwd -import-> paths.py:",
+				"app.py:cwd -uses-> paths.py:join",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			dir := filepath.Join("testdata", "python", tt.fixture)
+			analyzer := &PythonDependencyAnalyzer{
+				LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{Cache: newMemoryCache(), IgnoreStdlib: tt.ignoreStdlib},
+			}
+
+			deps, err := analyzer.AnalyzeFile(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatalf("AnalyzeFile: %v", err)
+			}
+			got := dependencyKeys(t, dir, deps)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("dependencies =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePythonFromImportsSpanningLines(t *testing.T) {
+	code := "from a import (\n    x,  # first\n    y as z,\n)\nfrom b import c, \\\n    d\nfrom e import *\n"
+
+	got := parsePythonFromImports(code)
+	want := []pythonFromImport{
+		{Module: "a", Elements: []string{"x", "y as z"}},
+		{Module: "b", Elements: []string{"c", "d"}},
+		{Module: "e", Elements: []string{"*"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePythonFromImports = %+v, want %+v", got, want)
+	}
+}
+
+func TestFileClassesReadsEachFileOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.py")
+	if err := os.WriteFile(path, []byte("class Base:\n    class Inner:\n        pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer := &PythonDependencyAnalyzer{}
+
+	want := map[string]bool{"Base": true}
+	if got := analyzer.fileClasses(path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("fileClasses = %v, want %v", got, want)
+	}
+	if err := os.WriteFile(path, []byte("class Other:\n    pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := analyzer.fileClasses(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("fileClasses after a rewrite = %v, want the classes read on the first lookup", got)
+	}
+}
+
+func TestPythonDependencyWeights(t *testing.T) {
+	requirePython(t)
+
+	weights := DependencyWeights{Import: 0.5, Uses: 0.25}
+	analyzer := &PythonDependencyAnalyzer{
+		LanguageSpecificAnalyzer: LanguageSpecificAnalyzer{Cache: newMemoryCache(), Weights: &weights},
+	}
+	deps, err := analyzer.AnalyzeFile(filepath.Join("testdata", "python", "from_import", "app.py"))
+	if err != nil {
+		t.Fatalf("AnalyzeFile: %v", err)
+	}
+
+	want := map[DependencyType]float64{ImportDependency: 0.5, UsesDependency: 0.25}
+	if len(deps) == 0 {
+		t.Fatal("no dependencies found")
+	}
+	for _, dep := range deps {
+		if dep.Weight != want[dep.Type] {
+			t.Errorf("%s dependency on %s has weight %v, want %v", dep.Type, dep.TargetElement, dep.Weight, want[dep.Type])
+		}
+	}
+}
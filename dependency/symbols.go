@@ -0,0 +1,148 @@
+package dependency
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/Marksagittarius/pinguis/scripts/python"
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// SymbolKind describes what kind of code element a symbol names.
+type SymbolKind string
+
+// Constants for symbol kinds
+const (
+	FunctionSymbol  SymbolKind = "function"
+	ClassSymbol     SymbolKind = "class"
+	MethodSymbol    SymbolKind = "method"
+	InterfaceSymbol SymbolKind = "interface"
+)
+
+// Symbol is a named code element and the file that defines it.
+// Methods are named "Class.method".
+type Symbol struct {
+	Name string     `json:"name"`
+	Kind SymbolKind `json:"kind"`
+	File string     `json:"file"`
+}
+
+// SymbolIndex maps the symbols defined in a set of parsed files to the files
+// defining them.
+type SymbolIndex struct {
+	symbols map[string][]Symbol
+	files   map[string]*types.File
+}
+
+// NewSymbolIndex creates a symbol index over the given files.
+func NewSymbolIndex(files ...*types.File) *SymbolIndex {
+	index := &SymbolIndex{
+		symbols: make(map[string][]Symbol),
+		files:   make(map[string]*types.File),
+	}
+	for _, file := range files {
+		index.Add(file)
+	}
+	return index
+}
+
+// NewPythonSymbolIndex parses the given Python files and creates a symbol index
+// over them, keyed by the given paths. Files that cannot be parsed are left out
+// of the index, files with syntax errors are indexed with the symbols that
+// could be parsed.
+func NewPythonSymbolIndex(filePaths []string) *SymbolIndex {
+	index := NewSymbolIndex()
+	for _, filePath := range filePaths {
+		file, err := python.GetFileMetaData(filePath)
+		if err != nil && !errors.Is(err, types.ErrIncompleteParse) {
+			continue
+		}
+		file.Path = filePath
+		index.Add(file)
+	}
+	return index
+}
+
+// Add indexes the functions, classes, methods and interfaces defined in the file.
+// Adding a file again replaces its previous symbols.
+func (si *SymbolIndex) Add(file *types.File) {
+	if _, exists := si.files[file.Path]; exists {
+		si.remove(file.Path)
+	}
+	si.files[file.Path] = file
+
+	for _, function := range file.Functions {
+		si.add(function.Name, FunctionSymbol, file.Path)
+	}
+	for _, class := range file.Classes {
+		si.add(class.Name, ClassSymbol, file.Path)
+		for _, method := range class.Methods {
+			si.add(class.Name+"."+method.Func.Name, MethodSymbol, file.Path)
+		}
+	}
+	for _, iface := range file.Interfaces {
+		si.add(iface.Name, InterfaceSymbol, file.Path)
+		for _, method := range iface.Methods {
+			si.add(iface.Name+"."+method.Name, MethodSymbol, file.Path)
+		}
+	}
+}
+
+func (si *SymbolIndex) add(name string, kind SymbolKind, filePath string) {
+	if name == "" {
+		return
+	}
+	si.symbols[name] = append(si.symbols[name], Symbol{Name: name, Kind: kind, File: filePath})
+}
+
+func (si *SymbolIndex) remove(filePath string) {
+	for name, symbols := range si.symbols {
+		kept := symbols[:0]
+		for _, symbol := range symbols {
+			if symbol.File != filePath {
+				kept = append(kept, symbol)
+			}
+		}
+		if len(kept) == 0 {
+			delete(si.symbols, name)
+		} else {
+			si.symbols[name] = kept
+		}
+	}
+	delete(si.files, filePath)
+}
+
+// Resolve returns the files defining the symbol with the given name, or nil if
+// no indexed file defines it. Methods are resolved by their "Class.method" name.
+func (si *SymbolIndex) Resolve(name string) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, symbol := range si.symbols[name] {
+		if !seen[symbol.File] {
+			seen[symbol.File] = true
+			files = append(files, symbol.File)
+		}
+	}
+	return files
+}
+
+// File returns the indexed file at the given path.
+func (si *SymbolIndex) File(filePath string) (*types.File, bool) {
+	file, ok := si.files[filePath]
+	return file, ok
+}
+
+// Symbols returns every indexed symbol, sorted by name and defining file.
+func (si *SymbolIndex) Symbols() []Symbol {
+	var symbols []Symbol
+	for _, named := range si.symbols {
+		symbols = append(symbols, named...)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Name != symbols[j].Name {
+			return symbols[i].Name < symbols[j].Name
+		}
+		return symbols[i].File < symbols[j].File
+	})
+	return symbols
+}
@@ -0,0 +1,96 @@
+package dependency
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+func TestSymbolIndexResolvesDefiningFiles(t *testing.T) {
+	index := NewSymbolIndex(
+		&types.File{
+			Path:      "shapes.py",
+			Functions: []types.Function{{Name: "area"}},
+			Classes: []types.Class{{
+				Name:    "Square",
+				Methods: []types.Method{{Func: types.Function{Name: "area"}}},
+			}},
+		},
+		&types.File{
+			Path:       "Shape.java",
+			Interfaces: []types.Interface{{Name: "Shape", Methods: []types.Function{{Name: "area"}}}},
+		},
+		&types.File{Path: "geometry.py", Functions: []types.Function{{Name: "area"}}},
+	)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"area", []string{"shapes.py", "geometry.py"}},
+		{"Square", []string{"shapes.py"}},
+		{"Square.area", []string{"shapes.py"}},
+		{"Shape.area", []string{"Shape.java"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := index.Resolve(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Resolve(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSymbolIndexAddReplacesSymbolsOfFile(t *testing.T) {
+	index := NewSymbolIndex(&types.File{Path: "a.py", Functions: []types.Function{{Name: "old"}}})
+	index.Add(&types.File{Path: "a.py", Functions: []types.Function{{Name: "new"}}})
+
+	if files := index.Resolve("old"); files != nil {
+		t.Errorf("Resolve(old) = %v after the file was added again without it", files)
+	}
+	want := []Symbol{{Name: "new", Kind: FunctionSymbol, File: "a.py"}}
+	if got := index.Symbols(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols = %+v, want %+v", got, want)
+	}
+	if file, ok := index.File("a.py"); !ok || file.Functions[0].Name != "new" {
+		t.Errorf("File(a.py) = %+v, %v, want the file added last", file, ok)
+	}
+}
+
+func TestSymbolIndexSymbolsAreSorted(t *testing.T) {
+	index := NewSymbolIndex(
+		&types.File{Path: "b.py", Classes: []types.Class{{Name: "Cart"}}},
+		&types.File{Path: "a.py", Classes: []types.Class{{Name: "Cart"}}, Functions: []types.Function{{Name: "add"}}},
+	)
+
+	want := []Symbol{
+		{Name: "Cart", Kind: ClassSymbol, File: "a.py"},
+		{Name: "Cart", Kind: ClassSymbol, File: "b.py"},
+		{Name: "add", Kind: FunctionSymbol, File: "a.py"},
+	}
+	if got := index.Symbols(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols = %+v, want %+v", got, want)
+	}
+}
+
+func TestPythonCallsResolveThroughSymbolIndex(t *testing.T) {
+	// The index is not limited to the directory of the calling file
+	analyzer := &PythonDependencyAnalyzer{Symbols: NewSymbolIndex(
+		&types.File{Path: "report.py", Functions: []types.Function{{Name: "render"}}},
+		&types.File{Path: "lib/formatting.py", Functions: []types.Function{{Name: "format_rows"}}},
+		&types.File{Path: "lib/parsing.py", Functions: []types.Function{{Name: "parse_rows"}}},
+	)}
+
+	deps := analyzer.extractFunctionCallsFromBody("report.py", "return format_rows(rows)", "render")
+	want := []Dependency{{
+		SourceFile:    "report.py",
+		TargetFile:    "lib/formatting.py",
+		Type:          UsesDependency,
+		SourceElement: "render",
+		TargetElement: "format_rows",
+		Weight:        DefaultDependencyWeights().Uses,
+	}}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("dependencies = %+v, want %+v", deps, want)
+	}
+}
@@ -0,0 +1,12 @@
+package com.example.app
+
+import com.example.model.User
+import com.example.model.Order
+import com.example.util.formatName
+import com.example.util.*
+import kotlin.collections.List
+
+fun main() {
+    val user = User(1, "Ada")
+    println(formatName(user.name))
+}
@@ -0,0 +1,5 @@
+package com.example.model
+
+data class Order(val id: Long, val user: User)
+
+data class LineItem(val sku: String, val quantity: Int)
@@ -0,0 +1,3 @@
+package com.example.model
+
+data class User(val id: Long, val name: String)
@@ -0,0 +1,3 @@
+package com.example.util
+
+fun formatName(name: String): String = name.trim().replaceFirstChar { it.uppercase() }
@@ -0,0 +1,2 @@
+def format_rows(rows):
+    return "\n".join(str(row) for row in rows)
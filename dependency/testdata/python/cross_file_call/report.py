@@ -0,0 +1,2 @@
+def render(rows):
+    return format_rows(rows)
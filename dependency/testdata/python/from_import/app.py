@@ -0,0 +1,3 @@
+def run(name):
+    from helpers import greet, shout
+    return shout(greet(name))
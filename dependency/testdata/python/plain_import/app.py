@@ -0,0 +1,3 @@
+def total(values):
+    import mathutils
+    return mathutils.add_all(values)
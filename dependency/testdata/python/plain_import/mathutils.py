@@ -0,0 +1,5 @@
+def add_all(values):
+    result = 0
+    for value in values:
+        result += value
+    return result
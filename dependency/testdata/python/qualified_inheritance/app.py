@@ -0,0 +1,14 @@
+import models
+from models import Inner
+
+
+class Record(models.Base):
+    pass
+
+
+class Nested(Inner):
+    pass
+
+
+class Failure(Exception):
+    pass
@@ -0,0 +1,7 @@
+class Base:
+    pass
+
+
+class Outer:
+    class Inner:
+        pass
@@ -0,0 +1,3 @@
+def main():
+    from pkg import parse
+    return parse("1")
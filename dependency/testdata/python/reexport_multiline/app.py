@@ -0,0 +1,3 @@
+def main():
+    from pkg import split, render
+    return render(split("1 2"))
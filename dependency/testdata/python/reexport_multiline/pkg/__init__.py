@@ -0,0 +1,5 @@
+from .parser import (
+    parse,  # the entry point
+    tokenize as split,
+)
+from .render import *
@@ -0,0 +1,6 @@
+def parse(text):
+    return [int(t) for t in tokenize(text)]
+
+
+def tokenize(text):
+    return text.split()
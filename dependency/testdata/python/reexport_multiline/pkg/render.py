@@ -0,0 +1,2 @@
+def render(values):
+    return ", ".join(str(v) for v in values)
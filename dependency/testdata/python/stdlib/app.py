@@ -0,0 +1,5 @@
+def cwd():
+    import os
+    import sys
+    import paths
+    return paths.join(os.getcwd(), sys.argv[0])
@@ -1,5 +1,7 @@
 package dependency
 
+import "path/filepath"
+
 type FileTree struct {
 	Root *FileNode `json:"root"`
 }
@@ -17,3 +19,58 @@ func NewFileTree(root *FileNode) *FileTree {
 		Root: root,
 	}
 }
+
+// Walk calls fn for every node of the tree in pre-order, a directory before
+// its children, together with the path of the node: the name of the root for
+// the root, joined with the names of the nodes below it for the others.
+//
+// The first error returned by fn stops the walk and is returned, except for
+// filepath.SkipDir and filepath.SkipAll. Returning filepath.SkipDir for a
+// directory skips its children, for a file it skips the remaining children of
+// the file's directory. Returning filepath.SkipAll stops the walk without an
+// error.
+func (t *FileTree) Walk(fn func(node *FileNode, path string) error) error {
+	if t.Root == nil {
+		return nil
+	}
+
+	err := walkNode(t.Root, t.Root.FileName, fn)
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkNode walks the node and the nodes below it for Walk.
+func walkNode(node *FileNode, path string, fn func(node *FileNode, path string) error) error {
+	if err := fn(node, path); err != nil {
+		if err == filepath.SkipDir && node.FileType == "dir" {
+			return nil
+		}
+		return err
+	}
+
+	for _, child := range node.Children {
+		if err := walkNode(child, filepath.Join(path, child.FileName), fn); err != nil {
+			if err == filepath.SkipDir {
+				// A file skipped the rest of this directory
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns the nodes of the tree for which predicate returns true, in the
+// pre-order of Walk.
+func (t *FileTree) Find(predicate func(*FileNode) bool) []*FileNode {
+	var nodes []*FileNode
+	t.Walk(func(node *FileNode, path string) error {
+		if predicate(node) {
+			nodes = append(nodes, node)
+		}
+		return nil
+	})
+	return nodes
+}
@@ -0,0 +1,86 @@
+package dependency
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newWalkTree returns the tree of a directory p holding a.py, a directory sub
+// holding b.py and c.py, and d.py.
+func newWalkTree() *FileTree {
+	root := NewFileNode("p", "dir")
+	sub := NewFileNode("sub", "dir")
+	root.AddChild(NewFileNode("a.py", "py"))
+	root.AddChild(sub)
+	sub.AddChild(NewFileNode("b.py", "py"))
+	sub.AddChild(NewFileNode("c.py", "py"))
+	root.AddChild(NewFileNode("d.py", "py"))
+	return NewFileTree(root)
+}
+
+// walkedPaths walks the tree, returning the paths visited, with fn deciding
+// what each visit returns.
+func walkedPaths(tree *FileTree, fn func(node *FileNode) error) ([]string, error) {
+	var paths []string
+	err := tree.Walk(func(node *FileNode, path string) error {
+		paths = append(paths, filepath.ToSlash(path))
+		return fn(node)
+	})
+	return paths, err
+}
+
+func TestWalkVisitsInPreOrder(t *testing.T) {
+	paths, err := walkedPaths(newWalkTree(), func(*FileNode) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"p", "p/a.py", "p/sub", "p/sub/b.py", "p/sub/c.py", "p/d.py"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("walked %v, want %v", paths, want)
+	}
+}
+
+// errStop is an error returned by a walk function.
+var errStop = errors.New("stop")
+
+func TestWalkSkipsAndStops(t *testing.T) {
+	cases := []struct {
+		name    string
+		stopAt  string
+		err     error
+		want    []string
+		wantErr error
+	}{
+		{"skip directory", "sub", filepath.SkipDir, []string{"p", "p/a.py", "p/sub", "p/d.py"}, nil},
+		{"skip rest of directory", "b.py", filepath.SkipDir, []string{"p", "p/a.py", "p/sub", "p/sub/b.py", "p/d.py"}, nil},
+		{"skip all", "b.py", filepath.SkipAll, []string{"p", "p/a.py", "p/sub", "p/sub/b.py"}, nil},
+		{"error", "sub", errStop, []string{"p", "p/a.py", "p/sub"}, errStop},
+	}
+	for _, c := range cases {
+		paths, err := walkedPaths(newWalkTree(), func(node *FileNode) error {
+			if node.FileName == c.stopAt {
+				return c.err
+			}
+			return nil
+		})
+		if !errors.Is(err, c.wantErr) {
+			t.Errorf("%s: Walk() = %v, want %v", c.name, err, c.wantErr)
+		}
+		if !reflect.DeepEqual(paths, c.want) {
+			t.Errorf("%s: walked %v, want %v", c.name, paths, c.want)
+		}
+	}
+}
+
+func TestFindReturnsMatchesInWalkOrder(t *testing.T) {
+	nodes := newWalkTree().Find(func(node *FileNode) bool { return node.FileType == "py" })
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.FileName)
+	}
+	if want := []string{"a.py", "b.py", "c.py", "d.py"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("found %v, want %v", names, want)
+	}
+}
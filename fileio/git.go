@@ -0,0 +1,34 @@
+package fileio
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// GitTrackedFiles returns the set of files below root that are tracked by the git
+// repository containing root. The paths are joined with root, so they compare
+// equal to the cleaned paths of a walk starting at root.
+//
+// It returns an error if git is not available or root is not inside a git
+// working tree, in which case callers fall back to considering every file.
+func GitTrackedFiles(root string) (map[string]bool, error) {
+	cmd := exec.Command("git", "-C", root, "ls-files", "-z", "--", ".")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list git-tracked files of %s: %v: %s", root, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	tracked := make(map[string]bool)
+	for _, name := range bytes.Split(output, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		tracked[filepath.Join(root, filepath.FromSlash(string(name)))] = true
+	}
+	return tracked, nil
+}
@@ -0,0 +1,123 @@
+package fileio
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+var (
+	tempDirMu sync.RWMutex
+	tempDir   string
+
+	// liveTemp holds the directories created by MkdirTempFor that have not
+	// been removed yet, by owner
+	liveTempMu sync.Mutex
+	liveTemp   = make(map[string]map[string]bool)
+)
+
+// SetTempDir sets the directory intermediate artifacts (metadata JSON, coverage
+// data, ...) are written to. An empty dir restores the system temp directory.
+func SetTempDir(dir string) {
+	tempDirMu.Lock()
+	defer tempDirMu.Unlock()
+	tempDir = dir
+}
+
+// TempDir returns the directory intermediate artifacts are written to.
+func TempDir() string {
+	tempDirMu.RLock()
+	defer tempDirMu.RUnlock()
+	if tempDir == "" {
+		return os.TempDir()
+	}
+	return tempDir
+}
+
+// MkdirTemp creates a new directory for intermediate artifacts inside TempDir,
+// creating TempDir first if needed. The caller removes it with RemoveTemp when
+// done; directories left behind are removed by CleanupTemp.
+func MkdirTemp(pattern string) (string, error) {
+	return MkdirTempFor("", pattern)
+}
+
+// MkdirTempFor works like MkdirTemp, but registers the directory for an owner,
+// e.g. the test file a callback runs, so that CleanupTempFor can remove the
+// directories an owner left behind without touching those of other owners.
+func MkdirTempFor(owner, pattern string) (string, error) {
+	dir := TempDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	created, err := os.MkdirTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	liveTempMu.Lock()
+	defer liveTempMu.Unlock()
+	if liveTemp[owner] == nil {
+		liveTemp[owner] = make(map[string]bool)
+	}
+	liveTemp[owner][created] = true
+	return created, nil
+}
+
+// RemoveTemp removes a directory created by MkdirTemp or MkdirTempFor along
+// with its contents.
+func RemoveTemp(dir string) error {
+	liveTempMu.Lock()
+	for owner, dirs := range liveTemp {
+		delete(dirs, dir)
+		if len(dirs) == 0 {
+			delete(liveTemp, owner)
+		}
+	}
+	liveTempMu.Unlock()
+	return os.RemoveAll(dir)
+}
+
+// CleanupTempFor removes the directories created by MkdirTempFor for the owners
+// that have not been removed yet, e.g. because the task creating them was
+// cancelled.
+func CleanupTempFor(owners ...string) error {
+	liveTempMu.Lock()
+	var dirs []string
+	for _, owner := range owners {
+		for dir := range liveTemp[owner] {
+			dirs = append(dirs, dir)
+		}
+		delete(liveTemp, owner)
+	}
+	liveTempMu.Unlock()
+
+	return removeAll(dirs)
+}
+
+// CleanupTemp removes every directory created by MkdirTemp or MkdirTempFor that
+// has not been removed yet, whatever its owner. It is meant for the end of the
+// process, when no other code is using its directories any longer.
+func CleanupTemp() error {
+	liveTempMu.Lock()
+	var dirs []string
+	for _, owned := range liveTemp {
+		for dir := range owned {
+			dirs = append(dirs, dir)
+		}
+	}
+	liveTemp = make(map[string]map[string]bool)
+	liveTempMu.Unlock()
+
+	return removeAll(dirs)
+}
+
+// removeAll removes the directories along with their contents.
+func removeAll(dirs []string) error {
+	var errs []error
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,81 @@
+package fileio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanupTempForRemovesOnlyTheOwnersDirectories(t *testing.T) {
+	SetTempDir(t.TempDir())
+	t.Cleanup(func() { SetTempDir("") })
+
+	mine, err := MkdirTempFor("a_test.py", "mine-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs, err := MkdirTempFor("b_test.py", "theirs-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveTemp(theirs)
+
+	if err := CleanupTempFor("a_test.py"); err != nil {
+		t.Fatalf("CleanupTempFor: %v", err)
+	}
+	if _, err := os.Stat(mine); !os.IsNotExist(err) {
+		t.Errorf("directory of the owner %s was not removed: %v", mine, err)
+	}
+	if _, err := os.Stat(theirs); err != nil {
+		t.Errorf("directory of another owner %s was removed: %v", theirs, err)
+	}
+}
+
+func TestRemoveTempForgetsTheDirectory(t *testing.T) {
+	SetTempDir(t.TempDir())
+	t.Cleanup(func() { SetTempDir("") })
+
+	dir, err := MkdirTempFor("a_test.py", "dir-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveTemp(dir); err != nil {
+		t.Fatalf("RemoveTemp: %v", err)
+	}
+
+	liveTempMu.Lock()
+	defer liveTempMu.Unlock()
+	if len(liveTemp["a_test.py"]) != 0 {
+		t.Errorf("registry still holds %v", liveTemp["a_test.py"])
+	}
+}
+
+func TestMkdirTempCreatesDirectoryInConfiguredTempDir(t *testing.T) {
+	// The configured directory is created on first use
+	custom := filepath.Join(t.TempDir(), "artifacts")
+	SetTempDir(custom)
+	t.Cleanup(func() { SetTempDir("") })
+
+	if TempDir() != custom {
+		t.Fatalf("TempDir() = %s, want %s", TempDir(), custom)
+	}
+	dir, err := MkdirTemp("coverage-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(dir) != custom {
+		t.Errorf("MkdirTemp created %s, want a directory in %s", dir, custom)
+	}
+
+	if err := RemoveTemp(dir); err != nil {
+		t.Fatalf("RemoveTemp: %v", err)
+	}
+	if entries, _ := os.ReadDir(custom); len(entries) != 0 {
+		t.Errorf("%s holds %d entries after RemoveTemp, want none", custom, len(entries))
+	}
+
+	SetTempDir("")
+	if TempDir() != os.TempDir() {
+		t.Errorf("TempDir() = %s after reset, want the system temp directory %s", TempDir(), os.TempDir())
+	}
+}
@@ -3,50 +3,59 @@ package main
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
 
 	"github.com/Marksagittarius/pinguis/dao"
 	"github.com/Marksagittarius/pinguis/fileio"
+	pmodel "github.com/Marksagittarius/pinguis/model"
 	"github.com/Marksagittarius/pinguis/prompt"
 	"github.com/Marksagittarius/pinguis/worker"
 	"github.com/cloudwego/eino-ext/components/model/ollama"
+	einomodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
 )
 
 type ChatModelTest struct {
 	model *ollama.ChatModel
+	name  string
 }
 
 func NewChatModelTest(ctx context.Context) *ChatModelTest {
-	model, err := ollama.NewChatModel(ctx, &ollama.ChatModelConfig{
+	config := &ollama.ChatModelConfig{
 		BaseURL: "http://localhost:11434",
 		Model:   "qwen2.5-coder:7b",
-	})
+	}
+	model, err := ollama.NewChatModel(ctx, config)
 
 	if err != nil {
 		panic(err)
 	}
 	return &ChatModelTest{
 		model: model,
+		name:  config.Model,
 	}
 }
 
+func (c *ChatModelTest) Name() string {
+	return c.name
+}
+
 func (c *ChatModelTest) Generate(ctx context.Context, prompt string) (*schema.Message, error) {
+	var opts []einomodel.Option
+	if maxTokens, ok := pmodel.MaxOutputTokens(ctx); ok {
+		opts = append(opts, einomodel.WithMaxTokens(maxTokens))
+	}
+
 	return c.model.Generate(ctx, []*schema.Message{
 		{
 			Role:    "user",
 			Content: prompt,
 		},
-	})
+	}, opts...)
 }
 
 func main() {
 	rootPath := "./test"
-	var pyFiles []string
 	weaviate, err := dao.New(weaviate.Config{
 		Host:   "localhost:8080",
 		Scheme: "http",
@@ -56,28 +65,9 @@ func main() {
 		panic(err)
 	}
 
-	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".py") {
-			if strings.HasSuffix(info.Name(), "_test.py") {
-				return nil
-			}
-			if strings.Contains(info.Name(), "test_case") {
-				return nil
-			}
-			pyFiles = append(pyFiles, path)
-		}
-		return nil
-	})
-
-	if err != nil {
-		panic(err)
-	}
-
 	simpleFileIO := &fileio.SimpleFileIO{}
-	promptTemplate, err := simpleFileIO.Read("./prompt.txt")
+	promptSource := prompt.FromFileOrDefault("./prompt.txt")
+	promptTemplate, err := promptSource()
 
 	if err != nil {
 		panic(err)
@@ -86,15 +76,17 @@ func main() {
 	ctx := context.Background()
 	model := NewChatModelTest(ctx)
 	symWorker := worker.NewSymPromptWorker(&worker.DeepWorkerConfig{
-		WorkerCount:       2,
-		Model:             model,
-		Callback:          worker.PyTestCallBack,
-		CoverageThreshold: 0.8,
-		MaxIterations:     3,
-		SourcePath:        rootPath,
-		TestPath:          rootPath,
+		WorkerCount:        2,
+		Model:              model,
+		StructuredCallback: worker.PyStructuredTestCallBack,
+		SeparateTestOutput: true,
+		CoverageThreshold:  0.8,
+		MaxIterations:      3,
+		SourcePath:         rootPath,
+		TestPath:           rootPath,
+		PromptSource:       promptSource,
 		PromptGenerator: func(task *worker.TestTask) string {
-			npg := prompt.NewNeoPromptGenerator(string(promptTemplate), task.SourceCode, task.SourcePath)
+			npg := prompt.NewNeoPromptGenerator(promptTemplate, task.SourceCode, task.SourcePath)
 			basePrompt := npg.WithCode(task.SourceCode, task.SourcePath).WithWeaviate(weaviate, dao.FileInfoHandler).String()
 			if task.Iterations == 0 {
 				return basePrompt
@@ -108,20 +100,45 @@ func main() {
 			return basePrompt
 		},
 	}, simpleFileIO)
+
+	plan, err := symWorker.Plan(rootPath)
+	if err != nil {
+		fmt.Printf("Unable to Plan: %v\n", err)
+	}
+	fmt.Printf("Planned %d functions in %d files with %d paths (about %d prompt tokens)\n",
+		plan.Functions, len(plan.Files), plan.Paths, plan.PromptTokens)
 	
-	for _, pyFile := range pyFiles {
-		if err := symWorker.SubmitSymTask(pyFile); err != nil {
-			fmt.Printf("Unable to Submit %s: %v\n", pyFile, err)
-			continue
-		}
+	submitted, errs := symWorker.SubmitDirectory(rootPath, nil)
+	for _, err := range errs {
+		fmt.Printf("Unable to Submit: %v\n", err)
 	}
+	fmt.Printf("Submitted %d files\n", submitted)
 
 	symWorker.Run()
-	for symWorker.ActiveTaskCount() > 0 {
-		time.Sleep(1 * time.Second)
-		fmt.Printf("Tasks Remain: %d\n", symWorker.ActiveTaskCount())
+	results, done := symWorker.Results(), symWorker.Done()
+	printResult := func(result worker.TaskResult) {
+		fmt.Printf("Finished %s %s: %.2f%% coverage after %d iterations, %d tasks remain\n",
+			result.SourcePath, result.FunctionName, result.BestCoverage*100, result.Iterations, symWorker.ActiveTaskCount())
+	}
+	for running := true; running; {
+		select {
+		case result := <-results:
+			printResult(result)
+		case <-done:
+			running = false
+		}
+	}
+	// Done may fire before every result was received, Shutdown closes Results
+	// once the remaining ones are sent
+	symWorker.Shutdown()
+	for result := range results {
+		printResult(result)
 	}
 
 	fmt.Println("All Tasks Completed.")
-	symWorker.Shutdown()
+	if coverage, err := symWorker.CombineCoverage(); err != nil {
+		fmt.Printf("Unable to Combine Coverage: %v\n", err)
+	} else {
+		fmt.Printf("Combined coverage: %.2f%% of %d statements\n", coverage.Coverage*100, coverage.Statements)
+	}
 }
@@ -12,3 +12,18 @@ import (
 type ChatModel interface {
 	Generate(ctx context.Context, prompt string) (*schema.Message, error)
 }
+
+// NamedModel is implemented by chat models that can identify the model they
+// generate with, e.g. "qwen2.5-coder:7b".
+type NamedModel interface {
+	Name() string
+}
+
+// Name returns the name the chat model identifies itself with, or an empty string
+// if it does not implement NamedModel.
+func Name(m ChatModel) string {
+	if named, ok := m.(NamedModel); ok {
+		return named.Name()
+	}
+	return ""
+}
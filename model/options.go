@@ -0,0 +1,18 @@
+package model
+
+import "context"
+
+type maxOutputTokensKey struct{}
+
+// WithMaxOutputTokens returns a context asking the ChatModel receiving it to
+// limit the generated output to the given number of tokens.
+func WithMaxOutputTokens(ctx context.Context, maxTokens int) context.Context {
+	return context.WithValue(ctx, maxOutputTokensKey{}, maxTokens)
+}
+
+// MaxOutputTokens returns the output token limit requested through the context.
+// ChatModel implementations pass it on to the underlying model if it is set.
+func MaxOutputTokens(ctx context.Context) (int, bool) {
+	maxTokens, ok := ctx.Value(maxOutputTokensKey{}).(int)
+	return maxTokens, ok && maxTokens > 0
+}
@@ -0,0 +1,60 @@
+// Package pinguis describes what pinguis supports across its packages.
+package pinguis
+
+import (
+	"github.com/Marksagittarius/pinguis/dependency"
+	"github.com/Marksagittarius/pinguis/worker"
+)
+
+// LanguageInfo describes the support pinguis has for a programming language.
+//
+// Fields:
+// - Name: The code type of the language, as used by the worker (e.g. "python").
+// - Extensions: The file extensions of the language's source files.
+// - Parser: Whether source files can be parsed into their structure.
+// - DependencyAnalyzer: Whether a language-specific dependency analyzer exists.
+// - TestCallback: Whether a test callback for the language is bundled.
+type LanguageInfo struct {
+	Name               string   `json:"name"`
+	Extensions         []string `json:"extensions"`
+	Parser             bool     `json:"parser"`
+	DependencyAnalyzer bool     `json:"dependency_analyzer"`
+	TestCallback       bool     `json:"test_callback"`
+}
+
+// languages lists the languages the worker recognizes by extension, and whether
+// each of them has a parser. Builds without the kotlin tag scan Kotlin files for
+// their declarations instead of parsing them with the tree-sitter grammar.
+var languages = []struct {
+	name       string
+	extensions []string
+	parser     bool
+}{
+	{"python", []string{".py"}, true},
+	{"java", []string{".java"}, true},
+	{"kotlin", []string{".kt"}, true},
+	{"go", []string{".go"}, false},
+	{"javascript", []string{".js"}, false},
+	{"cpp", []string{".cpp"}, false},
+}
+
+// SupportedLanguages returns the languages pinguis recognizes along with the
+// capabilities available for each of them in this build.
+func SupportedLanguages() []LanguageInfo {
+	infos := make([]LanguageInfo, 0, len(languages))
+	for _, language := range languages {
+		info := LanguageInfo{
+			Name:       language.name,
+			Extensions: append([]string(nil), language.extensions...),
+			Parser:     language.parser,
+		}
+		for _, ext := range language.extensions {
+			if dependency.HasLanguageAnalyzer(ext) {
+				info.DependencyAnalyzer = true
+			}
+		}
+		_, info.TestCallback = worker.BundledTestCallback(language.name)
+		infos = append(infos, info)
+	}
+	return infos
+}
@@ -0,0 +1,53 @@
+package pinguis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/worker"
+)
+
+func TestSupportedLanguages(t *testing.T) {
+	infos := map[string]LanguageInfo{}
+	for _, info := range SupportedLanguages() {
+		infos[info.Name] = info
+	}
+
+	tests := []struct {
+		name               string
+		extensions         []string
+		parser             bool
+		dependencyAnalyzer bool
+	}{
+		{"python", []string{".py"}, true, true},
+		{"java", []string{".java"}, true, true},
+		{"kotlin", []string{".kt"}, true, true},
+		{"go", []string{".go"}, false, true},
+		{"javascript", []string{".js"}, false, false},
+		{"cpp", []string{".cpp"}, false, false},
+	}
+	if len(infos) != len(tests) {
+		t.Errorf("SupportedLanguages() reports %d languages, want %d", len(infos), len(tests))
+	}
+	for _, tt := range tests {
+		info, ok := infos[tt.name]
+		if !ok {
+			t.Errorf("%s is not reported", tt.name)
+			continue
+		}
+		if !reflect.DeepEqual(info.Extensions, tt.extensions) {
+			t.Errorf("%s extensions = %v, want %v", tt.name, info.Extensions, tt.extensions)
+		}
+		if info.Parser != tt.parser || info.DependencyAnalyzer != tt.dependencyAnalyzer {
+			t.Errorf("%s parser %v, dependency analyzer %v, want %v, %v",
+				tt.name, info.Parser, info.DependencyAnalyzer, tt.parser, tt.dependencyAnalyzer)
+		}
+		// The reported callback is the one the worker bundles
+		if _, bundled := worker.BundledTestCallback(tt.name); info.TestCallback != bundled {
+			t.Errorf("%s test callback = %v, but the worker bundles one: %v", tt.name, info.TestCallback, bundled)
+		}
+	}
+	if !infos["python"].TestCallback {
+		t.Error("python reports no test callback, want the bundled pytest callback")
+	}
+}
@@ -0,0 +1,242 @@
+package postprocessor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultDuplicateThreshold is the similarity from which DedupTestFunctions
+// considers two test functions duplicates. Tests checking different values of
+// the same call differ in few tokens, so by default only tests that are
+// identical up to their names, comment lines and formatting are duplicates.
+const DefaultDuplicateThreshold = 1.0
+
+var (
+	pythonTestStartPattern = regexp.MustCompile(`^(\s*)(?:async\s+)?def\s+(test\w*)\s*\(`)
+	goTestStartPattern     = regexp.MustCompile(`^func\s+(Test\w*)\s*\(`)
+	testTokenPattern       = regexp.MustCompile(`\w+|[^\s\w]`)
+	testCommentPatterns    = map[string]*regexp.Regexp{
+		"python": regexp.MustCompile(`(?m)^\s*#.*$`),
+		"go":     regexp.MustCompile(`(?m)^\s*//.*$`),
+	}
+)
+
+// testFunctionSpan is the range of lines [start, end) a test function occupies,
+// its decorators and the comments directly above it included, and its name.
+type testFunctionSpan struct {
+	start int
+	end   int
+	name  string
+}
+
+// DedupTestFunctions removes test functions that are near-duplicates of an
+// earlier test function in the same code, e.g. after the tests generated for
+// several functions were merged into one file. Two test functions are
+// duplicates when the similarity of their tokens, ignoring their names and
+// comment lines, is at least the threshold (DefaultDuplicateThreshold if threshold
+// is 0). The similarity is 2*M/T, where M is the length of the longest common
+// subsequence of the two token sequences and T their total length.
+//
+// Parameters:
+//
+//	code - the test code to deduplicate.
+//	lang - the language of the code; Python and Go tests are deduplicated,
+//	       code in other languages is returned unchanged.
+//	threshold - the similarity between 0 and 1 from which tests are duplicates.
+//
+// Returns:
+//
+//	The code without the duplicate test functions.
+func DedupTestFunctions(code, lang string, threshold float64) string {
+	if threshold == 0 {
+		threshold = DefaultDuplicateThreshold
+	}
+
+	lines := strings.Split(code, "\n")
+	var spans []testFunctionSpan
+	switch lang {
+	case "python":
+		spans = pythonTestSpans(lines)
+	case "go":
+		spans = goTestSpans(lines)
+	}
+	if len(spans) < 2 {
+		return code
+	}
+
+	var kept [][]string
+	drop := make(map[int]bool)
+	for i, span := range spans {
+		tokens := testTokens(lines[span.start:span.end], span.name, testCommentPatterns[lang])
+		duplicate := false
+		for _, other := range kept {
+			if tokenSimilarity(tokens, other) >= threshold {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			drop[i] = true
+			continue
+		}
+		kept = append(kept, tokens)
+	}
+	if len(drop) == 0 {
+		return code
+	}
+	return removeSpans(lines, spans, drop)
+}
+
+// TrimTestFunctions removes the test functions of the code after the first max,
+// e.g. when a model generated more tests than asked for. It reports false,
+// returning the code unchanged, if the test functions of the language cannot be
+// told apart; Python and Go tests can. The code may be a whole model response,
+// with the tests in fenced blocks.
+func TrimTestFunctions(code, lang string, max int) (string, bool) {
+	lines := strings.Split(code, "\n")
+	var spans []testFunctionSpan
+	switch lang {
+	case "python":
+		spans = pythonTestSpans(lines)
+	case "go":
+		spans = goTestSpans(lines)
+	default:
+		return code, false
+	}
+	if len(spans) <= max {
+		return code, true
+	}
+
+	drop := make(map[int]bool)
+	for i := max; i < len(spans); i++ {
+		drop[i] = true
+	}
+	return removeSpans(lines, spans, drop), true
+}
+
+// removeSpans returns the lines without the spans whose index is in drop, joined
+// into code.
+func removeSpans(lines []string, spans []testFunctionSpan, drop map[int]bool) string {
+	var result []string
+	next := 0
+	for i, span := range spans {
+		if !drop[i] {
+			continue
+		}
+		result = append(result, lines[next:span.start]...)
+		next = span.end
+		// Drop the blank lines separating the removed function from the next statement
+		for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+			next++
+		}
+	}
+	result = append(result, lines[next:]...)
+	return strings.Join(result, "\n")
+}
+
+// pythonTestSpans returns the test functions of Python code. A test function
+// ends before the first non-blank line indented no deeper than its def line.
+func pythonTestSpans(lines []string) []testFunctionSpan {
+	var spans []testFunctionSpan
+	for i := 0; i < len(lines); i++ {
+		match := pythonTestStartPattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		indent := len(match[1])
+
+		start := i
+		for start > 0 && leadingSpace(lines[start-1]) == indent &&
+			(strings.HasPrefix(strings.TrimSpace(lines[start-1]), "@") || strings.HasPrefix(strings.TrimSpace(lines[start-1]), "#")) {
+			start--
+		}
+
+		end := i + 1
+		for end < len(lines) {
+			line := lines[end]
+			if strings.TrimSpace(line) != "" && leadingSpace(line) <= indent {
+				break
+			}
+			end++
+		}
+		// Leave the blank lines after the function to the code that follows
+		for end > i+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+
+		spans = append(spans, testFunctionSpan{start: start, end: end, name: match[2]})
+		i = end - 1
+	}
+	return spans
+}
+
+// goTestSpans returns the top-level test functions of Go code, each ending with
+// the first closing brace at the start of a line.
+func goTestSpans(lines []string) []testFunctionSpan {
+	var spans []testFunctionSpan
+	for i := 0; i < len(lines); i++ {
+		match := goTestStartPattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+
+		start := i
+		for start > 0 && strings.HasPrefix(lines[start-1], "//") {
+			start--
+		}
+
+		end := i
+		for end < len(lines) && !strings.HasPrefix(lines[end], "}") {
+			end++
+		}
+		if end == len(lines) {
+			break
+		}
+
+		spans = append(spans, testFunctionSpan{start: start, end: end + 1, name: match[1]})
+		i = end
+	}
+	return spans
+}
+
+func leadingSpace(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// testTokens splits the lines of a test function into tokens, without comment
+// lines and with its name replaced so that tests differing only in their names
+// compare equal.
+func testTokens(lines []string, name string, comment *regexp.Regexp) []string {
+	code := strings.Join(lines, "\n")
+	if comment != nil {
+		code = comment.ReplaceAllString(code, "")
+	}
+	tokens := testTokenPattern.FindAllString(code, -1)
+	for i, token := range tokens {
+		if token == name {
+			tokens[i] = "_"
+		}
+	}
+	return tokens
+}
+
+// tokenSimilarity returns the similarity of two token sequences between 0 and 1.
+func tokenSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				current[j] = previous[j-1] + 1
+			} else {
+				current[j] = max(previous[j], current[j-1])
+			}
+		}
+		previous, current = current, previous
+	}
+	return 2 * float64(previous[len(b)]) / float64(len(a)+len(b))
+}
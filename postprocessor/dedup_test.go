@@ -0,0 +1,79 @@
+package postprocessor
+
+import "testing"
+
+func TestTrimTestFunctionsKeepsTheFirstTests(t *testing.T) {
+	code := "import calc\n\n" +
+		"def test_one():\n    assert calc.add(1, 1) == 2\n\n" +
+		"# Adds zero\ndef test_two():\n    assert calc.add(1, 0) == 1\n\n" +
+		"def test_three():\n    assert calc.add(0, 0) == 0\n"
+
+	trimmed, ok := TrimTestFunctions(code, "python", 1)
+	if !ok {
+		t.Fatal("TrimTestFunctions cannot trim Python tests")
+	}
+	want := "import calc\n\ndef test_one():\n    assert calc.add(1, 1) == 2\n"
+	if trimmed != want {
+		t.Errorf("trimmed code = %q, want %q", trimmed, want)
+	}
+	if count := CountTestFunctions(trimmed, "python"); count != 1 {
+		t.Errorf("trimmed code has %d tests, want 1", count)
+	}
+}
+
+func TestTrimTestFunctionsOfGoResponse(t *testing.T) {
+	response := "```go\npackage calc\n\nfunc TestA(t *testing.T) {\n}\n\nfunc TestB(t *testing.T) {\n}\n```"
+
+	trimmed, ok := TrimTestFunctions(response, "go", 1)
+	if !ok {
+		t.Fatal("TrimTestFunctions cannot trim Go tests")
+	}
+	want := "```go\npackage calc\n\nfunc TestA(t *testing.T) {\n}\n\n```"
+	if trimmed != want {
+		t.Errorf("trimmed response = %q, want %q", trimmed, want)
+	}
+}
+
+func TestTrimTestFunctionsOfUnsupportedLanguage(t *testing.T) {
+	code := "@Test void a() {}\n@Test void b() {}\n"
+	if trimmed, ok := TrimTestFunctions(code, "java", 1); ok || trimmed != code {
+		t.Errorf("TrimTestFunctions(java) = %q, %v, want the code unchanged and false", trimmed, ok)
+	}
+}
+
+func TestDedupTestFunctionsOfMergedTests(t *testing.T) {
+	// The tests generated for inc and dec, merged into one file
+	inc := "def test_inc():\n    assert calc.inc(1) == 2\n\n" +
+		"def test_zero():\n    assert calc.zero() == 0\n"
+	dec := "# Zero stays zero\ndef test_zero_again():\n    assert calc.zero()  ==  0\n\n" +
+		"def test_dec():\n    assert calc.dec(1) == 0\n"
+	merged := "import calc\n\n" + inc + "\n" + dec
+
+	want := "import calc\n\n" + inc + "\n" + "def test_dec():\n    assert calc.dec(1) == 0\n"
+	if got := DedupTestFunctions(merged, "python", 0); got != want {
+		t.Errorf("deduplicated code =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDedupTestFunctionsThreshold(t *testing.T) {
+	code := "func TestAddOne(t *testing.T) {\n\tif Add(1, 1) != 2 {\n\t\tt.Fail()\n\t}\n}\n\n" +
+		"func TestAddTwo(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fail()\n\t}\n}\n"
+
+	tests := []struct {
+		name      string
+		threshold float64
+		tests     int
+	}{
+		// By default only identical tests are duplicates
+		{"default", 0, 2},
+		{"lower threshold", 0.8, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DedupTestFunctions(code, "go", tt.threshold)
+			if count := CountTestFunctions(got, "go"); count != tt.tests {
+				t.Errorf("%d tests left, want %d:\n%s", count, tt.tests, got)
+			}
+		})
+	}
+}
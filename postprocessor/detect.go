@@ -0,0 +1,56 @@
+package postprocessor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// codeStartPattern matches a line that starts code rather than prose: an
+// import, a function, class or package declaration, or a decorator.
+var codeStartPattern = regexp.MustCompile(`^\s*(?:(?:import|package|class|def|func|function)\s|async\s+def\s|from\s+\S+\s+import\s|@\w)`)
+
+// DetectingCodeExtractor extracts code like CodeExtractor, but does not give up
+// when the model fences its code with another tag, e.g. ```text, or without one:
+// it falls back to the first fenced block of any language and, if the response
+// has no fenced block at all, to the response without the prose leading up to
+// the first line that looks like code.
+type DetectingCodeExtractor struct {
+	language *CodeExtractor
+	any      *CodeExtractor
+}
+
+// NewDetectingCodeExtractor creates a DetectingCodeExtractor preferring the
+// fenced blocks of codeType and its aliases, as NewCodeExtractor matches them.
+func NewDetectingCodeExtractor(codeType string, aliases ...string) *DetectingCodeExtractor {
+	return &DetectingCodeExtractor{
+		language: NewCodeExtractor(codeType, aliases...),
+		any:      NewCodeExtractor(""),
+	}
+}
+
+// Postprocess extracts the code of the model response raw. It returns the
+// trimmed content of the first fenced block of the configured language, or else
+// of the first fenced block of any language, or else the trimmed response from
+// its first line that looks like code on. If no line looks like code, the
+// trimmed response is returned whole.
+func (dce *DetectingCodeExtractor) Postprocess(raw string) string {
+	if blocks := dce.language.PostprocessAll(raw); len(blocks) > 0 {
+		return blocks[0]
+	}
+	if blocks := dce.any.PostprocessAll(raw); len(blocks) > 0 {
+		return blocks[0]
+	}
+	return stripLeadingProse(raw)
+}
+
+// stripLeadingProse drops the lines of the text before the first one that looks
+// like code.
+func stripLeadingProse(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if codeStartPattern.MatchString(line) {
+			return strings.TrimSpace(strings.Join(lines[i:], "\n"))
+		}
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,62 @@
+package postprocessor
+
+import "testing"
+
+func TestDetectingCodeExtractorFallsBack(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "block of the language",
+			raw:  "```text\nnotes\n```\n```python\nx = 1\n```",
+			want: "x = 1",
+		},
+		{
+			name: "block of another language",
+			raw:  "Here it is:\n```text\nimport pytest\n```\n```sh\npytest\n```",
+			want: "import pytest",
+		},
+		{
+			name: "block without a tag",
+			raw:  "Here it is:\n```\nx = 1\n```",
+			want: "x = 1",
+		},
+		{
+			name: "leading prose",
+			raw:  "Sure, here are the tests.\nThey cover both branches.\n\nimport pytest\n\ndef test_a():\n    assert a() == 1\n",
+			want: "import pytest\n\ndef test_a():\n    assert a() == 1",
+		},
+		{
+			name: "leading prose before a decorator",
+			raw:  "The tests:\n@pytest.mark.slow\ndef test_a():\n    pass",
+			want: "@pytest.mark.slow\ndef test_a():\n    pass",
+		},
+		{
+			name: "leading prose before an import from a module",
+			raw:  "The tests:\nfrom calc import add\n",
+			want: "from calc import add",
+		},
+		{
+			name: "no line looks like code",
+			raw:  "  The function cannot be tested.\n",
+			want: "The function cannot be tested.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDetectingCodeExtractor("python").Postprocess(tt.raw); got != tt.want {
+				t.Errorf("Postprocess() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectingCodeExtractorIsCodePostprocessor(t *testing.T) {
+	var pp CodePostprocessor = NewDetectingCodeExtractor("go", "golang")
+	if got := pp.Postprocess("```golang\npackage calc\n```"); got != "package calc" {
+		t.Errorf("Postprocess() = %q, want the golang block", got)
+	}
+}
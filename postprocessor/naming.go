@@ -0,0 +1,233 @@
+package postprocessor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	namePlaceholderPattern = regexp.MustCompile(`\{(function|Function|case|Case)\}`)
+	nameWordPattern        = regexp.MustCompile(`[A-Z]+[a-z0-9]*|[a-z0-9]+`)
+	caseOperatorWords      = strings.NewReplacer(
+		">=", " ge ", "<=", " le ", "==", " eq ", "!=", " ne ", ">", " gt ", "<", " lt ",
+		"&&", " and ", "||", " or ", "!", " not ",
+	)
+)
+
+// maxCaseWords is the most words a case description derived from a path
+// constraint keeps, so that long conditions still give readable names.
+const maxCaseWords = 8
+
+// RenameTestFunctions renames the test functions of the code that do not follow
+// the naming pattern, keeping their bodies. The pattern is a test function name
+// with placeholders: {function} is the name of the function under test and
+// {Function} the same name capitalized, {case} describes the test case in
+// snake_case and {Case} in CamelCase. For example, "test_{function}_{case}"
+// names a Python test test_add_negative_numbers and "Test{Function}_{Case}" a Go
+// test TestAdd_NegativeNumbers.
+//
+// The case of the i-th test function is derived from cases[i] if there is a case
+// for every test function, e.g. the path constraint the test was asked to cover;
+// otherwise, or if that case is empty, it is derived from the name the test
+// function already has, without its test prefix and the name of the function
+// under test. Test functions whose new name would not be recognized as a test
+// any more, or would not be an identifier, keep their name, and a name already
+// taken gets a numbered suffix.
+//
+// Parameters:
+//
+//	code - the test code to rename the test functions of.
+//	lang - the language of the code; Python and Go tests are renamed, code in
+//	       other languages is returned unchanged.
+//	pattern - the naming pattern of test functions.
+//	function - the name of the function under test.
+//	cases - the descriptions of the test cases in the order of the test functions.
+//
+// Returns:
+//
+//	The code with every test function following the pattern.
+func RenameTestFunctions(code, lang, pattern, function string, cases []string) string {
+	var startPattern *regexp.Regexp
+	var spans func([]string) []testFunctionSpan
+	switch lang {
+	case "python":
+		startPattern, spans = pythonTestStartPattern, pythonTestSpans
+	case "go":
+		startPattern, spans = goTestStartPattern, goTestSpans
+	default:
+		return code
+	}
+
+	lines := strings.Split(code, "\n")
+	tests := spans(lines)
+	if len(tests) == 0 || pattern == "" {
+		return code
+	}
+	if len(cases) != len(tests) {
+		cases = nil
+	}
+
+	conforming := namePatternRegexp(pattern, function)
+	taken := make(map[string]bool)
+	for _, test := range tests {
+		taken[test.name] = true
+	}
+
+	for i, test := range tests {
+		if conforming.MatchString(test.name) {
+			continue
+		}
+
+		var words []string
+		if cases != nil {
+			words = caseWords(cases[i])
+		}
+		if len(words) == 0 {
+			words = nameCaseWords(test.name, function)
+		}
+		if len(words) == 0 {
+			words = []string{"case", fmt.Sprint(i + 1)}
+		}
+
+		name := renderTestName(pattern, function, words)
+		if !isIdentifier(name) {
+			continue
+		}
+		base := name
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+
+		line := definitionLine(lines, test, startPattern)
+		renamed := renameDefinition(lines[line], startPattern, name)
+		if !startPattern.MatchString(renamed) {
+			continue
+		}
+		lines[line] = renamed
+		taken[name] = true
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// namePatternRegexp returns the regexp matching the names that follow the
+// pattern for the function.
+func namePatternRegexp(pattern, function string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, loc := range namePlaceholderPattern.FindAllStringSubmatchIndex(pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(pattern[last:loc[0]]))
+		switch pattern[loc[2]:loc[3]] {
+		case "function":
+			expr.WriteString(regexp.QuoteMeta(function))
+		case "Function":
+			expr.WriteString(regexp.QuoteMeta(capitalize(function)))
+		case "case":
+			expr.WriteString(`[a-z0-9]+(?:_[a-z0-9]+)*`)
+		case "Case":
+			expr.WriteString(`(?:[A-Z0-9][a-z0-9]*)+`)
+		}
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// renderTestName fills in the placeholders of the pattern.
+func renderTestName(pattern, function string, words []string) string {
+	return namePlaceholderPattern.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		switch placeholder {
+		case "{function}":
+			return function
+		case "{Function}":
+			return capitalize(function)
+		case "{case}":
+			return strings.Join(words, "_")
+		default:
+			var camel strings.Builder
+			for _, word := range words {
+				camel.WriteString(capitalize(word))
+			}
+			return camel.String()
+		}
+	})
+}
+
+// caseWords splits the description of a test case into lowercase words, with
+// the comparison and logical operators of a path constraint spelled out, e.g.
+// "not(x >= 0)" into not, x, ge and 0.
+func caseWords(description string) []string {
+	words := identifierWords(caseOperatorWords.Replace(description))
+	if len(words) > maxCaseWords {
+		words = words[:maxCaseWords]
+	}
+	return words
+}
+
+// nameCaseWords returns the words of a test function name that describe its
+// case: those after the test prefix and the name of the function under test.
+func nameCaseWords(name, function string) []string {
+	words := identifierWords(name)
+	if len(words) > 0 && words[0] == "test" {
+		words = words[1:]
+	}
+	functionWords := identifierWords(function)
+	if len(functionWords) > 0 && len(words) >= len(functionWords) &&
+		strings.Join(words[:len(functionWords)], "_") == strings.Join(functionWords, "_") {
+		words = words[len(functionWords):]
+	}
+	return words
+}
+
+// identifierWords splits snake_case and CamelCase identifiers and any other
+// text into lowercase words.
+func identifierWords(text string) []string {
+	var words []string
+	for _, word := range nameWordPattern.FindAllString(text, -1) {
+		words = append(words, strings.ToLower(word))
+	}
+	return words
+}
+
+// definitionLine returns the index of the line declaring the test function.
+func definitionLine(lines []string, test testFunctionSpan, startPattern *regexp.Regexp) int {
+	for i := test.start; i < test.end; i++ {
+		if startPattern.MatchString(lines[i]) {
+			return i
+		}
+	}
+	return test.start
+}
+
+// renameDefinition replaces the name in the line declaring a test function,
+// the last group of the start pattern.
+func renameDefinition(line string, startPattern *regexp.Regexp, name string) string {
+	loc := startPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return line
+	}
+	start, end := loc[len(loc)-2], loc[len(loc)-1]
+	return line[:start] + name + line[end:]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,45 @@
+package postprocessor
+
+import "testing"
+
+func TestRenameTestFunctionsFromPathConstraints(t *testing.T) {
+	code := "from calc import clamp\n\n" +
+		"def test_one():\n    assert clamp(-1) == 0\n\n" +
+		"def test_two():\n    assert clamp(5) == 5\n"
+
+	renamed := RenameTestFunctions(code, "python", "test_{function}_{case}", "clamp", []string{"x < 0", "not(x < 0)"})
+	want := "from calc import clamp\n\n" +
+		"def test_clamp_x_lt_0():\n    assert clamp(-1) == 0\n\n" +
+		"def test_clamp_not_x_lt_0():\n    assert clamp(5) == 5\n"
+	if renamed != want {
+		t.Errorf("renamed code:\n%s\nwant:\n%s", renamed, want)
+	}
+}
+
+func TestRenameTestFunctionsFromTheirNames(t *testing.T) {
+	code := "func TestNegative(t *testing.T) {\n\tif Abs(-2) != 2 {\n\t\tt.Fail()\n\t}\n}\n\n" +
+		"func TestAbs_Zero(t *testing.T) {\n}\n\n" +
+		"func TestAbsLarge(t *testing.T) {\n}\n"
+
+	renamed := RenameTestFunctions(code, "go", "Test{Function}_{Case}", "Abs", nil)
+	want := "func TestAbs_Negative(t *testing.T) {\n\tif Abs(-2) != 2 {\n\t\tt.Fail()\n\t}\n}\n\n" +
+		"func TestAbs_Zero(t *testing.T) {\n}\n\n" +
+		"func TestAbs_Large(t *testing.T) {\n}\n"
+	if renamed != want {
+		t.Errorf("renamed code:\n%s\nwant:\n%s", renamed, want)
+	}
+}
+
+func TestRenameTestFunctionsNumbersTakenNames(t *testing.T) {
+	code := "def test_add_small():\n    pass\n\n" +
+		"def check_small():\n    pass\n\n" +
+		"def test_small():\n    pass\n"
+
+	renamed := RenameTestFunctions(code, "python", "test_{function}_{case}", "add", nil)
+	want := "def test_add_small():\n    pass\n\n" +
+		"def check_small():\n    pass\n\n" +
+		"def test_add_small_2():\n    pass\n"
+	if renamed != want {
+		t.Errorf("renamed code:\n%s\nwant:\n%s", renamed, want)
+	}
+}
@@ -35,19 +35,87 @@ func (pce *PythonCodeExtractor) Postprocess(raw string) string {
 
 type CodeExtractor struct{
 	codeType string
+	aliases  []string
 }
 
-func NewCodeExtractor(codeType string) *CodeExtractor {
+// languageAliases lists the other fence tags models commonly use for a
+// language, matched by every CodeExtractor of that language.
+var languageAliases = map[string][]string{
+	"python":     {"py", "python3", "py3"},
+	"javascript": {"js", "node", "mjs"},
+	"typescript": {"ts"},
+	"go":         {"golang"},
+	"kotlin":     {"kt"},
+}
+
+// NewCodeExtractor creates a CodeExtractor matching fenced blocks tagged with
+// codeType, regardless of case and of whitespace after the tag. Blocks tagged
+// with a common alias of the language (e.g. "py" for "python") or any of the
+// given aliases are matched as well. An empty codeType matches every fenced
+// block, whatever its tag.
+func NewCodeExtractor(codeType string, aliases ...string) *CodeExtractor {
 	return &CodeExtractor{
 		codeType: codeType,
+		aliases:  aliases,
 	}
 }
 
+// blockPattern returns the regular expression matching the fenced blocks of the
+// configured language and its aliases, or of any language if none is
+// configured.
+func (ce *CodeExtractor) blockPattern() *regexp.Regexp {
+	if ce.codeType == "" {
+		return regexp.MustCompile("(?s)```[^`\\n]*\\n(.*?)```")
+	}
+	tags := []string{regexp.QuoteMeta(ce.codeType)}
+	for _, alias := range languageAliases[strings.ToLower(ce.codeType)] {
+		tags = append(tags, regexp.QuoteMeta(alias))
+	}
+	for _, alias := range ce.aliases {
+		tags = append(tags, regexp.QuoteMeta(alias))
+	}
+	return regexp.MustCompile("(?s)```(?i:" + strings.Join(tags, "|") + ")[ \\t]*\\r?\\n(.*?)```")
+}
+
 func (ce *CodeExtractor) Postprocess(raw string) string {
-	re := regexp.MustCompile("(?s)```" + ce.codeType + "\\n(.*?)```")
-	match := re.FindStringSubmatch(raw)
+	match := ce.blockPattern().FindStringSubmatch(raw)
 	if len(match) > 1 {
 		return strings.TrimSpace(match[1])
 	}
 	return strings.TrimSpace(raw)
 }
+
+// PostprocessAll extracts every fenced block of the configured language from the
+// given raw string, in the order they appear.
+//
+// Parameters:
+//   raw - the input string potentially containing several code blocks.
+//
+// Returns:
+//   The trimmed contents of the matching code blocks, or nil if the input
+//   contains none.
+func (ce *CodeExtractor) PostprocessAll(raw string) []string {
+	var blocks []string
+	for _, match := range ce.blockPattern().FindAllStringSubmatch(raw, -1) {
+		blocks = append(blocks, strings.TrimSpace(match[1]))
+	}
+	return blocks
+}
+
+// PostprocessJoined extracts every fenced block of the configured language from
+// the given raw string and joins them, in the order they appear, separated by a
+// blank line, so a test split over several blocks is kept whole.
+//
+// Parameters:
+//   raw - the input string potentially containing several code blocks.
+//
+// Returns:
+//   The joined contents of the matching code blocks, or the trimmed original
+//   input string if it contains none, like Postprocess.
+func (ce *CodeExtractor) PostprocessJoined(raw string) string {
+	blocks := ce.PostprocessAll(raw)
+	if len(blocks) == 0 {
+		return strings.TrimSpace(raw)
+	}
+	return strings.Join(blocks, "\n\n")
+}
@@ -0,0 +1,83 @@
+package postprocessor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostprocessAllReturnsBlocksInOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{"zero blocks", "The function cannot be tested.", nil},
+		{"one block", "Here is the test:\n```python\ndef test_a():\n    pass\n```\n", []string{"def test_a():\n    pass"}},
+		{
+			name: "three blocks",
+			raw: "```python\nimport pytest\n```\nA fixture:\n```python\n@pytest.fixture\ndef calc():\n    return Calc()\n```\n" +
+				"Run it with:\n```sh\npytest\n```\n```python\ndef test_add(calc):\n    assert calc.add(1, 2) == 3\n```",
+			want: []string{
+				"import pytest",
+				"@pytest.fixture\ndef calc():\n    return Calc()",
+				"def test_add(calc):\n    assert calc.add(1, 2) == 3",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewCodeExtractor("python").PostprocessAll(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PostprocessAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostprocessJoinedConcatenatesBlocks(t *testing.T) {
+	raw := "```python\nimport pytest\n```\n```sh\npytest\n```\n```python\ndef test_a():\n    pass\n```"
+	ce := NewCodeExtractor("python")
+
+	if got, want := ce.PostprocessJoined(raw), "import pytest\n\ndef test_a():\n    pass"; got != want {
+		t.Errorf("PostprocessJoined() = %q, want %q", got, want)
+	}
+	// Postprocess still returns only the first block
+	if got, want := ce.Postprocess(raw), "import pytest"; got != want {
+		t.Errorf("Postprocess() = %q, want %q", got, want)
+	}
+	if got, want := ce.PostprocessJoined("  no code here\n"), "no code here"; got != want {
+		t.Errorf("PostprocessJoined() without blocks = %q, want %q", got, want)
+	}
+}
+
+func TestPostprocessMatchesLanguageAliasesAndWhitespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		codeType string
+		raw      string
+	}{
+		{"capitalized tag", "python", "Sure:\n```Python\nx = 1\n```"},
+		{"alias", "python", "Sure:\n```py\nx = 1\n```"},
+		{"alias of another case", "javascript", "Sure:\n```JS\nx = 1\n```"},
+		{"trailing space", "python", "Sure:\n```python \nx = 1\n```"},
+		{"trailing tab and carriage return", "python", "Sure:\r\n```python\t\r\nx = 1\n```"},
+		{"extra alias", "python", "Sure:\n```pytest\nx = 1\n```"},
+		{"any tag without a code type", "", "Sure:\n```text\nx = 1\n```"},
+		{"no tag without a code type", "", "Sure:\n```\nx = 1\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewCodeExtractor(tt.codeType, "pytest").Postprocess(tt.raw); got != "x = 1" {
+				t.Errorf("Postprocess() = %q, want %q", got, "x = 1")
+			}
+		})
+	}
+}
+
+func TestPostprocessSkipsBlocksOfOtherLanguages(t *testing.T) {
+	raw := "```pyx\ncdef int x\n```\n```python\nx = 1\n```"
+	if got := NewCodeExtractor("python").Postprocess(raw); got != "x = 1" {
+		t.Errorf("Postprocess() = %q, want the python block", got)
+	}
+}
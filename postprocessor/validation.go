@@ -0,0 +1,66 @@
+package postprocessor
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	testFunctionPatterns = map[string]*regexp.Regexp{
+		"python":     regexp.MustCompile(`(?m)^\s*(?:async\s+)?def\s+test\w*\s*\(`),
+		"go":         regexp.MustCompile(`(?m)^func\s+Test\w*\s*\(`),
+		"java":       regexp.MustCompile(`(?m)@Test\b`),
+		"kotlin":     regexp.MustCompile(`(?m)@Test\b`),
+		"javascript": regexp.MustCompile(`(?m)\b(?:it|test)\s*\(`),
+	}
+	assertionPatterns = map[string]*regexp.Regexp{
+		"python":     regexp.MustCompile(`(?m)^\s*assert\b|\bself\.assert\w*\s*\(|\bpytest\.raises\s*\(`),
+		"go":         regexp.MustCompile(`\bt\.(?:Error|Errorf|Fatal|Fatalf)\s*\(|\b(?:assert|require)\.\w+\s*\(`),
+		"java":       regexp.MustCompile(`\bassert\w*\s*\(`),
+		"kotlin":     regexp.MustCompile(`\bassert\w*\s*\(`),
+		"javascript": regexp.MustCompile(`\bexpect\s*\(|\bassert\w*(?:\.\w+)?\s*\(`),
+	}
+)
+
+// CountTestFunctions counts the test functions in the code according to the
+// test-naming convention of the given language (def test_, func TestX, @Test, ...).
+// It returns 0 for languages without a known convention.
+func CountTestFunctions(code, lang string) int {
+	re, ok := testFunctionPatterns[lang]
+	if !ok {
+		return 0
+	}
+	return len(re.FindAllStringIndex(code, -1))
+}
+
+// CountAssertions counts the assertion statements in the code using the
+// assertion idioms of the given language. It returns 0 for languages without
+// known idioms.
+func CountAssertions(code, lang string) int {
+	re, ok := assertionPatterns[lang]
+	if !ok {
+		return 0
+	}
+	return len(re.FindAllStringIndex(code, -1))
+}
+
+// IsLikelyTest reports whether the code looks like a test in the given language,
+// i.e. it defines at least one test function and makes at least one assertion.
+// Code in a language without known test idioms is accepted unless it is empty.
+//
+// Parameters:
+//   code - the extracted code to check.
+//   lang - the language of the code (e.g. "python", "go").
+//
+// Returns:
+//   false for empty code and for code that is not a test, such as the
+//   implementation under test echoed back by the model.
+func IsLikelyTest(code, lang string) bool {
+	if strings.TrimSpace(code) == "" {
+		return false
+	}
+	if _, ok := testFunctionPatterns[lang]; !ok {
+		return true
+	}
+	return CountTestFunctions(code, lang) > 0 && CountAssertions(code, lang) > 0
+}
@@ -0,0 +1,34 @@
+package postprocessor
+
+import "testing"
+
+func TestIsLikelyTest(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		lang string
+		want bool
+	}{
+		{"python test", "def test_add():\n    assert add(1, 2) == 3\n", "python", true},
+		{"python unittest", "class CalcTest(unittest.TestCase):\n    def test_add(self):\n        self.assertEqual(add(1, 2), 3)\n", "python", true},
+		{"go test", "func TestAdd(t *testing.T) {\n\tif add(1, 2) != 3 {\n\t\tt.Errorf(\"add\")\n\t}\n}\n", "go", true},
+		{"java test", "class CalcTest {\n    @Test\n    void add() {\n        assertEquals(3, add(1, 2));\n    }\n}\n", "java", true},
+		{"javascript test", "test('add', () => {\n  expect(add(1, 2)).toBe(3);\n});\n", "javascript", true},
+		{"python implementation", "def add(a, b):\n    return a + b\n", "python", false},
+		{"go implementation", "func Add(a, b int) int {\n\treturn a + b\n}\n", "go", false},
+		{"test function without assertion", "def test_add():\n    add(1, 2)\n", "python", false},
+		{"assertion outside a test function", "def check():\n    assert add(1, 2) == 3\n", "python", false},
+		{"empty", "", "python", false},
+		{"blank", "  \n\t\n", "python", false},
+		{"language without idioms", "fun add() = 1\n", "rust", true},
+		{"empty in a language without idioms", "", "rust", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsLikelyTest(tt.code, tt.lang); got != tt.want {
+				t.Errorf("IsLikelyTest(%q, %s) = %v, want %v", tt.code, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package preprocessor turns sources stored in other formats, such as Jupyter
+// notebooks, into plain source files the workers can analyze and test.
+package preprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// notebook is the part of the .ipynb JSON format the code is extracted from.
+type notebook struct {
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   json.RawMessage `json:"source"`
+	} `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// NotebookSourcePath returns the path of the Python source extracted from a
+// notebook, the notebook's path with a .py extension, e.g. analysis.py for
+// analysis.ipynb. Tests generated for the notebook import it under that name.
+func NotebookSourcePath(notebookPath string) string {
+	return strings.TrimSuffix(notebookPath, ".ipynb") + ".py"
+}
+
+// NotebookSourceHeader is the first line of the source extracted from a
+// notebook. It tells the extracted source apart from a Python file of the user
+// that happens to have the notebook's name, so that only the former is
+// regenerated when the notebook changes.
+const NotebookSourceHeader = "# Extracted from a Jupyter notebook by pinguis, edit the notebook instead.\n"
+
+// ExtractNotebookCode returns the code cells of a Jupyter notebook as a single
+// Python source, in the order of the notebook, after NotebookSourceHeader. Each
+// cell starts with a "# %%" marker naming its position among the code cells,
+// and empty cells are left out. IPython magics and shell escapes, which are not Python, are commented
+// out: line magics (%) and shell commands (!) line by line, and cells starting
+// with a cell magic (%%) as a whole. Notebooks of a kernel for another language
+// are rejected.
+func ExtractNotebookCode(data []byte) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", fmt.Errorf("failed to parse notebook: %w", err)
+	}
+
+	language := nb.Metadata.Kernelspec.Language
+	if language == "" {
+		language = nb.Metadata.LanguageInfo.Name
+	}
+	if language != "" && !strings.EqualFold(language, "python") {
+		return "", fmt.Errorf("notebook kernel language %s is not supported", language)
+	}
+
+	var cells []string
+	for _, cell := range nb.Cells {
+		if cell.CellType != "code" {
+			continue
+		}
+		source, err := cellSource(cell.Source)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+		code := commentOutMagics(strings.TrimRight(source, "\n"))
+		cells = append(cells, fmt.Sprintf("# %%%% In[%d]\n%s", len(cells)+1, code))
+	}
+
+	if len(cells) == 0 {
+		return "", nil
+	}
+	return NotebookSourceHeader + "\n" + strings.Join(cells, "\n\n") + "\n", nil
+}
+
+// cellSource returns the source of a cell, which notebooks store either as a
+// single string or as a list of lines.
+func cellSource(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var source string
+	if err := json.Unmarshal(raw, &source); err == nil {
+		return source, nil
+	}
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err != nil {
+		return "", fmt.Errorf("failed to parse cell source: %w", err)
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// commentOutMagics comments out the IPython-only lines of a cell.
+func commentOutMagics(code string) string {
+	lines := strings.Split(code, "\n")
+	cellMagic := strings.HasPrefix(strings.TrimSpace(lines[0]), "%%")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if cellMagic || strings.HasPrefix(trimmed, "%") || strings.HasPrefix(trimmed, "!") {
+			lines[i] = "# " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,44 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxExampleSize is the default cap, in bytes of code and test, on the
+// few-shot examples rendered into a prompt.
+const DefaultMaxExampleSize = 4000
+
+// Example is a piece of source code paired with a test written for it. Examples
+// are rendered into prompts as few-shot demonstrations.
+type Example struct {
+	Code string `json:"code"`
+	Test string `json:"test"`
+}
+
+func (e Example) size() int {
+	return len(e.Code) + len(e.Test)
+}
+
+// RenderExamples renders the examples as few-shot demonstrations, keeping their
+// order. Examples that would push the total size of code and test past maxSize
+// are dropped; a maxSize of 0 or less keeps every example. It returns an empty
+// string if no example is kept.
+func RenderExamples(examples []Example, maxSize int) string {
+	var sb strings.Builder
+	total, kept := 0, 0
+	for _, example := range examples {
+		if maxSize > 0 && total+example.size() > maxSize {
+			continue
+		}
+		total += example.size()
+		kept++
+
+		if kept == 1 {
+			sb.WriteString("Here are examples of code and the tests written for it:\n")
+		}
+		sb.WriteString(fmt.Sprintf("\nExample %d:\nCode:\n```\n%s\n```\nTest:\n```\n%s\n```\n",
+			kept, strings.TrimSpace(example.Code), strings.TrimSpace(example.Test)))
+	}
+	return sb.String()
+}
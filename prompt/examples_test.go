@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderExamplesFormat(t *testing.T) {
+	examples := []Example{
+		{Code: "def inc(x):\n    return x + 1\n", Test: "def test_inc():\n    assert inc(1) == 2\n"},
+		{Code: "def neg(x):\n    return -x", Test: "def test_neg():\n    assert neg(1) == -1"},
+	}
+
+	want := "Here are examples of code and the tests written for it:\n" +
+		"\nExample 1:\nCode:\n```\ndef inc(x):\n    return x + 1\n```\nTest:\n```\ndef test_inc():\n    assert inc(1) == 2\n```\n" +
+		"\nExample 2:\nCode:\n```\ndef neg(x):\n    return -x\n```\nTest:\n```\ndef test_neg():\n    assert neg(1) == -1\n```\n"
+	if got := RenderExamples(examples, 0); got != want {
+		t.Errorf("RenderExamples() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderExamplesDropsExamplesPastTheCap(t *testing.T) {
+	small := Example{Code: "a", Test: "b"}
+	large := Example{Code: strings.Repeat("x", 10), Test: "y"}
+	last := Example{Code: "c", Test: "d"}
+
+	tests := []struct {
+		name     string
+		examples []Example
+		maxSize  int
+		want     []string
+	}{
+		// The large example would pass the cap, the last one still fits after it
+		{"large example dropped", []Example{small, large, last}, 5, []string{"```\na\n```", "```\nc\n```"}},
+		{"no cap", []Example{small, large, last}, 0, []string{"```\na\n```", "```\nxxxxxxxxxx\n```", "```\nc\n```"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderExamples(tt.examples, tt.maxSize)
+			if n := strings.Count(got, "\nExample "); n != len(tt.want) {
+				t.Errorf("rendered %d examples, want %d:\n%s", n, len(tt.want), got)
+			}
+			for _, code := range tt.want {
+				if !strings.Contains(got, code) {
+					t.Errorf("rendered examples do not contain %q:\n%s", code, got)
+				}
+			}
+		})
+	}
+
+	if got := RenderExamples([]Example{large}, 5); got != "" {
+		t.Errorf("RenderExamples() = %q without an example under the cap, want an empty string", got)
+	}
+}
+
+func TestWithExamplesAppendsToTemplate(t *testing.T) {
+	examples := []Example{{Code: "a", Test: "b"}, {Code: "cc", Test: "dd"}}
+	npg := NewNeoPromptGenerator("Test {code}", "x = 1", "calc.py")
+	npg.MaxExampleSize = 3
+
+	got := npg.WithExamples(examples).String()
+	if want := "Test {code}\n" + RenderExamples(examples[:1], 0); got != want {
+		t.Errorf("template = %q, want %q", got, want)
+	}
+
+	if got := NewNeoPromptGenerator("Test {code}", "", "").WithExamples(nil).String(); got != "Test {code}" {
+		t.Errorf("template without examples = %q, want it unchanged", got)
+	}
+}
@@ -10,8 +10,14 @@
 //       Returns the current template as a string.
 //   - WithContent(content string) *NeoPromptGenerator:
 //       Updates the template with the provided content and returns the updated instance.
+//   - WithExamples(examples []Example) *NeoPromptGenerator:
+//       Appends the examples to the template as few-shot demonstrations, dropping
+//       examples beyond MaxExampleSize (DefaultMaxExampleSize when 0).
+//   - WithPathConstraints(constraints string) *NeoPromptGenerator:
+//       Replaces the {path_constraints} placeholder with the described execution paths.
 //   - GeneratePrompt(code string, fileName string) string:
 //       Generates a prompt by replacing placeholders in the template with the provided code and file name.
+//       The file name replaces both the {fileName} and the {file_name} placeholder.
 //
 // GeneratePrompt Method:
 //   - Parameters:
@@ -35,6 +41,7 @@ type NeoPromptGenerator struct {
 	Template string
 	Code string
 	FileName string
+	MaxExampleSize int
 }
 
 func NewNeoPromptGenerator(template string, code string, fileName string) *NeoPromptGenerator {
@@ -54,6 +61,17 @@ func (npg *NeoPromptGenerator) WithContent(content string) *NeoPromptGenerator {
 	return npg
 }
 
+func (npg *NeoPromptGenerator) WithExamples(examples []Example) *NeoPromptGenerator {
+	maxSize := npg.MaxExampleSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxExampleSize
+	}
+	if rendered := RenderExamples(examples, maxSize); rendered != "" {
+		npg.Template += "\n" + rendered
+	}
+	return npg
+}
+
 type WeaviateHandler func(*dao.Weaviate, string, string) string
 
 func (npg *NeoPromptGenerator) WithWeaviate(weaviate *dao.Weaviate, handler WeaviateHandler) *NeoPromptGenerator {
@@ -65,12 +83,19 @@ func (npg *NeoPromptGenerator) GeneratePrompt(code string, fileName string) stri
 	prompt := npg.Template
 	prompt = strings.ReplaceAll(prompt, "{code}", code)
 	prompt = strings.ReplaceAll(prompt, "{fileName}", fileName)
+	prompt = strings.ReplaceAll(prompt, "{file_name}", fileName)
 	return prompt
 }
 
 func (npg *NeoPromptGenerator) WithCode(code, fileName string) *NeoPromptGenerator {
 	npg.Template = strings.ReplaceAll(npg.Template, "{code}", code)
 	npg.Template = strings.ReplaceAll(npg.Template, "{fileName}", fileName)
+	npg.Template = strings.ReplaceAll(npg.Template, "{file_name}", fileName)
+	return npg
+}
+
+func (npg *NeoPromptGenerator) WithPathConstraints(constraints string) *NeoPromptGenerator {
+	npg.Template = strings.ReplaceAll(npg.Template, "{path_constraints}", constraints)
 	return npg
 }
 
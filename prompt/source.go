@@ -0,0 +1,60 @@
+package prompt
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+//go:embed default_prompt.txt
+var embeddedTemplate string
+
+// DefaultTemplate returns the prompt template built into the binary. It asks for
+// one test case per execution path of the code under test and contains the
+// {code}, {file_name} and {path_constraints} placeholders.
+func DefaultTemplate() string {
+	return embeddedTemplate
+}
+
+// PromptSource loads a prompt template. The template may contain the {code},
+// {file_name} and {path_constraints} placeholders.
+type PromptSource func() (string, error)
+
+// FromFile returns a source reading the template from the file at path every
+// time it is loaded, so edits to the file apply to the prompts built afterwards.
+func FromFile(path string) PromptSource {
+	return func() (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		return string(data), nil
+	}
+}
+
+// FromString returns a source providing the given template.
+func FromString(template string) PromptSource {
+	return func() (string, error) {
+		return template, nil
+	}
+}
+
+// FromEmbedded returns a source providing DefaultTemplate.
+func FromEmbedded() PromptSource {
+	return FromString(DefaultTemplate())
+}
+
+// FromFileOrDefault returns a source reading the template from the file at path
+// like FromFile, but providing DefaultTemplate if the file does not exist.
+func FromFileOrDefault(path string) PromptSource {
+	fromFile := FromFile(path)
+	return func() (string, error) {
+		template, err := fromFile()
+		if errors.Is(err, fs.ErrNotExist) {
+			return DefaultTemplate(), nil
+		}
+		return template, err
+	}
+}
@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFromFileReadsTemplateOnEveryLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(path, []byte("Test {code}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := FromFile(path)
+
+	if template, err := source(); err != nil || template != "Test {code}" {
+		t.Fatalf("template = %q, %v, want the file content", template, err)
+	}
+	if err := os.WriteFile(path, []byte("Cover {code}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if template, err := source(); err != nil || template != "Cover {code}" {
+		t.Errorf("template after an edit = %q, %v, want the edited content", template, err)
+	}
+}
+
+func TestFromFileReportsMissingFile(t *testing.T) {
+	template, err := FromFile(filepath.Join(t.TempDir(), "missing.txt"))()
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("error = %v, want one wrapping fs.ErrNotExist", err)
+	}
+	if template != "" {
+		t.Errorf("template = %q, want none", template)
+	}
+}
+
+func TestFromStringAndFromEmbedded(t *testing.T) {
+	tests := []struct {
+		name   string
+		source PromptSource
+		want   string
+	}{
+		{"string", FromString("Test {code}"), "Test {code}"},
+		{"embedded", FromEmbedded(), embeddedTemplate},
+	}
+	for _, tt := range tests {
+		if template, err := tt.source(); err != nil || template != tt.want {
+			t.Errorf("%s: template = %q, %v, want %q", tt.name, template, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultTemplateHasEveryPlaceholder(t *testing.T) {
+	for _, placeholder := range []string{"{code}", "{file_name}", "{path_constraints}"} {
+		if !strings.Contains(DefaultTemplate(), placeholder) {
+			t.Errorf("default template has no %s placeholder", placeholder)
+		}
+	}
+}
+
+func TestFromFileOrDefault(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prompt.txt")
+	if err := os.WriteFile(path, []byte("Test {code}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"existing file", path, "Test {code}", false},
+		{"missing file", filepath.Join(dir, "missing.txt"), DefaultTemplate(), false},
+		// Only a missing file falls back, other read errors are reported
+		{"directory", dir, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := FromFileOrDefault(tt.path)()
+			if (err != nil) != tt.wantErr || template != tt.want {
+				t.Errorf("template = %q, %v, want %q with error %v", template, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
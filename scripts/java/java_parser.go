@@ -2,6 +2,8 @@ package java
 
 import "github.com/Marksagittarius/pinguis/types"
 
+// JavaParser parses Java source files and modules into their metadata.
+// TreeSitterJavaParser is the implementation used by default.
 type JavaParser interface {
 	ParseFile(filePath string) (*types.File, error)
 	ParseModule(modulePath string) (*types.Module, error)
@@ -0,0 +1,128 @@
+package java
+
+import (
+	"fmt"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+)
+
+// JavaQueries are the tree-sitter S-expression queries selecting the declarations
+// the Java parser extracts. Each query names the nodes it selects with captures:
+//
+//   - Classes: Run on the root of the file; @class captures class-like
+//     declarations with "name" and "body" fields.
+//   - Interfaces: Run on the root of the file; @interface captures interface
+//     declarations.
+//   - NestedClasses: Run on each class; @class captures the classes declared in
+//     its body, which are extracted with a qualified name (Outer.Inner).
+//   - Fields: Run on each class; @name and @type capture the name and type of a field.
+//   - Methods: Run on each class; @method captures method declarations.
+//   - Constructors: Run on each class; @constructor captures constructor declarations.
+//
+// Queries run on a node only match patterns rooted at that node, so a class
+// query does not pick up the members of other classes nested in it. Empty
+// queries fall back to the corresponding DefaultJavaQueries.
+type JavaQueries struct {
+	Classes       string
+	Interfaces    string
+	NestedClasses string
+	Fields        string
+	Methods       string
+	Constructors  string
+}
+
+// DefaultJavaQueries returns the queries the parser uses unless configured
+// otherwise: the top-level classes and interfaces of a file, and the nested
+// classes, fields, methods and constructors declared directly in a class body.
+func DefaultJavaQueries() JavaQueries {
+	return JavaQueries{
+		Classes:       `(program (class_declaration) @class)`,
+		Interfaces:    `(program (interface_declaration) @interface)`,
+		NestedClasses: `(class_declaration body: (class_body (class_declaration) @class))`,
+		Fields: `(class_declaration body: (class_body (field_declaration
+			type: (_) @type
+			declarator: (variable_declarator name: (identifier) @name))))`,
+		Methods:      `(class_declaration body: (class_body (method_declaration) @method))`,
+		Constructors: `(class_declaration body: (class_body (constructor_declaration) @constructor))`,
+	}
+}
+
+// compiledJavaQueries holds the compiled form of a JavaQueries.
+type compiledJavaQueries struct {
+	classes       *javaQuery
+	interfaces    *javaQuery
+	nestedClasses *javaQuery
+	fields        *javaQuery
+	methods       *javaQuery
+	constructors  *javaQuery
+}
+
+// javaQuery is a compiled query.
+type javaQuery struct {
+	query *tree_sitter.Query
+}
+
+func (q JavaQueries) compile() (*compiledJavaQueries, error) {
+	language := tree_sitter.NewLanguage(tree_sitter_java.Language())
+	defaults := DefaultJavaQueries()
+	compiled := &compiledJavaQueries{}
+
+	targets := []struct {
+		name     string
+		source   string
+		fallback string
+		dest     **javaQuery
+	}{
+		{"Classes", q.Classes, defaults.Classes, &compiled.classes},
+		{"Interfaces", q.Interfaces, defaults.Interfaces, &compiled.interfaces},
+		{"NestedClasses", q.NestedClasses, defaults.NestedClasses, &compiled.nestedClasses},
+		{"Fields", q.Fields, defaults.Fields, &compiled.fields},
+		{"Methods", q.Methods, defaults.Methods, &compiled.methods},
+		{"Constructors", q.Constructors, defaults.Constructors, &compiled.constructors},
+	}
+	for _, target := range targets {
+		source := target.source
+		if source == "" {
+			source = target.fallback
+		}
+		query, queryErr := tree_sitter.NewQuery(language, source)
+		if queryErr != nil {
+			compiled.close()
+			return nil, fmt.Errorf("invalid %s query: %w", target.name, queryErr)
+		}
+		*target.dest = &javaQuery{query: query}
+	}
+	return compiled, nil
+}
+
+func (c *compiledJavaQueries) close() {
+	for _, q := range []*javaQuery{c.classes, c.interfaces, c.nestedClasses, c.fields, c.methods, c.constructors} {
+		if q != nil {
+			q.query.Close()
+		}
+	}
+}
+
+// matches runs the query on the node and returns the captured nodes of every
+// match keyed by capture name, in document order. Only patterns rooted at the
+// node itself are matched.
+func (q *javaQuery) matches(node *tree_sitter.Node, code []byte) []map[string]*tree_sitter.Node {
+	cursor := tree_sitter.NewQueryCursor()
+	defer cursor.Close()
+	depth := uint(0)
+	cursor.SetMaxStartDepth(&depth)
+
+	names := q.query.CaptureNames()
+	var result []map[string]*tree_sitter.Node
+	matches := cursor.Matches(q.query, node, code)
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		captured := make(map[string]*tree_sitter.Node, len(match.Captures))
+		for _, capture := range match.Captures {
+			captureNode := capture.Node
+			captured[names[capture.Index]] = &captureNode
+		}
+		result = append(result, captured)
+	}
+	return result
+}
@@ -0,0 +1,17 @@
+package java
+
+import "github.com/Marksagittarius/pinguis/types"
+
+// ErrIncompleteParse is wrapped by the errors returned together with a file
+// whose source has syntax errors. Tree-sitter recovers from syntax errors, so
+// the file holds every declaration that could be parsed, but declarations
+// around the errors may be missing or incomplete. It is the
+// types.ErrIncompleteParse every parser reports.
+var ErrIncompleteParse = types.ErrIncompleteParse
+
+// SyntaxError is a position tree-sitter could not parse.
+type SyntaxError = types.SyntaxError
+
+// IncompleteParseError reports the syntax errors of a file that was parsed only
+// partially. It wraps ErrIncompleteParse.
+type IncompleteParseError = types.IncompleteParseError
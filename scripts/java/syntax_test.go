@@ -0,0 +1,71 @@
+package java
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const brokenJava = `package shop;
+
+public class Cart {
+    private int total;
+
+    public int add(int price) {
+        total += price;
+        return total;
+    }
+
+    public void broken( {
+        total = ;
+    }
+
+    public int getTotal() {
+        return total;
+    }
+}
+`
+
+func TestParseFileReportsSyntaxErrorsWithParsedParts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Cart.java")
+	if err := os.WriteFile(path, []byte(brokenJava), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := NewTreeSitterJavaParser().ParseFile(path)
+	if !errors.Is(err, ErrIncompleteParse) {
+		t.Fatalf("ParseFile error = %v, want one wrapping ErrIncompleteParse", err)
+	}
+	var incomplete *IncompleteParseError
+	if !errors.As(err, &incomplete) || len(incomplete.Errors) == 0 {
+		t.Fatalf("ParseFile error = %v, want an IncompleteParseError listing the syntax errors", err)
+	}
+	if line := incomplete.Errors[0].Line; line != 11 {
+		t.Errorf("first syntax error on line %d, want 11", line)
+	}
+
+	if file == nil || file.Module != "shop" || len(file.Classes) != 1 || file.Classes[0].Name != "Cart" {
+		t.Fatalf("file = %+v, want the Cart class of package shop", file)
+	}
+	methods := map[string]bool{}
+	for _, method := range file.Classes[0].Methods {
+		methods[method.Func.Name] = true
+	}
+	for _, name := range []string{"add", "getTotal"} {
+		if !methods[name] {
+			t.Errorf("methods = %v, want %s, which parses", methods, name)
+		}
+	}
+}
+
+func TestParseFileWithoutSyntaxErrorsReportsNone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Item.java")
+	if err := os.WriteFile(path, []byte("class Item { int price() { return 1; } }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTreeSitterJavaParser().ParseFile(path); err != nil {
+		t.Errorf("ParseFile error = %v, want none", err)
+	}
+}
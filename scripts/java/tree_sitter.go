@@ -1,9 +1,12 @@
 package java
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/Marksagittarius/pinguis/scripts/treesitter"
 	"github.com/Marksagittarius/pinguis/types"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -13,8 +16,11 @@ import (
 // TreeSitterJavaParser is a struct that serves as a parser for Java code
 // using the Tree-sitter parsing library. It provides functionality to
 // analyze and manipulate Java syntax trees.
+//
+// Queries customizes the declarations the parser extracts; nil uses
+// DefaultJavaQueries.
 type TreeSitterJavaParser struct {
-
+	Queries *JavaQueries
 }
 
 // NewTreeSitterJavaParser creates and returns a new instance of TreeSitterJavaParser.
@@ -35,7 +41,9 @@ func NewTreeSitterJavaParser() *TreeSitterJavaParser {
 //
 // This function uses the Tree-sitter library to parse the Java source code
 // and analyze its syntax tree. If the file cannot be read or if there is
-// an issue during parsing, an error is returned.
+// an issue during parsing, an error is returned. If the file has syntax
+// errors, the declarations that could be parsed are returned together with
+// an *IncompleteParseError wrapping ErrIncompleteParse.
 func (p *TreeSitterJavaParser) ParseFile(filePath string) (*types.File, error) {
 	parser := tree_sitter.NewParser()
 	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_java.Language()))
@@ -46,8 +54,16 @@ func (p *TreeSitterJavaParser) ParseFile(filePath string) (*types.File, error) {
 	}
 	tree := parser.Parse(code, nil)
 	rootNode := tree.RootNode()
-	file := AnalyzeJavaFile(rootNode, code, filePath)
-	return &file, nil
+
+	queries := DefaultJavaQueries()
+	if p.Queries != nil {
+		queries = *p.Queries
+	}
+	file, err := AnalyzeJavaFileWithQueries(rootNode, code, filePath, queries)
+	if err != nil && !errors.Is(err, ErrIncompleteParse) {
+		return nil, err
+	}
+	return &file, err
 }
 
 // ParseModule parses a Java module from the specified module path and returns
@@ -79,6 +95,38 @@ func getNodeText(node *tree_sitter.Node, code []byte) string {
     return string(code[node.StartByte():node.EndByte()])
 }
 
+// extractJavadoc returns the text of the Javadoc comment directly preceding the
+// given declaration node, without the comment delimiters and the leading
+// asterisks of its lines.
+//
+// Parameters:
+//   - node: A pointer to a tree-sitter Node representing a declaration.
+//   - code: A byte slice containing the source code being analyzed.
+//
+// Returns:
+//   - The cleaned-up Javadoc text, or an empty string if the declaration is not
+//     preceded by a Javadoc comment.
+func extractJavadoc(node *tree_sitter.Node, code []byte) string {
+    prev := node.PrevSibling()
+    if prev == nil || prev.Kind() != "block_comment" {
+        return ""
+    }
+
+    comment := getNodeText(prev, code)
+    if !strings.HasPrefix(comment, "/**") {
+        return ""
+    }
+    comment = strings.TrimSuffix(strings.TrimPrefix(comment, "/**"), "*/")
+
+    var lines []string
+    for _, line := range strings.Split(comment, "\n") {
+        line = strings.TrimSpace(line)
+        line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+        lines = append(lines, line)
+    }
+    return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
 // extractParameters extracts a list of parameters from a given tree-sitter node.
 // It traverses the child nodes of the provided parameter node to identify formal
 // parameters, extracting their names and types.
@@ -173,144 +221,232 @@ func extractReturnType(methodNode *tree_sitter.Node, code []byte) string {
     return ""
 }
 
-// extractMethods extracts method declarations from a given tree-sitter node
-// representing a class or struct body and returns a slice of Method objects.
+// extractMethod extracts a method declaration node into a Method, with its
+// name, parameters, return type, body, Javadoc comment and whether it is static
+// or abstract.
 //
 // Parameters:
-//   - bodyNode: A pointer to a tree-sitter Node representing the body of a class or struct.
+//   - node: A pointer to a tree-sitter Node of kind "method_declaration".
 //   - code: A byte slice containing the source code being analyzed.
 //
 // Returns:
-//   - A slice of types.Method, where each Method represents a method declaration
-//     found within the provided bodyNode. Each Method includes details such as
-//     the method name, parameters, return types, and body.
-//
-// Notes:
-//   - If the bodyNode is nil, an empty slice is returned.
-//   - The function uses a tree-sitter cursor to traverse the child nodes of the bodyNode
-//     and identifies nodes of kind "method_declaration" to extract method details.
-func extractMethods(bodyNode *tree_sitter.Node, code []byte) []types.Method {
-    var methods []types.Method
-    if bodyNode == nil {
-        return methods
+//   - A types.Method describing the method. The receiver is left empty.
+func extractMethod(node *tree_sitter.Node, code []byte) types.Method {
+    var methodName string
+    var parameters []types.Parameter
+    
+    nameNode := node.ChildByFieldName("name")
+    if nameNode != nil {
+        methodName = getNodeText(nameNode, code)
     }
     
-    cursor := bodyNode.Walk()
-    defer cursor.Close()
+    returnType := extractReturnType(node, code)
     
-    if cursor.GotoFirstChild() {
-        for {
-            node := cursor.Node()
-            
-            if node.Kind() == "method_declaration" {
-                var methodName, returnType string
-                var parameters []types.Parameter
-                
-                nameNode := node.ChildByFieldName("name")
-                if nameNode != nil {
-                    methodName = getNodeText(nameNode, code)
-                }
-                
-                returnType = extractReturnType(node, code)
-                
-                paramNode := node.ChildByFieldName("parameters")
-                if paramNode != nil {
-                    parameters = extractParameters(paramNode, code)
-                }
-                
-                bodyNode := node.ChildByFieldName("body")
-                body := ""
-                if bodyNode != nil {
-                    body = getNodeText(bodyNode, code)
-                }
-                
-                method := types.Method{
-                    Reciever: "", 
-                    Func: types.Function{
-                        Name:        methodName,
-                        Parameters:  parameters,
-                        ReturnTypes: []string{returnType},
-                        Body:        body,
-                    },
-                }
-                
-                methods = append(methods, method)
-            }
-            
-            if !cursor.GotoNextSibling() {
-                break
+    paramNode := node.ChildByFieldName("parameters")
+    if paramNode != nil {
+        parameters = extractParameters(paramNode, code)
+    }
+    
+    bodyNode := node.ChildByFieldName("body")
+    body := ""
+    if bodyNode != nil {
+        body = getNodeText(bodyNode, code)
+    }
+    
+    modifiers := extractModifiers(node, code)
+
+    return types.Method{
+        Reciever: "", 
+        Func: types.Function{
+            Name:        methodName,
+            Parameters:  parameters,
+            ReturnTypes: []string{returnType},
+            Body:        body,
+            Doc:         extractJavadoc(node, code),
+        },
+        IsStatic:   modifiers["static"],
+        IsAbstract: modifiers["abstract"],
+    }
+}
+
+// extractModifiers returns the keyword modifiers of a declaration node, such
+// as public, static or abstract. Annotations are not included.
+//
+// Parameters:
+//   - node: A pointer to a tree-sitter Node of a declaration.
+//   - code: A byte slice containing the source code being analyzed.
+//
+// Returns:
+//   - A set of the modifier keywords of the declaration.
+func extractModifiers(node *tree_sitter.Node, code []byte) map[string]bool {
+    modifiers := make(map[string]bool)
+    for i := uint(0); i < node.NamedChildCount(); i++ {
+        child := node.NamedChild(i)
+        if child.Kind() != "modifiers" {
+            continue
+        }
+        for j := uint(0); j < child.ChildCount(); j++ {
+            modifier := child.Child(j)
+            if modifier.IsNamed() {
+                continue
             }
+            modifiers[getNodeText(modifier, code)] = true
         }
     }
-    
-    return methods
+    return modifiers
 }
 
-// extractFields extracts a list of fields from the given bodyNode in a tree-sitter
-// syntax tree. It traverses the node tree to identify "field_declaration" nodes,
-// retrieves their types and names, and returns them as a slice of types.Field.
+// ExtractMethod extracts a method declaration node of an already parsed Java
+// file into a Method the way ParseFile does, e.g. to describe the signature of
+// a method found while walking the syntax tree.
 //
 // Parameters:
-// - bodyNode: A pointer to a tree-sitter Node representing the body of the code
-//   to analyze. If nil, an empty slice is returned.
-// - code: A byte slice containing the source code being analyzed.
+//   - node: A pointer to a tree-sitter Node of kind "method_declaration".
+//   - code: A byte slice containing the source code the node was parsed from.
 //
 // Returns:
-// - A slice of types.Field, where each field contains the name and type of a
-//   field declaration found in the bodyNode.
-func extractFields(bodyNode *tree_sitter.Node, code []byte) []types.Field {
-    var fields []types.Field
-    if bodyNode == nil {
-        return fields
+//   - A types.Method describing the method. The receiver is left empty.
+func ExtractMethod(node *tree_sitter.Node, code []byte) types.Method {
+    return extractMethod(node, code)
+}
+
+// extractConstructor extracts a constructor declaration node into a Function
+// named after the class. Constructors have no return types.
+//
+// Parameters:
+//   - node: A pointer to a tree-sitter Node of kind "constructor_declaration".
+//   - code: A byte slice containing the source code being analyzed.
+//
+// Returns:
+//   - A types.Function with the constructor's name, parameters, body and Javadoc comment.
+func extractConstructor(node *tree_sitter.Node, code []byte) types.Function {
+    var constructorName, body string
+    var parameters []types.Parameter
+
+    nameNode := node.ChildByFieldName("name")
+    if nameNode != nil {
+        constructorName = getNodeText(nameNode, code)
     }
-    
-    cursor := bodyNode.Walk()
-    defer cursor.Close()
-    
-    if cursor.GotoFirstChild() {
-        for {
-            node := cursor.Node()
-            
-            if node.Kind() == "field_declaration" {
-                var fieldType string
-                
-                typeNode := node.ChildByFieldName("type")
-                if typeNode != nil {
-                    fieldType = getNodeText(typeNode, code)
-                }
-                
-                declaratorCursor := node.Walk()
-                defer declaratorCursor.Close()
-                
-                if declaratorCursor.GotoFirstChild() {
-                    for {
-                        declaratorNode := declaratorCursor.Node()
-                        
-                        if declaratorNode.Kind() == "variable_declarator" {
-                            nameNode := declaratorNode.ChildByFieldName("name")
-                            if nameNode != nil {
-                                fieldName := getNodeText(nameNode, code)
-                                fields = append(fields, types.Field{
-                                    Name: fieldName,
-                                    Type: fieldType,
-                                })
-                            }
-                        }
-                        
-                        if !declaratorCursor.GotoNextSibling() {
-                            break
-                        }
-                    }
-                }
-            }
-            
-            if !cursor.GotoNextSibling() {
-                break
-            }
+
+    paramNode := node.ChildByFieldName("parameters")
+    if paramNode != nil {
+        parameters = extractParameters(paramNode, code)
+    }
+
+    bodyNode := node.ChildByFieldName("body")
+    if bodyNode != nil {
+        body = getNodeText(bodyNode, code)
+    }
+
+    return types.Function{
+        Name:        constructorName,
+        Parameters:  parameters,
+        ReturnTypes: []string{},
+        Body:        body,
+        Doc:         extractJavadoc(node, code),
+    }
+}
+
+// extractClasses extracts a class-like declaration node into a Class, running the
+// Fields, Methods and Constructors queries on the node to select its members,
+// followed by the classes nested in it selected by the NestedClasses query.
+//
+// Parameters:
+//   - node: A pointer to a tree-sitter Node captured by the Classes or NestedClasses query.
+//   - code: A byte slice containing the source code being analyzed.
+//   - queries: The compiled extraction queries.
+//   - outer: The qualified name of the enclosing class, or "" for a top-level class.
+//
+// Returns:
+//   - A slice of types.Class holding the class with its name, fields, methods,
+//     constructors, superclass, implemented interfaces and Javadoc comment, then
+//     its nested classes in document order. Nested classes are named after their
+//     enclosing classes (Outer.Inner).
+func extractClasses(node *tree_sitter.Node, code []byte, queries *compiledJavaQueries, outer string) []types.Class {
+    class := types.Class{Doc: extractJavadoc(node, code)}
+
+    nameNode := node.ChildByFieldName("name")
+    if nameNode != nil {
+        class.Name = getNodeText(nameNode, code)
+        if outer != "" {
+            class.Name = outer + "." + class.Name
         }
     }
-    
-    return fields
+
+    for _, match := range queries.fields.matches(node, code) {
+        name, hasName := match["name"]
+        if !hasName {
+            continue
+        }
+        field := types.Field{Name: getNodeText(name, code)}
+        if fieldType, hasType := match["type"]; hasType {
+            field.Type = getNodeText(fieldType, code)
+        }
+        class.Fields = append(class.Fields, field)
+    }
+
+    for _, match := range queries.methods.matches(node, code) {
+        if method, ok := match["method"]; ok {
+            class.Methods = append(class.Methods, extractMethod(method, code))
+        }
+    }
+
+    for _, match := range queries.constructors.matches(node, code) {
+        if constructor, ok := match["constructor"]; ok {
+            class.Constructors = append(class.Constructors, extractConstructor(constructor, code))
+        }
+    }
+
+    if superclass := node.ChildByFieldName("superclass"); superclass != nil && superclass.NamedChildCount() > 0 {
+        class.Extends = getNodeText(superclass.NamedChild(0), code)
+    }
+    class.Implements = extractSuperInterfaces(node, code)
+
+    classes := []types.Class{class}
+    for _, match := range queries.nestedClasses.matches(node, code) {
+        if nested, ok := match["class"]; ok {
+            classes = append(classes, extractClasses(nested, code, queries, class.Name)...)
+        }
+    }
+    return classes
+}
+
+// extractSuperInterfaces returns the interfaces listed in the implements clause
+// of a class declaration node, as written (e.g. Comparable<Item>).
+//
+// Parameters:
+//   - node: A pointer to a tree-sitter Node representing a class declaration.
+//   - code: A byte slice containing the source code being analyzed.
+//
+// Returns:
+//   - The names of the implemented interfaces, or nil if the class has no
+//     implements clause.
+func extractSuperInterfaces(node *tree_sitter.Node, code []byte) []string {
+    interfaces := node.ChildByFieldName("interfaces")
+    if interfaces == nil {
+        return nil
+    }
+
+    var names []string
+    for i := uint(0); i < interfaces.NamedChildCount(); i++ {
+        typeList := interfaces.NamedChild(i)
+        if typeList.Kind() != "type_list" {
+            continue
+        }
+        for j := uint(0); j < typeList.NamedChildCount(); j++ {
+            names = append(names, getNodeText(typeList.NamedChild(j), code))
+        }
+    }
+    return names
+}
+
+// extractImport returns the name imported by an import declaration node, such as
+// java.util.List, java.util.* or, for static imports, java.lang.Math.max.
+func extractImport(node *tree_sitter.Node, code []byte) string {
+    imported := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(getNodeText(node, code)), ";"))
+    imported = strings.TrimSpace(strings.TrimPrefix(imported, "import"))
+    imported = strings.TrimSpace(strings.TrimPrefix(imported, "static"))
+    return strings.Join(strings.Fields(imported), "")
 }
 
 // extractInterfaceMethods extracts a list of methods from the body of an interface node.
@@ -358,6 +494,7 @@ func extractInterfaceMethods(bodyNode *tree_sitter.Node, code []byte) []types.Fu
                     Parameters:  parameters,
                     ReturnTypes: []string{returnType},
                     Body:        "",
+                    Doc:         extractJavadoc(node, code),
                 })
             }
             
@@ -382,12 +519,46 @@ func extractInterfaceMethods(bodyNode *tree_sitter.Node, code []byte) []types.Fu
 //   - A types.File object containing the extracted information, including:
 //       - Path: The file path of the Java source file.
 //       - Module: The package name of the Java file (if present).
+//       - Doc: The Javadoc comment preceding the package declaration (if present).
+//       - Imports: The names imported by the file's import declarations.
 //       - Classes: A slice of types.Class representing the classes in the file,
-//         including their names, fields, and methods.
+//         including their names, fields, methods, constructors, superclass,
+//         implemented interfaces and Javadoc comments (methods and constructors
+//         carry their own). Nested classes follow their enclosing class and are
+//         named Outer.Inner.
 //       - Interfaces: A slice of types.Interface representing the interfaces in the file,
 //         including their names and methods.
 //       - Functions: A slice of types.Function representing standalone functions (if any).
-func AnalyzeJavaFile(root *tree_sitter.Node, code []byte, filePath string) types.File {
+//   - An *IncompleteParseError wrapping ErrIncompleteParse if the syntax tree has
+//     syntax errors, in which case the file holds the declarations that could be
+//     parsed; nil otherwise.
+//
+// The declarations are selected by DefaultJavaQueries; use AnalyzeJavaFileWithQueries
+// to customize them.
+func AnalyzeJavaFile(root *tree_sitter.Node, code []byte, filePath string) (types.File, error) {
+    file, err := AnalyzeJavaFileWithQueries(root, code, filePath, DefaultJavaQueries())
+    if err != nil && !errors.Is(err, ErrIncompleteParse) {
+        // The default queries always compile
+        panic(err)
+    }
+    return file, err
+}
+
+// AnalyzeJavaFileWithQueries works like AnalyzeJavaFile, but selects the classes,
+// interfaces and class members to extract with the given queries.
+//
+// Returns:
+//   - types.File: The extracted file structure.
+//   - error: An error if one of the queries is not valid for the Java grammar, or
+//     an *IncompleteParseError together with the declarations that could be
+//     parsed if the syntax tree has syntax errors.
+func AnalyzeJavaFileWithQueries(root *tree_sitter.Node, code []byte, filePath string, queries JavaQueries) (types.File, error) {
+    compiled, err := queries.compile()
+    if err != nil {
+        return types.File{}, err
+    }
+    defer compiled.close()
+
     file := types.File{
         Path:    filePath,
         Classes: []types.Class{},
@@ -402,53 +573,20 @@ func AnalyzeJavaFile(root *tree_sitter.Node, code []byte, filePath string) types
         for {
             node := cursor.Node()
             
-            switch node.Kind() {
-            case "package_declaration":
-                nameNode := node.ChildByFieldName("name")
-                if nameNode != nil {
-                    file.Module = getNodeText(nameNode, code)
-                }
-                
-            case "class_declaration":
-                var className string
-                var fields []types.Field
-                var methods []types.Method
-                
-                nameNode := node.ChildByFieldName("name")
-                if nameNode != nil {
-                    className = getNodeText(nameNode, code)
-                }
-                
-                bodyNode := node.ChildByFieldName("body")
-                if bodyNode != nil {
-                    fields = extractFields(bodyNode, code)
-                    methods = extractMethods(bodyNode, code)
-                }
-                
-                file.Classes = append(file.Classes, types.Class{
-                    Name:    className,
-                    Fields:  fields,
-                    Methods: methods,
-                })
-                
-            case "interface_declaration":
-                var interfaceName string
-                var methods []types.Function
-                
-                nameNode := node.ChildByFieldName("name")
-                if nameNode != nil {
-                    interfaceName = getNodeText(nameNode, code)
-                }
-                
-                bodyNode := node.ChildByFieldName("body")
-                if bodyNode != nil {
-                    methods = extractInterfaceMethods(bodyNode, code)
+            if node.Kind() == "package_declaration" {
+                // The package name is not a field of the declaration, but its
+                // only identifier child (annotations aside)
+                for i := uint(0); i < node.NamedChildCount(); i++ {
+                    child := node.NamedChild(i)
+                    if child.Kind() == "identifier" || child.Kind() == "scoped_identifier" {
+                        file.Module = getNodeText(child, code)
+                    }
                 }
-                
-                file.Interfaces = append(file.Interfaces, types.Interface{
-                    Name:    interfaceName,
-                    Methods: methods,
-                })
+                file.Doc = extractJavadoc(node, code)
+            }
+
+            if node.Kind() == "import_declaration" {
+                file.Imports = append(file.Imports, extractImport(node, code))
             }
             
             if !cursor.GotoNextSibling() {
@@ -456,8 +594,39 @@ func AnalyzeJavaFile(root *tree_sitter.Node, code []byte, filePath string) types
             }
         }
     }
+
+    for _, match := range compiled.classes.matches(root, code) {
+        if classNode, ok := match["class"]; ok {
+            file.Classes = append(file.Classes, extractClasses(classNode, code, compiled, "")...)
+        }
+    }
+
+    for _, match := range compiled.interfaces.matches(root, code) {
+        node, ok := match["interface"]
+        if !ok {
+            continue
+        }
+
+        var interfaceName string
+        var methods []types.Function
+        
+        nameNode := node.ChildByFieldName("name")
+        if nameNode != nil {
+            interfaceName = getNodeText(nameNode, code)
+        }
+        
+        bodyNode := node.ChildByFieldName("body")
+        if bodyNode != nil {
+            methods = extractInterfaceMethods(bodyNode, code)
+        }
+        
+        file.Interfaces = append(file.Interfaces, types.Interface{
+            Name:    interfaceName,
+            Methods: methods,
+        })
+    }
     
-    return file
+    return file, treesitter.CheckSyntax(root, filePath)
 }
 
 // AnalyzeJavaModule analyzes a Java module located at the specified path and returns a structured representation of the module.
@@ -475,6 +644,7 @@ func AnalyzeJavaFile(root *tree_sitter.Node, code []byte, filePath string) types
 // Behavior:
 //   - Skips hidden directories (those starting with a dot).
 //   - Parses files with the ".java" extension using a Tree-Sitter-based Java parser.
+//     Files with syntax errors are kept with the declarations that could be parsed.
 //   - Recursively analyzes subdirectories as submodules, except for the root directory itself.
 //
 // Example:
@@ -502,7 +672,7 @@ func AnalyzeJavaModule(modulePath string) (*types.Module, error) {
         if !info.IsDir() && filepath.Ext(info.Name()) == ".java" {
             parser := NewTreeSitterJavaParser()
             file, err := parser.ParseFile(path)
-            if err != nil {
+            if err != nil && !errors.Is(err, ErrIncompleteParse) {
                 return err
             }
             module.Files = append(module.Files, *file)
@@ -0,0 +1,305 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const shapeJava = `package shapes;
+
+public abstract class Shape {
+    public static Shape unit() {
+        return new Square(1);
+    }
+
+    public abstract double area();
+
+    public String describe() {
+        return "area " + area();
+    }
+}
+`
+
+func TestParseFileFlagsStaticAndAbstractMethods(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Shape.java")
+	if err := os.WriteFile(path, []byte(shapeJava), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := NewTreeSitterJavaParser().ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if len(file.Classes) != 1 {
+		t.Fatalf("parsed %d classes, want 1", len(file.Classes))
+	}
+
+	want := map[string][2]bool{
+		"unit":     {true, false},
+		"area":     {false, true},
+		"describe": {false, false},
+	}
+	for _, method := range file.Classes[0].Methods {
+		flags, ok := want[method.Func.Name]
+		if !ok {
+			t.Errorf("unexpected method %s", method.Func.Name)
+			continue
+		}
+		if method.IsStatic != flags[0] || method.IsAbstract != flags[1] {
+			t.Errorf("%s: static %v, abstract %v, want %v, %v", method.Func.Name, method.IsStatic, method.IsAbstract, flags[0], flags[1])
+		}
+		delete(want, method.Func.Name)
+	}
+	for name := range want {
+		t.Errorf("method %s was not parsed", name)
+	}
+}
+
+const pointJava = `package shapes;
+
+public class Point {
+    private final int x;
+    private final int y;
+
+    public Point() {
+        this(0, 0);
+    }
+
+    public Point(int x, int y) {
+        this.x = x;
+        this.y = y;
+    }
+
+    public int x() {
+        return x;
+    }
+}
+`
+
+func TestParseFileExtractsConstructors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Point.java")
+	if err := os.WriteFile(path, []byte(pointJava), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := NewTreeSitterJavaParser().ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if len(file.Classes) != 1 {
+		t.Fatalf("parsed %d classes, want 1", len(file.Classes))
+	}
+	class := file.Classes[0]
+
+	var constructors []string
+	for _, constructor := range class.Constructors {
+		var params []string
+		for _, param := range constructor.Parameters {
+			params = append(params, param.Type+" "+param.Name)
+		}
+		constructors = append(constructors, constructor.Name+"("+strings.Join(params, ", ")+")")
+	}
+	if want := []string{"Point()", "Point(int x, int y)"}; !reflect.DeepEqual(constructors, want) {
+		t.Errorf("constructors = %q, want %q", constructors, want)
+	}
+	// Constructors are not methods of the class
+	if len(class.Methods) != 1 || class.Methods[0].Func.Name != "x" {
+		t.Errorf("methods = %+v, want only x", class.Methods)
+	}
+}
+
+const accountJava = `/** Banking primitives. */
+package bank;
+
+/**
+ * An account holding a balance.
+ */
+public class Account {
+    /** Opens an empty account. */
+    public Account() {
+    }
+
+    /**
+     * Withdraws an amount.
+     *
+     * @param amount the amount to withdraw
+     */
+    public void withdraw(int amount) {
+    }
+
+    // Not a Javadoc comment
+    public int balance() {
+        return 0;
+    }
+}
+`
+
+func TestParseFileCapturesJavadoc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Account.java")
+	if err := os.WriteFile(path, []byte(accountJava), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := NewTreeSitterJavaParser().ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if file.Doc != "Banking primitives." {
+		t.Errorf("file doc = %q, want the comment before the package", file.Doc)
+	}
+	if len(file.Classes) != 1 {
+		t.Fatalf("parsed %d classes, want 1", len(file.Classes))
+	}
+	class := file.Classes[0]
+	if class.Doc != "An account holding a balance." {
+		t.Errorf("class doc = %q", class.Doc)
+	}
+	if len(class.Constructors) != 1 || class.Constructors[0].Doc != "Opens an empty account." {
+		t.Errorf("constructors = %+v, want the documented constructor", class.Constructors)
+	}
+
+	docs := map[string]string{}
+	for _, method := range class.Methods {
+		docs[method.Func.Name] = method.Func.Doc
+	}
+	want := map[string]string{
+		"withdraw": "Withdraws an amount.\n\n@param amount the amount to withdraw",
+		"balance":  "",
+	}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("method docs = %q, want %q", docs, want)
+	}
+}
+
+const recordJava = `package shapes;
+
+public record Point(int x, int y) {
+}
+
+public class Line {
+    private Point start;
+}
+`
+
+func TestParseFileWithCustomQueriesExtractsRecordComponents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Point.java")
+	if err := os.WriteFile(path, []byte(recordJava), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default queries only know classes
+	file, err := NewTreeSitterJavaParser().ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if len(file.Classes) != 1 || file.Classes[0].Name != "Line" {
+		t.Errorf("classes with the default queries = %+v, want only Line", file.Classes)
+	}
+
+	queries := DefaultJavaQueries()
+	queries.Classes = `(program [(class_declaration) (record_declaration)] @class)`
+	queries.Fields = queries.Fields + `
+		(record_declaration parameters: (formal_parameters (formal_parameter
+			type: (_) @type
+			name: (identifier) @name)))`
+	parser := NewTreeSitterJavaParser()
+	parser.Queries = &queries
+	file, err = parser.ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile with custom queries: %v", err)
+	}
+
+	fields := map[string][]string{}
+	for _, class := range file.Classes {
+		for _, field := range class.Fields {
+			fields[class.Name] = append(fields[class.Name], field.Type+" "+field.Name)
+		}
+	}
+	want := map[string][]string{"Point": {"int x", "int y"}, "Line": {"Point start"}}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %q, want %q", fields, want)
+	}
+}
+
+func TestParseFileRejectsInvalidQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Point.java")
+	if err := os.WriteFile(path, []byte(recordJava), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := JavaQueries{Methods: "(method_declaration"}
+	parser := NewTreeSitterJavaParser()
+	parser.Queries = &queries
+	if _, err := parser.ParseFile(path); err == nil || !strings.Contains(err.Error(), "invalid Methods query") {
+		t.Errorf("ParseFile error = %v, want one naming the invalid Methods query", err)
+	}
+}
+
+const outerJava = `package shapes;
+
+public class Outer {
+    private int size;
+
+    public int size() {
+        return size;
+    }
+
+    public static class Inner {
+        private String label;
+
+        public Inner(String label) {
+            this.label = label;
+        }
+
+        public String label() {
+            return label;
+        }
+
+        class Deepest {
+        }
+    }
+}
+`
+
+func TestParseFileExtractsNestedClasses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Outer.java")
+	if err := os.WriteFile(path, []byte(outerJava), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := NewTreeSitterJavaParser().ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+
+	type members struct {
+		fields, methods, constructors []string
+	}
+	got := map[string]members{}
+	for _, class := range file.Classes {
+		var m members
+		for _, field := range class.Fields {
+			m.fields = append(m.fields, field.Name)
+		}
+		for _, method := range class.Methods {
+			m.methods = append(m.methods, method.Func.Name)
+		}
+		for _, constructor := range class.Constructors {
+			m.constructors = append(m.constructors, constructor.Name)
+		}
+		got[class.Name] = m
+	}
+
+	// Every class only holds its own members
+	want := map[string]members{
+		"Outer":               {fields: []string{"size"}, methods: []string{"size"}},
+		"Outer.Inner":         {fields: []string{"label"}, methods: []string{"label"}, constructors: []string{"Inner"}},
+		"Outer.Inner.Deepest": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("classes = %+v, want %+v", got, want)
+	}
+}
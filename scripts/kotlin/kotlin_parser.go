@@ -0,0 +1,8 @@
+package kotlin
+
+import "github.com/Marksagittarius/pinguis/types"
+
+type KotlinParser interface {
+	ParseFile(filePath string) (*types.File, error)
+	ParseModule(modulePath string) (*types.Module, error)
+}
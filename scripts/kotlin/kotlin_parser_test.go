@@ -0,0 +1,148 @@
+package kotlin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+const userSource = `package com.example.model
+
+import com.example.util.Validator
+import kotlinx.coroutines.*
+
+/** A user of the app. */
+data class User(
+    val id: Long,
+    var name: String = "anonymous",
+    private val tags: List<String> = emptyList(),
+) : Comparable<User> {
+    val displayName: String
+        get() = "$name ($id)"
+
+    override fun compareTo(other: User): Int {
+        // "}" in comments and strings is not a brace
+        return id.compareTo(other.id)
+    }
+}
+
+fun greet(user: User, greeting: String = "Hello"): String {
+    val message = "$greeting, ${user.name}!"
+    return message
+}
+
+fun log(message: String) = println(message)
+`
+
+func parseSource(t *testing.T, source string) *types.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "User.kt")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := NewTreeSitterKotlinParser().ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	return file
+}
+
+func TestParseFileExtractsPackageAndImports(t *testing.T) {
+	file := parseSource(t, userSource)
+
+	if file.Module != "com.example.model" {
+		t.Errorf("Module = %q, want com.example.model", file.Module)
+	}
+	if want := []string{"com.example.util.Validator", "kotlinx.coroutines.*"}; !reflect.DeepEqual(file.Imports, want) {
+		t.Errorf("Imports = %q, want %q", file.Imports, want)
+	}
+}
+
+func TestParseFileExtractsDataClass(t *testing.T) {
+	file := parseSource(t, userSource)
+
+	if len(file.Classes) != 1 {
+		t.Fatalf("Classes = %+v, want the User data class", file.Classes)
+	}
+	user := file.Classes[0]
+	if user.Name != "User" {
+		t.Errorf("class name = %q, want User", user.Name)
+	}
+	wantFields := []types.Field{
+		{Name: "id", Type: "Long"},
+		{Name: "name", Type: "String"},
+		{Name: "tags", Type: "List<String>"},
+		{Name: "displayName", Type: "String"},
+	}
+	if !reflect.DeepEqual(user.Fields, wantFields) {
+		t.Errorf("fields = %+v, want %+v", user.Fields, wantFields)
+	}
+	if len(user.Methods) != 1 {
+		t.Fatalf("methods = %+v, want compareTo", user.Methods)
+	}
+	method := user.Methods[0]
+	if method.Reciever != "User" || method.Func.Name != "compareTo" {
+		t.Errorf("method = %s.%s, want User.compareTo", method.Reciever, method.Func.Name)
+	}
+	if want := []types.Parameter{{Name: "other", Type: "User"}}; !reflect.DeepEqual(method.Func.Parameters, want) {
+		t.Errorf("compareTo parameters = %+v, want %+v", method.Func.Parameters, want)
+	}
+	if want := []string{"Int"}; !reflect.DeepEqual(method.Func.ReturnTypes, want) {
+		t.Errorf("compareTo return types = %q, want %q", method.Func.ReturnTypes, want)
+	}
+}
+
+func TestParseFileExtractsTopLevelFunctions(t *testing.T) {
+	file := parseSource(t, userSource)
+
+	if len(file.Functions) != 2 {
+		t.Fatalf("Functions = %+v, want greet and log", file.Functions)
+	}
+	greet := file.Functions[0]
+	if greet.Name != "greet" {
+		t.Errorf("function name = %q, want greet", greet.Name)
+	}
+	wantParams := []types.Parameter{{Name: "user", Type: "User"}, {Name: "greeting", Type: "String"}}
+	if !reflect.DeepEqual(greet.Parameters, wantParams) {
+		t.Errorf("greet parameters = %+v, want %+v", greet.Parameters, wantParams)
+	}
+	if want := []string{"String"}; !reflect.DeepEqual(greet.ReturnTypes, want) {
+		t.Errorf("greet return types = %q, want %q", greet.ReturnTypes, want)
+	}
+	wantBody := "{\n    val message = \"$greeting, ${user.name}!\"\n    return message\n}"
+	if greet.Body != wantBody {
+		t.Errorf("greet body = %q, want %q", greet.Body, wantBody)
+	}
+
+	log := file.Functions[1]
+	if log.Name != "log" || !reflect.DeepEqual(log.ReturnTypes, []string{"Unit"}) {
+		t.Errorf("log = %s returning %q, want log returning Unit", log.Name, log.ReturnTypes)
+	}
+	if log.Body != "= println(message)" {
+		t.Errorf("log body = %q, want the expression body", log.Body)
+	}
+}
+
+func TestParseFileExtractsInterfaces(t *testing.T) {
+	file := parseSource(t, `package shapes
+
+interface Shape {
+    fun area(): Double
+    fun scale(factor: Double): Shape
+}
+`)
+
+	if len(file.Interfaces) != 1 || file.Interfaces[0].Name != "Shape" {
+		t.Fatalf("Interfaces = %+v, want Shape", file.Interfaces)
+	}
+	var names []string
+	for _, method := range file.Interfaces[0].Methods {
+		names = append(names, method.Name)
+	}
+	if want := []string{"area", "scale"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Shape methods = %q, want %q", names, want)
+	}
+}
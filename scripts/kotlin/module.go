@@ -0,0 +1,64 @@
+package kotlin
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// AnalyzeKotlinModule analyzes a Kotlin module located at the specified path and
+// returns a structured representation of the module.
+//
+// Parameters:
+//   - modulePath: The root directory path of the Kotlin module to analyze.
+//
+// Returns:
+//   - *types.Module: A pointer to the structured representation of the module,
+//     containing its name, files, and submodules.
+//   - error: An error if any issues occur during the analysis, or nil if the
+//     analysis is successful.
+//
+// Behavior:
+//   - Skips hidden directories (those starting with a dot).
+//   - Parses files with the ".kt" extension using the Tree-Sitter-based Kotlin parser.
+//   - Analyzes each subdirectory as a submodule.
+func AnalyzeKotlinModule(modulePath string) (*types.Module, error) {
+	module := &types.Module{
+		Name:       filepath.Base(modulePath),
+		Files:      []types.File{},
+		SubModules: []types.Module{},
+	}
+
+	entries, err := os.ReadDir(modulePath)
+	if err != nil {
+		return &types.Module{}, err
+	}
+
+	parser := NewTreeSitterKotlinParser()
+	for _, entry := range entries {
+		path := filepath.Join(modulePath, entry.Name())
+
+		if entry.IsDir() {
+			if entry.Name()[0] == '.' {
+				continue
+			}
+			subModule, err := AnalyzeKotlinModule(path)
+			if err != nil {
+				return &types.Module{}, err
+			}
+			module.SubModules = append(module.SubModules, *subModule)
+			continue
+		}
+
+		if filepath.Ext(entry.Name()) == ".kt" {
+			file, err := parser.ParseFile(path)
+			if err != nil {
+				return &types.Module{}, err
+			}
+			module.Files = append(module.Files, *file)
+		}
+	}
+
+	return module, nil
+}
@@ -0,0 +1,371 @@
+//go:build !kotlin
+
+package kotlin
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// TreeSitterKotlinParser is the Kotlin parser of builds without the kotlin build
+// tag. The tree-sitter-kotlin grammar is only linked into builds made with
+// -tags kotlin, so this parser scans the source for its declarations instead.
+// For conventionally formatted code it extracts the same structure as the
+// grammar-backed parser, but it may miss declarations whose headers nest
+// parentheses, such as parameters with default values that call functions.
+type TreeSitterKotlinParser struct {
+}
+
+// NewTreeSitterKotlinParser creates and returns a new instance of TreeSitterKotlinParser.
+func NewTreeSitterKotlinParser() *TreeSitterKotlinParser {
+	return &TreeSitterKotlinParser{}
+}
+
+// ParseFile parses a Kotlin source file located at the specified file path
+// and returns a representation of the file as a *types.File object.
+//
+// Parameters:
+//   - filePath: The path to the Kotlin source file to be parsed.
+//
+// Returns:
+//   - *types.File: A pointer to the parsed file representation.
+//   - error: An error if the file cannot be read.
+func (p *TreeSitterKotlinParser) ParseFile(filePath string) (*types.File, error) {
+	code, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file := ScanKotlinFile(code, filePath)
+	return &file, nil
+}
+
+// ParseModule parses a Kotlin module from the specified module path and returns
+// a representation of the module as a *types.Module.
+//
+// Parameters:
+//   - modulePath: The directory of the Kotlin module to be parsed.
+//
+// Returns:
+//   - *types.Module: A pointer to the parsed module representation.
+//   - error: An error object if parsing fails, otherwise nil.
+func (p *TreeSitterKotlinParser) ParseModule(modulePath string) (*types.Module, error) {
+	return AnalyzeKotlinModule(modulePath)
+}
+
+var (
+	packagePattern = regexp.MustCompile(`(?m)^[ \t]*package[ \t]+([\w.]+)`)
+
+	// declarationPattern matches the start of a declaration after its
+	// modifiers, capturing its keyword.
+	declarationPattern = regexp.MustCompile(`(?m)^[ \t]*(?:(?:public|private|internal|protected|open|abstract|final|sealed|data|enum|inner|annotation|value|inline|override|suspend|operator|infix|tailrec|external|lateinit|const)[ \t]+)*(class|interface|object|fun|val|var)\b`)
+
+	typeNamePattern = regexp.MustCompile(`^(?:class|interface|object)\s+(\w+)`)
+	functionPattern = regexp.MustCompile(`^fun\s+(?:<[^>]*>\s*)?(?:[\w.<>?, ]+\.)?(\w+)\s*\(`)
+	// constructorPattern matches the header of a type up to the parenthesis
+	// opening its primary constructor, which may follow type parameters and
+	// the constructor keyword, but never a supertype.
+	constructorPattern = regexp.MustCompile(`^\s*(?:<[^>]*>\s*)?(?:(?:public|private|protected|internal|@\w+)\s+)*(?:constructor\s*)?\(`)
+
+	returnPattern   = regexp.MustCompile(`^\s*:\s*([^{=\n]+)`)
+	propertyPattern = regexp.MustCompile(`^(?:val|var)\s+(\w+)\s*(?::\s*([^=\n{]+))?`)
+
+	// parameterPattern matches a parameter without its default value, capturing
+	// its modifiers, name and type.
+	parameterPattern = regexp.MustCompile(`(?s)^\s*(?:@[\w.]+(?:\([^)]*\))?\s+)*((?:\w+\s+)*?)(\w+)\s*:\s*([^=]+?)\s*(?:=.*)?$`)
+)
+
+// kotlinDeclaration is a declaration found by the scanner: its keyword and the
+// offset the keyword starts at.
+type kotlinDeclaration struct {
+	keyword string
+	start   int
+}
+
+// ScanKotlinFile extracts the package, imports, classes, interfaces and
+// top-level functions of a Kotlin source file from its text, the way
+// AnalyzeKotlinFile of kotlin builds extracts them from the syntax tree.
+//
+// Parameters:
+//   - code: The source code of the Kotlin file.
+//   - filePath: The file path of the Kotlin source file.
+//
+// Returns:
+//   - A types.File object containing the extracted information. The package of
+//     the file is stored as its Module, classes include data classes and objects,
+//     and the val/var parameters of primary constructors become class fields.
+func ScanKotlinFile(code []byte, filePath string) types.File {
+	blank := blankOut(code)
+	file := types.File{
+		Path:       filePath,
+		Imports:    ParseImports(code),
+		Classes:    []types.Class{},
+		Interfaces: []types.Interface{},
+		Functions:  []types.Function{},
+	}
+	if match := packagePattern.FindSubmatch(blank); match != nil {
+		file.Module = string(match[1])
+	}
+
+	declarations := scopeDeclarations(blank, 0, len(blank))
+	for i, decl := range declarations {
+		limit := len(blank)
+		if i+1 < len(declarations) {
+			limit = declarations[i+1].start
+		}
+		switch decl.keyword {
+		case "class", "object":
+			if class, ok := scanClass(code, blank, decl.start, limit); ok {
+				file.Classes = append(file.Classes, class)
+			}
+		case "interface":
+			if iface, ok := scanInterface(code, blank, decl.start, limit); ok {
+				file.Interfaces = append(file.Interfaces, iface)
+			}
+		case "fun":
+			if function, ok := scanFunction(code, blank, decl.start); ok {
+				file.Functions = append(file.Functions, function)
+			}
+		}
+	}
+	return file
+}
+
+// scopeDeclarations returns the declarations directly inside the scope of the
+// blanked source between from and to, skipping those nested in braces or
+// parentheses, such as local functions or constructor parameters.
+func scopeDeclarations(blank []byte, from int, to int) []kotlinDeclaration {
+	var declarations []kotlinDeclaration
+	depth, pos := 0, from
+	for _, match := range declarationPattern.FindAllSubmatchIndex(blank[from:to], -1) {
+		lineStart := from + match[0]
+		depth += nestingDelta(blank[pos:lineStart])
+		pos = lineStart
+		if depth == 0 {
+			declarations = append(declarations, kotlinDeclaration{
+				keyword: string(blank[from+match[2] : from+match[3]]),
+				start:   from + match[2],
+			})
+		}
+	}
+	return declarations
+}
+
+// nestingDelta returns by how much the braces and parentheses of the text
+// change the nesting depth.
+func nestingDelta(text []byte) int {
+	delta := 0
+	for _, c := range text {
+		switch c {
+		case '{', '(':
+			delta++
+		case '}', ')':
+			delta--
+		}
+	}
+	return delta
+}
+
+// matchingClose returns the offset of the brace or parenthesis closing the one
+// at open, or the length of the source if it is never closed.
+func matchingClose(blank []byte, open int) int {
+	depth := 0
+	for i := open; i < len(blank); i++ {
+		switch blank[i] {
+		case '{', '(':
+			depth++
+		case '}', ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(blank)
+}
+
+// bodyOpen returns the offset of the brace opening the body of the type
+// declared at start, or -1 if the declaration has no body before limit.
+func bodyOpen(blank []byte, start int, limit int) int {
+	for i := start; i < limit; i++ {
+		switch blank[i] {
+		case '{':
+			return i
+		case '(':
+			i = matchingClose(blank, i)
+		}
+	}
+	return -1
+}
+
+// scanClass extracts the class or object declared at start, including the
+// properties declared by its primary constructor.
+func scanClass(code []byte, blank []byte, start int, limit int) (types.Class, bool) {
+	match := typeNamePattern.FindSubmatchIndex(blank[start:limit])
+	if match == nil {
+		return types.Class{}, false
+	}
+	class := types.Class{
+		Name:    string(blank[start+match[2] : start+match[3]]),
+		Methods: []types.Method{},
+	}
+
+	headerEnd := limit
+	open := bodyOpen(blank, start+match[1], limit)
+	if open >= 0 {
+		headerEnd = open
+	}
+	if ctor := constructorPattern.FindIndex(blank[start+match[1] : headerEnd]); ctor != nil {
+		ctor := start + match[1] + ctor[1] - 1
+		for _, param := range splitParameters(blank[ctor+1 : matchingClose(blank, ctor)]) {
+			modifiers, field, ok := scanParameter(param)
+			if ok && (hasWord(modifiers, "val") || hasWord(modifiers, "var")) {
+				class.Fields = append(class.Fields, types.Field{Name: field.Name, Type: field.Type})
+			}
+		}
+	}
+	if open < 0 {
+		return class, true
+	}
+
+	closeAt := matchingClose(blank, open)
+	for _, decl := range scopeDeclarations(blank, open+1, closeAt) {
+		switch decl.keyword {
+		case "fun":
+			if function, ok := scanFunction(code, blank, decl.start); ok {
+				class.Methods = append(class.Methods, types.Method{Reciever: class.Name, Func: function})
+			}
+		case "val", "var":
+			if match := propertyPattern.FindSubmatch(blank[decl.start:closeAt]); match != nil {
+				class.Fields = append(class.Fields, types.Field{Name: string(match[1]), Type: normalizeType(match[2])})
+			}
+		}
+	}
+	return class, true
+}
+
+// scanInterface extracts the interface declared at start with its functions.
+func scanInterface(code []byte, blank []byte, start int, limit int) (types.Interface, bool) {
+	match := typeNamePattern.FindSubmatchIndex(blank[start:limit])
+	if match == nil {
+		return types.Interface{}, false
+	}
+	iface := types.Interface{
+		Name:    string(blank[start+match[2] : start+match[3]]),
+		Methods: []types.Function{},
+	}
+
+	if open := bodyOpen(blank, start+match[1], limit); open >= 0 {
+		for _, decl := range scopeDeclarations(blank, open+1, matchingClose(blank, open)) {
+			if decl.keyword != "fun" {
+				continue
+			}
+			if function, ok := scanFunction(code, blank, decl.start); ok {
+				iface.Methods = append(iface.Methods, function)
+			}
+		}
+	}
+	return iface, true
+}
+
+// scanFunction extracts the function declared at start. Functions without a
+// declared return type return Unit. The body is the block or the expression
+// after the parameters, as in the source.
+func scanFunction(code []byte, blank []byte, start int) (types.Function, bool) {
+	match := functionPattern.FindSubmatchIndex(blank[start:])
+	if match == nil {
+		return types.Function{}, false
+	}
+	function := types.Function{
+		Name:       string(blank[start+match[2] : start+match[3]]),
+		Parameters: []types.Parameter{},
+	}
+
+	open := start + match[1] - 1
+	closeAt := matchingClose(blank, open)
+	for _, param := range splitParameters(blank[open+1 : closeAt]) {
+		if _, parameter, ok := scanParameter(param); ok {
+			function.Parameters = append(function.Parameters, parameter)
+		}
+	}
+
+	returnType := "Unit"
+	rest := closeAt + 1
+	if rest < len(blank) {
+		if returns := returnPattern.FindSubmatchIndex(blank[rest:]); returns != nil {
+			if declared := normalizeType(blank[rest+returns[2] : rest+returns[3]]); declared != "" {
+				returnType = declared
+			}
+			rest += returns[1]
+		}
+	}
+	function.ReturnTypes = []string{returnType}
+
+	for rest < len(blank) && strings.ContainsRune(" \t\r\n", rune(blank[rest])) {
+		rest++
+	}
+	if rest < len(blank) {
+		switch blank[rest] {
+		case '{':
+			function.Body = string(code[rest:min(matchingClose(blank, rest)+1, len(code))])
+		case '=':
+			end := len(code)
+			if newline := strings.IndexByte(string(blank[rest:]), '\n'); newline >= 0 {
+				end = rest + newline
+			}
+			function.Body = strings.TrimSpace(string(code[rest:end]))
+		}
+	}
+	return function, true
+}
+
+// splitParameters splits a parameter list at its top-level commas.
+func splitParameters(list []byte) []string {
+	var params []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '(', '<', '[', '{':
+			depth++
+		case ')', '>', ']', '}':
+			if i == 0 || list[i-1] != '-' {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				params = append(params, string(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := string(list[start:]); strings.TrimSpace(last) != "" {
+		params = append(params, last)
+	}
+	return params
+}
+
+// scanParameter extracts a parameter with its modifiers, e.g. "val" for a
+// constructor parameter declaring a property.
+func scanParameter(param string) (string, types.Parameter, bool) {
+	match := parameterPattern.FindStringSubmatch(param)
+	if match == nil {
+		return "", types.Parameter{}, false
+	}
+	return match[1], types.Parameter{Name: match[2], Type: normalizeType([]byte(match[3]))}, true
+}
+
+// normalizeType returns the type with its whitespace collapsed.
+func normalizeType(text []byte) string {
+	return strings.Join(strings.Fields(string(text)), " ")
+}
+
+// hasWord reports whether the space-separated words contain the word.
+func hasWord(words string, word string) bool {
+	for _, w := range strings.Fields(words) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package kotlin
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// importPattern matches an import directive, capturing the imported name with
+// a trailing .* for on-demand imports and without an alias.
+var importPattern = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+(\w+(?:\.\w+)*(?:\.\*)?)`)
+
+// ParseImports returns the names the Kotlin source imports in source order,
+// e.g. "com.example.model.User" for "import com.example.model.User as U" and
+// "com.example.util.*" for an on-demand import. Imports in comments and
+// strings are ignored.
+func ParseImports(code []byte) []string {
+	var imports []string
+	for _, match := range importPattern.FindAllSubmatch(blankOut(code), -1) {
+		imports = append(imports, string(match[1]))
+	}
+	return imports
+}
+
+// blankOut returns a copy of the Kotlin source with the content of comments,
+// string and character literals replaced by spaces, keeping newlines, so that
+// the copy can be searched for declarations and braces at the offsets of the
+// source.
+func blankOut(code []byte) []byte {
+	blank := make([]byte, len(code))
+	copy(blank, code)
+	blankRange := func(from, to int) {
+		for i := from; i < to && i < len(blank); i++ {
+			if blank[i] != '\n' {
+				blank[i] = ' '
+			}
+		}
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		var end int
+		switch {
+		case bytes.HasPrefix(rest, []byte("//")):
+			end = bytes.IndexByte(rest, '\n')
+		case bytes.HasPrefix(rest, []byte("/*")):
+			if end = bytes.Index(rest[2:], []byte("*/")); end >= 0 {
+				end += 4
+			}
+		case bytes.HasPrefix(rest, []byte(`"""`)):
+			if end = bytes.Index(rest[3:], []byte(`"""`)); end >= 0 {
+				end += 6
+			}
+		case rest[0] == '"' || rest[0] == '\'':
+			if end = literalEnd(rest); end < 0 {
+				end = 1
+			}
+		default:
+			i++
+			continue
+		}
+		if end < 0 {
+			end = len(rest)
+		}
+		blankRange(i, i+end)
+		i += end
+	}
+	return blank
+}
+
+// literalEnd returns the length of the string or character literal at the start
+// of the text, including its quotes, or -1 if it is not closed on its line.
+func literalEnd(text []byte) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '\n':
+			return -1
+		case quote:
+			return i + 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,318 @@
+//go:build kotlin
+
+package kotlin
+
+import (
+	"os"
+
+	"github.com/Marksagittarius/pinguis/types"
+
+	tree_sitter_kotlin "github.com/fwcd/tree-sitter-kotlin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// TreeSitterKotlinParser is a struct that serves as a parser for Kotlin code
+// using the Tree-sitter parsing library. It extracts the structure of Kotlin
+// files into the same representation as the Java parser.
+type TreeSitterKotlinParser struct {
+}
+
+// NewTreeSitterKotlinParser creates and returns a new instance of TreeSitterKotlinParser.
+func NewTreeSitterKotlinParser() *TreeSitterKotlinParser {
+	return &TreeSitterKotlinParser{}
+}
+
+// ParseFile parses a Kotlin source file located at the specified file path
+// and returns a representation of the file as a *types.File object.
+//
+// Parameters:
+//   - filePath: The path to the Kotlin source file to be parsed.
+//
+// Returns:
+//   - *types.File: A pointer to the parsed file representation.
+//   - error: An error if the file cannot be read or parsed.
+func (p *TreeSitterKotlinParser) ParseFile(filePath string) (*types.File, error) {
+	code, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_kotlin.Language())); err != nil {
+		return nil, err
+	}
+
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+	file := AnalyzeKotlinFile(tree.RootNode(), code, filePath)
+	file.Imports = ParseImports(code)
+	return &file, nil
+}
+
+// ParseModule parses a Kotlin module from the specified module path and returns
+// a representation of the module as a *types.Module.
+//
+// Parameters:
+//   - modulePath: The directory of the Kotlin module to be parsed.
+//
+// Returns:
+//   - *types.Module: A pointer to the parsed module representation.
+//   - error: An error object if parsing fails, otherwise nil.
+func (p *TreeSitterKotlinParser) ParseModule(modulePath string) (*types.Module, error) {
+	return AnalyzeKotlinModule(modulePath)
+}
+
+// typeKinds are the node kinds of Kotlin type annotations.
+var typeKinds = map[string]bool{
+	"user_type":          true,
+	"nullable_type":      true,
+	"function_type":      true,
+	"parenthesized_type": true,
+	"non_nullable_type":  true,
+}
+
+func getNodeText(node *tree_sitter.Node, code []byte) string {
+	return string(code[node.StartByte():node.EndByte()])
+}
+
+// namedChildren returns the named children of the node.
+func namedChildren(node *tree_sitter.Node) []*tree_sitter.Node {
+	var children []*tree_sitter.Node
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		children = append(children, node.NamedChild(i))
+	}
+	return children
+}
+
+// childOfKind returns the first named child of the given kind, or nil.
+func childOfKind(node *tree_sitter.Node, kind string) *tree_sitter.Node {
+	for _, child := range namedChildren(node) {
+		if child.Kind() == kind {
+			return child
+		}
+	}
+	return nil
+}
+
+// hasKeyword reports whether the node has an anonymous child with the given text,
+// e.g. the "interface" keyword of a class declaration.
+func hasKeyword(node *tree_sitter.Node, keyword string) bool {
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if !child.IsNamed() && child.Kind() == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// typeAfter returns the text of the first type annotation following the child
+// of the given kind, e.g. the return type after a function's parameter list.
+func typeAfter(node *tree_sitter.Node, kind string, code []byte) string {
+	found := false
+	for _, child := range namedChildren(node) {
+		if child.Kind() == kind {
+			found = true
+			continue
+		}
+		if found && typeKinds[child.Kind()] {
+			return getNodeText(child, code)
+		}
+	}
+	return ""
+}
+
+// firstType returns the text of the first type annotation among the node's
+// children, or an empty string if it has none.
+func firstType(node *tree_sitter.Node, code []byte) string {
+	for _, child := range namedChildren(node) {
+		if typeKinds[child.Kind()] {
+			return getNodeText(child, code)
+		}
+	}
+	return ""
+}
+
+// extractParameters extracts the parameters of a function_value_parameters node.
+func extractParameters(paramsNode *tree_sitter.Node, code []byte) []types.Parameter {
+	params := []types.Parameter{}
+	if paramsNode == nil {
+		return params
+	}
+
+	for _, child := range namedChildren(paramsNode) {
+		if child.Kind() != "parameter" {
+			continue
+		}
+		var param types.Parameter
+		if nameNode := childOfKind(child, "simple_identifier"); nameNode != nil {
+			param.Name = getNodeText(nameNode, code)
+		}
+		param.Type = firstType(child, code)
+		params = append(params, param)
+	}
+	return params
+}
+
+// extractFunction extracts a function_declaration node. Functions without a
+// declared return type return Unit.
+func extractFunction(node *tree_sitter.Node, code []byte) types.Function {
+	function := types.Function{
+		Parameters: extractParameters(childOfKind(node, "function_value_parameters"), code),
+	}
+
+	if nameNode := childOfKind(node, "simple_identifier"); nameNode != nil {
+		function.Name = getNodeText(nameNode, code)
+	}
+
+	returnType := typeAfter(node, "function_value_parameters", code)
+	if returnType == "" {
+		returnType = "Unit"
+	}
+	function.ReturnTypes = []string{returnType}
+
+	if bodyNode := childOfKind(node, "function_body"); bodyNode != nil {
+		function.Body = getNodeText(bodyNode, code)
+	}
+	return function
+}
+
+// extractProperty extracts a property_declaration node as a field.
+func extractProperty(node *tree_sitter.Node, code []byte) (types.Field, bool) {
+	decl := childOfKind(node, "variable_declaration")
+	if decl == nil {
+		return types.Field{}, false
+	}
+	nameNode := childOfKind(decl, "simple_identifier")
+	if nameNode == nil {
+		return types.Field{}, false
+	}
+	return types.Field{
+		Name: getNodeText(nameNode, code),
+		Type: firstType(decl, code),
+	}, true
+}
+
+// extractConstructorProperties extracts the val and var parameters of a primary
+// constructor, which declare properties of the class (e.g. of data classes).
+func extractConstructorProperties(ctorNode *tree_sitter.Node, code []byte) []types.Field {
+	var fields []types.Field
+	if ctorNode == nil {
+		return fields
+	}
+
+	for _, child := range namedChildren(ctorNode) {
+		if child.Kind() != "class_parameter" {
+			continue
+		}
+		if !hasKeyword(child, "val") && !hasKeyword(child, "var") {
+			continue
+		}
+		nameNode := childOfKind(child, "simple_identifier")
+		if nameNode == nil {
+			continue
+		}
+		fields = append(fields, types.Field{
+			Name: getNodeText(nameNode, code),
+			Type: firstType(child, code),
+		})
+	}
+	return fields
+}
+
+// extractClass extracts a class_declaration or object_declaration node,
+// including the properties declared by its primary constructor.
+func extractClass(node *tree_sitter.Node, code []byte) types.Class {
+	class := types.Class{
+		Fields:  extractConstructorProperties(childOfKind(node, "primary_constructor"), code),
+		Methods: []types.Method{},
+	}
+	if nameNode := childOfKind(node, "type_identifier"); nameNode != nil {
+		class.Name = getNodeText(nameNode, code)
+	}
+
+	body := childOfKind(node, "class_body")
+	if body == nil {
+		body = childOfKind(node, "enum_class_body")
+	}
+	if body == nil {
+		return class
+	}
+
+	for _, member := range namedChildren(body) {
+		switch member.Kind() {
+		case "function_declaration":
+			class.Methods = append(class.Methods, types.Method{
+				Reciever: class.Name,
+				Func:     extractFunction(member, code),
+			})
+		case "property_declaration":
+			if field, ok := extractProperty(member, code); ok {
+				class.Fields = append(class.Fields, field)
+			}
+		}
+	}
+	return class
+}
+
+// extractInterface extracts an interface declaration, which the Kotlin grammar
+// parses as a class_declaration with the interface keyword.
+func extractInterface(node *tree_sitter.Node, code []byte) types.Interface {
+	iface := types.Interface{Methods: []types.Function{}}
+	if nameNode := childOfKind(node, "type_identifier"); nameNode != nil {
+		iface.Name = getNodeText(nameNode, code)
+	}
+
+	if body := childOfKind(node, "class_body"); body != nil {
+		for _, member := range namedChildren(body) {
+			if member.Kind() == "function_declaration" {
+				iface.Methods = append(iface.Methods, extractFunction(member, code))
+			}
+		}
+	}
+	return iface
+}
+
+// AnalyzeKotlinFile analyzes a Kotlin source file represented as a tree-sitter
+// syntax tree and extracts its package, classes, interfaces and top-level
+// functions.
+//
+// Parameters:
+//   - root: The root node of the tree-sitter syntax tree representing the Kotlin file.
+//   - code: The byte slice containing the source code of the Kotlin file.
+//   - filePath: The file path of the Kotlin source file.
+//
+// Returns:
+//   - A types.File object containing the extracted information. The package of
+//     the file is stored as its Module, classes include data classes and objects,
+//     and the val/var parameters of primary constructors become class fields.
+func AnalyzeKotlinFile(root *tree_sitter.Node, code []byte, filePath string) types.File {
+	file := types.File{
+		Path:       filePath,
+		Classes:    []types.Class{},
+		Interfaces: []types.Interface{},
+		Functions:  []types.Function{},
+	}
+
+	for _, node := range namedChildren(root) {
+		switch node.Kind() {
+		case "package_header":
+			if nameNode := childOfKind(node, "identifier"); nameNode != nil {
+				file.Module = getNodeText(nameNode, code)
+			}
+		case "class_declaration":
+			if hasKeyword(node, "interface") {
+				file.Interfaces = append(file.Interfaces, extractInterface(node, code))
+			} else {
+				file.Classes = append(file.Classes, extractClass(node, code))
+			}
+		case "object_declaration":
+			file.Classes = append(file.Classes, extractClass(node, code))
+		case "function_declaration":
+			file.Functions = append(file.Functions, extractFunction(node, code))
+		}
+	}
+
+	return file
+}
@@ -90,12 +90,62 @@ class PythonAnalyzer:
         else:
             return os.path.basename(file_path)
 
+    def _parse_source(self, source_code: str):
+        """
+        Parse Python source code, recovering from syntax errors.
+        
+        A file with syntax errors is parsed one top-level statement at a time,
+        keeping every statement that parses on its own, so the declarations
+        outside the broken statements are still extracted.
+        
+        Args:
+            source_code: The source code to parse
+            
+        Returns:
+            The module of the statements that could be parsed, and the syntax
+            errors as dicts with the line, column and message of each error
+        """
+        try:
+            return ast.parse(source_code), []
+        except SyntaxError:
+            pass
+        
+        lines = source_code.splitlines(keepends=True)
+        starts = []
+        for i, line in enumerate(lines):
+            stripped = line.strip()
+            if not stripped or line[0].isspace() or stripped.startswith('#'):
+                continue
+            if stripped.startswith((')', ']', '}', 'else', 'elif', 'except', 'finally', 'case')):
+                continue
+            previous = next((l for l in reversed(lines[:i]) if l.strip()), '')
+            if previous.lstrip().startswith('@') or previous.rstrip().endswith('\\'):
+                continue
+            starts.append(i)
+        if not starts or starts[0] != 0:
+            starts.insert(0, 0)
+        
+        body = []
+        syntax_errors = []
+        for start, end in zip(starts, starts[1:] + [len(lines)]):
+            # Leading newlines keep the line numbers of the statement in the file
+            chunk = '\n' * start + ''.join(lines[start:end])
+            try:
+                body.extend(ast.parse(chunk).body)
+            except SyntaxError as e:
+                syntax_errors.append({
+                    "line": e.lineno or start + 1,
+                    "column": e.offset or 1,
+                    "message": e.msg
+                })
+        return ast.Module(body=body, type_ignores=[]), syntax_errors
+
     def _process_file_and_return(self, file_path: str) -> Dict:
         try:
             with open(file_path, 'r', encoding='utf-8') as f:
                 source_code = f.read()
             
-            tree = ast.parse(source_code)
+            tree, syntax_errors = self._parse_source(source_code)
             
             module_name = self._get_module_name(file_path)
             
@@ -107,8 +157,11 @@ class PythonAnalyzer:
                 "module": module_name,
                 "classes": [],
                 "interfaces": [],
-                "functions": []
+                "functions": [],
+                "doc": ast.get_docstring(tree) or ""
             }
+            if syntax_errors:
+                file_data["syntax_errors"] = syntax_errors
             
             for node in tree.body:
                 if isinstance(node, ast.FunctionDef):
@@ -161,7 +214,8 @@ class PythonAnalyzer:
             "name": node.name,
             "parameters": self._extract_parameters(node),
             "return_types": self._extract_return_types(node),
-            "body": self._get_function_body(node)
+            "body": self._get_function_body(node),
+            "doc": ast.get_docstring(node) or ""
         }
         return function
 
@@ -205,7 +259,9 @@ class PythonAnalyzer:
         class_data = {
             "name": node.name,
             "fields": [],
-            "methods": []
+            "methods": [],
+            "doc": ast.get_docstring(node) or "",
+            "base_classes": [self._get_base_name(base) for base in node.bases]
         }
         
         for item in node.body:
@@ -226,6 +282,19 @@ class PythonAnalyzer:
         
         return class_data
 
+    def _get_base_name(self, base) -> str:
+        """
+        Get the name a base class is referred to by, e.g. 'Base' or 'models.Base',
+        without the type arguments of generic bases such as 'Generic[T]'.
+        """
+        if isinstance(base, ast.Subscript):
+            base = base.value
+        if isinstance(base, ast.Name):
+            return base.id
+        if isinstance(base, ast.Attribute):
+            return self._get_name_from_attribute(base)
+        return ast.unparse(base) if hasattr(ast, 'unparse') else ""
+
     def _is_interface(self, node: ast.ClassDef) -> bool:
         for base in node.bases:
             if isinstance(base, ast.Name):
@@ -2,20 +2,36 @@ package python
 
 import (
     "fmt"
-    "os"
     "os/exec"
     "path/filepath"
     "runtime"
     "strings"
 
+    "github.com/Marksagittarius/pinguis/fileio"
     "github.com/Marksagittarius/pinguis/types"
 )
 
+// syntaxReport is the part of the metadata gen_metadata.py writes that lists
+// the syntax errors of the file.
+type syntaxReport struct {
+    SyntaxErrors []types.SyntaxError `json:"syntax_errors"`
+}
+
+// GetFileMetaData extracts the structure of the Python file with
+// gen_metadata.py. A file with syntax errors is returned with the declarations
+// that could be parsed, together with a *types.IncompleteParseError wrapping
+// types.ErrIncompleteParse that lists the errors.
 func GetFileMetaData(filePath string) (*types.File, error) {
     baseFileName := filepath.Base(filePath)
     jsonFileName := strings.TrimSuffix(baseFileName, filepath.Ext(baseFileName)) + ".json"
     
-    jsonFilePath := filepath.Join(filepath.Dir(filePath), jsonFileName)
+    tempDir, err := fileio.MkdirTemp("pinguis-metadata-*")
+    if err != nil {
+        return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+    }
+    defer fileio.RemoveTemp(tempDir)
+
+    jsonFilePath := filepath.Join(tempDir, jsonFileName)
     
     _, currentFile, _, ok := runtime.Caller(0)
     if !ok {
@@ -44,9 +60,10 @@ func GetFileMetaData(filePath string) (*types.File, error) {
         return nil, fmt.Errorf("failed to load JSON file %s: %v", jsonFilePath, err)
     }
     
-    if err := os.Remove(jsonFilePath); err != nil {
-        fmt.Printf("Warning: Failed to delete temporary JSON file %s: %v\n", jsonFilePath, err)
+    report, err := types.LoadFromJSON[syntaxReport](jsonFilePath)
+    if err == nil && len(report.SyntaxErrors) > 0 {
+        return &fileData, &types.IncompleteParseError{Path: filePath, Errors: report.SyntaxErrors}
     }
-    
+
     return &fileData, nil
 }
@@ -0,0 +1,139 @@
+package python
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+const brokenPython = `"""Shopping cart helpers."""
+
+
+def add(total, price):
+    return total + price
+
+
+def broken(:
+    pass
+
+
+class Cart:
+    def total(self):
+        return 0
+`
+
+func writePythonSource(t *testing.T, source string) string {
+	t.Helper()
+	if _, err := exec.LookPath("python"); err != nil {
+		t.Skip("python is not installed")
+	}
+	path := filepath.Join(t.TempDir(), "cart.py")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGetFileMetaDataReportsSyntaxErrorsWithParsedParts(t *testing.T) {
+	path := writePythonSource(t, brokenPython)
+
+	file, err := GetFileMetaData(path)
+	if !errors.Is(err, types.ErrIncompleteParse) {
+		t.Fatalf("GetFileMetaData error = %v, want one wrapping types.ErrIncompleteParse", err)
+	}
+	var incomplete *types.IncompleteParseError
+	if !errors.As(err, &incomplete) || len(incomplete.Errors) != 1 || incomplete.Errors[0].Line != 8 {
+		t.Fatalf("GetFileMetaData error = %v, want a single syntax error on line 8", err)
+	}
+
+	if file == nil {
+		t.Fatal("GetFileMetaData returned no file")
+	}
+	if len(file.Functions) != 1 || file.Functions[0].Name != "add" {
+		t.Errorf("functions = %+v, want add, which parses", file.Functions)
+	}
+	if len(file.Classes) != 1 || file.Classes[0].Name != "Cart" {
+		t.Errorf("classes = %+v, want Cart, which parses", file.Classes)
+	}
+	if file.Doc != "Shopping cart helpers." {
+		t.Errorf("doc = %q, want the module docstring", file.Doc)
+	}
+}
+
+func TestGetFileMetaDataWithoutSyntaxErrorsReportsNone(t *testing.T) {
+	path := writePythonSource(t, "def add(a, b):\n    return a + b\n")
+
+	if _, err := GetFileMetaData(path); err != nil {
+		t.Errorf("GetFileMetaData error = %v, want none", err)
+	}
+}
+
+func TestGetFileMetaDataLeavesNoIntermediateFiles(t *testing.T) {
+	path := writePythonSource(t, "def add(a, b):\n    return a + b\n")
+	tempDir := t.TempDir()
+	fileio.SetTempDir(tempDir)
+	t.Cleanup(func() { fileio.SetTempDir("") })
+
+	if _, err := GetFileMetaData(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("temp directory holds %d entries after parsing, want none", len(entries))
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("source directory holds %d entries, want only the source file", len(entries))
+	}
+}
+
+func TestGetFileMetaDataCapturesDocstrings(t *testing.T) {
+	path := writePythonSource(t, `def total(prices):
+    """Sums the prices.
+
+    An empty cart costs nothing.
+    """
+    return sum(prices)
+
+
+class Cart:
+    """A shopping cart."""
+
+    def add(self, price):
+        """Adds a price."""
+        self.prices.append(price)
+
+    def clear(self):
+        self.prices = []
+`)
+
+	file, err := GetFileMetaData(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Functions) != 1 || file.Functions[0].Doc != "Sums the prices.\n\nAn empty cart costs nothing." {
+		t.Errorf("functions = %+v, want total with its docstring", file.Functions)
+	}
+	if len(file.Classes) != 1 || file.Classes[0].Doc != "A shopping cart." {
+		t.Fatalf("classes = %+v, want Cart with its docstring", file.Classes)
+	}
+	docs := map[string]string{}
+	for _, method := range file.Classes[0].Methods {
+		docs[method.Func.Name] = method.Func.Doc
+	}
+	if want := map[string]string{"add": "Adds a price.", "clear": ""}; !reflect.DeepEqual(docs, want) {
+		t.Errorf("method docs = %q, want %q", docs, want)
+	}
+	if file.Doc != "" {
+		t.Errorf("file doc = %q for a module without a docstring", file.Doc)
+	}
+}
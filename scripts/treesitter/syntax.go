@@ -0,0 +1,44 @@
+// Package treesitter holds helpers shared by the parsers built on tree-sitter,
+// whatever the grammar of the trees they parse.
+package treesitter
+
+import (
+	"github.com/Marksagittarius/pinguis/types"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// SyntaxErrors returns the positions of the ERROR and MISSING nodes below the
+// root of a syntax tree, in source order. A tree without syntax errors has none.
+func SyntaxErrors(root *tree_sitter.Node) []types.SyntaxError {
+	var syntaxErrors []types.SyntaxError
+	var visit func(node *tree_sitter.Node)
+	visit = func(node *tree_sitter.Node) {
+		if node.IsError() || node.IsMissing() {
+			position := node.StartPosition()
+			syntaxErr := types.SyntaxError{Line: int(position.Row) + 1, Column: int(position.Column) + 1}
+			if node.IsMissing() {
+				syntaxErr.Missing = node.Kind()
+			}
+			syntaxErrors = append(syntaxErrors, syntaxErr)
+			return
+		}
+		if !node.HasError() {
+			return
+		}
+		for i := uint(0); i < node.ChildCount(); i++ {
+			visit(node.Child(i))
+		}
+	}
+	visit(root)
+	return syntaxErrors
+}
+
+// CheckSyntax returns an IncompleteParseError for the file if its syntax tree
+// has syntax errors, nil otherwise.
+func CheckSyntax(root *tree_sitter.Node, filePath string) error {
+	if !root.HasError() {
+		return nil
+	}
+	return &types.IncompleteParseError{Path: filePath, Errors: SyntaxErrors(root)}
+}
@@ -0,0 +1,82 @@
+package treesitter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/Marksagittarius/pinguis/types"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// parse parses code with the grammar and closes the tree when the test ends.
+func parse(t *testing.T, grammar func() unsafe.Pointer, code string) *tree_sitter.Tree {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(grammar())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(code), nil)
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+func TestSyntaxErrorsOfEveryGrammar(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar func() unsafe.Pointer
+		code    string
+		want    []types.SyntaxError
+	}{
+		{
+			name:    "valid python",
+			grammar: tree_sitter_python.Language,
+			code:    "def f(x):\n    return x\n",
+		},
+		{
+			name:    "broken python",
+			grammar: tree_sitter_python.Language,
+			code:    "def f(x):\n    return x +\n",
+			want:    []types.SyntaxError{{Line: 2, Column: 5}},
+		},
+		{
+			name:    "broken javascript",
+			grammar: tree_sitter_javascript.Language,
+			code:    "function f(x) {\n  return (x;\n}\n",
+			want:    []types.SyntaxError{{Line: 2, Column: 12, Missing: ")"}},
+		},
+		{
+			name:    "broken java",
+			grammar: tree_sitter_java.Language,
+			code:    "class A {\n  int f() { return 1 }\n}\n",
+			want:    []types.SyntaxError{{Line: 2, Column: 21, Missing: ";"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := parse(t, tt.grammar, tt.code).RootNode()
+			if got := SyntaxErrors(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SyntaxErrors = %v, want %v", got, tt.want)
+			}
+
+			err := CheckSyntax(root, "source")
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("CheckSyntax = %v, want nil", err)
+				}
+				return
+			}
+			var incomplete *types.IncompleteParseError
+			if !errors.As(err, &incomplete) || incomplete.Path != "source" || !errors.Is(err, types.ErrIncompleteParse) {
+				t.Errorf("CheckSyntax = %v, want an IncompleteParseError of source", err)
+			}
+		})
+	}
+}
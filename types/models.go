@@ -10,11 +10,14 @@ type Function struct {
 	Parameters []Parameter `json:"parameters"`
 	ReturnTypes []string `json:"return_types"`
 	Body string `json:"body"`
+	Doc string `json:"doc"`
 }
 
 type Method struct {
 	Reciever string `json:"reciever"`
 	Func Function `json:"function"`
+	IsStatic bool `json:"is_static"`
+	IsAbstract bool `json:"is_abstract"`
 }
 
 type Field struct {
@@ -26,6 +29,11 @@ type Class struct {
 	Name string `json:"name"`
 	Fields []Field `json:"fields"`
 	Methods []Method `json:"methods"`
+	Constructors []Function `json:"constructors"`
+	Doc string `json:"doc"`
+	Extends string `json:"extends"`
+	Implements []string `json:"implements"`
+	BaseClasses []string `json:"base_classes"`
 }
 
 type Interface struct {
@@ -36,9 +44,11 @@ type Interface struct {
 type File struct {
 	Path string `json:"path"`
 	Module string `json:"module"`
+	Imports []string `json:"imports"`
 	Classes []Class `json:"classes"`
 	Interfaces []Interface `json:"interfaces"`
 	Functions []Function `json:"functions"`
+	Doc string `json:"doc"`
 }
 
 type Module struct {
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrIncompleteParse is wrapped by the errors the parsers return together with
+// a file whose source has syntax errors. The parsers recover from syntax
+// errors, so the file holds every declaration that could be parsed, but
+// declarations around the errors may be missing or incomplete.
+var ErrIncompleteParse = errors.New("the source has syntax errors, the extracted structure may be incomplete")
+
+// SyntaxError is a position a parser could not parse, with 1-based line and
+// column numbers.
+//
+// Fields:
+//   - Line: The line the unparsable code starts on.
+//   - Column: The column the unparsable code starts at.
+//   - Missing: The node the parser inserted to recover, e.g. ";", empty if the
+//     code at the position was skipped instead.
+//   - Message: The parser's description of the error, if it gives one.
+type SyntaxError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Missing string `json:"missing,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e SyntaxError) String() string {
+	switch {
+	case e.Missing != "":
+		return fmt.Sprintf("%d:%d: missing %s", e.Line, e.Column, e.Missing)
+	case e.Message != "":
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	default:
+		return fmt.Sprintf("%d:%d: unexpected code", e.Line, e.Column)
+	}
+}
+
+// IncompleteParseError reports the syntax errors of a file that was parsed only
+// partially. It wraps ErrIncompleteParse.
+type IncompleteParseError struct {
+	Path   string
+	Errors []SyntaxError
+}
+
+func (e *IncompleteParseError) Error() string {
+	positions := make([]string, len(e.Errors))
+	for i, syntaxErr := range e.Errors {
+		positions[i] = syntaxErr.String()
+	}
+	return fmt.Sprintf("%s: %v (%s)", e.Path, ErrIncompleteParse, strings.Join(positions, ", "))
+}
+
+func (e *IncompleteParseError) Unwrap() error {
+	return ErrIncompleteParse
+}
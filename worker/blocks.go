@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/postprocessor"
+)
+
+// fileHintPattern matches a first-line comment naming the file a code block
+// belongs to, e.g. "# conftest.py" or "// file: helpers_test.go".
+var fileHintPattern = regexp.MustCompile(`^(?:#|//)\s*(?:(?:file|filename|path)\s*:\s*)?([\w./-]+\.\w+)\s*$`)
+
+// codeExtractor returns the extractor of the code of responses without a block
+// of the code type: a DetectingCodeExtractor if detectCodeLanguage is set, a
+// CodeExtractor of the code type otherwise.
+func (dw *DeepWorker) codeExtractor(codeType string) postprocessor.CodePostprocessor {
+	if dw.detectCodeLanguage {
+		return postprocessor.NewDetectingCodeExtractor(codeType)
+	}
+	return postprocessor.NewCodeExtractor(codeType)
+}
+
+// responseBlocks returns the code blocks of the response: its fenced blocks of
+// the code type or, if it has none and detectCodeLanguage is set, its fenced
+// blocks of any language, e.g. ```text. A response without such blocks is a
+// single block of the code codeExtractor extracts.
+func (dw *DeepWorker) responseBlocks(content, codeType string) []string {
+	if blocks := postprocessor.NewCodeExtractor(codeType).PostprocessAll(content); len(blocks) > 0 {
+		return blocks
+	}
+	if dw.detectCodeLanguage {
+		if blocks := postprocessor.NewCodeExtractor("").PostprocessAll(content); len(blocks) > 0 {
+			return blocks
+		}
+	}
+	return []string{dw.codeExtractor(codeType).Postprocess(content)}
+}
+
+// testFile is a generated file and its content.
+type testFile struct {
+	Path string
+	Code string
+}
+
+// blockFileHint returns the file name hinted by the first line of the block, or
+// an empty string if the block carries no usable hint.
+func blockFileHint(block string) string {
+	firstLine, _, _ := strings.Cut(block, "\n")
+	match := fileHintPattern.FindStringSubmatch(strings.TrimSpace(firstLine))
+	if match == nil {
+		return ""
+	}
+
+	hint := filepath.Clean(filepath.FromSlash(match[1]))
+	if filepath.IsAbs(hint) || hint == ".." || strings.HasPrefix(hint, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return hint
+}
+
+// routeTestBlocks assigns every code block to the file hinted in its header
+// comment, relative to the directory of the default test file. Blocks without a
+// hint go to the default test file. Blocks routed to the same file are joined.
+// The default test file is always the first entry.
+func routeTestBlocks(blocks []string, defaultPath string) []testFile {
+	files := []testFile{{Path: defaultPath}}
+	index := map[string]int{defaultPath: 0}
+
+	for _, block := range blocks {
+		path := defaultPath
+		if hint := blockFileHint(block); hint != "" {
+			path = filepath.Join(filepath.Dir(defaultPath), hint)
+		}
+
+		i, ok := index[path]
+		if !ok {
+			i = len(files)
+			index[path] = i
+			files = append(files, testFile{Path: path})
+		}
+		if files[i].Code != "" {
+			files[i].Code += "\n\n"
+		}
+		files[i].Code += block
+	}
+
+	return files
+}
+
+// writeTestFiles extracts every code block of the response (see
+// responseBlocks), writes each to the
+// file it is routed to and returns the content of the task's test file, its
+// TestPath. Python test files get their import of the module under test fixed,
+// Go and Java test files are moved to the configured test package, the test
+// functions of the task's test file are renamed to the configured naming
+// pattern, and every file is stripped of duplicate test functions and formatted
+// before it is written. The written files are recorded on the task, for the test
+// written hook to run once the task is finished.
+func (dw *DeepWorker) writeTestFiles(task *TestTask, content string) (string, error) {
+	defaultPath, sourcePath, sourceCode, codeType := task.TestPath, task.SourcePath, task.SourceCode, task.CodeType
+	files := routeTestBlocks(dw.responseBlocks(content, codeType), defaultPath)
+	if codeType == "python" && files[0].Code != "" {
+		files[0].Code = fixPythonImports(files[0].Code, defaultPath, sourcePath)
+	}
+	if files[0].Code != "" {
+		files[0].Code = dw.enforceTestPackage(files[0].Code, sourceCode, sourcePath, codeType)
+		files[0].Code = dw.renameTests(files[0].Code, task)
+	}
+
+	for i := range files {
+		file := &files[i]
+		if file.Path == defaultPath && file.Code == "" {
+			continue
+		}
+		file.Code = postprocessor.DedupTestFunctions(file.Code, getCodeType(file.Path), dw.duplicateThreshold)
+		file.Code = dw.format(file.Code, getCodeType(file.Path))
+		if err := dw.fileIO.Write(file.Path, []byte(file.Code)); err != nil {
+			return "", fmt.Errorf("failed to write test file %s: %w", file.Path, err)
+		}
+		task.addWrittenFile(file.Path)
+	}
+
+	return files[0].Code, nil
+}
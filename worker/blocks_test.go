@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+)
+
+// textResponse is a complete response fencing its files with ```text, with
+// prose around the blocks that ends like a truncated statement.
+const textResponse = "Here are the tests (and a fixture:\n" +
+	"```text\ndef test_add(numbers):\n    assert add(*numbers) == 3\n```\n" +
+	"```text\n# conftest.py\nimport pytest\n\n@pytest.fixture\ndef numbers():\n    return (1, 2)\n```\n" +
+	"The fixture provides the numbers as follows:"
+
+func newDetectingWorker() *DeepWorker {
+	dw := newTestWorker(newFakeModel(textResponse), func(config *DeepWorkerConfig) {
+		config.DetectCodeLanguage = true
+	})
+	dw.fileIO = &fileio.SimpleFileIO{}
+	return dw
+}
+
+func TestLooksTruncatedChecksTheDetectedCode(t *testing.T) {
+	dw := newDetectingWorker()
+	if dw.looksTruncated(textResponse, "python") {
+		t.Error("complete ```text response looks truncated")
+	}
+
+	cut := "```text\ndef test_add():\n    assert add(1,\n```"
+	if !dw.looksTruncated(cut, "python") {
+		t.Error("```text response cut off in a call does not look truncated")
+	}
+}
+
+func TestWriteTestFilesRoutesEveryBlockOfAnotherLanguage(t *testing.T) {
+	dir := t.TempDir()
+	task := &TestTask{
+		SourcePath: filepath.Join(dir, "calc.py"),
+		SourceCode: "def add(a, b):\n    return a + b\n",
+		TestPath:   filepath.Join(dir, "test_calc.py"),
+		CodeType:   "python",
+	}
+
+	testCode, err := newDetectingWorker().writeTestFiles(task, textResponse)
+	if err != nil {
+		t.Fatalf("writeTestFiles: %v", err)
+	}
+	if !strings.Contains(testCode, "def test_add(numbers):") {
+		t.Errorf("test code = %q, want the first block", testCode)
+	}
+	conftest, err := os.ReadFile(filepath.Join(dir, "conftest.py"))
+	if err != nil {
+		t.Fatalf("conftest.py was not written: %v", err)
+	}
+	if !strings.Contains(string(conftest), "def numbers():") {
+		t.Errorf("conftest.py = %q, want the fixture", conftest)
+	}
+}
+
+func TestBlockFileHint(t *testing.T) {
+	tests := []struct {
+		block string
+		want  string
+	}{
+		{"# conftest.py\nimport pytest", "conftest.py"},
+		{"// file: helpers_test.go\npackage calc", "helpers_test.go"},
+		{"#  filename : fixtures/data.py\nDATA = 1", filepath.Join("fixtures", "data.py")},
+		{"def test_add():\n    pass", ""},
+		{"# Tests of the calculator\nimport pytest", ""},
+		{"# /etc/passwd.py\nx = 1", ""},
+		{"# ../outside.py\nx = 1", ""},
+	}
+
+	for _, tt := range tests {
+		if got := blockFileHint(tt.block); got != tt.want {
+			t.Errorf("blockFileHint(%q) = %q, want %q", tt.block, got, tt.want)
+		}
+	}
+}
+
+func TestSymTaskWritesConftestBlock(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+	response := "```python\ndef test_add(numbers):\n    assert add(*numbers) == 3\n```\n" +
+		"```python\n# conftest.py\nimport pytest\n\n@pytest.fixture\ndef numbers():\n    return (1, 2)\n```"
+
+	sw := newTestSymWorker(newFakeModel(response), nil)
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	written := map[string]string{
+		"calc_add_test_case_1.py": "def test_add(numbers):",
+		"conftest.py":             "def numbers():",
+	}
+	for name, want := range written {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s was not written: %v", name, err)
+			continue
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("%s = %q, want it to contain %q", name, content, want)
+		}
+	}
+	test, _ := os.ReadFile(filepath.Join(dir, "calc_add_test_case_1.py"))
+	if strings.Contains(string(test), "@pytest.fixture") {
+		t.Errorf("test file holds the conftest block:\n%s", test)
+	}
+}
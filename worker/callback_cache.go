@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// callbackCacheEntry is a cached test result as stored in the cache file.
+type callbackCacheEntry struct {
+	Key    string     `json:"key"`
+	Result TestResult `json:"result"`
+}
+
+// callbackCache keeps the results of test callbacks keyed by the source and test
+// code they were run with and the local files the test depends on, so an identical test is not executed again. It holds
+// at most size results and evicts the least recently used one beyond that. A
+// cache with a path rewrites the file after every new result and starts from its
+// content, so results survive reruns. A nil cache caches nothing.
+type callbackCache struct {
+	mu      sync.Mutex
+	size    int
+	path    string
+	order   *list.List // Least recently used entry at the back
+	entries map[string]*list.Element
+}
+
+// newCallbackCache returns a cache of the given size persisted to path (not
+// persisted if path is empty), or nil if size is not positive.
+func newCallbackCache(size int, path string) *callbackCache {
+	if size <= 0 {
+		return nil
+	}
+
+	cache := &callbackCache{
+		size:    size,
+		path:    path,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if err := cache.load(); err != nil {
+		log.Printf("Failed to load callback cache, starting empty: %v", err)
+	}
+	return cache
+}
+
+// callbackCacheKey returns the key of the result of running testCode against
+// sourceCode, the hash of the hashes of both and of the paths and contents of the
+// local files the test depends on (see testDependencies).
+func callbackCacheKey(sourceCode, testCode string, dependencies []string) string {
+	sourceHash := sha256.Sum256([]byte(sourceCode))
+	testHash := sha256.Sum256([]byte(testCode))
+	hashes := append(sourceHash[:], testHash[:]...)
+	for _, path := range dependencies {
+		code, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		dependencyHash := sha256.Sum256(append([]byte(filepath.ToSlash(path)+"\x00"), code...))
+		hashes = append(hashes, dependencyHash[:]...)
+	}
+	key := sha256.Sum256(hashes)
+	return hex.EncodeToString(key[:])
+}
+
+// testDependencies returns the local files whose changes can change the result of
+// the test at testPath: for Python the modules it imports found in the test
+// directory or above it with the packages holding them, and the conftest.py files
+// applying to it. Tests of other languages have none, so their cached results
+// are only safe for tests depending on nothing but the source and test code.
+func testDependencies(testCode, testPath string) []string {
+	if filepath.Ext(testPath) != ".py" {
+		return nil
+	}
+
+	testDir := filepath.Dir(testPath)
+	seen := make(map[string]bool)
+	var dependencies []string
+	add := func(path string) bool {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+		if !seen[path] {
+			seen[path] = true
+			dependencies = append(dependencies, path)
+		}
+		return true
+	}
+
+	for dir := testDir; ; dir = filepath.Dir(dir) {
+		add(filepath.Join(dir, "conftest.py"))
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for _, module := range pythonImportedModules(testCode) {
+		rel := filepath.FromSlash(strings.ReplaceAll(module, ".", "/"))
+		for dir := testDir; ; dir = filepath.Dir(dir) {
+			if add(filepath.Join(dir, rel+".py")) || add(filepath.Join(dir, rel, "__init__.py")) {
+				// Importing a submodule runs the packages above it as well
+				for pkg := filepath.Dir(rel); pkg != "."; pkg = filepath.Dir(pkg) {
+					add(filepath.Join(dir, pkg, "__init__.py"))
+				}
+				break
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return dependencies
+}
+
+func (c *callbackCache) get(key string) (*TestResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	result := element.Value.(*callbackCacheEntry).Result
+	return &result, true
+}
+
+// put caches a copy of the result for the key and persists the cache.
+func (c *callbackCache) put(key string, result *TestResult) {
+	if c == nil || result == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(key, *result)
+	if err := c.save(); err != nil {
+		log.Printf("Failed to persist callback cache: %v", err)
+	}
+}
+
+// add inserts the entry at the front of the cache and evicts the entries beyond
+// its size. The caller must hold the mutex.
+func (c *callbackCache) add(key string, result TestResult) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*callbackCacheEntry).Result = result
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&callbackCacheEntry{Key: key, Result: result})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*callbackCacheEntry).Key)
+	}
+}
+
+// load fills the cache from its file. A missing file leaves the cache empty.
+func (c *callbackCache) load() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries []callbackCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", c.path, err)
+	}
+	// The file lists the most recently used entry first
+	for i := len(entries) - 1; i >= 0; i-- {
+		c.add(entries[i].Key, entries[i].Result)
+	}
+	return nil
+}
+
+// save writes the cache to its file, the most recently used entry first. The
+// caller must hold the mutex.
+func (c *callbackCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	entries := make([]callbackCacheEntry, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, *element.Value.(*callbackCacheEntry))
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCallbackCacheRerunsTestAfterDependencyChanges(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sourceCode := "def add(a, b):\n    return a + b\n"
+	testCode := "from helpers import pair\nfrom calc import add\n\ndef test_add(numbers):\n    assert add(*pair()) == 3\n"
+	testPath := filepath.Join(dir, "tests", "test_calc.py")
+	writeFile(t, filepath.Join(dir, "calc.py"), sourceCode)
+	writeFile(t, filepath.Join(dir, "tests", "helpers.py"), "def pair():\n    return (1, 2)\n")
+	writeFile(t, filepath.Join(dir, "conftest.py"), "import pytest\n")
+
+	runs := 0
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.CallbackCacheSize = 10
+		config.Callback = func(sourceCode, testCode, testPath string) (float64, string, error) {
+			runs++
+			return 1, "ok", nil
+		}
+	})
+	run := func() {
+		t.Helper()
+		if _, err := dw.runCallback(sourceCode, testCode, testPath); err != nil {
+			t.Fatalf("runCallback: %v", err)
+		}
+	}
+
+	run()
+	run()
+	if runs != 1 {
+		t.Fatalf("callback ran %d times for an unchanged test, want 1", runs)
+	}
+
+	writeFile(t, filepath.Join(dir, "tests", "helpers.py"), "def pair():\n    return (2, 1)\n")
+	run()
+	if runs != 2 {
+		t.Errorf("callback ran %d times after the imported helper changed, want 2", runs)
+	}
+
+	writeFile(t, filepath.Join(dir, "conftest.py"), "import pytest\n\n@pytest.fixture\ndef numbers():\n    return (1, 2)\n")
+	run()
+	if runs != 3 {
+		t.Errorf("callback ran %d times after conftest.py changed, want 3", runs)
+	}
+}
+
+func TestTestDependenciesFindsImportedModulesAndConftest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "pkg", "__init__.py"), "")
+	writeFile(t, filepath.Join(dir, "pkg", "calc.py"), "def add(a, b):\n    return a + b\n")
+	writeFile(t, filepath.Join(dir, "conftest.py"), "")
+
+	got := testDependencies("import os\nfrom pkg.calc import add\n", filepath.Join(dir, "test_calc.py"))
+	want := map[string]bool{
+		filepath.Join(dir, "conftest.py"):        true,
+		filepath.Join(dir, "pkg", "__init__.py"): true,
+		filepath.Join(dir, "pkg", "calc.py"):     true,
+	}
+	for _, path := range got {
+		if !want[path] {
+			t.Errorf("unexpected dependency %s", path)
+		}
+		delete(want, path)
+	}
+	for path := range want {
+		t.Errorf("missing dependency %s", path)
+	}
+
+	if got := testDependencies("import calc\n", filepath.Join(dir, "calc_test.go")); got != nil {
+		t.Errorf("dependencies of a Go test = %v, want none", got)
+	}
+}
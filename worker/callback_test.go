@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeCoverage is a stand-in for the coverage command: run writes to both of
+// its outputs, report prints a total and exits with FAKE_COVERAGE_REPORT_EXIT.
+// The arguments of every call are appended to FAKE_COVERAGE_LOG if it is set.
+const fakeCoverage = `#!/bin/sh
+if [ -n "$FAKE_COVERAGE_LOG" ]; then
+	echo "$@" >> "$FAKE_COVERAGE_LOG"
+fi
+case "$1" in
+run)
+	echo "test output"
+	echo "test warning" >&2
+	;;
+report)
+	echo "TOTAL 10 2 80%"
+	exit "${FAKE_COVERAGE_REPORT_EXIT:-0}"
+	;;
+esac
+`
+
+func installFakeCoverage(t *testing.T, reportExit string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake coverage command is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "coverage"), []byte(fakeCoverage), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_COVERAGE_REPORT_EXIT", reportExit)
+}
+
+func TestPyStructuredTestCallBackSeparatesOutputs(t *testing.T) {
+	installFakeCoverage(t, "0")
+	testPath := filepath.Join(t.TempDir(), "test_x.py")
+
+	result, err := PyStructuredTestCallBack("", "def test_x():\n    pass\n", testPath)
+	if err != nil {
+		t.Fatalf("PyStructuredTestCallBack: %v", err)
+	}
+	if got := strings.TrimSpace(result.Stdout); got != "test output" {
+		t.Errorf("Stdout = %q, want %q", got, "test output")
+	}
+	if got := strings.TrimSpace(result.Stderr); got != "test warning" {
+		t.Errorf("Stderr = %q, want %q", got, "test warning")
+	}
+	if got := strings.TrimSpace(result.CoverageReport); got != "TOTAL 10 2 80%" {
+		t.Errorf("CoverageReport = %q, want %q", got, "TOTAL 10 2 80%")
+	}
+	if !strings.Contains(result.Report, "TOTAL 10 2 80%") {
+		t.Errorf("Report = %q, want it to include the coverage report", result.Report)
+	}
+	if result.Coverage != 0.8 {
+		t.Errorf("Coverage = %v, want the total of the report", result.Coverage)
+	}
+}
+
+func TestReportedCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		want   float64
+		ok     bool
+	}{
+		{"total", "Name Stmts Miss Cover\n---\ncalc.py 10 2 80%\nutil.py 10 10 0%\n---\nTOTAL 20 12 40%\n", 0.4, true},
+		{"branches", "Name Stmts Miss Branch BrPart Cover\ncalc.py 10 1 4 1 86%\nTOTAL 10 1 4 1 86%\n", 0.86, true},
+		{"single file without total", "Name Stmts Miss Cover\ncalc.py 8 2 75%\n", 0.75, true},
+		{"precision", "TOTAL 3 1 66.67%\n", 0.6667, true},
+		{"no data", "No data to report.\n", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := reportedCoverage(tt.report)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("reportedCoverage() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPyStructuredTestCallBackKeepsOutputWhenReportFails(t *testing.T) {
+	installFakeCoverage(t, "1")
+	testPath := filepath.Join(t.TempDir(), "test_x.py")
+
+	result, err := PyStructuredTestCallBack("", "def test_x():\n    pass\n", testPath)
+	if err == nil {
+		t.Fatal("PyStructuredTestCallBack succeeded, want the coverage report error")
+	}
+	if result == nil {
+		t.Fatal("result = nil, want the output of the test run")
+	}
+	if got := strings.TrimSpace(result.Stdout); got != "test output" {
+		t.Errorf("Stdout = %q, want %q", got, "test output")
+	}
+	if got := strings.TrimSpace(result.Stderr); got != "test warning" {
+		t.Errorf("Stderr = %q, want %q", got, "test warning")
+	}
+	if got := strings.TrimSpace(result.CoverageReport); got != "TOTAL 10 2 80%" {
+		t.Errorf("CoverageReport = %q, want %q", got, "TOTAL 10 2 80%")
+	}
+}
+
+func TestBundledTestCallbackIsStructured(t *testing.T) {
+	callback, ok := BundledTestCallback("python")
+	if !ok {
+		t.Fatal("no bundled callback for python")
+	}
+	if reflect.ValueOf(callback).Pointer() != reflect.ValueOf(PyStructuredTestCallBack).Pointer() {
+		t.Error("bundled python callback is not PyStructuredTestCallBack")
+	}
+	if _, ok := BundledTestCallback("java"); ok {
+		t.Error("bundled callback for java, want none")
+	}
+}
+
+func TestPyStructuredTestCallBackMeasuresModuleUnderTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceFile string
+		testCode   string
+		want       string
+	}{
+		{"module", "calc.py", "from helpers import make\nfrom calc import add\n", "run --source=calc test_calc.py"},
+		{"module of a package", "pkg/calc.py", "import helpers\nfrom pkg.calc import add\n", "run --source=pkg.calc test_calc.py"},
+		{"helper only", "calc.py", "from helpers import make\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installFakeCoverage(t, "0")
+			log := filepath.Join(t.TempDir(), "coverage.log")
+			t.Setenv("FAKE_COVERAGE_LOG", log)
+
+			project := t.TempDir()
+			sourceCode := "def add(a, b):\n    return a + b\n"
+			for path, code := range map[string]string{tt.sourceFile: sourceCode, "helpers.py": "def make():\n    return 1\n"} {
+				path = filepath.Join(project, filepath.FromSlash(path))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, path, code)
+			}
+			testPath := filepath.Join(project, "tests", "test_calc.py")
+			if err := os.MkdirAll(filepath.Dir(testPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := PyStructuredTestCallBack(sourceCode, tt.testCode+"\ndef test_add():\n    assert add(1, 2) == 3\n", testPath)
+			if err != nil {
+				t.Fatalf("PyStructuredTestCallBack: %v", err)
+			}
+			calls, _ := os.ReadFile(log)
+			if tt.want == "" {
+				if len(calls) > 0 {
+					t.Errorf("coverage called with %q, want the test not to run", calls)
+				}
+				if !strings.Contains(result.Report, "does not import the module under test") {
+					t.Errorf("Report = %q, want it to ask for the import", result.Report)
+				}
+				return
+			}
+			if got := strings.SplitN(string(calls), "\n", 2)[0]; got != tt.want {
+				t.Errorf("coverage called with %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"math"
+
+	"github.com/Marksagittarius/pinguis/postprocessor"
+)
+
+// ConfidenceFunc combines the final coverage, the pass rate and the assertion
+// density of a generated test into a single quality score between 0 and 1.
+type ConfidenceFunc func(coverage, passRate, assertionDensity float64) float64
+
+// targetAssertionDensity is the number of assertions per test function at which
+// a test is considered assertion-rich by DefaultConfidence.
+const targetAssertionDensity = 3.0
+
+// DefaultConfidence is the ConfidenceFunc used when none is configured.
+// It computes
+//
+//	0.5*coverage + 0.3*passRate + 0.2*min(assertionDensity/3, 1)
+//
+// so coverage dominates the score, failing tests pull it down, and tests
+// averaging three or more assertions per test function get the full
+// assertion share. The result is clamped to [0, 1].
+func DefaultConfidence(coverage, passRate, assertionDensity float64) float64 {
+	assertionScore := math.Min(assertionDensity/targetAssertionDensity, 1)
+	score := 0.5*coverage + 0.3*passRate + 0.2*assertionScore
+	return math.Max(0, math.Min(1, score))
+}
+
+// assertionDensity returns the average number of assertions per test function.
+// Code without recognizable test functions is treated as a single test.
+func assertionDensity(testCode, codeType string) float64 {
+	tests := postprocessor.CountTestFunctions(testCode, codeType)
+	if tests == 0 {
+		tests = 1
+	}
+	return float64(postprocessor.CountAssertions(testCode, codeType)) / float64(tests)
+}
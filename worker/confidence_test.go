@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultConfidence(t *testing.T) {
+	tests := []struct {
+		name                        string
+		coverage, passRate, density float64
+		min, max                    float64
+	}{
+		{"full coverage, passing, assertion-rich", 1, 1, 4, 0.99, 1},
+		{"high coverage, passing, assertion-rich", 0.95, 1, 3, 0.95, 1},
+		{"no coverage, failing, no assertions", 0, 0, 0, 0, 0.01},
+		{"low coverage, mostly failing, few assertions", 0.1, 0.1, 0.3, 0, 0.15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultConfidence(tt.coverage, tt.passRate, tt.density)
+			if got < tt.min || got > tt.max {
+				t.Errorf("DefaultConfidence(%v, %v, %v) = %v, want between %v and %v",
+					tt.coverage, tt.passRate, tt.density, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// runConfidenceTask runs a task with the configured worker and returns its
+// report entry.
+func runConfidenceTask(t *testing.T, m *fakeModel, sourcePath string, configure func(*DeepWorkerConfig)) TaskReport {
+	t.Helper()
+	dw := newTestWorker(m, configure)
+	dw.Run()
+	if err := dw.SubmitTask("", sourcePath); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-dw.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not finish")
+	}
+	dw.Shutdown()
+	return dw.Report().Tasks[0]
+}
+
+func TestConfidenceFuncScoresReport(t *testing.T) {
+	var got [3]float64
+	task := runConfidenceTask(t, newFakeModel(pythonTestResponse), "a.py", func(config *DeepWorkerConfig) {
+		config.ConfidenceFunc = func(coverage, passRate, assertionDensity float64) float64 {
+			got = [3]float64{coverage, passRate, assertionDensity}
+			return 0.42
+		}
+	})
+
+	if task.Confidence != 0.42 {
+		t.Errorf("Confidence = %v, want the score of the ConfidenceFunc", task.Confidence)
+	}
+	if want := [3]float64{1, 1, 1}; got != want {
+		t.Errorf("ConfidenceFunc called with %v, want coverage, pass rate and assertion density %v", got, want)
+	}
+}
+
+func TestConfidenceOfBundledCallbackReflectsCoverage(t *testing.T) {
+	installFakeCoverage(t, "0")
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+
+	task := runConfidenceTask(t, newFakeModel(pythonTestResponse), sourcePath, func(config *DeepWorkerConfig) {
+		config.Callback = nil
+		config.StructuredCallback = PyStructuredTestCallBack
+	})
+
+	// The fake coverage report totals 80%, with one assertion per test
+	if want := DefaultConfidence(0.8, 1, 1); task.BestCoverage != 0.8 || task.Confidence != want {
+		t.Errorf("coverage %v and confidence %v, want 0.8 and %v", task.BestCoverage, task.Confidence, want)
+	}
+}
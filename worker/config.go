@@ -0,0 +1,99 @@
+package worker
+
+import "errors"
+
+// Defaults NewDeepWorkerChecked puts in place of non-positive counts.
+const (
+	DefaultWorkerCount   = 1
+	DefaultMaxIterations = 3
+)
+
+// Errors returned by DeepWorkerConfig.Validate for configurations the worker
+// cannot run with.
+var (
+	ErrNoModel           = errors.New("no model configured")
+	ErrNoCallback        = errors.New("no test callback configured")
+	ErrNoPromptGenerator = errors.New("no prompt generator configured")
+	ErrFlakinessCallback = errors.New("flakiness check requires a structured test callback")
+)
+
+// Validate checks that the config has everything a DeepWorker needs to process
+// whole-file tasks, a model, a test callback (plain or structured) and a prompt
+// generator, as well as a structured callback if FlakinessCheck is set, and
+// returns the joined errors of what is missing. It leaves the config unchanged;
+// NewDeepWorkerChecked normalizes a copy of it.
+func (c *DeepWorkerConfig) Validate() error {
+	var errs []error
+	if c.Model == nil {
+		errs = append(errs, ErrNoModel)
+	}
+	if c.Callback == nil && c.StructuredCallback == nil {
+		errs = append(errs, ErrNoCallback)
+	}
+	if c.PromptGenerator == nil {
+		errs = append(errs, ErrNoPromptGenerator)
+	}
+	if c.FlakinessCheck > 0 && c.StructuredCallback == nil {
+		errs = append(errs, ErrFlakinessCallback)
+	}
+	return errors.Join(errs...)
+}
+
+// normalized returns a copy of the config in which a non-positive WorkerCount or
+// MaxIterations is replaced by its default, coverage thresholds are clamped to
+// [0, 1], and negative limits and sizes are reset to 0, their unset value.
+func (c *DeepWorkerConfig) normalized() *DeepWorkerConfig {
+	n := *c
+	if n.WorkerCount <= 0 {
+		n.WorkerCount = DefaultWorkerCount
+	}
+	if n.MaxIterations <= 0 {
+		n.MaxIterations = DefaultMaxIterations
+	}
+
+	n.CoverageThreshold = clampUnit(n.CoverageThreshold)
+	if n.CoverageThresholds != nil {
+		thresholds := make(map[string]float64, len(n.CoverageThresholds))
+		for codeType, threshold := range n.CoverageThresholds {
+			thresholds[codeType] = clampUnit(threshold)
+		}
+		n.CoverageThresholds = thresholds
+	}
+
+	for _, value := range []*int{
+		&n.MaxOutputTokens,
+		&n.MaxExampleSize,
+		&n.MaxTestsPerFunction,
+		&n.MinTestsPerFunction,
+		&n.MaxTotalRetries,
+		&n.CallbackCacheSize,
+		&n.FlakinessCheck,
+		&n.MaxPathsPerFunction,
+	} {
+		if *value < 0 {
+			*value = 0
+		}
+	}
+	if n.MinCoverageDelta < 0 {
+		n.MinCoverageDelta = 0
+	}
+	if n.TaskTimeout < 0 {
+		n.TaskTimeout = 0
+	}
+	return &n
+}
+
+// NewDeepWorkerChecked validates the config like Validate, then creates the
+// worker with a normalized copy of it (see normalized). It returns the
+// validation errors instead of a worker if the config is incomplete.
+func NewDeepWorkerChecked(config *DeepWorkerConfig) (*DeepWorker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewDeepWorker(config.normalized()), nil
+}
+
+// clampUnit clamps the value to [0, 1].
+func clampUnit(value float64) float64 {
+	return min(max(value, 0), 1)
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateReportsMissingParts(t *testing.T) {
+	cases := []struct {
+		name   string
+		config DeepWorkerConfig
+		want   []error
+	}{
+		{"empty", DeepWorkerConfig{}, []error{ErrNoModel, ErrNoCallback, ErrNoPromptGenerator}},
+		{"no model", DeepWorkerConfig{Callback: fullCoverage, PromptGenerator: func(*TestTask) string { return "" }}, []error{ErrNoModel}},
+		{"flakiness without structured callback", DeepWorkerConfig{
+			Model:           newFakeModel(pythonTestResponse),
+			Callback:        fullCoverage,
+			PromptGenerator: func(*TestTask) string { return "" },
+			FlakinessCheck:  2,
+		}, []error{ErrFlakinessCallback}},
+	}
+	for _, c := range cases {
+		err := c.config.Validate()
+		for _, want := range c.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: Validate() = %v, want %v among the errors", c.name, err, want)
+			}
+		}
+		if _, checkedErr := NewDeepWorkerChecked(&c.config); checkedErr == nil {
+			t.Errorf("%s: NewDeepWorkerChecked created a worker", c.name)
+		}
+	}
+}
+
+func TestValidateLeavesConfigUnchanged(t *testing.T) {
+	config := &DeepWorkerConfig{
+		Model:             newFakeModel(pythonTestResponse),
+		Callback:          fullCoverage,
+		PromptGenerator:   func(*TestTask) string { return "" },
+		WorkerCount:       -1,
+		CoverageThreshold: 1.5,
+		MaxOutputTokens:   -10,
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if config.WorkerCount != -1 || config.CoverageThreshold != 1.5 || config.MaxOutputTokens != -10 {
+		t.Errorf("Validate changed the config to %+v", config)
+	}
+}
+
+func TestNewDeepWorkerCheckedNormalizesCopy(t *testing.T) {
+	config := &DeepWorkerConfig{
+		Model:              newFakeModel(pythonTestResponse),
+		Callback:           fullCoverage,
+		PromptGenerator:    func(*TestTask) string { return "" },
+		MaxIterations:      0,
+		CoverageThreshold:  -0.5,
+		CoverageThresholds: map[string]float64{"go": 2},
+		MaxTotalRetries:    -1,
+	}
+	normalized := config.normalized()
+	if normalized.WorkerCount != DefaultWorkerCount || normalized.MaxIterations != DefaultMaxIterations {
+		t.Errorf("counts = %d workers, %d iterations, want the defaults", normalized.WorkerCount, normalized.MaxIterations)
+	}
+	if normalized.CoverageThreshold != 0 || !reflect.DeepEqual(normalized.CoverageThresholds, map[string]float64{"go": 1}) {
+		t.Errorf("thresholds = %v and %v, want them clamped", normalized.CoverageThreshold, normalized.CoverageThresholds)
+	}
+	if normalized.MaxTotalRetries != 0 {
+		t.Errorf("MaxTotalRetries = %d, want 0", normalized.MaxTotalRetries)
+	}
+
+	dw, err := NewDeepWorkerChecked(config)
+	if err != nil {
+		t.Fatalf("NewDeepWorkerChecked: %v", err)
+	}
+	if dw.maxIterations != DefaultMaxIterations {
+		t.Errorf("worker runs %d iterations, want %d", dw.maxIterations, DefaultMaxIterations)
+	}
+	if config.MaxIterations != 0 || config.CoverageThresholds["go"] != 2 {
+		t.Errorf("NewDeepWorkerChecked changed the caller's config to %+v", config)
+	}
+}
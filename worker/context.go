@@ -0,0 +1,217 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/scripts/java"
+	"github.com/Marksagittarius/pinguis/scripts/kotlin"
+	"github.com/Marksagittarius/pinguis/scripts/python"
+	"github.com/Marksagittarius/pinguis/types"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// parseContextFile parses a source file into its structural representation
+// using the parser of the file's language. Python and Java files with syntax
+// errors are summarized with the declarations that could be parsed, logging a
+// warning.
+func parseContextFile(filePath string) (*types.File, error) {
+	switch getCodeType(filePath) {
+	case "python":
+		file, err := python.GetFileMetaData(filePath)
+		return file, acceptIncompleteParse(filePath, err)
+	case "java":
+		file, err := java.NewTreeSitterJavaParser().ParseFile(filePath)
+		return file, acceptIncompleteParse(filePath, err)
+	case "kotlin":
+		return kotlin.NewTreeSitterKotlinParser().ParseFile(filePath)
+	default:
+		return nil, fmt.Errorf("no parser available for context file %s", filePath)
+	}
+}
+
+// acceptIncompleteParse logs a warning and returns nil if the context file was
+// parsed only partially, and returns err otherwise.
+func acceptIncompleteParse(filePath string, err error) error {
+	if errors.Is(err, types.ErrIncompleteParse) {
+		log.Printf("Warning: the summary of context file %s may be incomplete: %v", filePath, err)
+		return nil
+	}
+	return err
+}
+
+// summarizeContextFiles parses each context file and renders the structure of all
+// of them into a single prompt section.
+func summarizeContextFiles(filePaths []string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("The following files are relevant context for the code under test:\n")
+
+	for _, filePath := range filePaths {
+		file, err := parseContextFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize context file %s: %w", filePath, err)
+		}
+		sb.WriteString("\n")
+		sb.WriteString(summarizeFileStructure(filepath.ToSlash(filePath), file))
+	}
+
+	return sb.String(), nil
+}
+
+// summarizeFileStructure renders the classes, interfaces and functions of a file
+// as a list of signatures.
+func summarizeFileStructure(filePath string, file *types.File) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("File '%s'", filePath))
+	if file.Module != "" {
+		sb.WriteString(fmt.Sprintf(" (module '%s')", file.Module))
+	}
+	sb.WriteString(":\n")
+
+	for _, class := range file.Classes {
+		sb.WriteString(fmt.Sprintf("- class %s\n", class.Name))
+		for _, field := range class.Fields {
+			sb.WriteString(fmt.Sprintf("  - field %s: %s\n", field.Name, field.Type))
+		}
+		for _, constructor := range class.Constructors {
+			sb.WriteString("  - constructor " + functionSignature(constructor) + "\n")
+		}
+		for _, method := range class.Methods {
+			sb.WriteString("  - " + methodKind(method) + " " + functionSignature(method.Func) + "\n")
+		}
+	}
+
+	for _, iface := range file.Interfaces {
+		sb.WriteString(fmt.Sprintf("- interface %s\n", iface.Name))
+		for _, method := range iface.Methods {
+			sb.WriteString("  - method " + functionSignature(method) + "\n")
+		}
+	}
+
+	for _, function := range file.Functions {
+		sb.WriteString("- function " + functionSignature(function) + "\n")
+	}
+
+	return sb.String()
+}
+
+// methodKind describes a method as static or abstract when it is, since static
+// methods are called on the class and abstract methods have no body to test.
+func methodKind(method types.Method) string {
+	switch {
+	case method.IsStatic:
+		return "static method"
+	case method.IsAbstract:
+		return "abstract method"
+	default:
+		return "method"
+	}
+}
+
+// functionSignature renders a function as name(param: type, ...) -> returns.
+func functionSignature(function types.Function) string {
+	params := make([]string, len(function.Parameters))
+	for i, param := range function.Parameters {
+		if param.Type == "" {
+			params[i] = param.Name
+		} else {
+			params[i] = fmt.Sprintf("%s: %s", param.Name, param.Type)
+		}
+	}
+
+	signature := fmt.Sprintf("%s(%s)", function.Name, strings.Join(params, ", "))
+	if len(function.ReturnTypes) > 0 {
+		signature += " -> " + strings.Join(function.ReturnTypes, ", ")
+	}
+	return signature
+}
+
+// ContextLevel controls how much of the source file under test is included in
+// the prompt of a symbolic test.
+type ContextLevel string
+
+const (
+	// FullContext includes the whole source file (the default).
+	FullContext ContextLevel = "full"
+	// SignaturesPlusTarget includes the whole file with the bodies of every
+	// function but the target replaced by "...", keeping imports, classes and
+	// signatures as context at a fraction of the size.
+	SignaturesPlusTarget ContextLevel = "signatures"
+	// TargetOnly includes only the target function.
+	TargetOnly ContextLevel = "target"
+)
+
+// symPromptCode renders the code of the function's source file at the given
+// context level.
+func symPromptCode(code string, fn symFunction, level ContextLevel) string {
+	switch level {
+	case TargetOnly:
+		// Dedent methods and nested functions to the level of their def line
+		start := fn.Node.StartByte()
+		indent := code[strings.LastIndex(code[:start], "\n")+1 : start]
+		lines := strings.Split(code[start:fn.Node.EndByte()], "\n")
+		for i := range lines {
+			lines[i] = strings.TrimPrefix(lines[i], indent)
+		}
+		return strings.Join(lines, "\n")
+	case SignaturesPlusTarget:
+		root := fn.Node
+		for root.Parent() != nil {
+			root = root.Parent()
+		}
+		return elideFunctionBodies(root, fn.Node, code)
+	default:
+		return code
+	}
+}
+
+// elidedBodies maps the node kinds of function definitions to the text their
+// elided bodies are replaced with. Java and JavaScript bodies include their
+// braces. JavaScript function expressions and arrow functions are kept, they
+// are mostly callbacks that belong to the function they are passed in.
+var elidedBodies = map[string]string{
+	"function_definition":            "...",
+	"method_declaration":             "{ ... }",
+	"constructor_declaration":        "{ ... }",
+	"function_declaration":           "{ ... }",
+	"generator_function_declaration": "{ ... }",
+	"method_definition":              "{ ... }",
+}
+
+// elideFunctionBodies returns the code below node with the bodies of every
+// function definition (Python), method and constructor (Java) and function
+// declaration and method (JavaScript) replaced by "...", except for the target
+// function and the functions enclosing it.
+func elideFunctionBodies(node *tree_sitter.Node, target *tree_sitter.Node, code string) string {
+	var sb strings.Builder
+	last := node.StartByte()
+
+	var walk func(n *tree_sitter.Node)
+	walk = func(n *tree_sitter.Node) {
+		if elided, ok := elidedBodies[n.Kind()]; ok && !encloses(n, target) {
+			if body := n.ChildByFieldName("body"); body != nil {
+				sb.WriteString(code[last:body.StartByte()])
+				sb.WriteString(elided)
+				last = body.EndByte()
+				return
+			}
+		}
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			walk(n.NamedChild(i))
+		}
+	}
+	walk(node)
+
+	sb.WriteString(code[last:node.EndByte()])
+	return sb.String()
+}
+
+// encloses reports whether the node spans the target node.
+func encloses(node *tree_sitter.Node, target *tree_sitter.Node) bool {
+	return node.StartByte() <= target.StartByte() && target.EndByte() <= node.EndByte()
+}
@@ -0,0 +1,75 @@
+package worker
+
+import "testing"
+
+const cartSource = `import math
+
+
+def rounded(x):
+    return math.floor(x + 0.5)
+
+
+class Cart:
+    def __init__(self):
+        self.prices = []
+
+    def total(self):
+        if not self.prices:
+            return 0
+        return rounded(sum(self.prices))
+`
+
+func TestSymPromptCodeContextLevels(t *testing.T) {
+	fn := parseSymFunction(t, cartSource, "python", "total")
+
+	tests := []struct {
+		level ContextLevel
+		want  string
+	}{
+		{FullContext, cartSource},
+		{
+			SignaturesPlusTarget,
+			`import math
+
+
+def rounded(x):
+    ...
+
+
+class Cart:
+    def __init__(self):
+        ...
+
+    def total(self):
+        if not self.prices:
+            return 0
+        return rounded(sum(self.prices))
+`,
+		},
+		{
+			TargetOnly,
+			"def total(self):\n    if not self.prices:\n        return 0\n    return rounded(sum(self.prices))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.level), func(t *testing.T) {
+			if got := symPromptCode(cartSource, fn, tt.level); got != tt.want {
+				t.Errorf("symPromptCode() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSymPromptCodeKeepsSignaturesOfJavaSiblings(t *testing.T) {
+	code := "class Cart {\n    Cart() {\n        prices = new int[0];\n    }\n\n" +
+		"    public int size() {\n        return prices.length;\n    }\n\n" +
+		"    public boolean isEmpty() {\n        return size() == 0;\n    }\n}\n"
+	fn := parseSymFunction(t, code, "java", "isEmpty")
+
+	want := "class Cart {\n    Cart() { ... }\n\n" +
+		"    public int size() { ... }\n\n" +
+		"    public boolean isEmpty() {\n        return size() == 0;\n    }\n}\n"
+	if got := symPromptCode(code, fn, SignaturesPlusTarget); got != want {
+		t.Errorf("symPromptCode() =\n%s\nwant:\n%s", got, want)
+	}
+}
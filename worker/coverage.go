@@ -0,0 +1,320 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+)
+
+// CoverageSummary is the coverage of the project measured by running every test
+// generated during a run together, so code covered by the tests of different
+// tasks counts once.
+//
+// Fields:
+// - Files: The coverage of every measured source file, sorted by path.
+// - Statements: The number of statements of all measured files.
+// - Covered: The number of those statements executed by at least one test.
+// - Coverage: The fraction of the statements covered.
+type CoverageSummary struct {
+	Files      []FileCoverage `json:"files"`
+	Statements int            `json:"statements"`
+	Covered    int            `json:"covered"`
+	Coverage   float64        `json:"coverage"`
+}
+
+// FileCoverage is the part of a CoverageSummary for a single source file.
+type FileCoverage struct {
+	Path       string  `json:"path"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Coverage   float64 `json:"coverage"`
+}
+
+// CombineCoverage runs a final coverage pass over the tests written by the
+// tasks of the run that neither failed nor timed out, and records the resulting
+// project-level summary in the run report. Python tests run under coverage.py
+// in parallel mode and their data files are combined; Go test packages are run
+// with a coverage profile each and the profiles are merged. Tests of other
+// languages are left out. A test that fails still contributes the coverage it reached,
+// so the pass only fails if the coverage tools cannot be run.
+func (dw *DeepWorker) CombineCoverage() (*CoverageSummary, error) {
+	testPaths := make(map[string][]string)
+	sourcePaths := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, task := range dw.report.snapshot().Tasks {
+		if !taskSucceeded(task.Status) || task.TestPath == "" || seen[task.TestPath] {
+			continue
+		}
+		if _, err := os.Stat(task.TestPath); err != nil {
+			continue
+		}
+		seen[task.TestPath] = true
+		sourcePaths[task.TestPath] = task.SourcePath
+		testPaths[task.CodeType] = append(testPaths[task.CodeType], task.TestPath)
+	}
+
+	statements := make(map[string]map[string]bool)
+	for codeType, paths := range testPaths {
+		var err error
+		switch codeType {
+		case "python":
+			err = pythonCombinedCoverage(paths, sourcePaths, statements)
+		case "go":
+			err = goCombinedCoverage(paths, statements)
+		default:
+			log.Printf("Combined coverage is not supported for %s, skipping %d test files", codeType, len(paths))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("combined %s coverage failed: %w", codeType, err)
+		}
+	}
+
+	summary := summarizeCoverage(statements)
+	dw.report.setCoverage(summary)
+	return summary, nil
+}
+
+// summarizeCoverage builds the summary from the statements of every file,
+// keyed by file and then by statement, each marked with whether it ran.
+func summarizeCoverage(statements map[string]map[string]bool) *CoverageSummary {
+	summary := &CoverageSummary{Files: []FileCoverage{}}
+	for path, fileStatements := range statements {
+		file := FileCoverage{Path: path, Statements: len(fileStatements)}
+		for _, covered := range fileStatements {
+			if covered {
+				file.Covered++
+			}
+		}
+		file.Coverage = coverageRatio(file.Covered, file.Statements)
+		summary.Files = append(summary.Files, file)
+		summary.Statements += file.Statements
+		summary.Covered += file.Covered
+	}
+	sort.Slice(summary.Files, func(i, j int) bool {
+		return summary.Files[i].Path < summary.Files[j].Path
+	})
+	summary.Coverage = coverageRatio(summary.Covered, summary.Statements)
+	return summary
+}
+
+func coverageRatio(covered, statements int) float64 {
+	if statements == 0 {
+		return 0
+	}
+	return float64(covered) / float64(statements)
+}
+
+// reportedCoverage returns the total coverage printed by `coverage report` as a
+// fraction, e.g. 0.8 for the "TOTAL 10 2 80%" line. A report of a single file
+// may lack the TOTAL line, so the last line ending in a percentage counts
+// otherwise. It returns false if the report holds no percentage.
+func reportedCoverage(report string) (float64, bool) {
+	coverage, found := 0.0, false
+	for _, line := range strings.Split(report, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasSuffix(fields[len(fields)-1], "%") {
+			continue
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(fields[len(fields)-1], "%"), 64)
+		if err != nil {
+			continue
+		}
+		coverage, found = percent/100, true
+		if fields[0] == "TOTAL" {
+			break
+		}
+	}
+	return coverage, found
+}
+
+// addStatement records a statement of the file, covered if any run executed it.
+func addStatement(statements map[string]map[string]bool, path, statement string, covered bool) {
+	if statements[path] == nil {
+		statements[path] = make(map[string]bool)
+	}
+	statements[path][statement] = statements[path][statement] || covered
+}
+
+// pythonCoverageJSON is the part of the report of "coverage json" the summary
+// is built from.
+type pythonCoverageJSON struct {
+	Files map[string]struct {
+		ExecutedLines []int `json:"executed_lines"`
+		MissingLines  []int `json:"missing_lines"`
+	} `json:"files"`
+}
+
+// pythonCombinedCoverage runs every Python test under coverage.py, combines the
+// data files and adds the statements of the measured source files. Each test
+// measures the module of its source file it imports, as PyStructuredTestCallBack
+// does, so the source is measured wherever the tests are written; a test that
+// does not import its source file measures its own directory. The test files
+// themselves and the tests kept for every iteration are not counted.
+func pythonCombinedCoverage(testPaths []string, sourcePaths map[string]string, statements map[string]map[string]bool) error {
+	dataDir, err := fileio.MkdirTemp("pinguis-combined-coverage-*")
+	if err != nil {
+		return fmt.Errorf("failed to create coverage data directory: %v", err)
+	}
+	defer fileio.RemoveTemp(dataDir)
+	coverageEnv := append(os.Environ(), "COVERAGE_FILE="+filepath.Join(dataDir, ".coverage"))
+
+	isTest := make(map[string]bool)
+	for _, testPath := range testPaths {
+		absPath, err := filepath.Abs(testPath)
+		if err != nil {
+			return err
+		}
+		isTest[absPath] = true
+
+		cmd := exec.Command("coverage", "run", "--parallel-mode", "--source="+pythonCoverageSource(absPath, sourcePaths[testPath]), filepath.Base(absPath))
+		cmd.Dir = filepath.Dir(absPath)
+		cmd.Env = coverageEnv
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if _, isExit := err.(*exec.ExitError); !isExit {
+				return fmt.Errorf("coverage run failed: %v", err)
+			}
+			log.Printf("Test %s failed during the combined coverage run: %s", testPath, strings.TrimSpace(string(output)))
+		}
+	}
+
+	for _, args := range [][]string{
+		{"combine", dataDir},
+		{"json", "-o", filepath.Join(dataDir, "coverage.json")},
+	} {
+		cmd := exec.Command("coverage", args...)
+		cmd.Dir = dataDir
+		cmd.Env = coverageEnv
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("coverage %s failed: %v: %s", args[0], err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dataDir, "coverage.json"))
+	if err != nil {
+		return err
+	}
+	var report pythonCoverageJSON
+	if err := json.Unmarshal(data, &report); err != nil {
+		return fmt.Errorf("failed to parse coverage report: %v", err)
+	}
+
+	for path, file := range report.Files {
+		// Files outside the working directory of "coverage json" are reported
+		// with their absolute path, which every measured file is
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dataDir, path)
+		}
+		if isTest[path] || isIterationArtifact(filepath.Base(path)) {
+			continue
+		}
+		for _, line := range file.ExecutedLines {
+			addStatement(statements, path, strconv.Itoa(line), true)
+		}
+		for _, line := range file.MissingLines {
+			addStatement(statements, path, strconv.Itoa(line), false)
+		}
+	}
+	return nil
+}
+
+// pythonCoverageSource returns what the combined run of the test measures: the
+// module of the source file the test imports, or else the test's directory.
+func pythonCoverageSource(testPath, sourcePath string) string {
+	testCode, testErr := os.ReadFile(testPath)
+	sourceCode, sourceErr := os.ReadFile(sourcePath)
+	if testErr == nil && sourceErr == nil {
+		if module, ok := findImportedSourceModule(string(testCode), filepath.Dir(testPath), string(sourceCode)); ok {
+			return module
+		}
+	}
+	return filepath.Dir(testPath)
+}
+
+// goCombinedCoverage runs the package of every Go test with a coverage profile
+// and adds the statements of the profiled files. Each package runs once, however
+// many of the tests belong to it.
+func goCombinedCoverage(testPaths []string, statements map[string]map[string]bool) error {
+	profileDir, err := fileio.MkdirTemp("pinguis-combined-coverage-*")
+	if err != nil {
+		return fmt.Errorf("failed to create coverage profile directory: %v", err)
+	}
+	defer fileio.RemoveTemp(profileDir)
+
+	packages := make(map[string]bool)
+	for _, testPath := range testPaths {
+		dir, err := filepath.Abs(filepath.Dir(testPath))
+		if err != nil {
+			return err
+		}
+		if packages[dir] {
+			continue
+		}
+		packages[dir] = true
+
+		profile := filepath.Join(profileDir, fmt.Sprintf("%d.out", len(packages)))
+		cmd := exec.Command("go", "test", "-covermode=set", "-coverprofile="+profile, ".")
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if _, isExit := err.(*exec.ExitError); !isExit {
+				return fmt.Errorf("go test failed: %v", err)
+			}
+			log.Printf("Tests of %s failed during the combined coverage run: %s", dir, strings.TrimSpace(string(output)))
+		}
+
+		if err := addGoProfile(profile, statements); err != nil {
+			log.Printf("No coverage profile for %s: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// addGoProfile adds the blocks of a Go coverage profile as statements. A block
+// line reads "file.go:startLine.startCol,endLine.endCol numStatements count";
+// every statement of a block is counted, keyed by the block and its index.
+func addGoProfile(profilePath string, statements map[string]map[string]bool) error {
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		colon := strings.LastIndex(fields[0], ":")
+		if colon < 0 {
+			continue
+		}
+		numStatements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+
+		path, block := fields[0][:colon], fields[0][colon+1:]
+		for i := 0; i < numStatements; i++ {
+			addStatement(statements, path, fmt.Sprintf("%s#%d", block, i), count > 0)
+		}
+	}
+	return scanner.Err()
+}
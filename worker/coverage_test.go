@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeCombinedCoverage is a stand-in for the coverage command recording the
+// arguments of every run to $FAKE_COVERAGE_LOG and writing an empty json report.
+const fakeCombinedCoverage = `#!/bin/sh
+case "$1" in
+run)
+	echo "$@" >> "$FAKE_COVERAGE_LOG"
+	;;
+json)
+	echo '{"files": {}}' > "$3"
+	;;
+esac
+`
+
+// recordTask records a finished task in the run report of the worker.
+func recordTask(dw *DeepWorker, sourcePath, testPath string) {
+	dw.report.record(TaskReport{SourcePath: sourcePath, TestPath: testPath, CodeType: "python", Status: "completed"})
+}
+
+func TestCombineCoverageMeasuresImportedModuleOfSeparateTestDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake coverage command is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "coverage"), []byte(fakeCombinedCoverage), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	log := filepath.Join(t.TempDir(), "runs.log")
+	t.Setenv("FAKE_COVERAGE_LOG", log)
+
+	project := t.TempDir()
+	sourcePath := filepath.Join(project, "calc.py")
+	testPath := filepath.Join(project, "tests", "test_calc.py")
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+	if err := os.MkdirAll(filepath.Dir(testPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, testPath, "from calc import add\n\nassert add(1, 2) == 3\n")
+
+	dw := newTestWorker(newFakeModel(pythonTestResponse), nil)
+	recordTask(dw, sourcePath, testPath)
+	if _, err := dw.CombineCoverage(); err != nil {
+		t.Fatalf("CombineCoverage: %v", err)
+	}
+
+	runs, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(runs), "--source=calc ") {
+		t.Errorf("coverage runs %q, want the test to measure the calc module", runs)
+	}
+}
+
+func TestCombineCoverageReflectsEveryModule(t *testing.T) {
+	if _, err := exec.LookPath("coverage"); err != nil {
+		t.Skip("coverage.py is not installed")
+	}
+
+	dir := t.TempDir()
+	modules := map[string]string{
+		"shapes.py": "def area(w, h):\n    return w * h\n\ndef unused():\n    return 0\n",
+		"words.py":  "def shout(s):\n    return s.upper()\n",
+	}
+	tests := map[string]string{
+		"test_shapes.py": "from shapes import area\n\nassert area(2, 3) == 6\n",
+		"test_words.py":  "from words import shout\n\nassert shout('a') == 'A'\n",
+	}
+	for name, code := range modules {
+		writeFile(t, filepath.Join(dir, name), code)
+	}
+	for name, code := range tests {
+		writeFile(t, filepath.Join(dir, name), code)
+	}
+
+	dw := newTestWorker(newFakeModel(pythonTestResponse), nil)
+	recordTask(dw, filepath.Join(dir, "shapes.py"), filepath.Join(dir, "test_shapes.py"))
+	recordTask(dw, filepath.Join(dir, "words.py"), filepath.Join(dir, "test_words.py"))
+	summary, err := dw.CombineCoverage()
+	if err != nil {
+		t.Fatalf("CombineCoverage: %v", err)
+	}
+
+	covered := make(map[string]FileCoverage)
+	for _, file := range summary.Files {
+		covered[filepath.Base(file.Path)] = file
+	}
+	if file := covered["shapes.py"]; file.Covered == 0 || file.Covered == file.Statements {
+		t.Errorf("shapes.py coverage = %+v, want it partly covered", file)
+	}
+	if file := covered["words.py"]; file.Statements == 0 || file.Covered != file.Statements {
+		t.Errorf("words.py coverage = %+v, want it fully covered", file)
+	}
+	for name := range tests {
+		if _, ok := covered[name]; ok {
+			t.Errorf("test file %s is counted in the summary", name)
+		}
+	}
+	if dw.Report().Coverage != summary {
+		t.Error("run report does not hold the combined coverage")
+	}
+}
+
+func TestCombineCoverageSkipsIterationArtifacts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake coverage command is a shell script")
+	}
+	project := t.TempDir()
+	sourcePath := filepath.Join(project, "calc.py")
+	testPath := filepath.Join(project, "calc_test.py")
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+	writeFile(t, testPath, "from calc import add\n\nassert add(1, 2) == 3\n")
+
+	// The report measures the whole test directory, artifact included
+	report := `{"files": {"` + sourcePath + `": {"executed_lines": [1, 2], "missing_lines": []}, "` +
+		filepath.Join(project, "calc_test_iter1.py") + `": {"executed_lines": [], "missing_lines": [1, 3]}}}`
+	bin := t.TempDir()
+	script := "#!/bin/sh\nif [ \"$1\" = json ]; then echo '" + report + "' > \"$3\"; fi\n"
+	if err := os.WriteFile(filepath.Join(bin, "coverage"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dw := newTestWorker(newFakeModel(pythonTestResponse), nil)
+	recordTask(dw, sourcePath, testPath)
+	summary, err := dw.CombineCoverage()
+	if err != nil {
+		t.Fatalf("CombineCoverage: %v", err)
+	}
+	if len(summary.Files) != 1 || summary.Files[0].Path != sourcePath || summary.Coverage != 1 {
+		t.Errorf("summary = %+v, want only the fully covered source", summary)
+	}
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// decoratorNotes explain what tests of a function must account for when it is
+// wrapped by a well-known Python decorator, keyed by the decorator's name
+// without its module or arguments. The notes are formatted with the name of the
+// decorated function.
+var decoratorNotes = map[string]string{
+	"lru_cache": cacheDecoratorNote,
+	"cache":     cacheDecoratorNote,
+	"cached_property": "It is computed once per instance and then cached: read %[1]s as an attribute, " +
+		"and use a new instance for every value a test expects instead of reading it again after a change.",
+	"property":     "It is a property: read %[1]s as an attribute of an instance instead of calling it.",
+	"staticmethod": "It is a static method: call %[1]s on the class, no instance is needed.",
+	"classmethod":  "It is a class method: call %[1]s on the class, it receives the class instead of an instance.",
+	"contextmanager": "It is a context manager: use %[1]s in a with statement and test the value it yields " +
+		"and what it does on leaving the block, also when the block raises.",
+}
+
+// cacheDecoratorNote is the note of the functools caching decorators. Calling a
+// cached function again returns the cached result, so tests repeating a call
+// test nothing and results cached by one test leak into the next.
+const cacheDecoratorNote = "Its results are cached by arguments: do not write tests that call %[1]s repeatedly " +
+	"with the same arguments to check that it computes the result again, and call %[1]s.cache_clear() " +
+	"before every test (e.g. in a fixture) so results cached by one test do not leak into another."
+
+// routeDecorators are the names of the decorators that register a function as
+// a handler of a web framework, such as @app.route or @router.get.
+var routeDecorators = map[string]bool{
+	"route": true, "get": true, "post": true, "put": true, "patch": true, "delete": true,
+}
+
+// pythonDecorators returns the decorators of the Python function as written,
+// e.g. "@lru_cache(maxsize=None)", outermost first. Functions without
+// decorators have none.
+func pythonDecorators(fn *tree_sitter.Node, code string) []string {
+	parent := fn.Parent()
+	if parent == nil || parent.Kind() != "decorated_definition" {
+		return nil
+	}
+	var decorators []string
+	for i := 0; i < int(parent.NamedChildCount()); i++ {
+		if c := parent.NamedChild(uint(i)); c.Kind() == "decorator" {
+			decorators = append(decorators, strings.TrimSpace(code[c.StartByte():c.EndByte()]))
+		}
+	}
+	return decorators
+}
+
+// decoratorName returns the name of a decorator without the @, its module or
+// receiver and its arguments, e.g. "lru_cache" for "@functools.lru_cache(128)",
+// and whether the decorator is an attribute of something, like @app.route.
+func decoratorName(decorator string) (string, bool) {
+	name := strings.TrimPrefix(decorator, "@")
+	if i := strings.Index(name, "("); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:], true
+	}
+	return name, false
+}
+
+// pythonDecoratorNote returns the prompt section naming the decorators of the
+// Python function and what they mean for its tests, or an empty string if the
+// function has no decorators.
+func pythonDecoratorNote(fn symFunction, code string) string {
+	decorators := pythonDecorators(fn.Node, code)
+	if len(decorators) == 0 {
+		return ""
+	}
+
+	note := fmt.Sprintf("\nThe function %s is decorated with %s. The decorators wrap the function, "+
+		"so the tests call the decorated function and must account for what the decorators do.",
+		fn.Name, strings.Join(decorators, ", "))
+	for _, decorator := range decorators {
+		name, attribute := decoratorName(decorator)
+		if attribute && routeDecorators[name] {
+			note += fmt.Sprintf(" %s registers it as a web route: call %s directly, or through the test client "+
+				"of the framework to test the route.", decorator, fn.Name)
+		} else if explanation, ok := decoratorNotes[name]; ok {
+			note += " " + fmt.Sprintf(explanation, fn.Name)
+		}
+	}
+	return note + "\n"
+}
@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSymPromptNotesCacheDecorator(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "fib.py")
+	writeFile(t, sourcePath, "from functools import lru_cache\n\n"+
+		"@lru_cache(maxsize=None)\ndef fib(n):\n    if n < 2:\n        return n\n    return fib(n - 1) + fib(n - 2)\n")
+
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, nil)
+	if err := sw.SubmitSymTaskForFunction(sourcePath, "fib"); err != nil {
+		t.Fatalf("SubmitSymTaskForFunction: %v", err)
+	}
+
+	prompts := m.recorded()
+	if len(prompts) == 0 {
+		t.Fatal("model was not prompted")
+	}
+	for _, prompt := range prompts {
+		for _, want := range []string{
+			"The function fib is decorated with @lru_cache(maxsize=None).",
+			"do not write tests that call fib repeatedly",
+			"call fib.cache_clear() before every test",
+		} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("prompt:\n%s\nwant it to contain %q", prompt, want)
+			}
+		}
+	}
+}
@@ -1,18 +1,24 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Marksagittarius/pinguis/dependency"
+	"github.com/Marksagittarius/pinguis/fileio"
 	"github.com/Marksagittarius/pinguis/model"
-	"github.com/Marksagittarius/pinguis/postprocessor"
+	"github.com/Marksagittarius/pinguis/prompt"
 )
 
 // TestTask represents a task for testing source code.
@@ -27,14 +33,52 @@ import (
 // - GeneratedTest: The most recently generated test code (initially empty).
 // - TestReport: The most recent test execution report (initially empty).
 // - CodeType: The programming language of the source code (e.g., "go", "python").
+// - Priority: The dispatch priority of the task; higher priorities are processed first.
+// - PassRate: The fraction of generated tests that passed in the latest run.
+// - Confidence: The quality score of the completed task, between 0 and 1.
+// - FunctionName: The function the task targets, empty for whole-file tasks.
+// - TestPath: The file the generated test is written to, empty to let the callback decide.
+// - BasePrompt: A fixed prompt used instead of the PromptGenerator (e.g. symbolic prompts).
+// - PathCover: The execution paths a symbolic test was asked to cover (nil for other tasks).
+// - PromptTokens: The number of prompt tokens sent to the model for the task so far.
+// - Flaky: Whether the latest passing test gave different results when run again.
+// - LastResult: The structured result of the latest test run (nil initially).
 type TestTask struct {
-	SourceCode    string  // The source code to test
-	SourcePath    string  // Path to the source file
-	Iterations    int     // Number of iterations done so far
-	BestCoverage  float64 // Best coverage rate achieved so far
-	GeneratedTest string  // The latest generated test code (empty initially)
-	TestReport    string  // The latest test execution report (empty initially)
-	CodeType      string  // The programming language of the source code (e.g., "go", "python")
+	SourceCode    string      // The source code to test
+	SourcePath    string      // Path to the source file
+	Iterations    int         // Number of iterations done so far
+	BestCoverage  float64     // Best coverage rate achieved so far
+	GeneratedTest string      // The latest generated test code (empty initially)
+	TestReport    string      // The latest test execution report (empty initially)
+	CodeType      string      // The programming language of the source code (e.g., "go", "python")
+	Priority      int         // Dispatch priority, higher values are processed first
+	PassRate      float64     // Fraction of passing tests in the latest run
+	Confidence    float64     // Quality score of the completed task (set on completion)
+	FunctionName  string      // Targeted function, empty for whole-file tasks
+	TestPath      string      // Where the generated test is written (empty: derived from SourcePath)
+	BasePrompt    string      // Fixed prompt replacing the PromptGenerator output (empty: use the generator)
+	PathCover     *PathCover  // Paths targeted by a symbolic test (nil for other tasks)
+	PromptTokens  int         // Prompt tokens sent to the model so far
+	Flaky         bool        // The latest passing test varied when run again
+	LastResult    *TestResult // The latest test result (nil initially)
+
+	writtenFiles []string // Test files written for the task, for the test written hook
+}
+
+// key returns the identifier of the task among the active tasks.
+func (t *TestTask) key() string {
+	if t.FunctionName == "" {
+		return t.SourcePath
+	}
+	return t.SourcePath + "::" + t.FunctionName
+}
+
+// testPath returns the path of the file the task's generated test belongs to.
+func (t *TestTask) testPath() string {
+	if t.TestPath != "" {
+		return t.TestPath
+	}
+	return processTestFilePath(t.SourcePath, t.CodeType)
 }
 
 // TestCallback defines a function type that is used to execute a test and return its results.
@@ -48,6 +92,41 @@ type TestTask struct {
 //   - err: An error object if any issues occur during the test execution.
 type TestCallback func(sourceCode, testCode, sourcePath string) (coverage float64, report string, err error)
 
+// TestResult is the structured outcome of executing a generated test.
+//
+// Fields:
+// - Coverage: The code coverage achieved by the test.
+// - Report: The test report or output.
+// - Passed: The number of test cases that passed.
+// - Failed: The number of test cases that failed or errored.
+// - Stdout: What the test run wrote to stdout, if the callback keeps it apart.
+// - Stderr: What the test run wrote to stderr, if the callback keeps it apart.
+// - CoverageReport: The coverage report, if the callback keeps it apart.
+type TestResult struct {
+	Coverage       float64
+	Report         string
+	Passed         int
+	Failed         int
+	Stdout         string
+	Stderr         string
+	CoverageReport string
+}
+
+// PassRate returns the fraction of test cases that passed. A result that does not
+// report any test counts is considered fully passing, since the callback would
+// have returned an error otherwise.
+func (r *TestResult) PassRate() float64 {
+	total := r.Passed + r.Failed
+	if total == 0 {
+		return 1
+	}
+	return float64(r.Passed) / float64(total)
+}
+
+// StructuredTestCallback executes a test like TestCallback but reports its
+// outcome as a TestResult.
+type StructuredTestCallback func(sourceCode, testCode, sourcePath string) (*TestResult, error)
+
 // DeepWorker represents a worker that processes test tasks in a concurrent manner.
 // It manages a pool of workers, handles task execution, and provides mechanisms
 // for controlling task flow and lifecycle.
@@ -55,63 +134,280 @@ type TestCallback func(sourceCode, testCode, sourcePath string) (coverage float6
 // Fields:
 // - pool: The WorkerPool used to manage worker goroutines.
 // - model: The ChatModel used for processing tasks.
-// - tasks: A channel for queuing test tasks to be processed.
+// - tasks: A priority queue of test tasks waiting to be processed.
 // - callback: A callback function invoked upon task completion.
+// - structuredCallback: A callback returning a TestResult, used instead of callback when set.
 // - coverageThreshold: The minimum coverage threshold required for task success.
+// - coverageThresholds: Per-language coverage thresholds keyed by code type,
+//   overriding coverageThreshold for tasks of that language.
 // - maxIterations: The maximum number of iterations allowed for task processing.
+// - minCoverageDelta: The minimum improvement of the best coverage an iteration must
+//   achieve for the task to keep iterating (0 disables the check).
+// - confidence: The function scoring the quality of completed tasks.
+// - report: The run report collecting the outcome of finished tasks.
+// - results: The stream receiving a JSON line per processed function.
+// - taskResults: The results of finished tasks, sent on the channel returned by Results.
+// - fileIO: Writes generated tests for tasks with an explicit TestPath (optional).
+// - forceGenerate: Generates symbolic tests even for functions with stub bodies.
+// - skipAbstract: Never generates symbolic tests for abstract methods, even with forceGenerate.
+// - functionOrder: The order in which symbolic tests are generated for the functions of a file.
+// - keepIterationArtifacts: Keeps a copy of every iteration's generated test next to the final one.
+// - maxOutputTokens: The output token limit passed to the model (0 for the model's default).
+// - formatter: Formats generated tests before they are written (nil leaves them as generated).
+// - examples: Few-shot examples keyed by code type, rendered into the prompts of tasks of that language.
+// - maxExampleSize: The cap on the total size of the examples rendered into a prompt.
+// - testStyle: The kind of tests requested from the model (example-based unless set).
+// - gitTrackedOnly: Restricts directory submission to files tracked by git.
+// - contextLevel: How much of the source file symbolic test prompts include.
+// - maxTestsPerFunction: The most test functions accepted for a single function (0 for no limit).
+// - minTestsPerFunction: The fewest test functions accepted for a single function.
+// - testPackage: The package generated Go and Java tests are declared in (left to the model unless set).
+// - retries: The retry budget all tasks of the run draw from when they ask the model again.
+// - modelName: The name of the model recorded in the run report.
+// - skipCompleted: Rejects the submission of files whose task already finished during the run.
+// - duplicateThreshold: The similarity from which generated test functions are dropped as
+//   duplicates of an earlier one (postprocessor.DefaultDuplicateThreshold if 0, above 1 keeps all).
+// - promptSource: Loads the template of symbolic test prompts (see promptTemplatePath if nil).
+// - callbackCache: The results of earlier callback runs, reused for identical tests (nil disables it).
+// - tokenizer: Counts the tokens of the prompts sent to the model (4 characters per token if not set).
+// - onTestWritten: Called once for every test file written for a task when the task is finished (nil for no hook).
+// - failOnHookError: Fails the task when onTestWritten returns an error instead of logging it.
+// - includeNotebooks: Makes directory submission discover Jupyter notebooks and test their code cells.
+// - flakinessCheck: The number of times every passing test is run again through the structured
+//   callback to detect flaky tests (0 disables the check).
+// - taskTimeout: The longest a single iteration of a task may take (0 for no limit).
+// - promptTemplatePath: The file the template of symbolic test prompts is read from through fileIO
+//   if promptSource is nil; prompt.txt in the working directory, or the built-in template if there
+//   is no prompt.txt, if empty as well.
+// - maxPathsPerFunction: The most execution paths a symbolic test prompt asks to cover
+//   (0 for no limit); the branches the capped paths miss are named in the prompt.
+// - detectCodeLanguage: Extracts the code of responses without a block of the task's language from
+//   a block of any language, or from the first line that looks like code (see
+//   postprocessor.DetectingCodeExtractor).
+// - separateTestOutput: Reports the stdout, stderr and coverage report of a test run in sections of
+//   their own in the iteration prompt, if the callback keeps them apart.
+// - testNamePattern: The naming pattern generated test functions are renamed to
+//   (see postprocessor.RenameTestFunctions), empty to keep the names of the model.
+// - dependencyRanks: The position of every file in the dependency order SubmitTask prioritizes
+//   files by, keyed by absolute path (nil unless DependencyOrder is set with a DependencyGraph).
+// - slots: Holds a token for every worker busy with a task, so tasks leave the queue only when a
+//   worker is free.
 // - wg: A WaitGroup to synchronize the completion of all tasks.
+// - timedOut: Tracks the iterations that outlived the task timeout, which Shutdown waits for.
 // - mu: A mutex to ensure thread-safe access to shared resources.
 // - activeTasks: A map of currently active tasks, keyed by task ID.
+// - idle: The channel returned by Done, closed once no task is active (nil until Done is called).
+// - completedTasks: The IDs of the tasks that finished without failing during the run.
+// - tempOwners: The test paths the worker ran callbacks for, whose temp directories Shutdown removes.
 // - ctx: A context for managing task cancellation and timeouts.
 // - cancel: A function to cancel the context and stop task processing.
 // - SourcePath: The file path to the source code being tested.
 // - TestPath: The file path to the test cases.
 // - PromptGenerator: A generator for creating task-specific prompts.
 type DeepWorker struct {
-	pool              WorkerPool
-	model             model.ChatModel
-	tasks             chan *TestTask
-	callback          TestCallback
-	coverageThreshold float64
-	maxIterations     int
-	wg                sync.WaitGroup
-	mu                sync.Mutex
-	activeTasks       map[string]*TestTask
-	ctx               context.Context
-	cancel            context.CancelFunc
-	SourcePath        string
-	TestPath          string
-	PromptGenerator   TaskPromptGenerator
+	pool                   WorkerPool
+	model                  model.ChatModel
+	tasks                  *taskQueue
+	callback               TestCallback
+	structuredCallback     StructuredTestCallback
+	coverageThreshold      float64
+	coverageThresholds     map[string]float64
+	maxIterations          int
+	minCoverageDelta       float64
+	confidence             ConfidenceFunc
+	report                 runReportRecorder
+	results                *resultStream
+	taskResults            *taskResults
+	fileIO                 FileIO
+	forceGenerate          bool
+	skipAbstract           bool
+	functionOrder          FunctionOrder
+	keepIterationArtifacts bool
+	maxOutputTokens        int
+	formatter              Formatter
+	examples               map[string][]prompt.Example
+	maxExampleSize         int
+	testStyle              TestStyle
+	gitTrackedOnly         bool
+	contextLevel           ContextLevel
+	maxTestsPerFunction    int
+	minTestsPerFunction    int
+	testPackage            TestPackage
+	retries                *retryBudget
+	modelName              string
+	skipCompleted          bool
+	duplicateThreshold     float64
+	promptSource           prompt.PromptSource
+	callbackCache          *callbackCache
+	tokenizer              Tokenizer
+	onTestWritten          TestWrittenHook
+	failOnHookError        bool
+	includeNotebooks       bool
+	flakinessCheck         int
+	taskTimeout            time.Duration
+	promptTemplatePath     string
+	maxPathsPerFunction    int
+	detectCodeLanguage     bool
+	separateTestOutput     bool
+	testNamePattern        string
+	dependencyRanks        map[string]int
+	slots                  chan struct{}
+	wg                     sync.WaitGroup
+	timedOut               sync.WaitGroup
+	mu                     sync.Mutex
+	activeTasks            map[string]*TestTask
+	idle                   chan struct{}
+	completedTasks         map[string]bool
+	tempOwners             map[string]bool
+	ctx                    context.Context
+	cancel                 context.CancelFunc
+	SourcePath             string
+	TestPath               string
+	PromptGenerator        TaskPromptGenerator
 }
 
 type DeepWorkerConfig struct {
-	WorkerCount       int
-	Model             model.ChatModel
-	Callback          TestCallback
-	CoverageThreshold float64
-	MaxIterations     int
-	SourcePath        string
-	TestPath          string
-	PromptGenerator   TaskPromptGenerator
+	WorkerCount            int
+	Model                  model.ChatModel
+	Callback               TestCallback
+	StructuredCallback     StructuredTestCallback
+	CoverageThreshold      float64
+	CoverageThresholds     map[string]float64
+	MaxIterations          int
+	MinCoverageDelta       float64
+	ConfidenceFunc         ConfidenceFunc
+	ResultsWriter          io.Writer
+	ForceGenerate          bool
+	SkipAbstractMethods    bool
+	FunctionOrder          FunctionOrder
+	KeepIterationArtifacts bool
+	MaxOutputTokens        int
+	FormatGenerated        bool
+	Formatter              Formatter
+	Examples               map[string][]prompt.Example
+	MaxExampleSize         int
+	TestStyle              TestStyle
+	GitTrackedOnly         bool
+	ContextLevel           ContextLevel
+	MaxTestsPerFunction    int
+	MinTestsPerFunction    int
+	TestPackage            TestPackage
+	MaxTotalRetries        int
+	ModelName              string
+	SkipCompleted          bool
+	DuplicateThreshold     float64
+	PromptSource           prompt.PromptSource
+	CallbackCacheSize      int
+	CallbackCachePath      string
+	Tokenizer              Tokenizer
+	OnTestWritten          TestWrittenHook
+	FailOnHookError        bool
+	IncludeNotebooks       bool
+	DependencyGraph        *dependency.DependencyGraph
+	DependencyOrder        bool
+	TestNamePattern        string
+	FlakinessCheck         int
+	TaskTimeout            time.Duration
+	PromptTemplatePath     string
+	MaxPathsPerFunction    int
+	DetectCodeLanguage     bool
+	SeparateTestOutput     bool
+	SourcePath             string
+	TestPath               string
+	PromptGenerator        TaskPromptGenerator
 }
 
 func NewDeepWorker(config *DeepWorkerConfig) *DeepWorker {
 	ctx, cancel := context.WithCancel(context.Background())
 	pool := NewGoWorkerPool(config.WorkerCount)
 
+	confidence := config.ConfidenceFunc
+	if confidence == nil {
+		confidence = DefaultConfidence
+	}
+
+	maxExampleSize := config.MaxExampleSize
+	if maxExampleSize == 0 {
+		maxExampleSize = prompt.DefaultMaxExampleSize
+	}
+
+	tokenizer := config.Tokenizer
+	if tokenizer == nil {
+		tokenizer = heuristicTokenizer{}
+	}
+
+	modelName := config.ModelName
+	if modelName == "" && config.Model != nil {
+		modelName = model.Name(config.Model)
+	}
+
+	var dependencyRanks map[string]int
+	if config.DependencyOrder && config.DependencyGraph != nil {
+		dependencyRanks = newDependencyRanks(config.DependencyGraph)
+	}
+
+	var formatter Formatter
+	if config.FormatGenerated {
+		formatter = config.Formatter
+		if formatter == nil {
+			formatter = DefaultFormatter
+		}
+	}
+
 	return &DeepWorker{
-		pool:              pool,
-		model:             config.Model,
-		tasks:             make(chan *TestTask, config.WorkerCount * 5),
-		callback:          config.Callback,
-		coverageThreshold: config.CoverageThreshold,
-		maxIterations:     config.MaxIterations,
-		activeTasks:       make(map[string]*TestTask),
-		ctx:               ctx,
-		cancel:            cancel,
-		SourcePath:        config.SourcePath,
-		TestPath:          config.TestPath,
-		PromptGenerator:   config.PromptGenerator,
+		pool:                   pool,
+		model:                  config.Model,
+		tasks:                  newTaskQueue(config.WorkerCount * 5),
+		callback:               config.Callback,
+		structuredCallback:     config.StructuredCallback,
+		coverageThreshold:      config.CoverageThreshold,
+		coverageThresholds:     config.CoverageThresholds,
+		maxIterations:          config.MaxIterations,
+		minCoverageDelta:       config.MinCoverageDelta,
+		confidence:             confidence,
+		results:                newResultStream(config.ResultsWriter),
+		taskResults:            newTaskResults(),
+		forceGenerate:          config.ForceGenerate,
+		skipAbstract:           config.SkipAbstractMethods,
+		functionOrder:          config.FunctionOrder,
+		keepIterationArtifacts: config.KeepIterationArtifacts,
+		maxOutputTokens:        config.MaxOutputTokens,
+		formatter:              formatter,
+		examples:               config.Examples,
+		maxExampleSize:         maxExampleSize,
+		testStyle:              config.TestStyle,
+		gitTrackedOnly:         config.GitTrackedOnly,
+		contextLevel:           config.ContextLevel,
+		maxTestsPerFunction:    config.MaxTestsPerFunction,
+		minTestsPerFunction:    config.MinTestsPerFunction,
+		testPackage:            config.TestPackage,
+		retries:                newRetryBudget(config.MaxTotalRetries),
+		modelName:              modelName,
+		skipCompleted:          config.SkipCompleted,
+		duplicateThreshold:     config.DuplicateThreshold,
+		promptSource:           config.PromptSource,
+		callbackCache:          newCallbackCache(config.CallbackCacheSize, config.CallbackCachePath),
+		tokenizer:              tokenizer,
+		onTestWritten:          config.OnTestWritten,
+		failOnHookError:        config.FailOnHookError,
+		includeNotebooks:       config.IncludeNotebooks,
+		flakinessCheck:         config.FlakinessCheck,
+		taskTimeout:            config.TaskTimeout,
+		promptTemplatePath:     config.PromptTemplatePath,
+		maxPathsPerFunction:    config.MaxPathsPerFunction,
+		detectCodeLanguage:     config.DetectCodeLanguage,
+		separateTestOutput:     config.SeparateTestOutput,
+		testNamePattern:        config.TestNamePattern,
+		dependencyRanks:        dependencyRanks,
+		slots:                  make(chan struct{}, max(config.WorkerCount, 1)),
+		activeTasks:            make(map[string]*TestTask),
+		completedTasks:         make(map[string]bool),
+		tempOwners:             make(map[string]bool),
+		ctx:                    ctx,
+		cancel:                 cancel,
+		SourcePath:             config.SourcePath,
+		TestPath:               config.TestPath,
+		PromptGenerator:        config.PromptGenerator,
 	}
 }
 
@@ -128,15 +424,20 @@ func getCodeType(sourcePath string) string {
 	if strings.HasSuffix(sourcePath, ".java") {
 		return "java"
 	}
+	if strings.HasSuffix(sourcePath, ".kt") {
+		return "kotlin"
+	}
 	if strings.HasSuffix(sourcePath, ".cpp") {
 		return "cpp"
 	}
 	return ""
 }
 
-// SubmitTask submits a new test task for processing. It ensures that no duplicate
-// tasks are submitted for the same sourcePath and that the task queue has capacity
-// to accept new tasks.
+// SubmitTask submits a new test task for processing with the default priority,
+// or, with DependencyOrder set, with the priority of the file's place in the
+// dependency order (see dependencyPriority).
+// It ensures that no duplicate tasks are submitted for the same sourcePath and
+// that the task queue has capacity to accept new tasks.
 //
 // Parameters:
 //   - sourceCode: The source code to be tested.
@@ -144,14 +445,35 @@ func getCodeType(sourcePath string) string {
 //
 // Returns:
 //   - error: An error is returned if a task for the given sourcePath is already
-//     being processed or if the task queue is full.
+//     being processed, has already completed and the worker skips completed
+//     files (see ErrAlreadyCompleted), or if the task queue is full.
 func (dw *DeepWorker) SubmitTask(sourceCode, sourcePath string) error {
+	return dw.SubmitTaskWithPriority(sourceCode, sourcePath, dw.dependencyPriority(sourcePath))
+}
+
+// SubmitTaskWithPriority submits a new test task for processing with the given
+// priority. Tasks with a higher priority are dispatched before tasks with a lower
+// one, and the priority is kept for every iteration of the task.
+//
+// Parameters:
+//   - sourceCode: The source code to be tested.
+//   - sourcePath: The file path of the source code.
+//   - priority: The dispatch priority of the task.
+//
+// Returns:
+//   - error: An error is returned if a task for the given sourcePath is already
+//     being processed, has already completed and the worker skips completed
+//     files (see ErrAlreadyCompleted), or if the task queue is full.
+func (dw *DeepWorker) SubmitTaskWithPriority(sourceCode, sourcePath string, priority int) error {
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
 	if _, exists := dw.activeTasks[sourcePath]; exists {
 		return fmt.Errorf("already processing tests for %s", sourcePath)
 	}
+	if dw.skipCompleted && dw.completedTasks[sourcePath] {
+		return fmt.Errorf("%w: %s", ErrAlreadyCompleted, sourcePath)
+	}
 
 	task := &TestTask{
 		SourceCode:   sourceCode,
@@ -160,69 +482,102 @@ func (dw *DeepWorker) SubmitTask(sourceCode, sourcePath string) error {
 		BestCoverage: 0.0,
 		CodeType:     getCodeType(sourcePath),
 		TestReport:   "",
+		Priority:     priority,
 	}
 	dw.activeTasks[sourcePath] = task
 
-	select {
-	case dw.tasks <- task:
-		return nil
-	default:
+	if !dw.tasks.push(task) {
 		delete(dw.activeTasks, sourcePath)
 		return fmt.Errorf("task queue is full")
 	}
+	return nil
 }
 
 // Run starts the DeepWorker's main processing loop. It initializes the worker pool
-// and launches a goroutine to process tasks from the task channel. Each task is
-// submitted to the worker pool for execution. If a task submission fails, it attempts
-// to requeue the task or marks it as complete after a timeout. The processing loop
-// listens for tasks or a cancellation signal from the context to gracefully shut down.
+// and launches a goroutine to process tasks from the task queue in priority order.
+// A task is only taken from the queue once a worker is free to run it, so a task
+// submitted later with a higher priority still overtakes the waiting ones. If a
+// task submission fails, the task is requeued, or marked as complete if the
+// queue is full, and the loop backs off before it tries again. The processing
+// loop waits for new tasks or a cancellation signal from the context to
+// gracefully shut down.
 // This method is non-blocking and logs the status of the worker and tasks.
 func (dw *DeepWorker) Run() {
-    dw.pool.Run()
-    dw.wg.Add(1)
-
-    go func() {
-        defer dw.wg.Done()
-        log.Println("Task processor started")
-        
-        for {
-            select {
-            case task, ok := <-dw.tasks:
-                if !ok {
-                    log.Println("Task channel closed, processor shutting down")
-                    return
-                }
-                
-                taskCopy := *task
-                err := dw.pool.Submit(func() {
-                    log.Printf("Processing task for: %s (iteration %d)", 
-                        taskCopy.SourcePath, taskCopy.Iterations)
-                    dw.processTask(&taskCopy)
-                })
-                
-                if err != nil {
-                    log.Printf("Failed to submit task for %s: %v", 
-                        task.SourcePath, err)
-                    
-                    select {
-                    case dw.tasks <- task:
-                        log.Printf("Requeued failed task for: %s", task.SourcePath)
-                    case <-time.After(3 * time.Second):
-                        log.Printf("Failed to requeue task, marking as complete: %s", 
-                            task.SourcePath)
-                        dw.completeTask(task.SourcePath)
-                    }
-                }
-                
-            case <-dw.ctx.Done():
-                log.Println("Context canceled, processor shutting down")
-                return
-            }
-        }
-    }()
-    
-    log.Println("DeepWorker is now running")
+	dw.pool.Run()
+	dw.wg.Add(1)
+
+	go func() {
+		defer dw.wg.Done()
+		log.Println("Task processor started")
+
+		backoff := submitRetryDelay
+		for {
+			// Wait for a free worker before choosing the task it runs
+			select {
+			case dw.slots <- struct{}{}:
+			case <-dw.ctx.Done():
+				log.Println("Context canceled, processor shutting down")
+				return
+			}
+
+			task, ok := dw.nextTask()
+			if !ok {
+				log.Println("Context canceled, processor shutting down")
+				return
+			}
+
+			err := dw.pool.Submit(func() {
+				defer func() { <-dw.slots }()
+				dw.processTask(task)
+			})
+			if err == nil {
+				backoff = submitRetryDelay
+				continue
+			}
+
+			<-dw.slots
+			log.Printf("Failed to submit task for %s: %v", task.SourcePath, err)
+			if dw.tasks.push(task) {
+				log.Printf("Requeued failed task for: %s", task.SourcePath)
+			} else {
+				log.Printf("Failed to requeue task, marking as complete: %s", task.SourcePath)
+				dw.failTask(task, fmt.Errorf("failed to requeue task: %v", err))
+			}
+
+			select {
+			case <-time.After(backoff):
+				backoff = min(backoff*2, maxSubmitRetryDelay)
+			case <-dw.ctx.Done():
+				log.Println("Context canceled, processor shutting down")
+				return
+			}
+		}
+	}()
+
+	log.Println("DeepWorker is now running")
+}
+
+// submitRetryDelay is how long the processing loop waits after the pool rejected
+// a task before it submits again; the delay doubles with every further rejection
+// up to maxSubmitRetryDelay.
+const (
+	submitRetryDelay    = 50 * time.Millisecond
+	maxSubmitRetryDelay = 2 * time.Second
+)
+
+// nextTask waits for the highest-priority queued task and removes it from the
+// queue. It returns false if the worker is shut down while waiting.
+func (dw *DeepWorker) nextTask() (*TestTask, bool) {
+	for {
+		if task, ok := dw.tasks.pop(); ok {
+			return task, true
+		}
+		select {
+		case <-dw.tasks.notify:
+		case <-dw.ctx.Done():
+			return nil, false
+		}
+	}
 }
 
 func processTestFilePath(sourcePath, codeType string) string {
@@ -236,89 +591,459 @@ func processTestFilePath(sourcePath, codeType string) string {
 		return strings.Replace(sourcePath, ".js", "_test.js", 1)
 	}
 	if codeType == "java" {
-		return strings.Replace(sourcePath, ".java", "Test"+sourcePath, 1)
+		return strings.Replace(sourcePath, ".java", "Test.java", 1)
+	}
+	if codeType == "kotlin" {
+		return strings.Replace(sourcePath, ".kt", "Test.kt", 1)
 	}
 	return sourcePath
 }
 
-// processTask processes a given test generation task by generating test code,
-// evaluating its coverage, and re-queuing the task if necessary based on the
-// coverage threshold and iteration limits.
+// processTask processes a given test generation task by running one iteration
+// of test generation and re-queuing the task if necessary based on the coverage
+// threshold and iteration limits.
 //
 // Parameters:
 //   - task (*TestTask): The test generation task to process.
 //
 // Behavior:
-//   1. Builds a prompt for the task using the buildPrompt method.
-//   2. Generates a response from the model using the prompt.
-//   3. If generation fails, marks the task as complete and exits.
-//   4. Extracts test code from the model's response and assigns it to the task.
-//   5. Evaluates the test code's coverage and generates a test report.
-//   6. Updates the task's best coverage if the new coverage is higher.
-//   7. If the coverage is below the threshold and the iteration limit is not
-//      reached, increments the iteration count and re-queues the task.
-//   8. If the task is completed (either due to sufficient coverage or reaching
-//      the iteration limit), logs the result and marks the task as complete.
+//   1. Runs a single generation iteration using the iterate method, bounded by
+//      the task timeout if one is set.
+//   2. If the iteration times out, records the timeout as the task's test report
+//      and, once the timed out iteration has ended, re-queues it, or finishes it
+//      as timed out on its last iteration.
+//   3. If the iteration fails, records the failure and marks the task as complete.
+//   4. If the task needs another iteration, re-queues it.
+//   5. If the task is completed (either due to sufficient coverage, reaching
+//      the iteration limit or a plateau), scores its confidence, records it in
+//      the run report and marks the task as complete.
 //
 // Notes:
 //   - The method ensures tasks are not re-queued if the task queue is full.
 //   - Logs relevant information about task completion and re-queuing failures.
+//   - The iteration runs on a copy of the task, which is stored back into the
+//     task under the worker's mutex, so GetTaskStatus never races with it.
 func (dw *DeepWorker) processTask(task *TestTask) {
-	prompt := dw.buildPrompt(task)
+	working := dw.snapshotTask(task)
+	log.Printf("Processing task for: %s (iteration %d)", working.SourcePath, working.Iterations)
 
-	msg, err := dw.model.Generate(dw.ctx, prompt)
+	status, running, err := dw.iterateWithTimeout(working)
+	if errors.Is(err, errTaskTimeout) {
+		dw.timeoutTask(task, running)
+		return
+	}
+	dw.storeTask(task, working)
 	if err != nil {
-		dw.completeTask(task.SourcePath)
+		dw.failTask(working, err)
+		return
+	}
+
+	if status != taskContinue {
+		dw.finishTask(working, status, nil)
 		return
 	}
 
-	testCode := extractCodeFromMessage(msg.Content, task.CodeType)
+	if !dw.tasks.push(task) {
+		log.Printf("Failed to re-queue task for %s: queue full", task.SourcePath)
+		dw.failTask(task, fmt.Errorf("failed to re-queue task: queue full"))
+	}
+}
+
+// taskContinue is returned by iterate when a task needs another iteration.
+const taskContinue = "continue"
+
+// errCallbackFailed wraps the errors of test callbacks returned by iterate, so
+// callers can tell a failed test run from a failed generation.
+var errCallbackFailed = errors.New("test callback failed")
+
+// ErrAlreadyCompleted is returned when a file whose tests were already generated
+// during the run is submitted again to a worker configured with SkipCompleted.
+var ErrAlreadyCompleted = errors.New("tests already generated during this run")
+
+// iterate runs a single test generation iteration for the task.
+//
+// Behavior:
+//   1. Builds a prompt for the task using the buildPrompt method.
+//   2. Generates a response from the model using the prompt, continuing it if it
+//      looks truncated and asking again if it does not contain an acceptable test.
+//   3. Extracts test code from the model's response and assigns it to the task.
+//      If the task has a TestPath, every code block of the response is written
+//      to the file its header comment names, and unnamed blocks to the TestPath.
+//   4. Evaluates the test code's coverage, pass rate and test report.
+//   5. Updates the task's best coverage if the new coverage is higher.
+//   6. If the coverage is below the threshold of the task's language, the
+//      iteration limit is not reached and the coverage has not plateaued,
+//      increments the iteration count and returns taskContinue.
+//   7. Otherwise returns the status the task finished with.
+//
+// Returns:
+//   - string: taskContinue, TaskCompleted or TaskPlateaued.
+//   - error: An error if generation, writing the test or the callback fails.
+func (dw *DeepWorker) iterate(ctx context.Context, task *TestTask) (string, error) {
+	prompt := dw.buildPrompt(task)
+
+	msg, err := dw.generateTest(ctx, prompt, task)
+	if err != nil {
+		return "", fmt.Errorf("model generation failed: %w", err)
+	}
+
+	// An iteration that outlived the task timeout stops before it writes or
+	// runs anything
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var testCode string
+	if task.TestPath != "" && dw.fileIO != nil {
+		testCode, err = dw.writeTestFiles(task, msg.Content)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		testCode = dw.enforceTestPackage(dw.codeExtractor(task.CodeType).Postprocess(msg.Content), task.SourceCode, task.SourcePath, task.CodeType)
+		testCode = dw.renameTests(testCode, task)
+		testCode = dw.format(testCode, task.CodeType)
+	}
 	task.GeneratedTest = testCode
 
-	coverage, report, err := dw.callback(task.SourceCode, testCode, processTestFilePath(task.SourcePath, task.CodeType))
+	if dw.keepIterationArtifacts {
+		artifactPath := iterationArtifactPath(task.testPath(), task.Iterations)
+		if err := dw.writeFile(artifactPath, []byte(iterationArtifact(testCode, task.CodeType))); err != nil {
+			return "", fmt.Errorf("failed to write iteration artifact: %w", err)
+		}
+	}
+
+	// A test written to an explicit path is kept even when there is no callback to
+	// evaluate it with, there is just nothing to improve it against
+	if task.TestPath != "" && !dw.hasCallback() {
+		return TaskCompleted, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	result, err := dw.runCallback(task.SourceCode, testCode, task.testPath())
 	if err != nil {
-		dw.completeTask(task.SourcePath)
-		return
+		return "", fmt.Errorf("%w: %w", errCallbackFailed, err)
 	}
 
-	task.TestReport = report
+	coverage := result.Coverage
+	task.TestReport = dw.testReport(result)
+	task.LastResult = result
+	task.PassRate = result.PassRate()
+	task.Flaky = result.Failed == 0 && dw.isFlaky(task, testCode, result)
 
+	previousBest := task.BestCoverage
 	if coverage > task.BestCoverage {
 		task.BestCoverage = coverage
 	}
 
-	if coverage < dw.coverageThreshold && task.Iterations < dw.maxIterations {
-		task.Iterations++
+	if coverage >= dw.thresholdFor(task.CodeType) || task.Iterations >= dw.maxIterations {
+		log.Printf("Completed test generation for %s after %d iterations with %.2f%% coverage",
+			task.key(), task.Iterations, task.BestCoverage*100)
+		return TaskCompleted, nil
+	}
 
-		select {
-		case dw.tasks <- task:
-		default:
-			log.Printf("Failed to re-queue task for %s: queue full", task.SourcePath)
-			dw.completeTask(task.SourcePath)
+	if dw.hasPlateaued(task, previousBest) {
+		log.Printf("Coverage for %s plateaued at %.2f%% after %d iterations, stopping early",
+			task.key(), task.BestCoverage*100, task.Iterations)
+		return TaskPlateaued, nil
+	}
+
+	task.Iterations++
+	return taskContinue, nil
+}
+
+// testReport returns the report of the test run the next iteration's prompt
+// includes. With separateTestOutput, a result whose callback kept the test
+// output and the coverage report apart is reported in labelled sections, so
+// test failures are not lost among coverage figures; empty sections are left
+// out. Other results are reported as the callback reported them.
+func (dw *DeepWorker) testReport(result *TestResult) string {
+	if !dw.separateTestOutput || (result.Stdout == "" && result.Stderr == "" && result.CoverageReport == "") {
+		return result.Report
+	}
+
+	var sections []string
+	for _, section := range []struct{ title, content string }{
+		{"Test errors (stderr)", result.Stderr},
+		{"Test output (stdout)", result.Stdout},
+		{"Coverage report", result.CoverageReport},
+	} {
+		if strings.TrimSpace(section.content) != "" {
+			sections = append(sections, section.title+":\n"+strings.TrimRight(section.content, "\n"))
 		}
-	} else {
-		log.Printf("Completed test generation for %s after %d iterations with %.2f%% coverage",
-			task.SourcePath, task.Iterations, task.BestCoverage*100)
-		dw.completeTask(task.SourcePath)
 	}
+	return strings.Join(sections, "\n\n")
+}
+
+// iterationArtifactPath returns the path the test generated in the given
+// iteration is kept at, e.g. foo_test_iter1.py for the first iteration of
+// foo_test.py.
+func iterationArtifactPath(testPath string, iteration int) string {
+	ext := filepath.Ext(testPath)
+	return fmt.Sprintf("%s_iter%d%s", strings.TrimSuffix(testPath, ext), iteration+1, ext)
+}
+
+// iterationArtifactPattern matches the base name of an iteration artifact
+// without its extension, capturing the base name of the test it was kept for.
+var iterationArtifactPattern = regexp.MustCompile(`^(.+)_iter\d+$`)
+
+// isIterationArtifact reports whether the file name is that of a test kept by
+// iterationArtifactPath, so it is neither discovered as a source nor measured.
+func isIterationArtifact(name string) bool {
+	ext := filepath.Ext(name)
+	m := iterationArtifactPattern.FindStringSubmatch(strings.TrimSuffix(name, ext))
+	if m == nil {
+		return false
+	}
+	return isGeneratedTestFile(m[1]+ext) || strings.HasPrefix(m[1], "test")
+}
+
+// iterationArtifact returns the content of the iteration artifact of the test
+// code. Go artifacts are excluded from the build, since they would be compiled
+// into the package of the test as ordinary files.
+func iterationArtifact(testCode, codeType string) string {
+	if codeType == "go" {
+		return "//go:build ignore\n\n" + testCode
+	}
+	return testCode
+}
+
+// writeFile writes data through the worker's FileIO, or directly to disk if the
+// worker has none.
+func (dw *DeepWorker) writeFile(filePath string, data []byte) error {
+	if dw.fileIO != nil {
+		return dw.fileIO.Write(filePath, data)
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// thresholdFor returns the coverage threshold for tasks of the given code type,
+// falling back to the global threshold for languages without their own.
+func (dw *DeepWorker) thresholdFor(codeType string) float64 {
+	if threshold, ok := dw.coverageThresholds[codeType]; ok {
+		return threshold
+	}
+	return dw.coverageThreshold
+}
+
+// hasCallback reports whether any test callback is configured.
+func (dw *DeepWorker) hasCallback() bool {
+	return dw.callback != nil || dw.structuredCallback != nil
+}
+
+// runCallback executes the generated test through the structured callback if one
+// is configured, and otherwise adapts the result of the plain callback. With a
+// callback cache, a test identical to one already run against the same source
+// code and unchanged dependencies is not executed again and gets the cached
+// result. Failed runs are not cached.
+func (dw *DeepWorker) runCallback(sourceCode, testCode, testPath string) (*TestResult, error) {
+	if dw.callbackCache == nil {
+		return dw.executeCallback(sourceCode, testCode, testPath)
+	}
+
+	key := callbackCacheKey(sourceCode, testCode, testDependencies(testCode, testPath))
+	if result, ok := dw.callbackCache.get(key); ok {
+		log.Printf("Reusing the cached test result for %s", testPath)
+		return result, nil
+	}
+	result, err := dw.executeCallback(sourceCode, testCode, testPath)
+	if err != nil {
+		return nil, err
+	}
+	dw.callbackCache.put(key, result)
+	return result, nil
+}
+
+// executeCallback runs the configured test callback on the generated test. The
+// temp directories the callback created for the test path with
+// fileio.MkdirTempFor and left behind are removed once it returns.
+func (dw *DeepWorker) executeCallback(sourceCode, testCode, testPath string) (*TestResult, error) {
+	dw.mu.Lock()
+	dw.tempOwners[testPath] = true
+	dw.mu.Unlock()
+	defer func() {
+		if err := fileio.CleanupTempFor(testPath); err != nil {
+			log.Printf("Failed to remove temporary artifacts of %s: %v", testPath, err)
+		}
+	}()
+
+	if dw.structuredCallback != nil {
+		return dw.structuredCallback(sourceCode, testCode, testPath)
+	}
+	if dw.callback == nil {
+		return nil, fmt.Errorf("no test callback configured")
+	}
+
+	coverage, report, err := dw.callback(sourceCode, testCode, testPath)
+	if err != nil {
+		return nil, err
+	}
+	return &TestResult{Coverage: coverage, Report: report}, nil
+}
+
+// finishTask runs the test written hook for the files written for the task,
+// scores the task's confidence, records it in the run report with the given
+// status and marks it as complete. A hook error fails the task if the worker is
+// configured to fail on hook errors.
+func (dw *DeepWorker) finishTask(task *TestTask, status string, taskErr error) {
+	for _, testPath := range task.writtenFiles {
+		if err := dw.testWritten(task.SourcePath, testPath); err != nil && taskErr == nil {
+			status, taskErr = TaskFailed, err
+		}
+	}
+
+	density := assertionDensity(task.GeneratedTest, task.CodeType)
+	if taskSucceeded(status) {
+		task.Confidence = dw.confidence(task.BestCoverage, task.PassRate, density)
+	}
+
+	entry := TaskReport{
+		SourcePath:       task.SourcePath,
+		FunctionName:     task.FunctionName,
+		TestPath:         task.testPath(),
+		CodeType:         task.CodeType,
+		Iterations:       task.Iterations,
+		BestCoverage:     task.BestCoverage,
+		PassRate:         task.PassRate,
+		AssertionDensity: density,
+		Confidence:       task.Confidence,
+		Status:           status,
+		Model:            dw.modelName,
+		PromptTokens:     task.PromptTokens,
+		PathCover:        task.PathCover,
+		Flaky:            task.Flaky,
+	}
+	if taskErr != nil {
+		entry.Error = taskErr.Error()
+	}
+	dw.report.record(entry)
+
+	if task.FunctionName != "" {
+		dw.results.emit(FunctionResult{
+			SourcePath:   task.SourcePath,
+			FunctionName: task.FunctionName,
+			TestPath:     entry.TestPath,
+			Coverage:     task.BestCoverage,
+			Passed:       taskSucceeded(status) && task.PassRate == 1,
+			Flaky:        task.Flaky,
+			Iterations:   task.Iterations,
+			Error:        entry.Error,
+		})
+	}
+
+	dw.taskResults.publish(TaskResult{
+		SourcePath:   task.SourcePath,
+		FunctionName: task.FunctionName,
+		BestCoverage: task.BestCoverage,
+		Iterations:   task.Iterations,
+		Err:          taskErr,
+	})
+	dw.completeTask(task.key(), taskSucceeded(status))
+}
+
+// failTask records the task as failed with the given error and marks it as complete.
+func (dw *DeepWorker) failTask(task *TestTask, err error) {
+	log.Printf("Test generation for %s failed: %v", task.key(), err)
+	dw.finishTask(task, TaskFailed, err)
+}
+
+// hasPlateaued reports whether the latest iteration improved the task's best
+// coverage by less than the configured minimum delta. The first iteration has
+// nothing to compare against and never counts as a plateau.
+func (dw *DeepWorker) hasPlateaued(task *TestTask, previousBest float64) bool {
+	if dw.minCoverageDelta <= 0 || task.Iterations == 0 {
+		return false
+	}
+	return task.BestCoverage-previousBest < dw.minCoverageDelta
 }
 
 type TaskPromptGenerator func(*TestTask) string
 
+// buildPrompt returns the prompt for the task's current iteration. Tasks with a
+// BasePrompt reuse it on every iteration, extended with the latest test report
+// once the first iteration is done; other tasks use the PromptGenerator. The
+// instructions for the test style and package and the few-shot examples
+// configured for the task's language follow the base prompt.
 func (dw *DeepWorker) buildPrompt(task *TestTask) string {
-	return dw.PromptGenerator(task)
+	instructions := dw.styleInstruction(task.CodeType) + dw.packageInstruction(task.CodeType) + dw.renderExamples(task.CodeType)
+	if task.BasePrompt == "" {
+		return dw.PromptGenerator(task) + instructions
+	}
+
+	basePrompt := task.BasePrompt + instructions
+	if task.Iterations == 0 {
+		return basePrompt
+	}
+
+	basePrompt += "\n"
+	basePrompt += "Your code need to be improved, the report is following:\n"
+	basePrompt += task.TestReport
+	basePrompt += "\n"
+	return basePrompt
 }
 
-func (dw *DeepWorker) completeTask(sourcePath string) {
+// renderExamples renders the few-shot examples configured for the code type as a
+// prompt section, or returns an empty string if there are none.
+func (dw *DeepWorker) renderExamples(codeType string) string {
+	rendered := prompt.RenderExamples(dw.examples[codeType], dw.maxExampleSize)
+	if rendered == "" {
+		return ""
+	}
+	return "\n" + rendered
+}
+
+// completeTask removes the task from the active tasks and, if it succeeded,
+// remembers it as completed. Failed tasks can be submitted again. Once no task
+// is active any more, the channel returned by Done is closed.
+func (dw *DeepWorker) completeTask(key string, succeeded bool) {
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
-	delete(dw.activeTasks, sourcePath)
+	delete(dw.activeTasks, key)
+	if succeeded {
+		dw.completedTasks[key] = true
+	}
+	dw.signalIdle()
+}
+
+// Report returns a snapshot of the run report, containing an entry for every task
+// that has finished so far and the retries left in the run's retry budget.
+func (dw *DeepWorker) Report() *RunReport {
+	report := dw.report.snapshot()
+	if remaining := dw.RemainingRetries(); remaining >= 0 {
+		report.RemainingRetries = &remaining
+	}
+	return report
 }
 
+// RemainingRetries returns the number of retries left in the run's retry budget,
+// or -1 if the worker has no MaxTotalRetries.
+func (dw *DeepWorker) RemainingRetries() int {
+	return dw.retries.left()
+}
+
+// Shutdown cancels the run, waits for running tasks, including iterations that
+// outlived the task timeout, closes the Results channel and removes the
+// intermediate artifacts that interrupted tasks of the worker left in the temp
+// directory. Artifacts of other workers are left alone.
 func (dw *DeepWorker) Shutdown() {
 	dw.cancel()
 	dw.wg.Wait()
 	dw.pool.Shutdown()
+	dw.timedOut.Wait()
+	dw.taskResults.close()
+
+	dw.mu.Lock()
+	owners := make([]string, 0, len(dw.tempOwners))
+	for owner := range dw.tempOwners {
+		owners = append(owners, owner)
+	}
+	dw.tempOwners = make(map[string]bool)
+	dw.mu.Unlock()
+	if err := fileio.CleanupTempFor(owners...); err != nil {
+		log.Printf("Failed to remove temporary artifacts: %v", err)
+	}
 }
 
 func (dw *DeepWorker) ActiveTaskCount() int {
@@ -327,45 +1052,122 @@ func (dw *DeepWorker) ActiveTaskCount() int {
 	return len(dw.activeTasks)
 }
 
+// GetTaskStatus returns a snapshot of the active task for the source path. The
+// snapshot is a copy and does not change as the task is processed.
 func (dw *DeepWorker) GetTaskStatus(sourcePath string) (*TestTask, bool) {
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 	task, exists := dw.activeTasks[sourcePath]
-	return task, exists
+	if !exists {
+		return nil, false
+	}
+	snapshot := *task
+	return &snapshot, true
+}
+
+// snapshotTask returns a copy of the task taken under the worker's mutex.
+func (dw *DeepWorker) snapshotTask(task *TestTask) *TestTask {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	snapshot := *task
+	return &snapshot
+}
+
+// storeTask stores the state of a processed copy back into the task under the
+// worker's mutex.
+func (dw *DeepWorker) storeTask(task *TestTask, processed *TestTask) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	*task = *processed
 }
 
-func extractCodeFromMessage(content, codeType string) string {
-	ce := postprocessor.NewCodeExtractor(codeType)
-	return ce.Postprocess(content)
+// BundledTestCallback returns the structured test callback shipped for the
+// code type, if there is one. It keeps the test output and the coverage report
+// apart, as SeparateTestOutput needs.
+func BundledTestCallback(codeType string) (StructuredTestCallback, bool) {
+	switch codeType {
+	case "python":
+		return PyStructuredTestCallBack, true
+	default:
+		return nil, false
+	}
 }
 
+// PyTestCallBack runs the Python test written to sourcePath, the path of the
+// test file, with coverage. It reports the result of PyStructuredTestCallBack
+// as its coverage and report.
 func PyTestCallBack(sourceCode, testCode, sourcePath string) (float64, string, error) {
-    testDir := filepath.Dir(sourcePath)
-    
-    if err := os.WriteFile(sourcePath, []byte(testCode), 0644); err != nil {
-        return 0, "", fmt.Errorf("failed to write test file to %s: %v", sourcePath, err)
-    }
-    
-    cmd := exec.Command("coverage", "run", "--source=.", filepath.Base(sourcePath))
-    cmd.Dir = testDir
-    
-    testOutput, err := cmd.CombinedOutput()
-    testReport := string(testOutput)
-    
-    if err != nil {
-        return 0, testReport, fmt.Errorf("coverage run failed: %v", err)
-    }
-    
-    reportCmd := exec.Command("coverage", "report")
-    reportCmd.Dir = testDir
-    
-    reportOutput, err := reportCmd.CombinedOutput()
+	result, err := PyStructuredTestCallBack(sourceCode, testCode, sourcePath)
+	if result == nil {
+		return 0, "", err
+	}
+	return result.Coverage, result.Report, err
+}
+
+// PyStructuredTestCallBack runs the Python test written to testPath with
+// coverage. Coverage is measured only for the module under test, the module
+// among those the test imports whose code is sourceCode, so imported helpers
+// and the test itself do not count. A test that does not import the module
+// under test is not run; its report asks for the import instead.
+//
+// The coverage of the result is the total percentage of the coverage report.
+// The result keeps the stdout and stderr of the test run and the coverage
+// report apart, and its Report joins all test output, in the order it was read,
+// with the coverage report. If the test run or the coverage report fails, the
+// result holds the output read so far and is returned along with the error.
+func PyStructuredTestCallBack(sourceCode, testCode, testPath string) (*TestResult, error) {
+	testDir := filepath.Dir(testPath)
+
+	coverageSource := "--source=."
+	if strings.TrimSpace(sourceCode) != "" {
+		module, ok := findImportedSourceModule(testCode, testDir, sourceCode)
+		if !ok {
+			return &TestResult{Report: "The test does not import the module under test, so it cannot cover it. " +
+				"Import the functions to test from their module instead of redefining or mocking them."}, nil
+		}
+		coverageSource = "--source=" + module
+	}
+
+	if err := os.WriteFile(testPath, []byte(testCode), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write test file to %s: %v", testPath, err)
+	}
+
+	// Keep the coverage data file out of the source tree
+	dataDir, err := fileio.MkdirTempFor(testPath, "pinguis-coverage-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coverage data directory: %v", err)
+	}
+	defer fileio.RemoveTemp(dataDir)
+	coverageEnv := append(os.Environ(), "COVERAGE_FILE="+filepath.Join(dataDir, ".coverage"))
+
+	cmd := exec.Command("coverage", "run", coverageSource, filepath.Base(testPath))
+	cmd.Dir = testDir
+	cmd.Env = coverageEnv
+
+	var stdout, stderr, combined bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&stdout, &combined)
+	cmd.Stderr = io.MultiWriter(&stderr, &combined)
+	err = cmd.Run()
+	result := &TestResult{Report: combined.String(), Stdout: stdout.String(), Stderr: stderr.String()}
 	if err != nil {
-		return 0, "", fmt.Errorf("coverage report failed: %v", err)
+		return result, fmt.Errorf("coverage run failed: %v", err)
 	}
-    coverageReport := string(reportOutput)
-    
-    fullReport := testReport + "\n" + coverageReport
-    
-    return 0, fullReport, nil
+
+	reportCmd := exec.Command("coverage", "report")
+	reportCmd.Dir = testDir
+	reportCmd.Env = coverageEnv
+
+	reportOutput, err := reportCmd.CombinedOutput()
+	result.CoverageReport = string(reportOutput)
+	result.Report += "\n" + result.CoverageReport
+	if err != nil {
+		return result, fmt.Errorf("coverage report failed: %v", err)
+	}
+	coverage, ok := reportedCoverage(result.CoverageReport)
+	if !ok {
+		return result, fmt.Errorf("coverage report has no total coverage")
+	}
+	result.Coverage = coverage
+
+	return result, nil
 }
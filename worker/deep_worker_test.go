@@ -0,0 +1,438 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+)
+
+func TestRunDispatchesHigherPriorityTaskSubmittedLater(t *testing.T) {
+	m := newFakeModel(pythonTestResponse)
+	m.block = make(chan struct{})
+	dw := newTestWorker(m, nil)
+	dw.Run()
+	defer dw.Shutdown()
+
+	// Occupy the only worker, so the next tasks have to wait in the queue
+	if err := dw.SubmitTaskWithPriority("", "first.py", 0); err != nil {
+		t.Fatal(err)
+	}
+	waitForPrompt(t, m)
+
+	for _, path := range []string{"low1.py", "low2.py"} {
+		if err := dw.SubmitTaskWithPriority("", path, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Give the dispatcher the chance to move the low-priority tasks on
+	time.Sleep(50 * time.Millisecond)
+	if err := dw.SubmitTaskWithPriority("", "high.py", 10); err != nil {
+		t.Fatal(err)
+	}
+	close(m.block)
+
+	select {
+	case <-dw.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks did not finish")
+	}
+
+	var order []string
+	for _, prompt := range m.recorded() {
+		order = append(order, strings.SplitN(prompt, "\n", 2)[0])
+	}
+	want := []string{"first.py", "high.py", "low1.py", "low2.py"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("dispatch order = %v, want %v", order, want)
+	}
+}
+
+func waitForPrompt(t *testing.T, m *fakeModel) string {
+	t.Helper()
+	select {
+	case prompt := <-m.received:
+		return prompt
+	case <-time.After(5 * time.Second):
+		t.Fatal("model was not called")
+		return ""
+	}
+}
+
+func TestResultsDeliversEveryResultAfterShutdown(t *testing.T) {
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.WorkerCount = 2
+	})
+	results := dw.Results()
+	dw.Run()
+
+	paths := []string{"a.py", "b.py", "c.py", "d.py"}
+	for _, path := range paths {
+		if err := dw.SubmitTask("", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-dw.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks did not finish")
+	}
+	// No result was received before Done, as when a caller's select picks Done
+	dw.Shutdown()
+
+	var finished []string
+	for result := range results {
+		finished = append(finished, result.SourcePath)
+	}
+	if len(finished) != len(paths) {
+		t.Errorf("received results for %v, want all of %v", finished, paths)
+	}
+}
+
+func TestShutdownRemovesTempArtifactsOfCancelledTask(t *testing.T) {
+	fileio.SetTempDir(t.TempDir())
+	t.Cleanup(func() { fileio.SetTempDir("") })
+
+	// A directory of another worker, which must survive this worker's shutdown
+	other, err := fileio.MkdirTempFor("other.py", "other-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileio.RemoveTemp(other)
+
+	created := make(chan string, 1)
+	release := make(chan struct{})
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.Callback = nil
+		config.StructuredCallback = func(sourceCode, testCode, testPath string) (*TestResult, error) {
+			dir, err := fileio.MkdirTempFor(testPath, "artifact-*")
+			if err != nil {
+				return nil, err
+			}
+			created <- dir
+			// Cancelled mid-run, the callback returns without removing its directory
+			<-release
+			return nil, context.Canceled
+		}
+	})
+	dw.Run()
+	if err := dw.SubmitTask("", "a.py"); err != nil {
+		t.Fatal(err)
+	}
+
+	var artifact string
+	select {
+	case artifact = <-created:
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not called")
+	}
+	shutdown := make(chan struct{})
+	go func() {
+		dw.Shutdown()
+		close(shutdown)
+	}()
+	close(release)
+	select {
+	case <-shutdown:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+
+	if _, err := os.Stat(artifact); !os.IsNotExist(err) {
+		t.Errorf("artifact %s of the cancelled task was not removed: %v", artifact, err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("artifact %s of another worker was removed: %v", other, err)
+	}
+}
+
+// TestGetTaskStatusWhileTasksIterate is meant for the race detector: it reads
+// the task status while the task iterates.
+func TestGetTaskStatusWhileTasksIterate(t *testing.T) {
+	lowCoverage := func(sourceCode, testCode, testPath string) (float64, string, error) {
+		return 0.1, "low", nil
+	}
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.Callback = lowCoverage
+		config.CoverageThreshold = 0.9
+		config.MaxIterations = 5
+	})
+	dw.Run()
+	defer dw.Shutdown()
+	if err := dw.SubmitTask("", "a.py"); err != nil {
+		t.Fatal(err)
+	}
+
+	done := dw.Done()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-done:
+			return
+		case <-deadline:
+			t.Fatal("task did not finish")
+		default:
+		}
+		if task, ok := dw.GetTaskStatus("a.py"); ok {
+			if task.Iterations > 5 || task.BestCoverage > 1 {
+				t.Errorf("status after %d iterations with %v coverage, want at most 5 iterations and coverage up to 1",
+					task.Iterations, task.BestCoverage)
+			}
+		}
+	}
+}
+
+func TestIterationArtifactsAreKeptButNotDiscovered(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+	testPath := filepath.Join(dir, "calc_test.py")
+
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.Callback = func(sourceCode, testCode, testPath string) (float64, string, error) {
+			return 0.1, "low", nil
+		}
+		config.CoverageThreshold = 0.9
+		config.MaxIterations = 2
+		config.KeepIterationArtifacts = true
+	})
+	dw.fileIO = &fileio.SimpleFileIO{}
+	task := &TestTask{SourcePath: sourcePath, SourceCode: "def add(a, b):\n    return a + b\n", TestPath: testPath, CodeType: "python"}
+	for i := 0; i < 2; i++ {
+		if _, err := dw.iterate(context.Background(), task); err != nil {
+			t.Fatalf("iteration %d: %v", i+1, err)
+		}
+	}
+
+	for _, name := range []string{"calc_test.py", "calc_test_iter1.py", "calc_test_iter2.py"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not written: %v", name, err)
+		}
+	}
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), nil)
+	if got, want := walkedFiles(t, sw, dir), []string{"calc.py"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walked %v, want only the source %v", got, want)
+	}
+}
+
+func TestIsIterationArtifact(t *testing.T) {
+	cases := map[string]bool{
+		"calc_test_iter1.py":            true,
+		"test_calc_iter12.py":           true,
+		"calc_add_test_case_1_iter2.py": true,
+		"CalcTest_iter1.java":           true,
+		"calc_test_iter1.go":            true,
+		"data_iter2.py":                 false,
+		"calc_test.py":                  false,
+		"calc_test_iterate.py":          false,
+	}
+	for name, want := range cases {
+		if got := isIterationArtifact(name); got != want {
+			t.Errorf("isIterationArtifact(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestGoIterationArtifactIsExcludedFromTheBuild(t *testing.T) {
+	artifact := iterationArtifact("package calc\n", "go")
+	if !strings.HasPrefix(artifact, "//go:build ignore\n\npackage calc\n") {
+		t.Errorf("Go artifact = %q, want it excluded from the build", artifact)
+	}
+	if artifact := iterationArtifact("def test_add():\n    pass\n", "python"); artifact != "def test_add():\n    pass\n" {
+		t.Errorf("Python artifact = %q, want the test code unchanged", artifact)
+	}
+}
+
+// runCoverageSequence runs a task of a.py whose callback reports the coverages
+// in turn, the last one from then on, and returns the report entry of the task
+// and the number of callback runs.
+func runCoverageSequence(t *testing.T, coverages []float64, configure func(*DeepWorkerConfig)) (TaskReport, int) {
+	t.Helper()
+	var mu sync.Mutex
+	runs := 0
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.Callback = func(sourceCode, testCode, testPath string) (float64, string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			coverage := coverages[min(runs, len(coverages)-1)]
+			runs++
+			return coverage, "report", nil
+		}
+		configure(config)
+	})
+	dw.Run()
+	if err := dw.SubmitTask("", "a.py"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-dw.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not finish")
+	}
+	dw.Shutdown()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return dw.Report().Tasks[0], runs
+}
+
+func TestMinCoverageDeltaStopsPlateauedTask(t *testing.T) {
+	tests := []struct {
+		name       string
+		minDelta   float64
+		wantStatus string
+		wantRuns   int
+	}{
+		// The second run improves coverage by 0.02, less than the minimum delta
+		{"plateau", 0.05, TaskPlateaued, 2},
+		// Without a minimum delta the task runs until its iterations are used up
+		{"no minimum delta", 0, TaskCompleted, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, runs := runCoverageSequence(t, []float64{0.5, 0.52, 0.6, 0.7}, func(config *DeepWorkerConfig) {
+				config.CoverageThreshold = 0.9
+				config.MaxIterations = 3
+				config.MinCoverageDelta = tt.minDelta
+			})
+			if task.Status != tt.wantStatus || runs != tt.wantRuns {
+				t.Errorf("status %q after %d callback runs, want %q after %d", task.Status, runs, tt.wantStatus, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestThresholdForFallsBackToCoverageThreshold(t *testing.T) {
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.CoverageThreshold = 0.8
+		config.CoverageThresholds = map[string]float64{"python": 0.6, "go": 0, "java": 1.5}
+	})
+
+	tests := []struct {
+		codeType string
+		want     float64
+	}{
+		{"python", 0.6},
+		// A threshold of 0 is an override too, not a missing one
+		{"go", 0},
+		// Overrides are clamped like the global threshold
+		{"java", 1},
+		{"javascript", 0.8},
+	}
+	for _, tt := range tests {
+		if got := dw.thresholdFor(tt.codeType); got != tt.want {
+			t.Errorf("thresholdFor(%q) = %v, want %v", tt.codeType, got, tt.want)
+		}
+	}
+}
+
+func TestCoverageThresholdsOverrideCompletesTask(t *testing.T) {
+	task, runs := runCoverageSequence(t, []float64{0.7}, func(config *DeepWorkerConfig) {
+		config.CoverageThreshold = 0.9
+		config.CoverageThresholds = map[string]float64{"python": 0.6}
+		config.MaxIterations = 3
+	})
+	if task.Status != TaskCompleted || runs != 1 {
+		t.Errorf("status %q after %d callback runs, want %q after the first", task.Status, runs, TaskCompleted)
+	}
+}
+
+// submitTwice runs a task of a.py to its end, then submits a.py again and
+// returns the error of the second submission.
+func submitTwice(t *testing.T, dw *DeepWorker) error {
+	t.Helper()
+	dw.Run()
+	defer dw.Shutdown()
+	if err := dw.SubmitTask("", "a.py"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-dw.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not finish")
+	}
+	return dw.SubmitTask("", "a.py")
+}
+
+func TestSkipCompletedRejectsCompletedFile(t *testing.T) {
+	failing := func(sourceCode, testCode, testPath string) (float64, string, error) {
+		return 0, "", errors.New("no interpreter")
+	}
+
+	tests := []struct {
+		name          string
+		skipCompleted bool
+		callback      TestCallback
+		wantRejected  bool
+	}{
+		{"completed", true, fullCoverage, true},
+		{"without SkipCompleted", false, fullCoverage, false},
+		// Failed tasks can be submitted again to retry them
+		{"failed", true, failing, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+				config.SkipCompleted = tt.skipCompleted
+				config.Callback = tt.callback
+			})
+			err := submitTwice(t, dw)
+			if rejected := errors.Is(err, ErrAlreadyCompleted); rejected != tt.wantRejected {
+				t.Errorf("second submission error = %v, want rejected %v", err, tt.wantRejected)
+			}
+		})
+	}
+}
+
+// namedModel is a fake model identifying itself with a name.
+type namedModel struct {
+	*fakeModel
+	name string
+}
+
+func (m *namedModel) Name() string {
+	return m.name
+}
+
+func TestReportRecordsModelName(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+
+	tests := []struct {
+		name       string
+		configured string
+		model      string
+		want       string
+	}{
+		{"configured name", "qwen2.5-coder:7b", "", "qwen2.5-coder:7b"},
+		{"name of the model", "", "llama3:8b", "llama3:8b"},
+		{"configured name takes precedence", "qwen2.5-coder:7b", "llama3:8b", "qwen2.5-coder:7b"},
+		{"no name", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+				config.ModelName = tt.configured
+				if tt.model != "" {
+					config.Model = &namedModel{fakeModel: newFakeModel(pythonTestResponse), name: tt.model}
+				}
+			})
+			if err := sw.SubmitSymTask(sourcePath); err != nil {
+				t.Fatalf("SubmitSymTask: %v", err)
+			}
+			if model := sw.Report().Tasks[0].Model; model != tt.want {
+				t.Errorf("model in the report = %q, want %q", model, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package worker
+
+import "sync"
+
+// TaskResult is the outcome of a finished task, sent on the Results channel of
+// the DeepWorker.
+//
+// Fields:
+// - SourcePath: The path of the source file the task tested.
+// - FunctionName: The function the task tested, empty for whole-file tasks.
+// - BestCoverage: The best coverage any iteration of the task achieved.
+// - Iterations: The number of iterations the task ran.
+// - Err: The error the task failed or timed out with, nil if it succeeded.
+type TaskResult struct {
+	SourcePath   string
+	FunctionName string
+	BestCoverage float64
+	Iterations   int
+	Err          error
+}
+
+// taskResults hands the results of finished tasks to the Results channel. The
+// results are queued without bound and sent by a goroutine of their own, so
+// finishing a task never blocks on a caller that reads the channel slowly or
+// not at all. The goroutine is started by the first call of Results; until then
+// the results are only queued.
+type taskResults struct {
+	mu      sync.Mutex
+	pending []TaskResult
+	closed  bool
+	wake    chan struct{}
+	out     chan TaskResult
+	start   sync.Once
+}
+
+func newTaskResults() *taskResults {
+	return &taskResults{
+		wake: make(chan struct{}, 1),
+		out:  make(chan TaskResult),
+	}
+}
+
+// publish queues the result, unless the results are closed.
+func (tr *taskResults) publish(result TaskResult) {
+	tr.mu.Lock()
+	if tr.closed {
+		tr.mu.Unlock()
+		return
+	}
+	tr.pending = append(tr.pending, result)
+	tr.mu.Unlock()
+	tr.signal()
+}
+
+// close makes the channel close once the queued results are sent.
+func (tr *taskResults) close() {
+	tr.mu.Lock()
+	tr.closed = true
+	tr.mu.Unlock()
+	tr.signal()
+}
+
+// channel returns the channel the results are sent on, starting to send them.
+func (tr *taskResults) channel() <-chan TaskResult {
+	tr.start.Do(func() {
+		go tr.forward()
+	})
+	return tr.out
+}
+
+func (tr *taskResults) signal() {
+	select {
+	case tr.wake <- struct{}{}:
+	default:
+	}
+}
+
+// forward sends the queued results in the order they were published and closes
+// the channel once the results are closed and every result is sent.
+func (tr *taskResults) forward() {
+	for {
+		tr.mu.Lock()
+		if len(tr.pending) > 0 {
+			result := tr.pending[0]
+			tr.pending = tr.pending[1:]
+			tr.mu.Unlock()
+			tr.out <- result
+			continue
+		}
+		if tr.closed {
+			tr.mu.Unlock()
+			close(tr.out)
+			return
+		}
+		tr.mu.Unlock()
+		<-tr.wake
+	}
+}
+
+// Results returns the channel receiving a TaskResult for every task that
+// finishes, whether it succeeded, failed or timed out, in the order the tasks
+// finished. Results of tasks that finished before the first call are kept and
+// sent too. The channel is closed by Shutdown once the remaining results are
+// received, so it can be ranged over.
+func (dw *DeepWorker) Results() <-chan TaskResult {
+	return dw.taskResults.channel()
+}
+
+// Done returns a channel that is closed once no submitted task is active any
+// more, i.e. every task has finished and none is queued for another
+// iteration. If no task is active when Done is called, the returned channel is
+// already closed; tasks submitted after the channel was closed are waited for
+// by the channel of the next call.
+func (dw *DeepWorker) Done() <-chan struct{} {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	if len(dw.activeTasks) == 0 {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	if dw.idle == nil {
+		dw.idle = make(chan struct{})
+	}
+	return dw.idle
+}
+
+// signalIdle closes the channel returned by Done once the last active task is
+// gone. The caller must hold the worker's mutex.
+func (dw *DeepWorker) signalIdle() {
+	if len(dw.activeTasks) == 0 && dw.idle != nil {
+		close(dw.idle)
+		dw.idle = nil
+	}
+}
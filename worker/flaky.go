@@ -0,0 +1,29 @@
+package worker
+
+import "log"
+
+// isFlaky runs the passing test of the task again flakinessCheck times through
+// the structured callback and reports whether any run gave a different result,
+// a different number of passed or failed test cases or an error, than the first
+// one. Without a structured callback there are no test counts to compare and no
+// test is considered flaky. The reruns bypass the callback cache, which would
+// return the first result every time.
+func (dw *DeepWorker) isFlaky(task *TestTask, testCode string, first *TestResult) bool {
+	if dw.flakinessCheck <= 0 || dw.structuredCallback == nil {
+		return false
+	}
+
+	for run := 1; run <= dw.flakinessCheck; run++ {
+		result, err := dw.executeCallback(task.SourceCode, testCode, task.testPath())
+		if err != nil {
+			log.Printf("Test for %s is flaky: rerun %d of %d failed: %v", task.key(), run, dw.flakinessCheck, err)
+			return true
+		}
+		if result.Passed != first.Passed || result.Failed != first.Failed {
+			log.Printf("Test for %s is flaky: rerun %d of %d had %d passed and %d failed test cases instead of %d and %d",
+				task.key(), run, dw.flakinessCheck, result.Passed, result.Failed, first.Passed, first.Failed)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// runFlakinessCheck runs a task with the results of results, cycled through,
+// and returns the report entry of the task and the number of callback runs.
+func runFlakinessCheck(t *testing.T, results []TestResult) (TaskReport, int) {
+	t.Helper()
+	var mu sync.Mutex
+	runs := 0
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.Callback = nil
+		config.FlakinessCheck = 3
+		config.StructuredCallback = func(sourceCode, testCode, testPath string) (*TestResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			result := results[runs%len(results)]
+			runs++
+			return &result, nil
+		}
+	})
+	dw.Run()
+	if err := dw.SubmitTask("", "a.py"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-dw.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not finish")
+	}
+	dw.Shutdown()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return dw.Report().Tasks[0], runs
+}
+
+func TestFlakinessCheckFlagsAlternatingResults(t *testing.T) {
+	task, runs := runFlakinessCheck(t, []TestResult{
+		{Coverage: 1, Passed: 2},
+		{Coverage: 1, Passed: 1, Failed: 1},
+	})
+	if !task.Flaky {
+		t.Error("test alternating between passing and failing is not flagged as flaky")
+	}
+	// The check stops at the first rerun that differs
+	if runs != 2 {
+		t.Errorf("callback ran %d times, want 2", runs)
+	}
+}
+
+func TestFlakinessCheckAcceptsStableResults(t *testing.T) {
+	task, runs := runFlakinessCheck(t, []TestResult{{Coverage: 1, Passed: 2}})
+	if task.Flaky {
+		t.Error("test with the same result every run is flagged as flaky")
+	}
+	if runs != 4 {
+		t.Errorf("callback ran %d times, want the first run and 3 reruns", runs)
+	}
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// Formatter formats generated test code of the given language.
+type Formatter func(code, codeType string) (string, error)
+
+// formatterCommands are the commands DefaultFormatter pipes code through, per
+// language. Each reads the code from stdin and writes the result to stdout.
+var formatterCommands = map[string][]string{
+	"python": {"black", "-q", "-"},
+	"go":     {"gofmt"},
+	"java":   {"google-java-format", "-"},
+}
+
+// DefaultFormatter formats code with the standard formatter of its language:
+// black for Python, gofmt for Go and google-java-format for Java.
+// It fails if the language has no formatter or the formatter is not installed.
+func DefaultFormatter(code, codeType string) (string, error) {
+	args, ok := formatterCommands[codeType]
+	if !ok {
+		return "", fmt.Errorf("no formatter available for %s", codeType)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(code)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// format runs the configured formatter over the generated code. Without a
+// formatter the code is returned as is, and if formatting fails the unformatted
+// code is kept with a warning instead of failing the task.
+func (dw *DeepWorker) format(code, codeType string) string {
+	if dw.formatter == nil || code == "" {
+		return code
+	}
+
+	formatted, err := dw.formatter(code, codeType)
+	if err != nil {
+		log.Printf("Warning: failed to format generated %s test, keeping it unformatted: %v", codeType, err)
+		return code
+	}
+	return formatted
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+)
+
+// unformattedTest is the test of pythonTestResponse once the import of the
+// module under test is added.
+const unformattedTest = "from calc import *\n\ndef test_add():\n    assert add(1, 2) == 3"
+
+// writeFormattedTest writes the test of pythonTestResponse through a worker
+// formatting generated tests with formatter and returns the written file.
+func writeFormattedTest(t *testing.T, formatter Formatter) string {
+	t.Helper()
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.FormatGenerated = true
+		config.Formatter = formatter
+	})
+	dw.fileIO = &fileio.SimpleFileIO{}
+
+	dir := t.TempDir()
+	task := &TestTask{
+		SourcePath: filepath.Join(dir, "calc.py"),
+		TestPath:   filepath.Join(dir, "calc_test.py"),
+		CodeType:   "python",
+	}
+	if _, err := dw.writeTestFiles(task, pythonTestResponse); err != nil {
+		t.Fatalf("writeTestFiles: %v", err)
+	}
+	written, err := os.ReadFile(task.TestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(written)
+}
+
+func TestFormatGeneratedWritesFormatterOutput(t *testing.T) {
+	var formatted []string
+	written := writeFormattedTest(t, func(code, codeType string) (string, error) {
+		formatted = append(formatted, codeType)
+		return "# formatted\n" + code, nil
+	})
+
+	if len(formatted) != 1 || formatted[0] != "python" {
+		t.Errorf("formatter called for %v, want the python test once", formatted)
+	}
+	if want := "# formatted\n" + unformattedTest; written != want {
+		t.Errorf("written test = %q, want %q", written, want)
+	}
+}
+
+func TestFormatGeneratedKeepsCodeWhenFormatterFails(t *testing.T) {
+	written := writeFormattedTest(t, func(code, codeType string) (string, error) {
+		return "", errors.New("black: command not found")
+	})
+
+	if written != unformattedTest {
+		t.Errorf("written test = %q, want the unformatted %q", written, unformattedTest)
+	}
+}
+
+func TestDefaultFormatterRunsGofmt(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt is not installed")
+	}
+
+	formatted, err := DefaultFormatter("package calc\nfunc  Add(a,b int) int {return a+b}\n", "go")
+	if err != nil {
+		t.Fatalf("DefaultFormatter: %v", err)
+	}
+	if want := "package calc\n\nfunc Add(a, b int) int { return a + b }\n"; formatted != want {
+		t.Errorf("formatted = %q, want %q", formatted, want)
+	}
+	if _, err := DefaultFormatter("x = 1", "ruby"); err == nil {
+		t.Error("DefaultFormatter formatted a language without a formatter")
+	}
+}
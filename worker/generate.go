@@ -0,0 +1,292 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/model"
+	"github.com/Marksagittarius/pinguis/postprocessor"
+	"github.com/cloudwego/eino/schema"
+)
+
+// maxContinuations is the number of times a truncated response is continued
+// before the worker gives up and uses what it has.
+const maxContinuations = 2
+
+// truncationTailLength is the number of trailing characters of a truncated
+// response quoted in the continuation prompt.
+const truncationTailLength = 200
+
+// danglingLineEndings are line endings after which code cannot end.
+var danglingLineEndings = []string{":", ",", "\\", "(", "[", "{", "=", "+", "-", "*", "/", "&&", "||", " and", " or"}
+
+// looksTruncated reports whether the model's response seems to have been cut off:
+// a code fence is left open, the brackets of the code extracted from it as by
+// responseCode are unbalanced, or the code ends in the middle of a statement.
+func (dw *DeepWorker) looksTruncated(content, codeType string) bool {
+	if strings.Count(content, "```")%2 == 1 {
+		return true
+	}
+
+	code := dw.responseCode(content, codeType)
+	if code == "" {
+		return false
+	}
+	if !bracketsBalanced(code, codeType) {
+		return true
+	}
+
+	lines := strings.Split(strings.TrimRight(code, " \t\n"), "\n")
+	lastLine := strings.TrimSpace(lines[len(lines)-1])
+	for _, ending := range danglingLineEndings {
+		if strings.HasSuffix(lastLine, ending) {
+			return true
+		}
+	}
+	return false
+}
+
+// bracketsBalanced reports whether every (, [ and { of the code is closed.
+// Brackets inside string literals and comments of the code type are skipped; a
+// multi-line string or block comment left open counts as unbalanced. It is a
+// heuristic rather than a parser.
+func bracketsBalanced(code, codeType string) bool {
+	hashComments := codeType == "python"
+	tripleQuotes := codeType == "python" || codeType == "kotlin"
+	multiLineBackticks := codeType == "go" || codeType == "javascript"
+
+	depth := map[byte]int{}
+	closing := map[byte]byte{')': '(', ']': '[', '}': '{'}
+	for i := 0; i < len(code); i++ {
+		rest := code[i:]
+		switch {
+		case hashComments && rest[0] == '#', !hashComments && strings.HasPrefix(rest, "//"):
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(code)
+			}
+		case !hashComments && strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return false
+			}
+			i += 2 + end + 1
+		case tripleQuotes && (strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''")):
+			end := strings.Index(rest[3:], rest[:3])
+			if end < 0 {
+				return false
+			}
+			i += 3 + end + 2
+		case multiLineBackticks && rest[0] == '`':
+			end := strings.IndexByte(rest[1:], '`')
+			if end < 0 {
+				return false
+			}
+			i += 1 + end
+		case rest[0] == '"' || rest[0] == '\'':
+			i += quotedLength(rest) - 1
+		case rest[0] == '(' || rest[0] == '[' || rest[0] == '{':
+			depth[rest[0]]++
+		case rest[0] == ')' || rest[0] == ']' || rest[0] == '}':
+			depth[closing[rest[0]]]--
+		}
+	}
+	return depth['('] <= 0 && depth['['] <= 0 && depth['{'] <= 0
+}
+
+// quotedLength returns the length of the single-line string or character
+// literal code starts with, up to its closing quote, or up to the end of the line
+// if it is not closed on it.
+func quotedLength(code string) int {
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case code[0]:
+			return i + 1
+		case '\n':
+			return i
+		}
+	}
+	return len(code)
+}
+
+// continuationPrompt asks the model to continue a truncated response.
+func continuationPrompt(prompt, partial string) string {
+	tail := partial
+	if len(tail) > truncationTailLength {
+		tail = tail[len(tail)-truncationTailLength:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nYour previous answer was cut off. It ended with:\n")
+	sb.WriteString(tail)
+	sb.WriteString("\n\nContinue exactly where it stopped. Do not repeat anything that was already written.\n")
+	return sb.String()
+}
+
+// joinContinuation appends the continuation to the truncated response. If the
+// truncated response left a code fence open, a fence the continuation reopens
+// is dropped.
+func joinContinuation(partial, continuation string) string {
+	if strings.Count(partial, "```")%2 == 1 {
+		trimmed := strings.TrimLeft(continuation, " \t\n")
+		if strings.HasPrefix(trimmed, "```") {
+			if _, rest, found := strings.Cut(trimmed, "\n"); found {
+				continuation = rest
+			}
+		}
+	}
+	return partial + continuation
+}
+
+// generate asks the model for a response to the prompt of the task, limited to
+// the configured output token budget. Responses that look truncated are
+// continued from where they were cut off, at most maxContinuations times and
+// only while the run's retry budget lasts. The tokens of every prompt sent are
+// added to the task's PromptTokens.
+func (dw *DeepWorker) generate(ctx context.Context, prompt string, task *TestTask) (*schema.Message, error) {
+	if dw.maxOutputTokens > 0 {
+		ctx = model.WithMaxOutputTokens(ctx, dw.maxOutputTokens)
+	}
+	codeType := task.CodeType
+
+	task.PromptTokens += dw.tokenizer.Count(prompt)
+	msg, err := dw.model.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < maxContinuations && dw.looksTruncated(msg.Content, codeType); i++ {
+		if !dw.retries.take() {
+			log.Printf("Model response looks truncated, but the retry budget is exhausted")
+			break
+		}
+		log.Printf("Model response looks truncated, requesting continuation %d/%d", i+1, maxContinuations)
+
+		request := continuationPrompt(prompt, msg.Content)
+		task.PromptTokens += dw.tokenizer.Count(request)
+		continuation, err := dw.model.Generate(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("continuation failed: %w", err)
+		}
+		msg = &schema.Message{
+			Role:    msg.Role,
+			Content: joinContinuation(msg.Content, continuation.Content),
+		}
+	}
+
+	return msg, nil
+}
+
+// maxNonTestRetries is the number of times the model is asked again when its
+// response does not contain a test.
+const maxNonTestRetries = 2
+
+// nonTestReminder is appended to the prompt when the model answered with
+// something other than a test, e.g. the implementation under test.
+const nonTestReminder = "\n\nYour previous answer did not contain a test. " +
+	"Respond only with test code that defines test functions and asserts on the behavior of the code under test.\n"
+
+// responseContainsTest reports whether the code blocks of the response look
+// like a test in the given language.
+func (dw *DeepWorker) responseContainsTest(content, codeType string) bool {
+	return postprocessor.IsLikelyTest(dw.responseCode(content, codeType), codeType)
+}
+
+// responseCode joins the code blocks of the response (see responseBlocks).
+func (dw *DeepWorker) responseCode(content, codeType string) string {
+	return strings.Join(dw.responseBlocks(content, codeType), "\n\n")
+}
+
+// rejectResponse returns a description of what the response lacks and the
+// reminder to ask the model again with, or empty strings if the response is an
+// acceptable test for the task.
+func (dw *DeepWorker) rejectResponse(content string, task *TestTask) (string, string) {
+	codeType := task.CodeType
+	if !dw.responseContainsTest(content, codeType) {
+		return "a test", nonTestReminder
+	}
+	if framework, ok := dw.propertyFramework(codeType); ok && !usesPropertyFramework(dw.responseCode(content, codeType), framework) {
+		return "a property-based test", propertyReminder(framework)
+	}
+	return dw.checkTestCount(dw.responseCode(content, codeType), task)
+}
+
+// checkTestCount enforces the configured number of test functions for tasks
+// targeting a single function. Responses without any test function the naming
+// convention of the language recognizes are not counted.
+func (dw *DeepWorker) checkTestCount(code string, task *TestTask) (string, string) {
+	if task.FunctionName == "" {
+		return "", ""
+	}
+	count := postprocessor.CountTestFunctions(code, task.CodeType)
+	if count == 0 {
+		return "", ""
+	}
+
+	if dw.maxTestsPerFunction > 0 && count > dw.maxTestsPerFunction {
+		return fmt.Sprintf("at most %d tests", dw.maxTestsPerFunction), fmt.Sprintf(
+			"\n\nYour previous answer contained %d test functions. Write at most %d test functions for %s, "+
+				"keeping the ones that check distinct behavior.\n", count, dw.maxTestsPerFunction, task.FunctionName)
+	}
+	if count < dw.minTestsPerFunction {
+		return fmt.Sprintf("at least %d tests", dw.minTestsPerFunction), fmt.Sprintf(
+			"\n\nYour previous answer contained only %d test functions. Write at least %d test functions for %s.\n",
+			count, dw.minTestsPerFunction, task.FunctionName)
+	}
+	return "", ""
+}
+
+// trimTests returns the response with the tests after the first
+// maxTestsPerFunction removed, if that leaves an acceptable test for the task.
+func (dw *DeepWorker) trimTests(content string, task *TestTask) (string, bool) {
+	if task.FunctionName == "" || dw.maxTestsPerFunction <= 0 {
+		return "", false
+	}
+	trimmed, ok := postprocessor.TrimTestFunctions(content, task.CodeType, dw.maxTestsPerFunction)
+	if !ok {
+		return "", false
+	}
+	if missing, _ := dw.rejectResponse(trimmed, task); missing != "" {
+		return "", false
+	}
+	return trimmed, true
+}
+
+// generateTest generates a response for the task like generate, but asks the
+// model again if the response is not an acceptable test (see rejectResponse),
+// at most maxNonTestRetries times. Every retry is drawn from the run's retry
+// budget. Once the model was asked as often as allowed, a response with more
+// tests than maxTestsPerFunction is trimmed to its first tests; the task fails
+// only if the response cannot be made acceptable that way.
+func (dw *DeepWorker) generateTest(ctx context.Context, prompt string, task *TestTask) (*schema.Message, error) {
+	msg, err := dw.generate(ctx, prompt, task)
+	for retry := 0; err == nil; retry++ {
+		missing, reminder := dw.rejectResponse(msg.Content, task)
+		if missing == "" {
+			break
+		}
+
+		var giveUp error
+		if retry == maxNonTestRetries {
+			giveUp = fmt.Errorf("model did not return %s after %d attempts", missing, retry+1)
+		} else if !dw.retries.take() {
+			giveUp = fmt.Errorf("model did not return %s after %d attempts: %w", missing, retry+1, errRetryBudgetExhausted)
+		}
+		if giveUp != nil {
+			if trimmed, ok := dw.trimTests(msg.Content, task); ok {
+				log.Printf("Model response has more than %d tests after %d attempts, keeping the first %d", dw.maxTestsPerFunction, retry+1, dw.maxTestsPerFunction)
+				return &schema.Message{Role: msg.Role, Content: trimmed}, nil
+			}
+			return nil, giveUp
+		}
+		log.Printf("Model response is not %s, asking again (%d/%d)", missing, retry+1, maxNonTestRetries)
+		msg, err = dw.generate(ctx, prompt+reminder, task)
+	}
+	return msg, err
+}
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/postprocessor"
+)
+
+// manyTestsResponse holds four tests of add.
+const manyTestsResponse = "```python\n" +
+	"def test_add_small():\n    assert add(1, 2) == 3\n\n" +
+	"def test_add_zero():\n    assert add(0, 0) == 0\n\n" +
+	"def test_add_negative():\n    assert add(-1, 1) == 0\n\n" +
+	"def test_add_large():\n    assert add(10, 20) == 30\n```"
+
+func TestGenerateTestTrimsTestsOverTheCap(t *testing.T) {
+	m := newFakeModel(manyTestsResponse)
+	dw := newTestWorker(m, func(config *DeepWorkerConfig) {
+		config.MaxTestsPerFunction = 2
+	})
+	task := &TestTask{SourcePath: "calc.py", FunctionName: "add", CodeType: "python"}
+
+	msg, err := dw.generateTest(context.Background(), "prompt", task)
+	if err != nil {
+		t.Fatalf("generateTest: %v", err)
+	}
+	if count := postprocessor.CountTestFunctions(msg.Content, "python"); count != 2 {
+		t.Errorf("response has %d tests, want the cap of 2:\n%s", count, msg.Content)
+	}
+	if !strings.Contains(msg.Content, "test_add_small") || !strings.Contains(msg.Content, "test_add_zero") {
+		t.Errorf("response = %q, want the first two tests", msg.Content)
+	}
+	if prompts := len(m.recorded()); prompts != maxNonTestRetries+1 {
+		t.Errorf("model was asked %d times, want %d", prompts, maxNonTestRetries+1)
+	}
+}
+
+func TestGenerateTestFailsWithTooFewTests(t *testing.T) {
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.MinTestsPerFunction = 2
+	})
+	task := &TestTask{SourcePath: "calc.py", FunctionName: "add", CodeType: "python"}
+
+	if _, err := dw.generateTest(context.Background(), "prompt", task); err == nil {
+		t.Error("generateTest accepted a response with fewer tests than the minimum")
+	}
+}
+
+func TestBracketsBalancedSkipsStringsAndComments(t *testing.T) {
+	cases := []struct {
+		code     string
+		codeType string
+		want     bool
+	}{
+		{"assert parse(\"(1\") == 1  # a ( in the comment\n", "python", true},
+		{"s = '''\nunclosed ( [\n'''\nassert f(s)\n", "python", true},
+		{"assert add(1,\n", "python", false},
+		{"doc = \"\"\"cut off (\n", "python", false},
+		{"x = a // 2\nassert f(x)\n", "python", true},
+		{"// wraps f(\nfunc TestF(t *testing.T) { f(\"{\") }\n", "go", true},
+		{"var s = `raw ( {\n`\n", "go", true},
+		{"/* block ( */ char c = '(';\n", "java", true},
+		{"/* cut off (\n", "java", false},
+		{"assertEquals(f(\"a\\\"(\"), 1)\n", "java", true},
+		{"func TestF(t *testing.T) {\n\tf(\n", "go", false},
+	}
+	for _, c := range cases {
+		if got := bracketsBalanced(c.code, c.codeType); got != c.want {
+			t.Errorf("bracketsBalanced(%q, %s) = %v, want %v", c.code, c.codeType, got, c.want)
+		}
+	}
+}
+
+func TestRetryBudgetIsSharedByTasks(t *testing.T) {
+	m := newFakeModel("I cannot write tests for this function.")
+	dw := newTestWorker(m, func(config *DeepWorkerConfig) {
+		config.MaxTotalRetries = 3
+	})
+	first := &TestTask{SourcePath: "a.py", CodeType: "python"}
+	second := &TestTask{SourcePath: "b.py", CodeType: "python"}
+
+	// The first task asks again as often as it may per task, leaving one retry
+	if _, err := dw.generateTest(context.Background(), "prompt", first); err == nil {
+		t.Fatal("generateTest accepted a response without a test")
+	}
+	if prompts := len(m.recorded()); prompts != maxNonTestRetries+1 {
+		t.Fatalf("model was asked %d times for the first task, want %d", prompts, maxNonTestRetries+1)
+	}
+
+	_, err := dw.generateTest(context.Background(), "prompt", second)
+	if !errors.Is(err, errRetryBudgetExhausted) {
+		t.Errorf("second task error = %v, want the retry budget to be exhausted", err)
+	}
+	if prompts := len(m.recorded()) - (maxNonTestRetries + 1); prompts != 2 {
+		t.Errorf("model was asked %d times for the second task, want 2", prompts)
+	}
+	if left := dw.RemainingRetries(); left != 0 {
+		t.Errorf("%d retries left, want 0", left)
+	}
+}
+
+func TestRetryBudgetStopsContinuations(t *testing.T) {
+	m := newFakeModel("```python\ndef test_add():\n    assert add(1,\n")
+	dw := newTestWorker(m, func(config *DeepWorkerConfig) {
+		config.MaxTotalRetries = 1
+	})
+
+	for _, path := range []string{"a.py", "b.py"} {
+		task := &TestTask{SourcePath: path, CodeType: "python"}
+		if _, err := dw.generate(context.Background(), "prompt", task); err != nil {
+			t.Fatalf("generate %s: %v", path, err)
+		}
+	}
+	// Only the first task gets its one continuation
+	if prompts := len(m.recorded()); prompts != 3 {
+		t.Errorf("model was asked %d times, want 3", prompts)
+	}
+}
@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+	"github.com/cloudwego/eino/schema"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// pythonTestResponse is a model response holding an acceptable Python test.
+const pythonTestResponse = "```python\ndef test_add():\n    assert add(1, 2) == 3\n```"
+
+// fakeModel answers every prompt with a fixed response and records the prompts
+// in the order it received them. If block is set, every call waits for it to be
+// closed after recording its prompt.
+type fakeModel struct {
+	mu       sync.Mutex
+	response string
+	prompts  []string
+	received chan string
+	block    chan struct{}
+}
+
+func newFakeModel(response string) *fakeModel {
+	return &fakeModel{response: response, received: make(chan string, 100)}
+}
+
+func (m *fakeModel) Generate(ctx context.Context, prompt string) (*schema.Message, error) {
+	m.mu.Lock()
+	m.prompts = append(m.prompts, prompt)
+	m.mu.Unlock()
+	m.received <- prompt
+	if m.block != nil {
+		select {
+		case <-m.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &schema.Message{Role: schema.Assistant, Content: m.response}, nil
+}
+
+func (m *fakeModel) recorded() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.prompts...)
+}
+
+// fullCoverage is a test callback reporting full coverage for every test.
+func fullCoverage(sourceCode, testCode, testPath string) (float64, string, error) {
+	return 1, "ok", nil
+}
+
+// newTestWorker creates a worker with the fake model, a callback reporting full
+// coverage and a prompt generator prompting with the source path, with the
+// config adjusted by configure.
+func newTestWorker(m *fakeModel, configure func(*DeepWorkerConfig)) *DeepWorker {
+	config := &DeepWorkerConfig{
+		WorkerCount:     1,
+		Model:           m,
+		Callback:        fullCoverage,
+		MaxIterations:   1,
+		PromptGenerator: func(task *TestTask) string { return task.SourcePath },
+	}
+	if configure != nil {
+		configure(config)
+	}
+	dw, err := NewDeepWorkerChecked(config)
+	if err != nil {
+		panic(err)
+	}
+	return dw
+}
+
+// parseSymFunction parses the code of the code type and returns its function
+// called name, failing the test if there is none.
+func parseSymFunction(t *testing.T, code, codeType, name string) symFunction {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	t.Cleanup(parser.Close)
+	parser.SetLanguage(tree_sitter.NewLanguage(symLanguages[codeType].grammar()))
+	tree := parser.Parse([]byte(code), nil)
+	t.Cleanup(tree.Close)
+
+	for _, fn := range symLanguages[codeType].collectFunctions(tree.RootNode(), code) {
+		if fn.Name == name {
+			return fn
+		}
+	}
+	t.Fatalf("no function %s in the %s code", name, codeType)
+	return symFunction{}
+}
+
+// describedPaths returns the conditions and the outcome of every minimized path
+// through the function, joined into one line per path, e.g. "x > 0 -> return:y".
+func describedPaths(paths [][]string) []string {
+	var described []string
+	for _, path := range paths {
+		line := strings.Join(symPathConditions(path), " and ")
+		if outcome, ok := pathOutcome(path); ok {
+			line += " -> " + outcome
+		}
+		described = append(described, line)
+	}
+	return described
+}
+
+// newTestSymWorker creates a symbolic worker like newTestWorker, reading and
+// writing files on disk and prompting with a fixed template.
+func newTestSymWorker(m *fakeModel, configure func(*DeepWorkerConfig)) *SymPromptWorker {
+	dw := newTestWorker(m, func(config *DeepWorkerConfig) {
+		config.PromptSource = func() (string, error) { return "Test {code}", nil }
+		if configure != nil {
+			configure(config)
+		}
+	})
+	dw.fileIO = &fileio.SimpleFileIO{}
+	return &SymPromptWorker{DeepWorker: dw}
+}
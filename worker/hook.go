@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+)
+
+// TestWrittenHook is called once for every file the worker wrote a generated
+// test to, when the task generating it is finished, so it sees the final test
+// rather than those of intermediate iterations. It can e.g. run a linter over the
+// test or record it in a manifest.
+type TestWrittenHook func(sourcePath, testPath string) error
+
+// testWritten runs the configured hook for a test file written for the source
+// file. A failing hook only logs a warning, unless the worker is configured to
+// fail the task, in which case the hook's error is returned.
+func (dw *DeepWorker) testWritten(sourcePath, testPath string) error {
+	if dw.onTestWritten == nil {
+		return nil
+	}
+
+	if err := dw.onTestWritten(sourcePath, testPath); err != nil {
+		if dw.failOnHookError {
+			return fmt.Errorf("test written hook failed for %s: %w", testPath, err)
+		}
+		log.Printf("Warning: test written hook failed for %s: %v", testPath, err)
+	}
+	return nil
+}
+
+// addWrittenFile records a test file written for the task, once however often
+// the iterations of the task rewrite it.
+func (t *TestTask) addWrittenFile(path string) {
+	for _, written := range t.writtenFiles {
+		if written == path {
+			return
+		}
+	}
+	t.writtenFiles = append(t.writtenFiles, path)
+}
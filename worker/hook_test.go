@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// hookCall is a call of a test written hook.
+type hookCall struct {
+	SourcePath, TestPath string
+}
+
+func TestTestWrittenHookRunsOncePerWrittenTest(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+
+	var calls []hookCall
+	lowCoverage := func(sourceCode, testCode, testPath string) (float64, string, error) {
+		return 0.1, "low", nil
+	}
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.Callback = lowCoverage
+		config.CoverageThreshold = 0.9
+		config.MaxIterations = 3
+		config.OnTestWritten = func(sourcePath, testPath string) error {
+			calls = append(calls, hookCall{sourcePath, testPath})
+			return nil
+		}
+	})
+
+	if err := sw.SubmitSymTaskForFunction(sourcePath, "add"); err != nil {
+		t.Fatalf("SubmitSymTaskForFunction: %v", err)
+	}
+
+	want := []hookCall{{sourcePath, filepath.Join(dir, "calc_add_test_case_1.py")}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls = %v, want %v", calls, want)
+	}
+	if iterations := sw.Report().Tasks[0].Iterations; iterations != 3 {
+		t.Errorf("task ran %d iterations, want 3", iterations)
+	}
+}
+
+func TestTestWrittenHookErrorFailsTask(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.OnTestWritten = func(sourcePath, testPath string) error {
+			return errors.New("lint failed")
+		}
+		config.FailOnHookError = true
+	})
+
+	if err := sw.SubmitSymTaskForFunction(sourcePath, "add"); err != nil {
+		t.Fatalf("SubmitSymTaskForFunction: %v", err)
+	}
+	if task := sw.Report().Tasks[0]; task.Status != TaskFailed {
+		t.Errorf("task status = %s, want %s", task.Status, TaskFailed)
+	}
+}
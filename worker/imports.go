@@ -0,0 +1,158 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	pythonFromImportPattern = regexp.MustCompile(`^(\s*)from\s+([\w.]+)\s+import\s+(.+)$`)
+	pythonImportPattern     = regexp.MustCompile(`^(\s*)import\s+([\w.]+)(\s+as\s+\w+)?\s*$`)
+	pythonFutureImport      = regexp.MustCompile(`^from\s+__future__\s+import\b`)
+)
+
+// pythonModulePath returns the dotted module name of a Python source file and
+// the directory that has to be on sys.path to import it under that name.
+// Enclosing directories containing an __init__.py are part of the module name.
+func pythonModulePath(sourcePath string) (module string, importRoot string) {
+	root := filepath.Dir(sourcePath)
+	parts := []string{strings.TrimSuffix(filepath.Base(sourcePath), filepath.Ext(sourcePath))}
+
+	for {
+		if _, err := os.Stat(filepath.Join(root, "__init__.py")); err != nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			break
+		}
+		parts = append([]string{filepath.Base(root)}, parts...)
+		root = parent
+	}
+
+	return strings.Join(parts, "."), root
+}
+
+// pythonImportPreamble returns the lines putting importRoot on sys.path for a
+// test file in testDir, or an empty string if the test directory is the import
+// root itself.
+func pythonImportPreamble(testDir, importRoot string) string {
+	rel, err := filepath.Rel(testDir, importRoot)
+	if err != nil || rel == "." {
+		return ""
+	}
+
+	return fmt.Sprintf("import os\nimport sys\n\nsys.path.insert(0, os.path.abspath(os.path.join(os.path.dirname(__file__), %q)))\n",
+		filepath.ToSlash(rel))
+}
+
+// fixPythonImports makes the generated test import the module under test from the
+// test file's location. Imports of the module under a wrong path are rewritten to
+// its dotted module name, an import of everything it defines is added if the test
+// does not import it at all, and the module's import root is put on sys.path when
+// the test lives in another directory (e.g. a tests/ subdirectory).
+func fixPythonImports(testCode, testPath, sourcePath string) string {
+	module, importRoot := pythonModulePath(sourcePath)
+
+	// A wrong path either drops leading packages of the module or puts the
+	// directories above its import root in front of it, e.g. src.calc for calc in
+	// src/. Names only ending in the same word, e.g. django.utils for a module
+	// utils, are other modules.
+	importDirs := strings.Split(filepath.ToSlash(importRoot), "/")
+	matchesModule := func(name string) bool {
+		if name == module || strings.HasSuffix(module, "."+name) {
+			return true
+		}
+		prefix, ok := strings.CutSuffix(name, "."+module)
+		if !ok {
+			return false
+		}
+		packages := strings.Split(prefix, ".")
+		return len(packages) <= len(importDirs) &&
+			slices.Equal(packages, importDirs[len(importDirs)-len(packages):])
+	}
+
+	lines := strings.Split(testCode, "\n")
+	imported := false
+	for i, line := range lines {
+		if m := pythonFromImportPattern.FindStringSubmatch(line); m != nil && matchesModule(m[2]) {
+			lines[i] = fmt.Sprintf("%sfrom %s import %s", m[1], module, m[3])
+			imported = true
+		} else if m := pythonImportPattern.FindStringSubmatch(line); m != nil && matchesModule(m[2]) {
+			alias := m[3]
+			if alias == "" && m[2] != module {
+				alias = " as " + m[2][strings.LastIndex(m[2], ".")+1:]
+			}
+			lines[i] = fmt.Sprintf("%simport %s%s", m[1], module, alias)
+			imported = true
+		}
+	}
+
+	header := pythonImportPreamble(filepath.Dir(testPath), importRoot)
+	if !imported {
+		header += fmt.Sprintf("from %s import *\n", module)
+	}
+	if header == "" {
+		return strings.Join(lines, "\n")
+	}
+
+	// __future__ imports must stay at the top of the file
+	insertAt := 0
+	for insertAt < len(lines) && pythonFutureImport.MatchString(lines[insertAt]) {
+		insertAt++
+	}
+
+	fixed := append([]string{}, lines[:insertAt]...)
+	fixed = append(fixed, strings.TrimSuffix(header, "\n"), "")
+	fixed = append(fixed, lines[insertAt:]...)
+	return strings.Join(fixed, "\n")
+}
+
+// pythonImportedModules returns the dotted names of the modules the Python code
+// imports absolutely, in the order they are imported. For a from-import the
+// imported names are listed as submodules as well, e.g. "pkg" and "pkg.calc"
+// for `from pkg import calc`, since either may be the module.
+func pythonImportedModules(code string) []string {
+	var modules []string
+	for _, line := range strings.Split(code, "\n") {
+		if m := pythonFromImportPattern.FindStringSubmatch(line); m != nil {
+			modules = append(modules, m[2])
+			for _, name := range strings.Split(strings.Trim(m[3], "() \t"), ",") {
+				if fields := strings.Fields(name); len(fields) > 0 && fields[0] != "*" {
+					modules = append(modules, m[2]+"."+fields[0])
+				}
+			}
+		} else if m := pythonImportPattern.FindStringSubmatch(line); m != nil {
+			modules = append(modules, m[2])
+		}
+	}
+	return modules
+}
+
+// findImportedSourceModule returns the name under which the test imports the
+// module whose code is sourceCode, looking the imported modules up in the test
+// directory and the directories above it, where a test in a subdirectory puts
+// the import root of the module on sys.path. It returns false if the test does
+// not import the source module, e.g. only helpers next to it.
+func findImportedSourceModule(testCode, testDir, sourceCode string) (string, bool) {
+	source := strings.TrimSpace(sourceCode)
+	for _, module := range pythonImportedModules(testCode) {
+		rel := filepath.FromSlash(strings.ReplaceAll(module, ".", "/"))
+		for dir := testDir; ; dir = filepath.Dir(dir) {
+			for _, candidate := range []string{rel + ".py", filepath.Join(rel, "__init__.py")} {
+				code, err := os.ReadFile(filepath.Join(dir, candidate))
+				if err == nil && strings.TrimSpace(string(code)) == source {
+					return module, true
+				}
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return "", false
+}
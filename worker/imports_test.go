@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixPythonImportsFromTestLocation(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "src", "calc.py")
+	if err := os.MkdirAll(filepath.Dir(sourcePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, sourcePath, "def add(a, b):\n    return a + b\n")
+
+	testCode := "from src.calc import add\n\ndef test_add():\n    assert add(1, 2) == 3\n"
+
+	sameDir := fixPythonImports(testCode, filepath.Join(dir, "src", "test_calc.py"), sourcePath)
+	if !strings.HasPrefix(sameDir, "from calc import add\n") {
+		t.Errorf("test next to the source:\n%s\nwant it to import calc without a path", sameDir)
+	}
+	if strings.Contains(sameDir, "sys.path") {
+		t.Errorf("test next to the source changes sys.path:\n%s", sameDir)
+	}
+
+	subdir := fixPythonImports(testCode, filepath.Join(dir, "src", "tests", "test_calc.py"), sourcePath)
+	if !strings.Contains(subdir, `os.path.join(os.path.dirname(__file__), "..")`) {
+		t.Errorf("test in a subdirectory:\n%s\nwant the parent directory on sys.path", subdir)
+	}
+	if !strings.Contains(subdir, "\nfrom calc import add\n") {
+		t.Errorf("test in a subdirectory:\n%s\nwant it to import calc", subdir)
+	}
+}
+
+func TestFixPythonImportsKeepsModulesSharingTheName(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "app", "utils.py")
+	if err := os.MkdirAll(filepath.Dir(sourcePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, sourcePath, "def slug(s):\n    return s.lower()\n")
+
+	testCode := "from django.utils import timezone\nfrom app.utils import slug\n"
+	fixed := fixPythonImports(testCode, filepath.Join(dir, "app", "test_utils.py"), sourcePath)
+	if want := "from django.utils import timezone\nfrom utils import slug\n"; fixed != want {
+		t.Errorf("fixed imports:\n%s\nwant:\n%s", fixed, want)
+	}
+}
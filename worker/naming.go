@@ -0,0 +1,26 @@
+package worker
+
+import (
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/postprocessor"
+)
+
+// renameTests renames the test functions generated for the task to the
+// configured naming pattern. The case of every test is described by the
+// conditions of the path it was asked to cover if the model wrote one test per
+// path, and by the name the model chose otherwise. Tests of whole-file tasks
+// are left as generated, there is no function under test to name them after.
+func (dw *DeepWorker) renameTests(testCode string, task *TestTask) string {
+	if dw.testNamePattern == "" || task.FunctionName == "" {
+		return testCode
+	}
+
+	var cases []string
+	if task.PathCover != nil {
+		for _, path := range task.PathCover.Paths {
+			cases = append(cases, strings.Join(symPathConditions(path), " and "))
+		}
+	}
+	return postprocessor.RenameTestFunctions(testCode, task.CodeType, dw.testNamePattern, task.FunctionName, cases)
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSymTestsFollowTheNamingPattern(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def sign(x):\n    if x < 0:\n        return -1\n    return 1\n")
+
+	response := "```python\nfrom calc import sign\n\n" +
+		"def test_first():\n    assert sign(-2) == -1\n\n" +
+		"def test_second():\n    assert sign(3) == 1\n```"
+	sw := newTestSymWorker(newFakeModel(response), func(config *DeepWorkerConfig) {
+		config.TestNamePattern = "test_{function}_{case}"
+	})
+	if err := sw.SubmitSymTaskForFunction(sourcePath, "sign"); err != nil {
+		t.Fatalf("SubmitSymTaskForFunction: %v", err)
+	}
+
+	written, err := os.ReadFile(filepath.Join(dir, "calc_sign_test_case_1.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each test is named after the path it covers, with its body kept
+	for _, want := range []string{
+		"def test_sign_x_lt_0():\n    assert sign(-2) == -1",
+		"def test_sign_not_x_lt_0():\n    assert sign(3) == 1",
+	} {
+		if !strings.Contains(string(written), want) {
+			t.Errorf("written test:\n%s\nwant it to contain:\n%s", written, want)
+		}
+	}
+}
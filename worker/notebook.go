@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/preprocessor"
+)
+
+// isNotebook reports whether the file is a Jupyter notebook.
+func isNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// hasNotebookSource reports whether a Python file of the user already has the
+// name the notebook's code would be extracted to. The source extracted by an
+// earlier run does not count: it is regenerated, so edits of the notebook are
+// picked up.
+func hasNotebookSource(notebookPath string) bool {
+	code, err := os.ReadFile(preprocessor.NotebookSourcePath(notebookPath))
+	return err == nil && !isExtractedNotebookSource(code)
+}
+
+// isNotebookSource reports whether the Python file is the source extracted from
+// the notebook next to it. Such a file is generated from the notebook, so it is
+// submitted through the notebook rather than on its own.
+func isNotebookSource(path string) bool {
+	notebookPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".ipynb"
+	if _, err := os.Stat(notebookPath); err != nil {
+		return false
+	}
+	code, err := os.ReadFile(path)
+	return err == nil && isExtractedNotebookSource(code)
+}
+
+// isExtractedNotebookSource reports whether the code was extracted from a
+// notebook by ExtractNotebookCode.
+func isExtractedNotebookSource(code []byte) bool {
+	return bytes.HasPrefix(code, []byte(preprocessor.NotebookSourceHeader))
+}
+
+// writeNotebookSource writes the code extracted from a notebook next to it, so
+// the generated tests can import it as a module. The source extracted by an
+// earlier run is replaced, but a different file of the user already at that path
+// is never overwritten.
+func (sw *SymPromptWorker) writeNotebookSource(src *symSource) error {
+	if existing, err := sw.fileIO.Read(src.Path); err == nil && string(existing) != src.Code && !isExtractedNotebookSource(existing) {
+		return fmt.Errorf("cannot extract the code of %s: %s already exists", src.Notebook, src.Path)
+	}
+	if err := sw.fileIO.Write(src.Path, []byte(src.Code)); err != nil {
+		return fmt.Errorf("failed to write the code of %s: %w", src.Notebook, err)
+	}
+	return nil
+}
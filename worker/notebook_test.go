@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+	"github.com/Marksagittarius/pinguis/preprocessor"
+)
+
+const testNotebook = `{"metadata": {"kernelspec": {"language": "python"}}, "cells": [
+	{"cell_type": "code", "source": ["def add(a, b):\n", "    return a + b\n"]}
+]}`
+
+func newNotebookWorker() *SymPromptWorker {
+	sw := NewSymPromptWorker(&DeepWorkerConfig{
+		WorkerCount:      1,
+		Model:            newFakeModel(pythonTestResponse),
+		Callback:         fullCoverage,
+		IncludeNotebooks: true,
+	}, &fileio.SimpleFileIO{})
+	return sw
+}
+
+// walkedFiles returns the names of the files walkSymSources visits below dir.
+func walkedFiles(t *testing.T, sw *SymPromptWorker, dir string) []string {
+	t.Helper()
+	var names []string
+	errs := sw.walkSymSources(dir, nil, func(path string) error {
+		names = append(names, filepath.Base(path))
+		return nil
+	})
+	if len(errs) > 0 {
+		t.Fatalf("walkSymSources: %v", errs)
+	}
+	return names
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalkSubmitsNotebookInsteadOfItsExtractedSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "analysis.ipynb"), testNotebook)
+	writeFile(t, filepath.Join(dir, "analysis.py"), preprocessor.NotebookSourceHeader+"\ndef stale():\n    pass\n")
+
+	got := walkedFiles(t, newNotebookWorker(), dir)
+	if want := []string{"analysis.ipynb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walked %v, want %v", got, want)
+	}
+}
+
+func TestWalkSkipsNotebookPairedWithUserSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "analysis.ipynb"), testNotebook)
+	writeFile(t, filepath.Join(dir, "analysis.py"), "def add(a, b):\n    return a + b\n")
+
+	got := walkedFiles(t, newNotebookWorker(), dir)
+	if want := []string{"analysis.py"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walked %v, want %v", got, want)
+	}
+}
+
+func TestWriteNotebookSourceReplacesOnlyExtractedSource(t *testing.T) {
+	dir := t.TempDir()
+	notebookPath := filepath.Join(dir, "analysis.ipynb")
+	writeFile(t, notebookPath, testNotebook)
+	code, err := preprocessor.ExtractNotebookCode([]byte(testNotebook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &symSource{Path: preprocessor.NotebookSourcePath(notebookPath), Code: code, Notebook: notebookPath}
+	sw := newNotebookWorker()
+
+	writeFile(t, src.Path, preprocessor.NotebookSourceHeader+"\ndef stale():\n    pass\n")
+	if err := sw.writeNotebookSource(src); err != nil {
+		t.Fatalf("writeNotebookSource over an extracted source: %v", err)
+	}
+	if written, _ := os.ReadFile(src.Path); string(written) != code {
+		t.Errorf("source = %q, want the code extracted again %q", written, code)
+	}
+
+	writeFile(t, src.Path, "def mine():\n    pass\n")
+	if err := sw.writeNotebookSource(src); err == nil {
+		t.Error("writeNotebookSource overwrote a file of the user")
+	}
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/Marksagittarius/pinguis/dependency"
+)
+
+// newDependencyRanks returns the position of every file of the graph in its
+// topological order, keyed by absolute path. A graph with a dependency cycle
+// has no order, in which case a warning is logged and nil is returned.
+func newDependencyRanks(graph *dependency.DependencyGraph) map[string]int {
+	order, err := graph.TopologicalOrder()
+	if err != nil {
+		log.Printf("Warning: ignoring the dependency order: %v", err)
+		return nil
+	}
+
+	ranks := make(map[string]int, len(order))
+	for i, path := range order {
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+		ranks[path] = i
+	}
+	return ranks
+}
+
+// dependencyPriority returns the priority of a task for the source file that
+// makes queued tasks run in dependency order: the earlier the file comes in the
+// order, the higher its priority, so the files it depends on are tested before
+// it. Files outside the order get DefaultTaskPriority and run after the files
+// in it.
+func (dw *DeepWorker) dependencyPriority(sourcePath string) int {
+	if dw.dependencyRanks == nil {
+		return DefaultTaskPriority
+	}
+	if absPath, err := filepath.Abs(sourcePath); err == nil {
+		sourcePath = absPath
+	}
+	rank, ok := dw.dependencyRanks[sourcePath]
+	if !ok {
+		return DefaultTaskPriority
+	}
+	return DefaultTaskPriority + len(dw.dependencyRanks) - rank
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/dependency"
+)
+
+func TestDependencyPriorityFollowsTopologicalOrder(t *testing.T) {
+	graph := &dependency.DependencyGraph{
+		FileNodes: map[string]*dependency.FileNode{
+			"app.py": {FileName: "app.py"},
+			"db.py":  {FileName: "db.py"},
+		},
+		Dependencies: []dependency.Dependency{{SourceFile: "app.py", TargetFile: "db.py", Type: dependency.ImportDependency}},
+	}
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.DependencyOrder = true
+		config.DependencyGraph = graph
+	})
+
+	db, app, other := dw.dependencyPriority("db.py"), dw.dependencyPriority("app.py"), dw.dependencyPriority("other.py")
+	if !(db > app && app > other) {
+		t.Errorf("priorities of db.py %d, app.py %d and other.py %d, want them decreasing", db, app, other)
+	}
+	if other != DefaultTaskPriority {
+		t.Errorf("priority of a file outside the graph = %d, want %d", other, DefaultTaskPriority)
+	}
+}
+
+func TestDependencyPriorityIgnoresCyclicGraph(t *testing.T) {
+	graph := &dependency.DependencyGraph{
+		FileNodes: map[string]*dependency.FileNode{
+			"a.py": {FileName: "a.py"},
+			"b.py": {FileName: "b.py"},
+		},
+		Dependencies: []dependency.Dependency{
+			{SourceFile: "a.py", TargetFile: "b.py", Type: dependency.ImportDependency},
+			{SourceFile: "b.py", TargetFile: "a.py", Type: dependency.ImportDependency},
+		},
+	}
+	dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.DependencyOrder = true
+		config.DependencyGraph = graph
+	})
+
+	for _, path := range []string{"a.py", "b.py"} {
+		if priority := dw.dependencyPriority(path); priority != DefaultTaskPriority {
+			t.Errorf("priority of %s in a cycle = %d, want %d", path, priority, DefaultTaskPriority)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// TestPackage controls the package generated Go and Java tests are declared in
+// relative to the code under test.
+type TestPackage string
+
+const (
+	// SamePackage declares tests in the package of the code under test, giving
+	// them access to its unexported (package-private) symbols.
+	SamePackage TestPackage = "same"
+	// ExternalTestPackage declares black-box tests that only use the exported API
+	// of the code under test: Go tests go to the pkg_test package, Java tests
+	// import the classes under test explicitly.
+	ExternalTestPackage TestPackage = "external"
+)
+
+var (
+	goPackagePattern        = regexp.MustCompile(`(?m)^package\s+(\w+)`)
+	goModulePattern         = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	goImportBlockPattern    = regexp.MustCompile(`(?m)^import\s*\(`)
+	goImportLinePattern     = regexp.MustCompile(`(?m)^import\s+(?:\w+\s+)?"[^"]*"\s*$`)
+	javaPackagePattern      = regexp.MustCompile(`(?m)^[^\S\n]*package\s+([\w.]+)\s*;`)
+	javaImportPattern       = regexp.MustCompile(`(?m)^[^\S\n]*import\s+([\w.]+(?:\.\*)?)\s*;[^\S\n]*\n?`)
+	javaTopLevelTypePattern = regexp.MustCompile(`(?m)^(?:(?:public|final|abstract|sealed|non-sealed|strictfp)\s+)*(?:class|interface|enum|record)\s+(\w+)`)
+)
+
+// packageInstruction returns the prompt section asking for tests in the
+// configured package, or an empty string if the worker leaves the package to the
+// model or the language has no notion of it.
+func (dw *DeepWorker) packageInstruction(codeType string) string {
+	switch {
+	case codeType == "go" && dw.testPackage == SamePackage:
+		return "\nDeclare the tests in the same package as the code under test, so they can use its unexported identifiers.\n"
+	case codeType == "go" && dw.testPackage == ExternalTestPackage:
+		return "\nDeclare the tests in the external test package (the package name of the code under test with a _test suffix), " +
+			"import the package under test and only use its exported identifiers.\n"
+	case codeType == "java" && dw.testPackage == SamePackage:
+		return "\nDeclare the test class in the same package as the class under test and refer to the classes of that package without importing them.\n"
+	case codeType == "java" && dw.testPackage == ExternalTestPackage:
+		return "\nImport the classes under test explicitly and only use their public members.\n"
+	default:
+		return ""
+	}
+}
+
+// enforceTestPackage rewrites the generated test so it is declared in the
+// configured package: the package clause of Go tests, and the package
+// declaration and imports of Java tests. Other languages and tests whose source
+// declares no package are returned unchanged.
+func (dw *DeepWorker) enforceTestPackage(testCode, sourceCode, sourcePath, codeType string) string {
+	if dw.testPackage != SamePackage && dw.testPackage != ExternalTestPackage {
+		return testCode
+	}
+
+	switch codeType {
+	case "go":
+		return enforceGoTestPackage(testCode, sourceCode, sourcePath, dw.testPackage)
+	case "java":
+		return enforceJavaTestPackage(testCode, sourceCode, dw.testPackage)
+	default:
+		return testCode
+	}
+}
+
+// enforceGoTestPackage rewrites the package clause of a Go test to the package
+// of the source, or to its _test package in external mode. External tests get an
+// import of the package under test if they do not import it yet and its import
+// path can be derived from the enclosing go.mod.
+func enforceGoTestPackage(testCode, sourceCode, sourcePath string, mode TestPackage) string {
+	match := goPackagePattern.FindStringSubmatch(sourceCode)
+	if match == nil {
+		return testCode
+	}
+	pkg := strings.TrimSuffix(match[1], "_test")
+
+	want := "package " + pkg
+	if mode == ExternalTestPackage {
+		want += "_test"
+	}
+	if loc := goPackagePattern.FindStringIndex(testCode); loc != nil {
+		testCode = testCode[:loc[0]] + want + testCode[loc[1]:]
+	} else {
+		testCode = want + "\n\n" + testCode
+	}
+
+	if mode != ExternalTestPackage {
+		return testCode
+	}
+	importPath, ok := goImportPath(sourcePath)
+	if !ok || strings.Contains(testCode, fmt.Sprintf("%q", importPath)) {
+		return testCode
+	}
+
+	spec := fmt.Sprintf("%q", importPath)
+	if path.Base(importPath) != pkg {
+		spec = pkg + " " + spec
+	}
+	if loc := goImportBlockPattern.FindStringIndex(testCode); loc != nil {
+		return testCode[:loc[1]] + "\n\t" + spec + testCode[loc[1]:]
+	}
+	if loc := goImportLinePattern.FindStringIndex(testCode); loc != nil {
+		return testCode[:loc[0]] + "import " + spec + "\n" + testCode[loc[0]:]
+	}
+	loc := goPackagePattern.FindStringIndex(testCode)
+	return testCode[:loc[1]] + "\n\nimport " + spec + testCode[loc[1]:]
+}
+
+// goImportPath returns the import path of the package in the directory of the
+// Go source file, derived from the module path of the nearest enclosing go.mod.
+func goImportPath(sourcePath string) (string, bool) {
+	sourceDir, err := filepath.Abs(filepath.Dir(sourcePath))
+	if err != nil {
+		return "", false
+	}
+
+	for dir := sourceDir; ; dir = filepath.Dir(dir) {
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			match := goModulePattern.FindSubmatch(data)
+			if match == nil {
+				return "", false
+			}
+			rel, err := filepath.Rel(dir, sourceDir)
+			if err != nil {
+				return "", false
+			}
+			return path.Join(string(match[1]), filepath.ToSlash(rel)), true
+		}
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// enforceJavaTestPackage rewrites the package declaration and imports of a Java
+// test. In same-package mode the test is declared in the package of the source
+// and drops the imports of classes of that package. In external mode the test
+// imports every public top-level type of the source it does not import yet.
+func enforceJavaTestPackage(testCode, sourceCode string, mode TestPackage) string {
+	match := javaPackagePattern.FindStringSubmatch(sourceCode)
+	if match == nil {
+		return testCode
+	}
+	pkg := match[1]
+
+	if mode == SamePackage {
+		testCode = javaImportPattern.ReplaceAllStringFunc(testCode, func(line string) string {
+			imported := javaImportPattern.FindStringSubmatch(line)[1]
+			if strings.HasPrefix(imported, pkg+".") && !strings.Contains(imported[len(pkg)+1:], ".") {
+				return ""
+			}
+			return line
+		})
+
+		declaration := "package " + pkg + ";"
+		if loc := javaPackagePattern.FindStringIndex(testCode); loc != nil {
+			return testCode[:loc[0]] + declaration + testCode[loc[1]:]
+		}
+		return declaration + "\n\n" + testCode
+	}
+
+	imported := make(map[string]bool)
+	for _, m := range javaImportPattern.FindAllStringSubmatch(testCode, -1) {
+		imported[m[1]] = true
+	}
+	testPkg := ""
+	if m := javaPackagePattern.FindStringSubmatch(testCode); m != nil {
+		testPkg = m[1]
+	}
+
+	var imports []string
+	for _, m := range javaTopLevelTypePattern.FindAllStringSubmatch(sourceCode, -1) {
+		name := pkg + "." + m[1]
+		if !strings.Contains(m[0], "public") {
+			continue
+		}
+		if !imported[name] && !imported[pkg+".*"] && testPkg != pkg {
+			imports = append(imports, "import "+name+";")
+			imported[name] = true
+		}
+	}
+	if len(imports) == 0 {
+		return testCode
+	}
+
+	block := strings.Join(imports, "\n") + "\n"
+	if loc := javaImportPattern.FindStringIndex(testCode); loc != nil {
+		return testCode[:loc[0]] + block + testCode[loc[0]:]
+	}
+	if loc := javaPackagePattern.FindStringIndex(testCode); loc != nil {
+		return testCode[:loc[1]] + "\n\n" + strings.TrimSuffix(block, "\n") + testCode[loc[1]:]
+	}
+	return block + "\n" + testCode
+}
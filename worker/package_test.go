@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+)
+
+// goTestResponse is a model response holding a Go test declared in the package
+// of the code under test.
+const goTestResponse = "```go\npackage calc\n\nimport \"testing\"\n\n" +
+	"func TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Error(\"Add(1, 2) != 3\")\n\t}\n}\n```"
+
+func TestExternalTestPackageRewritesGoPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/shop\n\ngo 1.22\n")
+	if err := os.Mkdir(filepath.Join(dir, "calc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	task := &TestTask{
+		SourcePath: filepath.Join(dir, "calc", "calc.go"),
+		SourceCode: "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		TestPath:   filepath.Join(dir, "calc", "calc_test.go"),
+		CodeType:   "go",
+	}
+
+	dw := newTestWorker(newFakeModel(goTestResponse), func(config *DeepWorkerConfig) {
+		config.TestPackage = ExternalTestPackage
+	})
+	dw.fileIO = &fileio.SimpleFileIO{}
+	if instruction := dw.packageInstruction("go"); !strings.Contains(instruction, "Declare the tests in the external test package") {
+		t.Errorf("instruction = %q, want it to ask for the external test package", instruction)
+	}
+	if _, err := dw.writeTestFiles(task, goTestResponse); err != nil {
+		t.Fatalf("writeTestFiles: %v", err)
+	}
+
+	written, err := os.ReadFile(task.TestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package calc_test\n\nimport \"example.com/shop/calc\"\nimport \"testing\"\n"
+	if !strings.HasPrefix(string(written), want) {
+		t.Errorf("written test =\n%s\nwant it to start with\n%s", written, want)
+	}
+}
+
+func TestEnforceTestPackage(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       TestPackage
+		codeType   string
+		sourceCode string
+		testCode   string
+		want       string
+	}{
+		{
+			name:       "go same package",
+			mode:       SamePackage,
+			codeType:   "go",
+			sourceCode: "package calc\n",
+			testCode:   "package calc_test\n\nfunc TestAdd(t *testing.T) {}\n",
+			want:       "package calc\n\nfunc TestAdd(t *testing.T) {}\n",
+		},
+		{
+			name:       "go without package clause",
+			mode:       SamePackage,
+			codeType:   "go",
+			sourceCode: "package calc\n",
+			testCode:   "func TestAdd(t *testing.T) {}\n",
+			want:       "package calc\n\nfunc TestAdd(t *testing.T) {}\n",
+		},
+		{
+			name:       "java same package drops imports of the package",
+			mode:       SamePackage,
+			codeType:   "java",
+			sourceCode: "package shop;\n\npublic class Cart {}\n",
+			testCode:   "import shop.Cart;\nimport java.util.List;\n\nclass CartTest {}\n",
+			want:       "package shop;\n\nimport java.util.List;\n\nclass CartTest {}\n",
+		},
+		{
+			name:       "java external package imports public types",
+			mode:       ExternalTestPackage,
+			codeType:   "java",
+			sourceCode: "package shop;\n\npublic class Cart {}\n\nclass Item {}\n",
+			testCode:   "package shop.tests;\n\nclass CartTest {}\n",
+			want:       "package shop.tests;\n\nimport shop.Cart;\n\nclass CartTest {}\n",
+		},
+		{
+			name:       "other languages",
+			mode:       ExternalTestPackage,
+			codeType:   "python",
+			sourceCode: "def add(a, b):\n    return a + b\n",
+			testCode:   "def test_add():\n    pass\n",
+			want:       "def test_add():\n    pass\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dw := newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+				config.TestPackage = tt.mode
+			})
+			got := dw.enforceTestPackage(tt.testCode, tt.sourceCode, filepath.Join(t.TempDir(), "src"), tt.codeType)
+			if got != tt.want {
+				t.Errorf("enforceTestPackage() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/Marksagittarius/pinguis/types"
+)
+
+// GenerationPlan describes the tests a SymPromptWorker would generate for a
+// directory, without generating any of them.
+//
+// Fields:
+// - Files: The plans of the source files that would be submitted.
+// - Functions: The number of functions tests would be generated for.
+// - Paths: The total number of execution paths the prompts would describe.
+// - PromptBytes: The total size of the first prompt of every function.
+// - PromptTokens: The number of tokens of those prompts, counted by the worker's Tokenizer.
+type GenerationPlan struct {
+	Files        []FilePlan `json:"files"`
+	Functions    int        `json:"functions"`
+	Paths        int        `json:"paths"`
+	PromptBytes  int        `json:"prompt_bytes"`
+	PromptTokens int        `json:"prompt_tokens"`
+}
+
+// FilePlan is the part of a GenerationPlan for a single source file. Error is
+// set if the file could not be parsed, in which case it has no functions.
+// Warning is set if the file has syntax errors, in which case functions around
+// them may be missing or have incomplete paths.
+type FilePlan struct {
+	SourcePath string         `json:"source_path"`
+	Functions  []FunctionPlan `json:"functions"`
+	Error      string         `json:"error,omitempty"`
+	Warning    string         `json:"warning,omitempty"`
+}
+
+// FunctionPlan is the part of a GenerationPlan for a single function: the test
+// file it would get, the number of minimized execution paths its prompt would
+// describe and the size of that prompt. Functions that would be skipped list the
+// reason in Skipped and do not count towards the totals of the plan. Without a
+// Tokenizer, the prompt tokens are estimated at 4 characters per token.
+type FunctionPlan struct {
+	Name         string `json:"name"`
+	TestPath     string `json:"test_path"`
+	Paths        int    `json:"paths"`
+	PromptBytes  int    `json:"prompt_bytes"`
+	PromptTokens int    `json:"prompt_tokens"`
+	Skipped      string `json:"skipped,omitempty"`
+}
+
+// Plan reports what SubmitDirectory would generate for the directory: the files
+// it would submit, their functions in processing order and, for each function,
+// the number of execution paths and the estimated size of its first prompt. It
+// discovers, parses and minimizes paths like SubmitDirectory but never calls the
+// model or the test callback, so it costs no model budget.
+//
+// Files that cannot be parsed are listed with their error. The returned error
+// joins the errors of the directory walk; the plan covers every file that could
+// be visited even if an error is returned.
+func (sw *SymPromptWorker) Plan(root string) (*GenerationPlan, error) {
+	plan := &GenerationPlan{}
+	errs := sw.walkSymSources(root, nil, func(path string) error {
+		file := sw.planFile(path)
+		for _, fn := range file.Functions {
+			if fn.Skipped != "" {
+				continue
+			}
+			plan.Functions++
+			plan.Paths += fn.Paths
+			plan.PromptBytes += fn.PromptBytes
+			plan.PromptTokens += fn.PromptTokens
+		}
+		plan.Files = append(plan.Files, file)
+		return nil
+	})
+	return plan, errors.Join(errs...)
+}
+
+// planFile returns the plan of a single source file.
+func (sw *SymPromptWorker) planFile(sourcePath string) FilePlan {
+	file := FilePlan{SourcePath: sourcePath, Functions: []FunctionPlan{}}
+	src, funcs, tree, err := sw.parseSymSource(sourcePath, symOptions{})
+	if errors.Is(err, types.ErrIncompleteParse) {
+		file.Warning = err.Error()
+	} else if err != nil {
+		file.Error = err.Error()
+		return file
+	}
+	defer tree.Close()
+
+	for _, fn := range funcs {
+		if reason := sw.skipReason(src, fn); reason != "" {
+			file.Functions = append(file.Functions, FunctionPlan{Name: fn.Name, Skipped: reason})
+			continue
+		}
+
+		task := sw.newSymTask(src, fn)
+		prompt := sw.buildPrompt(task)
+		file.Functions = append(file.Functions, FunctionPlan{
+			Name:         fn.Name,
+			TestPath:     task.TestPath,
+			Paths:        len(task.PathCover.Paths),
+			PromptBytes:  len(prompt),
+			PromptTokens: sw.tokenizer.Count(prompt),
+		})
+	}
+	return file
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanListsFunctionsWithoutCallingModel(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "calc.py"), "def sign(x):\n    if x > 0:\n        return 1\n    elif x < 0:\n        return -1\n    return 0\n\n"+
+		"def todo(x):\n    pass\n\n"+
+		"def inc(x):\n    return x + 1\n")
+	// Generated tests are not planned, as SubmitDirectory does not submit them
+	writeFile(t, filepath.Join(root, "calc_test.py"), "def test_inc():\n    assert True\n")
+
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, nil)
+	plan, err := sw.Plan(root)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	if prompts := m.recorded(); len(prompts) != 0 {
+		t.Errorf("model was prompted %d times, want none", len(prompts))
+	}
+	if len(sw.Report().Tasks) != 0 {
+		t.Errorf("report has %d tasks, want none", len(sw.Report().Tasks))
+	}
+	if len(plan.Files) != 1 || plan.Files[0].SourcePath != filepath.Join(root, "calc.py") {
+		t.Fatalf("plan files = %+v, want calc.py only", plan.Files)
+	}
+
+	functions := plan.Files[0].Functions
+	want := []struct {
+		name    string
+		paths   int
+		skipped string
+	}{
+		{"sign", 3, ""},
+		{"todo", 0, "function body is a stub"},
+		{"inc", 1, ""},
+	}
+	if len(functions) != len(want) {
+		t.Fatalf("planned functions = %+v, want %d", functions, len(want))
+	}
+	promptBytes, promptTokens := 0, 0
+	for i, fn := range functions {
+		if fn.Name != want[i].name || fn.Paths != want[i].paths || fn.Skipped != want[i].skipped {
+			t.Errorf("function %d = %+v, want %s with %d paths, skipped %q", i, fn, want[i].name, want[i].paths, want[i].skipped)
+		}
+		if fn.Skipped != "" {
+			continue
+		}
+		if fn.TestPath == "" || fn.PromptBytes == 0 || fn.PromptTokens != fn.PromptBytes/charsPerToken {
+			t.Errorf("function %s has test path %q, %d prompt bytes and %d tokens, want a test path and estimated tokens",
+				fn.Name, fn.TestPath, fn.PromptBytes, fn.PromptTokens)
+		}
+		promptBytes += fn.PromptBytes
+		promptTokens += fn.PromptTokens
+	}
+
+	if plan.Functions != 2 || plan.Paths != 4 || plan.PromptBytes != promptBytes || plan.PromptTokens != promptTokens {
+		t.Errorf("plan totals = %d functions, %d paths, %d bytes and %d tokens, want 2, 4, %d and %d",
+			plan.Functions, plan.Paths, plan.PromptBytes, plan.PromptTokens, promptBytes, promptTokens)
+	}
+}
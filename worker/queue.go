@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// DefaultTaskPriority is the priority assigned to tasks submitted without an
+// explicit priority. Tasks with a higher priority are dispatched first.
+const DefaultTaskPriority = 0
+
+type queuedTask struct {
+	task *TestTask
+	seq  uint64
+}
+
+// taskHeap implements heap.Interface ordering tasks by descending priority.
+// Tasks with equal priority keep their submission order.
+type taskHeap []queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].task.Priority != h[j].task.Priority {
+		return h[i].task.Priority > h[j].task.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) {
+	*h = append(*h, x.(queuedTask))
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = queuedTask{}
+	*h = old[:n-1]
+	return item
+}
+
+// taskQueue is a bounded, concurrency-safe priority queue of test tasks.
+// Consumers wait on notify to learn that new tasks have been pushed.
+type taskQueue struct {
+	mu       sync.Mutex
+	items    taskHeap
+	capacity int
+	seq      uint64
+	notify   chan struct{}
+}
+
+// newTaskQueue creates a task queue holding at most capacity tasks.
+func newTaskQueue(capacity int) *taskQueue {
+	return &taskQueue{
+		items:    taskHeap{},
+		capacity: capacity,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// push adds a task to the queue. It returns false if the queue is full.
+func (q *taskQueue) push(task *TestTask) bool {
+	q.mu.Lock()
+	if len(q.items) >= q.capacity {
+		q.mu.Unlock()
+		return false
+	}
+	q.seq++
+	heap.Push(&q.items, queuedTask{task: task, seq: q.seq})
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop removes and returns the highest-priority task without blocking.
+// The second return value is false if the queue is empty.
+func (q *taskQueue) pop() (*TestTask, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	item := heap.Pop(&q.items).(queuedTask)
+	return item.task, true
+}
+
+// Len returns the number of tasks waiting in the queue.
+func (q *taskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
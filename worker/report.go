@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+)
+
+// Task statuses recorded in the run report.
+const (
+	TaskCompleted = "completed"
+	TaskPlateaued = "plateaued"
+	TaskFailed    = "failed"
+	TaskTimedOut  = "timed_out"
+)
+
+// taskSucceeded reports whether a task that finished with the status produced
+// a test, i.e. it neither failed nor ran out of time.
+func taskSucceeded(status string) bool {
+	return status == TaskCompleted || status == TaskPlateaued
+}
+
+// TaskReport summarizes the outcome of a single test generation task.
+//
+// Fields:
+// - SourcePath: The file path of the source code under test.
+// - FunctionName: The function the test targets, empty for whole-file tasks.
+// - TestPath: The file path the generated test was written to.
+// - CodeType: The programming language of the source code.
+// - Iterations: The number of improvement iterations performed.
+// - BestCoverage: The highest coverage rate achieved.
+// - PassRate: The fraction of generated tests that passed in the last run.
+// - AssertionDensity: The average number of assertions per generated test function.
+// - Confidence: The overall quality score of the generated test, between 0 and 1.
+// - Status: How the task ended (completed, plateaued, failed or timed_out).
+// - Error: The error that failed the task, if any.
+// - Model: The name of the model that generated the test, if known.
+// - PromptTokens: The number of prompt tokens sent to the model for the task, counted by the
+//   worker's Tokenizer (estimated at 4 characters per token if it has none).
+// - PathCover: The branches and paths a symbolic test was asked to cover, if any.
+// - Flaky: Whether the final test passed but gave different results when run again.
+type TaskReport struct {
+	SourcePath       string     `json:"source_path"`
+	FunctionName     string     `json:"function_name,omitempty"`
+	TestPath         string     `json:"test_path"`
+	CodeType         string     `json:"code_type"`
+	Iterations       int        `json:"iterations"`
+	BestCoverage     float64    `json:"best_coverage"`
+	PassRate         float64    `json:"pass_rate"`
+	AssertionDensity float64    `json:"assertion_density"`
+	Confidence       float64    `json:"confidence"`
+	Status           string     `json:"status"`
+	Error            string     `json:"error,omitempty"`
+	Model            string     `json:"model,omitempty"`
+	PromptTokens     int        `json:"prompt_tokens"`
+	PathCover        *PathCover `json:"path_cover,omitempty"`
+	Flaky            bool       `json:"flaky,omitempty"`
+}
+
+// PathCover is the minimized set of execution paths a symbolic test targets and
+// the branches they cover together.
+//
+// Fields:
+// - Branches: The branch outcomes the paths take, e.g. "if:x > 0-then" and "for:items-zero".
+// - Paths: The selected paths, each the sequence of the branch outcomes it takes and
+//   the return or raise statement it ends in, if any.
+// - Uncovered: The conditions of the branches none of the selected paths covers
+//   because the paths were capped (see MinimizePathsN), e.g. "not(x > 0)".
+// - Incomplete: The function has syntax errors, so the paths may miss branches
+//   tree-sitter could not parse.
+type PathCover struct {
+	Branches   []string   `json:"branches"`
+	Paths      [][]string `json:"paths"`
+	Uncovered  []string   `json:"uncovered,omitempty"`
+	Incomplete bool       `json:"incomplete,omitempty"`
+}
+
+// RunReport collects the reports of every task finished during a worker run.
+// RemainingRetries is the number of retries left in the run's retry budget, and
+// is omitted if the run has none. Coverage is the project-level coverage of the
+// run's tests, set once CombineCoverage ran.
+type RunReport struct {
+	Tasks            []TaskReport     `json:"tasks"`
+	RemainingRetries *int             `json:"remaining_retries,omitempty"`
+	Coverage         *CoverageSummary `json:"coverage,omitempty"`
+}
+
+// runReportRecorder guards the run report while tasks finish concurrently.
+type runReportRecorder struct {
+	mu     sync.Mutex
+	report RunReport
+}
+
+func (r *runReportRecorder) record(entry TaskReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Tasks = append(r.report.Tasks, entry)
+}
+
+func (r *runReportRecorder) snapshot() *RunReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tasks := make([]TaskReport, len(r.report.Tasks))
+	copy(tasks, r.report.Tasks)
+	return &RunReport{Tasks: tasks, Coverage: r.report.Coverage}
+}
+
+func (r *runReportRecorder) setCoverage(summary *CoverageSummary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Coverage = summary
+}
+
+// FunctionResult is the outcome of generating the test for a single function.
+// The SymPromptWorker streams one FunctionResult per function as a JSON line to
+// the configured results writer.
+type FunctionResult struct {
+	SourcePath   string  `json:"source_path"`
+	FunctionName string  `json:"function_name"`
+	TestPath     string  `json:"test_path"`
+	Coverage     float64 `json:"coverage"`
+	Passed       bool    `json:"passed"`
+	Flaky        bool    `json:"flaky,omitempty"`
+	Iterations   int     `json:"iterations"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// resultStream writes records as JSON lines. Writes are serialized so records
+// emitted by concurrent tasks never interleave. A stream without a writer
+// discards every record.
+type resultStream struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newResultStream(w io.Writer) *resultStream {
+	if w == nil {
+		return &resultStream{}
+	}
+	return &resultStream{enc: json.NewEncoder(w)}
+}
+
+func (rs *resultStream) emit(record any) {
+	if rs == nil || rs.enc == nil {
+		return
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if err := rs.enc.Encode(record); err != nil {
+		log.Printf("Failed to write result record: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// errRetryBudgetExhausted is returned when a task needs to ask the model again
+// but the run has used up its retry budget.
+var errRetryBudgetExhausted = errors.New("retry budget of the run exhausted")
+
+// retryBudget is the number of extra model requests all tasks of a run may make
+// together. A budget without a limit never runs out.
+type retryBudget struct {
+	limited   bool
+	remaining atomic.Int64
+}
+
+func newRetryBudget(max int) *retryBudget {
+	budget := &retryBudget{limited: max > 0}
+	budget.remaining.Store(int64(max))
+	return budget
+}
+
+// take draws a retry from the budget and reports whether one was left.
+func (b *retryBudget) take() bool {
+	if b == nil || !b.limited {
+		return true
+	}
+	for {
+		remaining := b.remaining.Load()
+		if remaining <= 0 {
+			return false
+		}
+		if b.remaining.CompareAndSwap(remaining, remaining-1) {
+			return true
+		}
+	}
+}
+
+// left returns the number of retries left, or -1 if the budget has no limit.
+func (b *retryBudget) left() int {
+	if b == nil || !b.limited {
+		return -1
+	}
+	return int(b.remaining.Load())
+}
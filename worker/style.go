@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TestStyle controls the kind of tests the worker asks the model for.
+type TestStyle string
+
+const (
+	// ExampleStyle asks for example-based tests (the default).
+	ExampleStyle TestStyle = "example"
+	// PropertyStyle asks for property-based tests written with the property
+	// testing framework of the language.
+	PropertyStyle TestStyle = "property"
+)
+
+// propertyFramework describes the property testing framework requested for a
+// language and the markers a test written with it contains.
+type propertyFramework struct {
+	instruction string
+	markers     []string
+}
+
+// propertyFrameworks lists the property testing frameworks keyed by code type.
+// Languages without an entry get example-based tests in every style.
+var propertyFrameworks = map[string]propertyFramework{
+	"python": {
+		instruction: "Write property-based tests with hypothesis: decorate each test function with @given " +
+			"and generate its inputs with strategies from hypothesis.strategies.",
+		markers: []string{"@given"},
+	},
+	"java": {
+		instruction: "Write property-based tests with jqwik: annotate each test method with @Property " +
+			"and receive its inputs as @ForAll parameters.",
+		markers: []string{"@Property"},
+	},
+	"kotlin": {
+		instruction: "Write property-based tests with Kotest: check each property with checkAll or forAll " +
+			"over generators from io.kotest.property.Arb.",
+		markers: []string{"checkAll", "forAll"},
+	},
+	"javascript": {
+		instruction: "Write property-based tests with fast-check: check each property with fc.assert(fc.property(...)) " +
+			"over arbitraries from fast-check.",
+		markers: []string{"fc.assert"},
+	},
+	"go": {
+		instruction: "Write property-based tests with testing/quick: check each property with quick.Check.",
+		markers:     []string{"quick.Check"},
+	},
+}
+
+// styleInstruction returns the prompt section requesting tests of the worker's
+// style for the code type, or an empty string for example-based tests.
+func (dw *DeepWorker) styleInstruction(codeType string) string {
+	framework, ok := dw.propertyFramework(codeType)
+	if !ok {
+		return ""
+	}
+	return "\n" + framework.instruction + " Focus on properties that hold for every valid input " +
+		"rather than on individual examples.\n"
+}
+
+// propertyFramework returns the property testing framework tests for the code
+// type must use, if the worker generates property-based tests for it.
+func (dw *DeepWorker) propertyFramework(codeType string) (propertyFramework, bool) {
+	if dw.testStyle != PropertyStyle {
+		return propertyFramework{}, false
+	}
+	framework, ok := propertyFrameworks[codeType]
+	return framework, ok
+}
+
+// usesPropertyFramework reports whether the test code contains one of the
+// framework's markers.
+func usesPropertyFramework(code string, framework propertyFramework) bool {
+	for _, marker := range framework.markers {
+		if strings.Contains(code, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyReminder is appended to the prompt when the model answered with tests
+// that do not use the requested property testing framework.
+func propertyReminder(framework propertyFramework) string {
+	return fmt.Sprintf("\n\nYour previous answer did not contain property-based tests. %s\n", framework.instruction)
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hypothesisResponse is a model response holding a property-based Python test.
+const hypothesisResponse = "```python\nfrom hypothesis import given, strategies as st\n\n" +
+	"@given(st.integers())\ndef test_inc(x):\n    assert inc(x) > x\n```"
+
+func TestPropertyStyleAsksForHypothesisTests(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+
+	m := newFakeModel(hypothesisResponse)
+	sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+		config.TestStyle = PropertyStyle
+	})
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	prompts := m.recorded()
+	if len(prompts) != 1 {
+		t.Fatalf("model was prompted %d times, want once", len(prompts))
+	}
+	if want := "Write property-based tests with hypothesis: decorate each test function with @given"; !strings.Contains(prompts[0], want) {
+		t.Errorf("prompt %q does not ask for hypothesis tests", prompts[0])
+	}
+	if task := sw.Report().Tasks[0]; task.Status != TaskCompleted {
+		t.Errorf("task status = %s, want %s", task.Status, TaskCompleted)
+	}
+}
+
+func TestPropertyStyleRejectsTestsWithoutGiven(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+		config.TestStyle = PropertyStyle
+	})
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	prompts := m.recorded()
+	if len(prompts) != maxNonTestRetries+1 {
+		t.Fatalf("model was prompted %d times, want %d", len(prompts), maxNonTestRetries+1)
+	}
+	for _, prompt := range prompts[1:] {
+		if !strings.Contains(prompt, "Your previous answer did not contain property-based tests.") {
+			t.Errorf("prompt %q does not remind the model of property-based tests", prompt)
+		}
+	}
+	task := sw.Report().Tasks[0]
+	if task.Status != TaskFailed || !strings.Contains(task.Error, "model did not return a property-based test after 3 attempts") {
+		t.Errorf("task status = %s with error %q, want it failed for lacking property-based tests", task.Status, task.Error)
+	}
+}
+
+func TestExampleStyleAddsNoInstruction(t *testing.T) {
+	dw := newTestWorker(newFakeModel(pythonTestResponse), nil)
+	if instruction := dw.styleInstruction("python"); instruction != "" {
+		t.Errorf("instruction of the example style = %q, want none", instruction)
+	}
+
+	dw = newTestWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.TestStyle = PropertyStyle
+	})
+	if instruction := dw.styleInstruction("cpp"); instruction != "" {
+		t.Errorf("instruction for a language without a framework = %q, want none", instruction)
+	}
+}
@@ -0,0 +1,258 @@
+package worker
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Marksagittarius/pinguis/scripts/java"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+)
+
+// javaImportDeclarationPattern matches a Java import declaration, static or not.
+var javaImportDeclarationPattern = regexp.MustCompile(`(?m)^[^\S\n]*import\s+(?:static\s+)?[\w.]+(?:\.\*)?\s*;`)
+
+// collectJavaSymMethods returns every public method declared below the given
+// node in source order. Only public methods are tested, the others are an
+// implementation detail of their class.
+func collectJavaSymMethods(root *tree_sitter.Node, code string) []symFunction {
+	var funcs []symFunction
+	var collect func(node *tree_sitter.Node)
+	collect = func(node *tree_sitter.Node) {
+		if node.Kind() == "method_declaration" && isPublicJavaMethod(node, code) {
+			name := "unknown"
+			if nameNode := node.ChildByFieldName("name"); nameNode != nil {
+				name = code[nameNode.StartByte():nameNode.EndByte()]
+			}
+			funcs = append(funcs, symFunction{Node: node, Name: name})
+		}
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			collect(node.NamedChild(i))
+		}
+	}
+	collect(root)
+	return funcs
+}
+
+// isPublicJavaMethod reports whether the method declaration has the public modifier.
+func isPublicJavaMethod(node *tree_sitter.Node, code string) bool {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		child := node.NamedChild(i)
+		if child.Kind() != "modifiers" {
+			continue
+		}
+		for _, modifier := range strings.Fields(code[child.StartByte():child.EndByte()]) {
+			if modifier == "public" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isJavaStubMethod reports whether the Java method has no body, as abstract and
+// interface methods, or a body without statements.
+func isJavaStubMethod(fn *tree_sitter.Node) bool {
+	body := fn.ChildByFieldName("body")
+	if body == nil {
+		return true
+	}
+	for i := uint(0); i < body.NamedChildCount(); i++ {
+		kind := body.NamedChild(i).Kind()
+		if kind != "line_comment" && kind != "block_comment" {
+			return false
+		}
+	}
+	return true
+}
+
+// isAbstractJavaMethod reports whether the node is a Java method declared
+// abstract, using the method extraction of the Java parser.
+func isAbstractJavaMethod(node *tree_sitter.Node, code string) bool {
+	return node.Kind() == "method_declaration" && java.ExtractMethod(node, []byte(code)).IsAbstract
+}
+
+// javaMethodSignature renders the method the way it is declared, e.g.
+// "int max(int a, int b)", using the method extraction of the Java parser.
+func javaMethodSignature(fn symFunction, code string) string {
+	method := java.ExtractMethod(fn.Node, []byte(code))
+
+	params := make([]string, len(method.Func.Parameters))
+	for i, param := range method.Func.Parameters {
+		params[i] = strings.TrimSpace(param.Type + " " + param.Name)
+	}
+	signature := fmt.Sprintf("%s(%s)", method.Func.Name, strings.Join(params, ", "))
+	if len(method.Func.ReturnTypes) > 0 && method.Func.ReturnTypes[0] != "" {
+		signature = method.Func.ReturnTypes[0] + " " + signature
+	}
+	return signature
+}
+
+// javaThrownException returns the type of the exception thrown by a Java throw
+// statement, e.g. "IllegalArgumentException" for
+// `throw new IllegalArgumentException("negative")`, or the thrown expression if
+// it does not create the exception.
+func javaThrownException(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	thrown := node.NamedChild(0)
+	if thrown == nil {
+		return "an exception"
+	}
+	if thrown.Kind() == "object_creation_expression" {
+		if typeNode := thrown.ChildByFieldName("type"); typeNode != nil {
+			return getNodeText(typeNode)
+		}
+	}
+	return getNodeText(thrown)
+}
+
+// javaTestClassName returns the name of the test class of a Java source file,
+// e.g. CalcTest for Calc.java.
+func javaTestClassName(sourcePath string) string {
+	return strings.TrimSuffix(filepath.Base(sourcePath), filepath.Ext(sourcePath)) + "Test"
+}
+
+// javaSymInstruction asks for the JUnit tests of a single method, named so the
+// tests of the other methods of the class can join them in the same test class.
+func javaSymInstruction(sourcePath, methodName string) string {
+	return fmt.Sprintf("\nWrite the tests as a JUnit 5 test class named %s, with one @Test method per test case. "+
+		"Only test the method %s and start the name of every test method with test%s.\n",
+		javaTestClassName(sourcePath), methodName, strings.ToUpper(methodName[:1])+methodName[1:])
+}
+
+// mergeJavaTestClasses adds the members of the first class declared in next to
+// the first class declared in base, together with the imports of next that base
+// lacks. Members identical to one in base are left out. A test method whose
+// name is taken in base is added under a numbered name; other members whose
+// name is taken, such as shared fields and setup methods, keep the version of
+// base. If either code declares no class, next is returned.
+func mergeJavaTestClasses(base, next string) string {
+	if strings.TrimSpace(base) == "" {
+		return next
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_java.Language()))
+
+	baseTree := parser.Parse([]byte(base), nil)
+	defer baseTree.Close()
+	nextTree := parser.Parse([]byte(next), nil)
+	defer nextTree.Close()
+
+	baseClass := firstJavaClassBody(baseTree.RootNode())
+	nextClass := firstJavaClassBody(nextTree.RootNode())
+	if baseClass == nil || nextClass == nil {
+		return next
+	}
+
+	names := make(map[string]bool)
+	bodies := make(map[string]bool)
+	for i := uint(0); i < baseClass.NamedChildCount(); i++ {
+		member := baseClass.NamedChild(i)
+		names[javaMemberName(member, base)] = true
+		bodies[strings.TrimSpace(base[member.StartByte():member.EndByte()])] = true
+	}
+
+	var members []string
+	for i := uint(0); i < nextClass.NamedChildCount(); i++ {
+		member := nextClass.NamedChild(i)
+		text := next[member.StartByte():member.EndByte()]
+		if bodies[strings.TrimSpace(text)] || strings.HasSuffix(member.Kind(), "comment") {
+			continue
+		}
+
+		name := javaMemberName(member, next)
+		if names[name] {
+			if member.Kind() != "method_declaration" || !strings.Contains(text, "@Test") {
+				continue
+			}
+			nameNode := member.ChildByFieldName("name")
+			renamed := name
+			for n := 2; names[renamed]; n++ {
+				renamed = fmt.Sprintf("%s%d", name, n)
+			}
+			offset := nameNode.StartByte() - member.StartByte()
+			text = text[:offset] + renamed + text[offset+uint(len(name)):]
+			name = renamed
+		}
+		names[name] = true
+
+		// Keep the indentation of the member's first line
+		lineStart := strings.LastIndex(next[:member.StartByte()], "\n") + 1
+		if indent := next[lineStart:member.StartByte()]; strings.TrimSpace(indent) == "" {
+			text = indent + text
+		}
+		members = append(members, text)
+	}
+
+	merged := base
+	if len(members) > 0 {
+		closing := baseClass.EndByte() - 1
+		merged = strings.TrimRight(base[:closing], " \t\n") + "\n\n" + strings.Join(members, "\n\n") + "\n" + base[closing:]
+	}
+	return addJavaImports(merged, next)
+}
+
+// firstJavaClassBody returns the body of the first top-level class declaration.
+func firstJavaClassBody(root *tree_sitter.Node) *tree_sitter.Node {
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		node := root.NamedChild(i)
+		if node.Kind() == "class_declaration" {
+			return node.ChildByFieldName("body")
+		}
+	}
+	return nil
+}
+
+// javaMemberName returns the name a class member declares, qualified by its kind
+// so fields and methods of the same name do not collide.
+func javaMemberName(member *tree_sitter.Node, code string) string {
+	nameNode := member.ChildByFieldName("name")
+	if member.Kind() == "field_declaration" {
+		if declarator := member.ChildByFieldName("declarator"); declarator != nil {
+			nameNode = declarator.ChildByFieldName("name")
+		}
+	}
+	if nameNode == nil {
+		return member.Kind() + ":" + strings.TrimSpace(code[member.StartByte():member.EndByte()])
+	}
+	name := code[nameNode.StartByte():nameNode.EndByte()]
+	if member.Kind() == "field_declaration" {
+		return "field:" + name
+	}
+	return name
+}
+
+// addJavaImports adds the import declarations of next that code lacks after the
+// last import of code, or after its package declaration if it has no imports.
+func addJavaImports(code, next string) string {
+	present := make(map[string]bool)
+	for _, declaration := range javaImportDeclarationPattern.FindAllString(code, -1) {
+		present[strings.TrimSpace(declaration)] = true
+	}
+
+	var missing []string
+	for _, declaration := range javaImportDeclarationPattern.FindAllString(next, -1) {
+		line := strings.TrimSpace(declaration)
+		if !present[line] {
+			present[line] = true
+			missing = append(missing, line)
+		}
+	}
+	if len(missing) == 0 {
+		return code
+	}
+
+	block := strings.Join(missing, "\n") + "\n"
+	if locs := javaImportDeclarationPattern.FindAllStringIndex(code, -1); len(locs) > 0 {
+		end := locs[len(locs)-1][1]
+		return code[:end] + "\n" + strings.TrimSuffix(block, "\n") + code[end:]
+	}
+	if loc := javaPackagePattern.FindStringIndex(code); loc != nil {
+		return code[:loc[1]] + "\n\n" + strings.TrimSuffix(block, "\n") + code[loc[1]:]
+	}
+	return block + "\n" + code
+}
@@ -0,0 +1,273 @@
+package worker
+
+import tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+// jsFunctionKinds are the node kinds of JavaScript function definitions.
+var jsFunctionKinds = map[string]bool{
+	"function_declaration":           true,
+	"generator_function_declaration": true,
+	"function_expression":            true,
+	"generator_function":             true,
+	"arrow_function":                 true,
+	"method_definition":              true,
+}
+
+// collectJSSymFunctions returns every named JavaScript function below the given
+// node in source order: function declarations, class methods and function
+// expressions and arrow functions assigned to a variable, which are named after
+// the variable. Anonymous functions, such as callbacks, are tested with the
+// function they are passed to.
+func collectJSSymFunctions(root *tree_sitter.Node, code string) []symFunction {
+	var funcs []symFunction
+	var collect func(node *tree_sitter.Node)
+	collect = func(node *tree_sitter.Node) {
+		switch node.Kind() {
+		case "function_declaration", "generator_function_declaration", "method_definition":
+			name := "unknown"
+			if nameNode := node.ChildByFieldName("name"); nameNode != nil {
+				name = code[nameNode.StartByte():nameNode.EndByte()]
+			}
+			funcs = append(funcs, symFunction{Node: node, Name: name})
+		case "variable_declarator":
+			nameNode := node.ChildByFieldName("name")
+			value := node.ChildByFieldName("value")
+			if nameNode != nil && nameNode.Kind() == "identifier" && value != nil && jsFunctionKinds[value.Kind()] {
+				funcs = append(funcs, symFunction{Node: value, Name: code[nameNode.StartByte():nameNode.EndByte()]})
+			}
+		}
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			collect(node.NamedChild(i))
+		}
+	}
+	collect(root)
+	return funcs
+}
+
+// isJSStubFunction reports whether the JavaScript function has a body without
+// statements. Arrow functions with an expression body are never stubs.
+func isJSStubFunction(fn *tree_sitter.Node) bool {
+	body := fn.ChildByFieldName("body")
+	if body == nil {
+		return true
+	}
+	if body.Kind() != "statement_block" {
+		return false
+	}
+	for i := uint(0); i < body.NamedChildCount(); i++ {
+		if body.NamedChild(i).Kind() != "comment" {
+			return false
+		}
+	}
+	return true
+}
+
+// jsFunctionSignature renders the function as its name followed by its
+// parameters, e.g. "clamp(value, min = 0)". The single parameter of an arrow
+// function written without parentheses is put in parentheses.
+func jsFunctionSignature(fn symFunction, code string) string {
+	if params := fn.Node.ChildByFieldName("parameters"); params != nil {
+		return fn.Name + code[params.StartByte():params.EndByte()]
+	}
+	if param := fn.Node.ChildByFieldName("parameter"); param != nil {
+		return fn.Name + "(" + code[param.StartByte():param.EndByte()] + ")"
+	}
+	return fn.Name + "()"
+}
+
+// collectJSSymPaths collects the execution paths through the body of the
+// JavaScript function. The expression body of an arrow function is a single
+// path returning the expression. It reports false if the body has syntax errors.
+func collectJSSymPaths(fn *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, paths *[][]string) bool {
+	body := fn.ChildByFieldName("body")
+	if body != nil && body.Kind() != "statement_block" {
+		*paths = append(*paths, []string{"return:" + getNodeText(body)})
+		return !body.HasError()
+	}
+	return CollectPathsJS(body, getNodeText, []string{}, paths)
+}
+
+// CollectPathsJS collects the execution paths through a JavaScript node into
+// paths the way CollectPathsPython does for Python: every path starts with cur,
+// holds the branch markers of the branches it takes through the statements of
+// the node and ends where the node ends or in a return or throw. The cases of a
+// switch statement are branches of their own, like the cases of a Python match
+// statement. It reports false if the node has syntax errors.
+func CollectPathsJS(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, cur []string, paths *[][]string) bool {
+	open := jsPaths(node, getNodeText, [][]string{cur}, paths)
+	*paths = append(*paths, open...)
+	return node == nil || !node.HasError()
+}
+
+// jsPaths continues the open paths through a JavaScript statement. Paths ended
+// by a return or throw statement are added to paths, the paths continuing after
+// the statement are returned.
+func jsPaths(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, open [][]string, paths *[][]string) [][]string {
+	if node == nil || len(open) == 0 {
+		return open
+	}
+
+	switch node.Kind() {
+	case "statement_block", "else_clause":
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			open = jsPaths(node.NamedChild(i), getNodeText, open, paths)
+		}
+		return open
+	case "labeled_statement":
+		return jsPaths(node.ChildByFieldName("body"), getNodeText, open, paths)
+	case "if_statement":
+		condNode := node.ChildByFieldName("condition")
+		cond := "if"
+		if condNode != nil {
+			cond += ":" + jsConditionText(condNode, getNodeText)
+		}
+		next := jsPaths(node.ChildByFieldName("consequence"), getNodeText, extendPaths(open, cond+"-then"), paths)
+		// An else-if is an if statement of its own in the else clause
+		elsePaths := jsPaths(node.ChildByFieldName("alternative"), getNodeText, extendPaths(open, cond+"-else"), paths)
+		return limitPaths(append(next, elsePaths...))
+	case "for_statement", "for_in_statement", "while_statement", "do_statement":
+		body := node.ChildByFieldName("body")
+		return loopPaths(open, jsLoopHeader(node, getNodeText), func(open [][]string) [][]string {
+			return jsPaths(body, getNodeText, open, paths)
+		})
+	case "switch_statement":
+		subject := "switch"
+		if valueNode := node.ChildByFieldName("value"); valueNode != nil {
+			subject = jsConditionText(valueNode, getNodeText)
+		}
+		bodyNode := node.ChildByFieldName("body")
+		if bodyNode == nil {
+			return open
+		}
+		var cases []*tree_sitter.Node
+		var markers []string
+		hasDefault := false
+		for i := uint(0); i < bodyNode.NamedChildCount(); i++ {
+			c := bodyNode.NamedChild(i)
+			switch c.Kind() {
+			case "switch_case":
+				markers = append(markers, "case:"+getNodeText(c.ChildByFieldName("value")))
+			case "switch_default":
+				markers, hasDefault = append(markers, "case:default"), true
+			default:
+				continue
+			}
+			cases = append(cases, c)
+		}
+		var next [][]string
+		for i := range cases {
+			// A case without a break falls through into the bodies of the
+			// cases after it
+			casePaths := extendPaths(open, "switch:"+subject, markers[i])
+			for j := i; j < len(cases) && len(casePaths) > 0; j++ {
+				var broke bool
+				casePaths, broke = jsCasePaths(cases[j], getNodeText, casePaths, paths)
+				if broke {
+					break
+				}
+			}
+			next = append(next, casePaths...)
+		}
+		if !hasDefault {
+			next = append(next, extendPaths(open, "switch:"+subject, noCaseMatched)...)
+		}
+		return limitPaths(next)
+	case "try_statement":
+		handler := node.ChildByFieldName("handler")
+		tryPaths := open
+		if handler != nil {
+			tryPaths = extendPaths(open, "try")
+		}
+		next := jsPaths(node.ChildByFieldName("body"), getNodeText, tryPaths, paths)
+		if handler != nil {
+			// The parameter of the catch clause is not part of any path
+			catchPaths := extendPaths(open, "catch:an error")
+			next = append(next, jsPaths(handler.ChildByFieldName("body"), getNodeText, catchPaths, paths)...)
+		}
+		if finalizer := node.ChildByFieldName("finalizer"); finalizer != nil {
+			next = jsPaths(finalizer.ChildByFieldName("body"), getNodeText, limitPaths(next), paths)
+		}
+		return limitPaths(next)
+	case "throw_statement":
+		*paths = append(*paths, extendPaths(open, "throw:"+jsThrownException(node, getNodeText))...)
+		return nil
+	case "return_statement":
+		*paths = append(*paths, extendPaths(open, "return:"+jsReturnedValue(node, getNodeText))...)
+		return nil
+	}
+	return open
+}
+
+// jsCasePaths continues the open paths through the statements of a switch
+// case and reports whether the case ends in a break. Statements after the break
+// are never reached.
+func jsCasePaths(caseNode *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, open [][]string, paths *[][]string) ([][]string, bool) {
+	cursor := caseNode.Walk()
+	defer cursor.Close()
+	for _, statement := range caseNode.ChildrenByFieldName("body", cursor) {
+		if statement.Kind() == "break_statement" && statement.ChildByFieldName("label") == nil {
+			return open, true
+		}
+		open = jsPaths(&statement, getNodeText, open, paths)
+	}
+	return open, false
+}
+
+// jsConditionText returns the text of a JavaScript condition without the
+// parentheses around it, which are not part of the condition.
+func jsConditionText(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	if node.Kind() == "parenthesized_expression" && node.NamedChildCount() == 1 {
+		node = node.NamedChild(0)
+	}
+	return getNodeText(node)
+}
+
+// jsLoopHeader returns the loop marker of a JavaScript loop without its variant
+// suffix: "for:" and the iterated object for a for...in or for...of loop,
+// "while:" and the condition for other loops. A do...while loop always runs its
+// body once and a for loop without a condition never ends on one, so neither
+// has a marker.
+func jsLoopHeader(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	switch node.Kind() {
+	case "do_statement":
+		return ""
+	case "for_in_statement":
+		if right := node.ChildByFieldName("right"); right != nil {
+			return "for:" + jsConditionText(right, getNodeText)
+		}
+		return ""
+	}
+	condNode := node.ChildByFieldName("condition")
+	if condNode != nil && condNode.Kind() == "expression_statement" && condNode.NamedChildCount() == 1 {
+		condNode = condNode.NamedChild(0)
+	}
+	if condNode == nil || condNode.Kind() == "empty_statement" {
+		return ""
+	}
+	return "while:" + jsConditionText(condNode, getNodeText)
+}
+
+// jsThrownException returns the type of the error thrown by a JavaScript throw
+// statement, e.g. "RangeError" for `throw new RangeError("negative")`, or the
+// thrown expression if it does not create the error.
+func jsThrownException(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	thrown := node.NamedChild(0)
+	if thrown == nil {
+		return "an error"
+	}
+	if thrown.Kind() == "new_expression" {
+		if constructor := thrown.ChildByFieldName("constructor"); constructor != nil {
+			return getNodeText(constructor)
+		}
+	}
+	return getNodeText(thrown)
+}
+
+// jsReturnedValue returns the expression returned by a JavaScript return
+// statement, or "undefined" for a bare `return`.
+func jsReturnedValue(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	valueNode := node.NamedChild(0)
+	if valueNode == nil {
+		return "undefined"
+	}
+	return getNodeText(valueNode)
+}
@@ -1,19 +1,30 @@
 package worker
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"unsafe"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+	"github.com/Marksagittarius/pinguis/preprocessor"
+	"github.com/Marksagittarius/pinguis/prompt"
+	"github.com/Marksagittarius/pinguis/scripts/treesitter"
+	"github.com/Marksagittarius/pinguis/types"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
 	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
 )
 
 type SymPromptWorker struct {
 	*DeepWorker
-	fileIO FileIO
 }
 
 type FileIO interface {
@@ -22,141 +33,751 @@ type FileIO interface {
 }
 
 func NewSymPromptWorker(config *DeepWorkerConfig, fileIO FileIO) *SymPromptWorker {
-	return &SymPromptWorker{
-		DeepWorker: NewDeepWorker(config),
-		fileIO:     fileIO,
-	}
+	dw := NewDeepWorker(config)
+	dw.fileIO = fileIO
+	return &SymPromptWorker{DeepWorker: dw}
+}
+
+// symFunction is a function definition discovered in a source file.
+type symFunction struct {
+	Node *tree_sitter.Node
+	Name string
+}
+
+// FunctionOrder controls the order in which the SymPromptWorker generates tests
+// for the functions of a source file.
+type FunctionOrder string
+
+const (
+	// SourceOrder processes functions in the order they are defined (the default).
+	SourceOrder FunctionOrder = "source"
+	// NameSorted processes functions alphabetically by name.
+	NameSorted FunctionOrder = "name"
+	// ComplexityDesc processes functions with the highest cyclomatic complexity first.
+	ComplexityDesc FunctionOrder = "complexity"
+)
+
+// symSource is a source file whose functions are being processed, together with
+// the prompt template and any extra context shared by all of its functions.
+// MergedTest is the test class the tests of the Java methods processed so far
+// were merged into. Notebook is the notebook the code was extracted from, empty
+// for plain source files.
+type symSource struct {
+	Path           string
+	Code           string
+	CodeType       string
+	PromptTemplate string
+	ExtraContext   string
+	MergedTest     string
+	Notebook       string
+}
+
+// symOptions controls how a source file is processed by submitSymFunctions.
+//
+// Fields:
+// - Selector: Chooses which of the collected functions are processed (all if nil).
+// - ContextFiles: Files whose structural summary is appended to every prompt.
+type symOptions struct {
+	Selector     func([]symFunction) ([]symFunction, error)
+	ContextFiles []string
 }
 
+// SubmitSymTask generates a test for every function defined in the source file.
+// Each function's execution paths are collected and minimized, then described in
+// the prompt so that the model generates one test case per path. Python, Java
+// and JavaScript files and Jupyter notebooks are supported, chosen by the file
+// extension; other files fail with ErrUnsupportedLanguage. A function whose test
+// cannot be generated is recorded as failed in the report without failing the
+// file, which fails only if it cannot be parsed or the worker is shut down.
 func (sw *SymPromptWorker) SubmitSymTask(sourcePath string) error {
+	return sw.submitSymFunctions(sourcePath, symOptions{})
+}
+
+// FileFilter decides whether a discovered source file is submitted.
+type FileFilter func(path string, info os.FileInfo) bool
+
+// symLanguage is what the SymPromptWorker needs to generate tests for the
+// functions of a language.
+//
+// Fields:
+// - grammar: The tree-sitter grammar source files are parsed with.
+// - collectFunctions: Returns the functions to test below the root of a parsed file.
+// - collectPaths: Collects the execution paths through a function into paths and
+//   reports whether they are complete, which they may not be if the function has
+//   syntax errors.
+type symLanguage struct {
+	grammar          func() unsafe.Pointer
+	collectFunctions func(root *tree_sitter.Node, code string) []symFunction
+	collectPaths     func(fn *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, paths *[][]string) bool
+}
+
+// symLanguages are the languages the SymPromptWorker can collect paths for,
+// keyed by code type.
+var symLanguages = map[string]symLanguage{
+	"python": {
+		grammar:          tree_sitter_python.Language,
+		collectFunctions: collectSymFunctions,
+		collectPaths: func(fn *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, paths *[][]string) bool {
+			return CollectPathsPython(fn.ChildByFieldName("body"), getNodeText, []string{}, paths)
+		},
+	},
+	"java": {
+		grammar:          tree_sitter_java.Language,
+		collectFunctions: collectJavaSymMethods,
+		collectPaths: func(fn *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, paths *[][]string) bool {
+			return CollectPathsJava(fn.ChildByFieldName("body"), getNodeText, []string{}, paths)
+		},
+	},
+	"javascript": {
+		grammar:          tree_sitter_javascript.Language,
+		collectFunctions: collectJSSymFunctions,
+		collectPaths:     collectJSSymPaths,
+	},
+}
+
+// ErrUnsupportedLanguage is returned when a file of a language the
+// SymPromptWorker cannot collect paths for is submitted.
+var ErrUnsupportedLanguage = errors.New("symbolic test generation does not support this language")
+
+// isGeneratedTestFile reports whether the file name belongs to a test, either
+// written by hand next to the source or generated by a previous run. Java tests
+// are named after their test class, e.g. CalcTest.java, and JavaScript tests
+// often end in .test.js or .spec.js. The tests kept for every iteration (see
+// iterationArtifactPath) are tests as well.
+func isGeneratedTestFile(name string) bool {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if ext == ".java" && (strings.HasSuffix(base, "Test") || strings.HasSuffix(base, "Tests")) {
+		return true
+	}
+	if ext == ".js" && (strings.HasSuffix(base, ".test") || strings.HasSuffix(base, ".spec")) {
+		return true
+	}
+	return strings.HasSuffix(base, "_test") || strings.Contains(base, "_test_case_") || isIterationArtifact(name)
+}
+
+// SubmitDirectory walks the directory tree and calls SubmitSymTask for every
+// source file of a supported language, skipping hidden directories and test
+// files. With IncludeNotebooks set, Jupyter notebooks are submitted as well,
+// unless a Python file of the same name, such as a paired script, exists. The
+// source an earlier run extracted from a notebook is not submitted on its own,
+// it is extracted again from the notebook. With GitTrackedOnly set, files git does not track are skipped as well, unless root
+// is not inside a git repository. A failing file does not stop the walk.
+//
+// Parameters:
+//   - root: The directory to search for source files.
+//   - filter: Decides which of the discovered files are submitted (all if nil).
+//
+// Returns:
+//   - int: The number of files submitted successfully.
+//   - []error: The errors of the files that failed and of the walk itself.
+func (sw *SymPromptWorker) SubmitDirectory(root string, filter FileFilter) (int, []error) {
+	submitted := 0
+	errs := sw.walkSymSources(root, filter, func(path string) error {
+		if err := sw.SubmitSymTask(path); err != nil {
+			return fmt.Errorf("failed to submit %s: %w", path, err)
+		}
+		submitted++
+		return nil
+	})
+	return submitted, errs
+}
+
+// walkSymSources calls visit for every source file below root that
+// SubmitDirectory would submit, and returns the errors of the walk and of the
+// visits. A failing visit does not stop the walk.
+func (sw *SymPromptWorker) walkSymSources(root string, filter FileFilter, visit func(path string) error) []error {
+	var errs []error
+
+	var tracked map[string]bool
+	if sw.gitTrackedOnly {
+		var err error
+		if tracked, err = fileio.GitTrackedFiles(root); err != nil {
+			log.Printf("Submitting every file of %s: %v", root, err)
+		}
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isNotebook(path) {
+			if !sw.includeNotebooks || hasNotebookSource(path) {
+				return nil
+			}
+		} else if _, ok := symLanguages[getCodeType(path)]; !ok || isGeneratedTestFile(info.Name()) || isNotebookSource(path) {
+			return nil
+		}
+		if tracked != nil && !tracked[filepath.Clean(path)] {
+			return nil
+		}
+		if filter != nil && !filter(path, info) {
+			return nil
+		}
+
+		if err := visit(path); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// SubmitSymTaskWithContext works like SubmitSymTask but also summarizes the given
+// context files with the language parsers and appends their structure to every
+// prompt. This lets callers inject dependencies that cannot be discovered
+// automatically, such as configuration modules or base classes in other packages.
+func (sw *SymPromptWorker) SubmitSymTaskWithContext(sourcePath string, contextFiles []string) error {
+	return sw.submitSymFunctions(sourcePath, symOptions{ContextFiles: contextFiles})
+}
+
+// SubmitSymTaskForFunction generates a test only for the function with the given
+// name in the source file. It returns an error if the file does not define it.
+func (sw *SymPromptWorker) SubmitSymTaskForFunction(sourcePath, functionName string) error {
+	selector := func(funcs []symFunction) ([]symFunction, error) {
+		var selected []symFunction
+		for _, fn := range funcs {
+			if fn.Name == functionName {
+				selected = append(selected, fn)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("function %s not found in %s", functionName, sourcePath)
+		}
+		return selected, nil
+	}
+	return sw.submitSymFunctions(sourcePath, symOptions{Selector: selector})
+}
+
+// SubmitSymTaskForRange generates tests only for the functions of the source file
+// whose definition overlaps the lines from startLine to endLine, e.g. the lines
+// a diff changed. Lines are numbered from 1 and the range includes both ends.
+// Functions enclosing the range, such as the class method around a changed line,
+// overlap it as well. It returns an error if the range is invalid or no function
+// overlaps it.
+func (sw *SymPromptWorker) SubmitSymTaskForRange(sourcePath string, startLine, endLine int) error {
+	if startLine < 1 || endLine < startLine {
+		return fmt.Errorf("invalid line range %d-%d", startLine, endLine)
+	}
+
+	selector := func(funcs []symFunction) ([]symFunction, error) {
+		var selected []symFunction
+		for _, fn := range funcs {
+			// Tree-sitter rows are numbered from 0
+			first := int(fn.Node.StartPosition().Row) + 1
+			last := int(fn.Node.EndPosition().Row) + 1
+			if first <= endLine && startLine <= last {
+				selected = append(selected, fn)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("no function in %s overlaps lines %d-%d", sourcePath, startLine, endLine)
+		}
+		return selected, nil
+	}
+	return sw.submitSymFunctions(sourcePath, symOptions{Selector: selector})
+}
+
+// submitSymFunctions parses the source file, collects its function definitions
+// and generates a test for each of the functions chosen by the options.
+func (sw *SymPromptWorker) submitSymFunctions(sourcePath string, opts symOptions) error {
+	src, funcs, tree, err := sw.parseSymSource(sourcePath, opts)
+	if errors.Is(err, types.ErrIncompleteParse) {
+		log.Printf("Warning: the functions and paths collected from %v", err)
+	} else if err != nil {
+		return err
+	}
+	defer tree.Close()
+
+	if src.Notebook != "" {
+		if err := sw.writeNotebookSource(src); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range funcs {
+		if reason := sw.skipReason(src, fn); reason != "" {
+			log.Printf("Skipping %s in %s: %s", fn.Name, sourcePath, reason)
+			continue
+		}
+		if err := sw.generateSymTest(src, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSymSource reads and parses the source file and returns it together with
+// the functions chosen by the options, in the order they are processed. The
+// functions belong to the returned tree, which the caller must close once done
+// with them. The file is parsed with the grammar of the language its extension
+// names, and files of other languages than those in symLanguages are rejected
+// with ErrUnsupportedLanguage. A notebook is parsed as the Python source of its
+// code cells, at the path that source is written to. Tree-sitter recovers from
+// syntax errors, so a file with syntax errors is returned with the functions
+// that could be parsed, together with a *types.IncompleteParseError listing the
+// errors: functions around them may be missing or cut short.
+func (sw *SymPromptWorker) parseSymSource(sourcePath string, opts symOptions) (*symSource, []symFunction, *tree_sitter.Tree, error) {
+	if _, ok := symLanguages[getCodeType(sourcePath)]; !ok && !isNotebook(sourcePath) {
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, sourcePath)
+	}
+
 	codeBytes, err := sw.fileIO.Read(sourcePath)
 	if err != nil {
-		return fmt.Errorf("failed to read code: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to read code: %w", err)
 	}
 	code := string(codeBytes)
 
+	notebookPath := ""
+	if isNotebook(sourcePath) {
+		if code, err = preprocessor.ExtractNotebookCode(codeBytes); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to extract the code of %s: %w", sourcePath, err)
+		}
+		notebookPath, sourcePath = sourcePath, preprocessor.NotebookSourcePath(sourcePath)
+	}
+
+	promptTemplate, err := sw.loadPromptTemplate()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	src := &symSource{
+		Path:           sourcePath,
+		Code:           code,
+		CodeType:       getCodeType(sourcePath),
+		PromptTemplate: promptTemplate,
+		Notebook:       notebookPath,
+	}
+	if len(opts.ContextFiles) > 0 {
+		src.ExtraContext, err = summarizeContextFiles(opts.ContextFiles)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	lang := symLanguages[src.CodeType]
 	parser := tree_sitter.NewParser()
-	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_python.Language()))
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(lang.grammar()))
 	tree := parser.Parse([]byte(code), nil)
-	root := tree.RootNode()
+	funcs := lang.collectFunctions(tree.RootNode(), code)
+	if opts.Selector != nil {
+		funcs, err = opts.Selector(funcs)
+		if err != nil {
+			tree.Close()
+			return nil, nil, nil, err
+		}
+	}
+	if err := orderSymFunctions(funcs, sw.functionOrder); err != nil {
+		tree.Close()
+		return nil, nil, nil, err
+	}
+	return src, funcs, tree, treesitter.CheckSyntax(tree.RootNode(), sourcePath)
+}
+
+// loadPromptTemplate loads the template of the prompts of a source file, once for
+// all of its functions: from the configured prompt source, or else from the
+// configured template path through the worker's FileIO, so the template is found
+// whatever the working directory is. Without either, prompt.txt in the working
+// directory is read, falling back to the built-in template.
+func (sw *SymPromptWorker) loadPromptTemplate() (string, error) {
+	if sw.promptSource != nil {
+		return sw.promptSource()
+	}
+	if sw.promptTemplatePath == "" {
+		return prompt.FromFileOrDefault("prompt.txt")()
+	}
+
+	template, err := sw.fileIO.Read(sw.promptTemplatePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template %s: %w", sw.promptTemplatePath, err)
+	}
+	return string(template), nil
+}
 
-	var funcNodes []*tree_sitter.Node
-	var funcNames []string
+// collectSymFunctions returns every function definition below the given node
+// in source order.
+func collectSymFunctions(root *tree_sitter.Node, code string) []symFunction {
+	var funcs []symFunction
 	var collectFuncs func(node *tree_sitter.Node)
 	collectFuncs = func(node *tree_sitter.Node) {
 		if node == nil {
 			return
 		}
 		if node.Kind() == "function_definition" {
-			funcNodes = append(funcNodes, node)
+			name := "unknown"
 			nameNode := node.ChildByFieldName("name")
 			if nameNode != nil {
-				funcNames = append(funcNames, string(code[nameNode.StartByte():nameNode.EndByte()]))
-			} else {
-				funcNames = append(funcNames, "unknown")
+				name = string(code[nameNode.StartByte():nameNode.EndByte()])
 			}
+			funcs = append(funcs, symFunction{Node: node, Name: name})
 		}
 		for i := 0; i < int(node.NamedChildCount()); i++ {
 			collectFuncs(node.NamedChild(uint(i)))
 		}
 	}
 	collectFuncs(root)
+	return funcs
+}
 
-	promptTemplateBytes, err := os.ReadFile("prompt.txt")
-	if err != nil {
-		return fmt.Errorf("failed to read prompt template: %w", err)
+// orderSymFunctions sorts the functions in place according to the given order.
+// Functions that compare equal keep their source order.
+func orderSymFunctions(funcs []symFunction, order FunctionOrder) error {
+	switch order {
+	case "", SourceOrder:
+	case NameSorted:
+		sort.SliceStable(funcs, func(i, j int) bool {
+			return funcs[i].Name < funcs[j].Name
+		})
+	case ComplexityDesc:
+		complexity := make(map[*tree_sitter.Node]int, len(funcs))
+		for _, fn := range funcs {
+			complexity[fn.Node] = cyclomaticComplexity(fn.Node)
+		}
+		sort.SliceStable(funcs, func(i, j int) bool {
+			return complexity[funcs[i].Node] > complexity[funcs[j].Node]
+		})
+	default:
+		return fmt.Errorf("unknown function order %q", order)
 	}
-	promptTemplate := string(promptTemplateBytes)
+	return nil
+}
 
-	for idx, fn := range funcNodes {
-		var paths [][]string
-		bodyNode := fn.ChildByFieldName("body")
-		CollectPathsPython(bodyNode, func(n *tree_sitter.Node) string {
-			return string(code[n.StartByte():n.EndByte()])
-		}, []string{}, &paths)
-		minPaths := MinimizePaths(paths)
+// cyclomaticComplexity returns one plus the number of decision points in the
+// function, nested functions included.
+func cyclomaticComplexity(fn *tree_sitter.Node) int {
+	complexity := 1
+	var visit func(node *tree_sitter.Node)
+	visit = func(node *tree_sitter.Node) {
+		switch node.Kind() {
+		case "if_statement", "elif_clause", "for_statement", "while_statement",
+			"except_clause", "case_clause", "conditional_expression", "boolean_operator",
+			"for_in_clause", "if_clause",
+			"enhanced_for_statement", "do_statement", "catch_clause", "ternary_expression", "switch_label",
+			"for_in_statement", "switch_case":
+			complexity++
+		}
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			visit(node.NamedChild(uint(i)))
+		}
+	}
+	visit(fn)
+	return complexity
+}
 
-		nameNode := fn.ChildByFieldName("name")
-		funcName := "unknown"
-		if nameNode != nil {
-			funcName = string(code[nameNode.StartByte():nameNode.EndByte()])
+// collectSymPaths returns the minimized execution paths through the function
+// body, collected by the path collector of the code type, at most maxPaths of
+// them unless maxPaths is 0, the conditions of the branches the returned paths
+// leave uncovered, and whether the paths are complete, which they may not be if
+// the function has syntax errors.
+func collectSymPaths(fn symFunction, code, codeType string, maxPaths int) ([][]string, []string, bool) {
+	var paths [][]string
+	getNodeText := func(n *tree_sitter.Node) string {
+		return string(code[n.StartByte():n.EndByte()])
+	}
+	complete := symLanguages[codeType].collectPaths(fn.Node, getNodeText, &paths)
+	minPaths, uncovered := MinimizePathsN(paths, maxPaths)
+	return minPaths, uncovered, complete
+}
+
+// skipReason returns why no symbolic test is generated for the function, or
+// "" if one is: abstract methods are skipped with skipAbstract set, functions
+// with stub bodies unless forceGenerate is set.
+func (sw *SymPromptWorker) skipReason(src *symSource, fn symFunction) string {
+	if sw.skipAbstract && isAbstractJavaMethod(fn.Node, src.Code) {
+		return "method is abstract"
+	}
+	if !sw.forceGenerate && isStubFunction(fn.Node) {
+		return "function body is a stub"
+	}
+	return ""
+}
+
+// isStubFunction reports whether the function's body does nothing, i.e. consists
+// only of pass, ... and docstrings, as in abstract methods and stubs, or has no
+// statements for Java methods and JavaScript functions.
+func isStubFunction(fn *tree_sitter.Node) bool {
+	if fn.Kind() == "method_declaration" {
+		return isJavaStubMethod(fn)
+	}
+	if jsFunctionKinds[fn.Kind()] {
+		return isJSStubFunction(fn)
+	}
+
+	body := fn.ChildByFieldName("body")
+	if body == nil {
+		return true
+	}
+
+	for i := 0; i < int(body.NamedChildCount()); i++ {
+		stmt := body.NamedChild(uint(i))
+		switch stmt.Kind() {
+		case "pass_statement", "comment":
+			continue
+		case "expression_statement":
+			if stmt.NamedChildCount() == 1 {
+				kind := stmt.NamedChild(0).Kind()
+				if kind == "ellipsis" || kind == "string" {
+					continue
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// generateSymTest builds the path-constrained prompt for a single function,
+// generates its test with the model and writes it next to the source file. The
+// tests of Java methods are written to the test class of the source file, which
+// collects the tests of all its methods. The test is improved on the callback's
+// feedback like processTask does, with the worker's context and task timeout,
+// but the iterations run one after another. A function whose test cannot be
+// generated is recorded as failed in the report, so that the other functions of
+// the file still get tests; only a stopped worker ends the file with an error.
+func (sw *SymPromptWorker) generateSymTest(src *symSource, fn symFunction) error {
+	task := sw.newSymTask(src, fn)
+
+	for {
+		if err := sw.ctx.Err(); err != nil {
+			sw.failTask(task, err)
+			return err
+		}
+
+		status, running, err := sw.iterateWithTimeout(task)
+		if errors.Is(err, errTaskTimeout) {
+			<-running
+			if task.Iterations >= sw.maxIterations {
+				sw.finishTask(task, TaskTimedOut, err)
+				return nil
+			}
+			sw.reportTimeout(task)
+			task.Iterations++
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, errCallbackFailed) {
+				if mergeErr := sw.mergeSymTest(src, task); mergeErr != nil {
+					err = errors.Join(err, mergeErr)
+				}
+			}
+			sw.failTask(task, err)
+			if ctxErr := sw.ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return nil
 		}
-		parametersNode := fn.ChildByFieldName("parameters")
+		if status != taskContinue {
+			if err := sw.mergeSymTest(src, task); err != nil {
+				sw.failTask(task, err)
+				return nil
+			}
+			sw.finishTask(task, status, nil)
+			return nil
+		}
+	}
+}
+
+// mergeSymTest merges the test generated for a Java method into the test class
+// holding the tests of the methods processed before, and writes the result over
+// the test file, which the iterations of the method wrote its tests alone to.
+// Tests of other languages have a file per function and are left as they are.
+// The test written hook runs for the test class once the task is finished.
+func (sw *SymPromptWorker) mergeSymTest(src *symSource, task *TestTask) error {
+	if src.CodeType != "java" || task.GeneratedTest == "" {
+		return nil
+	}
+
+	src.MergedTest = mergeJavaTestClasses(src.MergedTest, task.GeneratedTest)
+	if err := sw.fileIO.Write(task.TestPath, []byte(src.MergedTest)); err != nil {
+		return fmt.Errorf("failed to write test file %s: %w", task.TestPath, err)
+	}
+	task.addWrittenFile(task.TestPath)
+	return nil
+}
+
+// newSymTask returns the task generating the test of a single function, with
+// the prompt describing the function's minimized execution paths.
+func (sw *SymPromptWorker) newSymTask(src *symSource, fn symFunction) *TestTask {
+	sourcePath, code := src.Path, src.Code
+
+	minPaths, uncovered, complete := collectSymPaths(fn, code, src.CodeType, sw.maxPathsPerFunction)
+	if !complete {
+		log.Printf("Warning: %s in %s has syntax errors, the paths collected from it may be incomplete", fn.Name, sourcePath)
+	}
+
+	funcName := fn.Name
+	var signature string
+	switch src.CodeType {
+	case "java":
+		signature = javaMethodSignature(fn, code)
+	case "javascript":
+		signature = jsFunctionSignature(fn, code)
+	default:
+		parametersNode := fn.Node.ChildByFieldName("parameters")
 		params := ""
 		if parametersNode != nil {
 			params = string(code[parametersNode.StartByte():parametersNode.EndByte()])
 		}
 		returns := ""
-		retNode := fn.ChildByFieldName("return_type")
+		retNode := fn.Node.ChildByFieldName("return_type")
 		if retNode != nil {
 			returns = string(code[retNode.StartByte():retNode.EndByte()])
 		}
+		signature = funcName + params + funcReturnTypeStr(returns)
+	}
 
-		pathDescs := []string{}
-		for i, p := range minPaths {
-			conds := []string{}
-			retVal := ""
-			for j, kind := range p {
-				if strings.HasPrefix(kind, "if:") {
-					condExpr := strings.TrimPrefix(kind, "if:")
-					if j+1 < len(p) && strings.HasSuffix(p[j+1], "-else") {
-						conds = append(conds, "not("+condExpr+")")
-					} else {
-						conds = append(conds, condExpr)
-					}
-				}
-				if strings.HasPrefix(kind, "elif:") {
-					condExpr := strings.TrimPrefix(kind, "elif:")
-					if j+1 < len(p) && strings.HasSuffix(p[j+1], "-else") {
-						conds = append(conds, "not("+condExpr+")")
-					} else {
-						conds = append(conds, condExpr)
-					}
-				}
-				if strings.HasPrefix(kind, "return") {
-					retVal = strings.TrimSpace(strings.TrimPrefix(kind, "return"))
+	pathDescs := []string{}
+	for i, p := range minPaths {
+		pathDescs = append(pathDescs, describeSymPath(i, signature, p, src.CodeType))
+	}
+	if len(uncovered) > 0 {
+		pathDescs = append(pathDescs, fmt.Sprintf("The test cases above are limited to %d paths and leave the branches of %s where these conditions hold uncovered: %s",
+			len(minPaths), funcName, strings.Join(uncovered, "; ")))
+	}
+	promptStr := src.PromptTemplate
+	promptStr = strings.ReplaceAll(promptStr, "{path_constraints}", strings.Join(pathDescs, "\n"))
+	promptStr = strings.ReplaceAll(promptStr, "{code}", symPromptCode(code, fn, sw.contextLevel))
+	promptStr = strings.ReplaceAll(promptStr, "{file_name}", sourcePath)
+	if src.ExtraContext != "" {
+		promptStr += "\n" + src.ExtraContext
+	}
+
+	testPath := filepath.Join(filepath.Dir(sourcePath), symTestFileName(sourcePath, funcName, 0, src.CodeType))
+	switch src.CodeType {
+	case "java":
+		promptStr += javaSymInstruction(sourcePath, funcName)
+	case "python":
+		promptStr += pythonDecoratorNote(fn, code)
+	}
+
+	cover := newPathCover(minPaths, uncovered)
+	cover.Incomplete = !complete
+	return &TestTask{
+		SourceCode:   code,
+		SourcePath:   sourcePath,
+		CodeType:     src.CodeType,
+		FunctionName: funcName,
+		TestPath:     testPath,
+		BasePrompt:   promptStr,
+		PathCover:    cover,
+	}
+}
+
+// symPathConditions returns the conditions that hold on the path, in the order
+// the path reaches them, e.g. "x > 0" and "not(y)".
+func symPathConditions(path []string) []string {
+	conds := []string{}
+	subject := ""
+	inSwitch := false
+	for _, marker := range path {
+		switch {
+		case strings.HasPrefix(marker, "match:"):
+			subject, inSwitch = strings.TrimPrefix(marker, "match:"), false
+		case strings.HasPrefix(marker, "switch:"):
+			subject, inSwitch = strings.TrimPrefix(marker, "switch:"), true
+		case strings.HasPrefix(marker, "case:"):
+			pattern := strings.TrimPrefix(marker, "case:")
+			if marker == noCaseMatched {
+				conds = append(conds, subject+" matches none of the cases")
+			} else if inSwitch {
+				// The default case of a JavaScript switch statement
+				if pattern == "default" {
+					conds = append(conds, subject+" equals none of the other cases")
+				} else {
+					conds = append(conds, subject+" === "+pattern)
 				}
+			} else if pattern == "_" {
+				conds = append(conds, subject+" matches none of the other cases")
+			} else {
+				conds = append(conds, subject+" matches the case pattern "+pattern)
 			}
-			desc := fmt.Sprintf("Testcase %d for %s%s%s:\n", i+1, funcName, params, funcReturnTypeStr(returns))
-			if len(conds) > 0 {
-				desc += "test case where " + conds[0] + ",\n"
-				for k := 1; k < len(conds); k++ {
-					desc += "and " + conds[k] + "\n"
-				}
+		case strings.HasPrefix(marker, "if"), strings.HasPrefix(marker, "elif:"):
+			condExpr := marker[strings.Index(marker, ":")+1:]
+			if strings.HasSuffix(condExpr, "-else") {
+				conds = append(conds, "not("+strings.TrimSuffix(condExpr, "-else")+")")
+			} else {
+				conds = append(conds, strings.TrimSuffix(condExpr, "-then"))
 			}
-			if retVal != "" {
-				desc += "returns '" + retVal + "'"
+		case marker == "try":
+			conds = append(conds, "the try block completes without an exception")
+		case strings.HasPrefix(marker, "except:"):
+			conds = append(conds, "the try block raises "+strings.TrimPrefix(marker, "except:"))
+		case strings.HasPrefix(marker, "catch:"):
+			conds = append(conds, "the try block throws "+strings.TrimPrefix(marker, "catch:"))
+		default:
+			if cond, ok := loopVariantCondition(marker); ok {
+				conds = append(conds, cond)
 			}
-			pathDescs = append(pathDescs, desc)
 		}
-		promptStr := promptTemplate
-		promptStr = strings.ReplaceAll(promptStr, "{path_constraints}", strings.Join(pathDescs, "\n"))
-		promptStr = strings.ReplaceAll(promptStr, "{code}", code)
-		promptStr = strings.ReplaceAll(promptStr, "{file_name}", sourcePath)
+	}
+	return conds
+}
 
-		msg, err := sw.model.Generate(context.Background(), promptStr)
-		if err != nil {
-			return fmt.Errorf("LLM generate failed: %w", err)
-		}
-		testCode := extractCodeFromMessage(msg.Content, "python")
+// describeSymPath describes one execution path of a function as a test case:
+// the conditions taken along the path, including the cases of match and switch
+// statements it takes, and the value returned or the exception raised (thrown in
+// Java and JavaScript) by the statement the path ends in.
+func describeSymPath(index int, signature string, path []string, codeType string) string {
+	conds := symPathConditions(path)
 
-		dir := filepath.Dir(sourcePath)
-		testFileName := symTestFileName(sourcePath, funcNames[idx], 0)
-		testFilePath := filepath.Join(dir, testFileName)
-		if err := sw.fileIO.Write(testFilePath, []byte(testCode)); err != nil {
-			return fmt.Errorf("failed to write test file: %w", err)
+	desc := fmt.Sprintf("Testcase %d for %s:\n", index+1, signature)
+	if len(conds) > 0 {
+		desc += "test case where " + conds[0] + ",\n"
+		for k := 1; k < len(conds); k++ {
+			desc += "and " + conds[k] + "\n"
 		}
+	}
 
-		if sw.callback != nil {
-			sw.callback(code, testCode, testFilePath)
+	// Return and raise statements end their path, so only the terminal node
+	// tells what this path returns or raises
+	if len(path) > 0 {
+		terminal := path[len(path)-1]
+		if strings.HasPrefix(terminal, "return:") {
+			desc += "returns '" + strings.TrimPrefix(terminal, "return:") + "'"
+		}
+		if strings.HasPrefix(terminal, "raise:") {
+			desc += "raises " + strings.TrimPrefix(terminal, "raise:") + " (assert it with pytest.raises)"
+		}
+		if strings.HasPrefix(terminal, "throw:") {
+			assertion := "assertThrows"
+			if codeType == "javascript" {
+				assertion = "expect(...).toThrow"
+			}
+			desc += "throws " + strings.TrimPrefix(terminal, "throw:") + " (assert it with " + assertion + ")"
 		}
 	}
-	return nil
+	return desc
 }
 
-func symTestFileName(sourcePath string, funcName string, idx int) string {
+// symTestFileName returns the name of the file the idx-th test of the function
+// is written to, following the test file convention of the code type: Java
+// tests of every method go to the test class of the source file, e.g.
+// CalcTest.java, JavaScript tests end in .test.js so test runners such as Jest
+// find them, and tests of other languages are named like
+// calc_add_test_case_1.py.
+func symTestFileName(sourcePath string, funcName string, idx int, codeType string) string {
 	base := strings.TrimSuffix(filepath.Base(sourcePath), filepath.Ext(sourcePath))
-	return fmt.Sprintf("%s_%s_test_case_%d.py", base, funcName, idx+1)
+	switch codeType {
+	case "java":
+		return javaTestClassName(sourcePath) + ".java"
+	case "javascript":
+		return fmt.Sprintf("%s_%s_test_case_%d.test.js", base, funcName, idx+1)
+	default:
+		return fmt.Sprintf("%s_%s_test_case_%d%s", base, funcName, idx+1, filepath.Ext(sourcePath))
+	}
 }
 
 func funcReturnTypeStr(returns string) string {
@@ -166,169 +787,601 @@ func funcReturnTypeStr(returns string) string {
 	return " -> " + returns
 }
 
-func CollectPathsJava(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, cur []string, paths *[][]string) {
-	if node == nil {
-		return
+// javaLoopHeader returns the loop marker of a Java loop without its variant
+// suffix: "for:" and the iterated collection for an enhanced for loop, "while:"
+// and the condition for other loops. A for loop without a condition has no
+// marker, it never ends on a condition.
+func javaLoopHeader(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	if node.Kind() == "enhanced_for_statement" {
+		if valueNode := node.ChildByFieldName("value"); valueNode != nil {
+			return "for:" + getNodeText(valueNode)
+		}
+		return ""
+	}
+	condNode := node.ChildByFieldName("condition")
+	if condNode == nil {
+		return ""
+	}
+	if condNode.Kind() == "parenthesized_expression" && condNode.NamedChildCount() == 1 {
+		condNode = condNode.NamedChild(0)
+	}
+	return "while:" + getNodeText(condNode)
+}
+
+// CollectPathsJava collects the execution paths through a Java node into paths:
+// every path starts with cur and holds the branch markers of the branches it
+// takes through the statements of the node, one after the other, e.g.
+// "if:x > 0-else" and "for:items-once", and ends where the node ends or in the
+// return or throw statement that ends it early, e.g. "return:x". Else-if chains
+// are nested if statements, the cases of a switch are branches like the cases
+// of a Python match statement and a try statement with catch clauses takes
+// either its "try" branch or one of its "catch:" branches. Like
+// CollectPathsPython, it reports false if the node has syntax errors.
+func CollectPathsJava(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, cur []string, paths *[][]string) bool {
+	open := javaPaths(node, getNodeText, [][]string{cur}, paths)
+	*paths = append(*paths, open...)
+	return node == nil || !node.HasError()
+}
+
+// javaPaths continues the open paths through a Java statement. Paths ended by
+// a return or throw statement are added to paths, the paths continuing after
+// the statement are returned.
+func javaPaths(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, open [][]string, paths *[][]string) [][]string {
+	if node == nil || len(open) == 0 {
+		return open
 	}
-	kind := node.Kind()
-	cur = append(cur, kind)
 
-	switch kind {
+	switch node.Kind() {
+	case "block", "constructor_body", "switch_block_statement_group", "switch_rule":
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			open = javaPaths(node.NamedChild(i), getNodeText, open, paths)
+		}
+		return open
+	case "labeled_statement":
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			if c := node.NamedChild(i); c.Kind() != "identifier" {
+				open = javaPaths(c, getNodeText, open, paths)
+			}
+		}
+		return open
+	case "synchronized_statement":
+		return javaPaths(node.ChildByFieldName("body"), getNodeText, open, paths)
 	case "if_statement":
 		condNode := node.ChildByFieldName("condition")
 		cond := "if"
 		if condNode != nil {
+			// Java conditions are parenthesized, the parentheses are not part of the condition
+			if condNode.Kind() == "parenthesized_expression" && condNode.NamedChildCount() == 1 {
+				condNode = condNode.NamedChild(0)
+			}
 			cond += ":" + getNodeText(condNode)
 		}
-		thenNode := node.ChildByFieldName("consequence")
-		thenPath := append(cur, cond+"-then")
-		CollectPathsJava(thenNode, getNodeText, thenPath, paths)
-		elseNode := node.ChildByFieldName("alternative")
-		if elseNode != nil {
-			elsePath := append(cur, cond+"-else")
-			CollectPathsJava(elseNode, getNodeText, elsePath, paths)
-		}
-		return
-	case "for_statement", "while_statement":
-		loopPath := append(cur, kind)
-		bodyNode := node.ChildByFieldName("body")
-		CollectPathsJava(bodyNode, getNodeText, loopPath, paths)
-		return
+		next := javaPaths(node.ChildByFieldName("consequence"), getNodeText, extendPaths(open, cond+"-then"), paths)
+		// An else-if is an if statement of its own in the alternative
+		elsePaths := javaPaths(node.ChildByFieldName("alternative"), getNodeText, extendPaths(open, cond+"-else"), paths)
+		return limitPaths(append(next, elsePaths...))
+	case "for_statement", "enhanced_for_statement", "while_statement", "do_statement":
+		body := node.ChildByFieldName("body")
+		return loopPaths(open, javaLoopHeader(node, getNodeText), func(open [][]string) [][]string {
+			return javaPaths(body, getNodeText, open, paths)
+		})
 	case "switch_expression", "switch_statement":
-		for i := 0; i < int(node.NamedChildCount()); i++ {
-			c := node.NamedChild(uint(i))
-			if c.Kind() == "switch_block" || c.Kind() == "switch_block_statement_group" {
-				CollectPathsJava(c, getNodeText, append(cur, "switch-case"), paths)
+		subject := "switch"
+		if condNode := node.ChildByFieldName("condition"); condNode != nil {
+			if condNode.Kind() == "parenthesized_expression" && condNode.NamedChildCount() == 1 {
+				condNode = condNode.NamedChild(0)
 			}
+			subject = getNodeText(condNode)
 		}
-		return
-	case "try_statement":
-		tryBlock := node.ChildByFieldName("body")
-		CollectPathsJava(tryBlock, getNodeText, append(cur, "try"), paths)
-		for i := 0; i < int(node.NamedChildCount()); i++ {
-			c := node.NamedChild(uint(i))
-			if c.Kind() == "catch_clause" {
-				CollectPathsJava(c, getNodeText, append(cur, "catch"), paths)
+		body := node.ChildByFieldName("body")
+		if body == nil {
+			return open
+		}
+		var next [][]string
+		// Switch expressions must be exhaustive, switch statements skip their
+		// body if no case matches and there is no default case
+		exhaustive := node.Kind() == "switch_expression"
+		for i := uint(0); i < body.NamedChildCount(); i++ {
+			c := body.NamedChild(i)
+			if c.Kind() != "switch_block_statement_group" && c.Kind() != "switch_rule" {
+				continue
+			}
+			label := javaCaseLabel(c, getNodeText)
+			exhaustive = exhaustive || label == "_"
+			casePaths := extendPaths(open, "match:"+subject, "case:"+label)
+			next = append(next, javaPaths(c, getNodeText, casePaths, paths)...)
+		}
+		if !exhaustive {
+			next = append(next, extendPaths(open, "match:"+subject, noCaseMatched)...)
+		}
+		return limitPaths(next)
+	case "try_statement", "try_with_resources_statement":
+		var handlers []*tree_sitter.Node
+		var finallyBlock *tree_sitter.Node
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			switch c := node.NamedChild(i); c.Kind() {
+			case "catch_clause":
+				handlers = append(handlers, c)
+			case "finally_clause":
+				finallyBlock = c.NamedChild(0)
 			}
 		}
-		finallyNode := node.ChildByFieldName("finally_block")
-		if finallyNode != nil {
-			CollectPathsJava(finallyNode, getNodeText, append(cur, "finally"), paths)
+		tryPaths := open
+		if len(handlers) > 0 {
+			tryPaths = extendPaths(open, "try")
+		}
+		next := javaPaths(node.ChildByFieldName("body"), getNodeText, tryPaths, paths)
+		for _, handler := range handlers {
+			catchPaths := extendPaths(open, "catch:"+javaCaughtTypes(handler, getNodeText))
+			next = append(next, javaPaths(handler.ChildByFieldName("body"), getNodeText, catchPaths, paths)...)
+		}
+		return javaPaths(finallyBlock, getNodeText, limitPaths(next), paths)
+	case "throw_statement":
+		*paths = append(*paths, extendPaths(open, "throw:"+javaThrownException(node, getNodeText))...)
+		return nil
+	case "return_statement":
+		// A bare return of a void method ends the path without a value
+		if valueNode := node.NamedChild(0); valueNode != nil {
+			*paths = append(*paths, extendPaths(open, "return:"+getNodeText(valueNode))...)
+		} else {
+			*paths = append(*paths, open...)
 		}
-		return
+		return nil
 	}
+	return open
+}
 
-	if node.NamedChildCount() == 0 {
-		*paths = append(*paths, cur)
-		return
+// javaCaseLabel returns the labels of a case of a Java switch, e.g. "1, 2" for
+// `case 1, 2:` and `case 1: case 2:`, or "_" for the default case.
+func javaCaseLabel(caseNode *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	var labels []string
+	for i := uint(0); i < caseNode.NamedChildCount(); i++ {
+		label := caseNode.NamedChild(i)
+		if label.Kind() != "switch_label" {
+			continue
+		}
+		if label.NamedChildCount() == 0 {
+			return "_"
+		}
+		for j := uint(0); j < label.NamedChildCount(); j++ {
+			labels = append(labels, getNodeText(label.NamedChild(j)))
+		}
 	}
-	for i := 0; i < int(node.NamedChildCount()); i++ {
-		CollectPathsJava(node.NamedChild(uint(i)), getNodeText, cur, paths)
+	return strings.Join(labels, ", ")
+}
+
+// javaCaughtTypes returns the exception types a Java catch clause catches, e.g.
+// "IOException | SQLException".
+func javaCaughtTypes(handler *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	for i := uint(0); i < handler.NamedChildCount(); i++ {
+		param := handler.NamedChild(i)
+		if param.Kind() != "catch_formal_parameter" {
+			continue
+		}
+		for j := uint(0); j < param.NamedChildCount(); j++ {
+			if c := param.NamedChild(j); c.Kind() == "catch_type" {
+				return getNodeText(c)
+			}
+		}
 	}
+	return "an exception"
+}
+
+// CollectPathsPython collects the execution paths through a Python node into
+// paths: every path starts with cur and holds the branch markers of the
+// branches it takes through the statements of the node, one after the other,
+// e.g. "if:x > 0-else", "elif:y-then" and "for:items-once", and ends where the
+// node ends or in the return or raise statement that ends it early, e.g.
+// "return:x". The cases of a match statement are branches of their own, and a
+// try statement with except clauses takes either its "try" branch or one of its
+// "except:" branches. It reports false if the node has syntax errors, in which
+// case tree-sitter may have dropped statements and the paths may be incomplete.
+func CollectPathsPython(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, cur []string, paths *[][]string) bool {
+	open := pythonPaths(node, getNodeText, [][]string{cur}, paths)
+	*paths = append(*paths, open...)
+	return node == nil || !node.HasError()
 }
 
-func CollectPathsPython(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, cur []string, paths *[][]string) {
-	if node == nil {
-		return
+// pythonPaths continues the open paths through a Python statement. Paths ended
+// by a return or raise statement are added to paths, the paths continuing after
+// the statement are returned.
+func pythonPaths(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string, open [][]string, paths *[][]string) [][]string {
+	if node == nil || len(open) == 0 {
+		return open
 	}
-	kind := node.Kind()
-	cur = append(cur, kind)
 
-	switch kind {
-	case "if_statement":
-		condNode := node.ChildByFieldName("condition")
-		cond := "if"
-		if condNode != nil {
-			cond += ":" + getNodeText(condNode)
+	switch node.Kind() {
+	case "block", "module":
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			open = pythonPaths(node.NamedChild(i), getNodeText, open, paths)
 		}
-		thenNode := node.ChildByFieldName("consequence")
-		thenPath := append(cur, cond+"-then")
-		CollectPathsPython(thenNode, getNodeText, thenPath, paths)
-		elseNode := node.ChildByFieldName("alternative")
-		if elseNode != nil {
-			elsePath := append(cur, cond+"-else")
-			CollectPathsPython(elseNode, getNodeText, elsePath, paths)
+		return open
+	case "with_statement", "else_clause":
+		return pythonPaths(node.ChildByFieldName("body"), getNodeText, open, paths)
+	case "finally_clause":
+		return pythonPaths(node.NamedChild(0), getNodeText, open, paths)
+	case "if_statement":
+		cursor := node.Walk()
+		defer cursor.Close()
+		cond := pythonCondition("if", node, getNodeText)
+		next := pythonPaths(node.ChildByFieldName("consequence"), getNodeText, extendPaths(open, cond+"-then"), paths)
+		// Every elif is reached only if the conditions before it are false
+		rest := extendPaths(open, cond+"-else")
+		var elseClause *tree_sitter.Node
+		for _, alternative := range node.ChildrenByFieldName("alternative", cursor) {
+			if alternative.Kind() == "else_clause" {
+				elseClause = &alternative
+				continue
+			}
+			elif := pythonCondition("elif", &alternative, getNodeText)
+			next = append(next, pythonPaths(alternative.ChildByFieldName("consequence"), getNodeText, extendPaths(rest, elif+"-then"), paths)...)
+			rest = extendPaths(rest, elif+"-else")
 		}
-		return
+		next = append(next, pythonPaths(elseClause, getNodeText, rest, paths)...)
+		return limitPaths(next)
 	case "for_statement", "while_statement":
-		loopPath := append(cur, kind)
-		bodyNode := node.ChildByFieldName("body")
-		CollectPathsPython(bodyNode, getNodeText, loopPath, paths)
-		return
+		body := node.ChildByFieldName("body")
+		open = loopPaths(open, pythonLoopHeader(node, getNodeText), func(open [][]string) [][]string {
+			return pythonPaths(body, getNodeText, open, paths)
+		})
+		// The else clause runs when the loop ends without a break
+		return pythonPaths(node.ChildByFieldName("alternative"), getNodeText, open, paths)
 	case "try_statement":
-		tryBlock := node.ChildByFieldName("body")
-		CollectPathsPython(tryBlock, getNodeText, append(cur, "try"), paths)
-		for i := 0; i < int(node.NamedChildCount()); i++ {
-			c := node.NamedChild(uint(i))
-			if c.Kind() == "except_clause" {
-				CollectPathsPython(c, getNodeText, append(cur, "except"), paths)
+		var handlers []*tree_sitter.Node
+		var elseClause, finallyClause *tree_sitter.Node
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			switch c := node.NamedChild(i); c.Kind() {
+			case "except_clause", "except_group_clause":
+				handlers = append(handlers, c)
+			case "else_clause":
+				elseClause = c
+			case "finally_clause":
+				finallyClause = c
+			}
+		}
+		tryPaths := open
+		if len(handlers) > 0 {
+			tryPaths = extendPaths(open, "try")
+		}
+		next := pythonPaths(node.ChildByFieldName("body"), getNodeText, tryPaths, paths)
+		next = pythonPaths(elseClause, getNodeText, next, paths)
+		for _, handler := range handlers {
+			exceptPaths := extendPaths(open, "except:"+pythonCaughtException(handler, getNodeText))
+			for i := uint(0); i < handler.NamedChildCount(); i++ {
+				if c := handler.NamedChild(i); c.Kind() == "block" {
+					next = append(next, pythonPaths(c, getNodeText, exceptPaths, paths)...)
+				}
+			}
+		}
+		return pythonPaths(finallyClause, getNodeText, limitPaths(next), paths)
+	case "match_statement":
+		// Every case clause starts a path of its own, like the sides of an if
+		subject := "match:" + pythonMatchSubject(node, getNodeText)
+		bodyNode := node.ChildByFieldName("body")
+		if bodyNode == nil {
+			return open
+		}
+		var next [][]string
+		exhaustive := false
+		for i := uint(0); i < bodyNode.NamedChildCount(); i++ {
+			c := bodyNode.NamedChild(i)
+			if c.Kind() != "case_clause" {
+				continue
 			}
+			pattern := pythonCasePattern(c, getNodeText)
+			exhaustive = exhaustive || isIrrefutablePattern(pattern)
+			casePaths := extendPaths(open, subject, "case:"+pattern)
+			next = append(next, pythonPaths(c.ChildByFieldName("consequence"), getNodeText, casePaths, paths)...)
 		}
-		finallyNode := node.ChildByFieldName("finalbody")
-		if finallyNode != nil {
-			CollectPathsPython(finallyNode, getNodeText, append(cur, "finally"), paths)
+		if !exhaustive {
+			// Without a wildcard case the match does nothing if no case matches
+			next = append(next, extendPaths(open, subject, noCaseMatched)...)
 		}
-		return
+		return limitPaths(next)
+	case "raise_statement":
+		*paths = append(*paths, extendPaths(open, "raise:"+pythonRaisedException(node, getNodeText))...)
+		return nil
+	case "return_statement":
+		*paths = append(*paths, extendPaths(open, "return:"+pythonReturnedValue(node, getNodeText))...)
+		return nil
 	}
+	return open
+}
+
+// pythonCapturePattern matches the case patterns of a Python match statement
+// that match any subject unless they are a literal (see isIrrefutablePattern):
+// the wildcard _ and a capture pattern binding the subject to a name, neither
+// with a guard.
+var pythonCapturePattern = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// isIrrefutablePattern reports whether the Python case pattern matches any
+// subject, so that no path skips every case of the match statement.
+func isIrrefutablePattern(pattern string) bool {
+	switch pattern {
+	case "None", "True", "False":
+		return false
+	}
+	return pythonCapturePattern.MatchString(pattern)
+}
+
+// noCaseMatched is the case marker of the path on which no case of a match or
+// switch statement without a wildcard or default case matches.
+const noCaseMatched = "case:<no case>"
+
+// pythonCondition returns the marker of the condition of a Python if statement
+// or elif clause without its side, e.g. "elif:x > 0".
+func pythonCondition(keyword string, node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	if condNode := node.ChildByFieldName("condition"); condNode != nil {
+		return keyword + ":" + getNodeText(condNode)
+	}
+	return keyword
+}
+
+// pythonCaughtException returns the exceptions a Python except clause catches,
+// e.g. "(KeyError, IndexError)", or "an exception" for a bare `except:`.
+func pythonCaughtException(handler *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	if value := handler.ChildByFieldName("value"); value != nil {
+		return getNodeText(value)
+	}
+	for i := uint(0); i < handler.NamedChildCount(); i++ {
+		// except* clauses have no value field
+		if c := handler.NamedChild(i); c.Kind() != "block" {
+			return getNodeText(c)
+		}
+	}
+	return "an exception"
+}
+
+// pythonLoopHeader returns the loop marker of a Python loop without its variant
+// suffix: "for:" and the iterated collection for a for loop, "while:" and the
+// condition for a while loop.
+func pythonLoopHeader(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	if node.Kind() == "for_statement" {
+		if right := node.ChildByFieldName("right"); right != nil {
+			return "for:" + getNodeText(right)
+		}
+		return ""
+	}
+	if condNode := node.ChildByFieldName("condition"); condNode != nil {
+		return "while:" + getNodeText(condNode)
+	}
+	return ""
+}
 
-	if node.NamedChildCount() == 0 {
-		*paths = append(*paths, cur)
-		return
+// pythonRaisedException returns the name of the exception raised by a Python
+// raise statement, e.g. "ValueError" for `raise ValueError("bad input")`.
+// A bare `raise` re-raises the exception currently being handled.
+func pythonRaisedException(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	excNode := node.NamedChild(0)
+	cause := node.ChildByFieldName("cause")
+	if excNode == nil || (cause != nil && excNode.Id() == cause.Id()) {
+		return "the current exception"
 	}
+	if excNode.Kind() == "call" {
+		if fnNode := excNode.ChildByFieldName("function"); fnNode != nil {
+			return getNodeText(fnNode)
+		}
+	}
+	return getNodeText(excNode)
+}
+
+// pythonMatchSubject returns the subject of a Python match statement, e.g.
+// "command" for `match command:`.
+func pythonMatchSubject(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	var subjects []string
+	cursor := node.Walk()
+	defer cursor.Close()
+	for _, subject := range node.ChildrenByFieldName("subject", cursor) {
+		subjects = append(subjects, getNodeText(&subject))
+	}
+	return strings.Join(subjects, ", ")
+}
+
+// pythonCasePattern returns the pattern of a Python case clause including its
+// guard, e.g. "Point(x=0, y=y) if y > 0".
+func pythonCasePattern(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	var patterns []string
 	for i := 0; i < int(node.NamedChildCount()); i++ {
-		CollectPathsPython(node.NamedChild(uint(i)), getNodeText, cur, paths)
+		if c := node.NamedChild(uint(i)); c.Kind() == "case_pattern" {
+			patterns = append(patterns, getNodeText(c))
+		}
 	}
+	pattern := strings.Join(patterns, ", ")
+	if guard := node.ChildByFieldName("guard"); guard != nil {
+		pattern += " " + getNodeText(guard)
+	}
+	return pattern
 }
 
-func MinimizePaths(paths [][]string) [][]string {
-	branchKinds := map[string]struct{}{
-		"if_statement": {}, "for_statement": {}, "while_statement": {},
-		"switch_expression": {}, "switch_statement": {},
-		"try_statement": {}, "catch_clause": {}, "except_clause": {}, "finally": {},
+// pythonReturnedValue returns the expression returned by a Python return
+// statement, or "None" for a bare `return`.
+func pythonReturnedValue(node *tree_sitter.Node, getNodeText func(*tree_sitter.Node) string) string {
+	valueNode := node.NamedChild(0)
+	if valueNode == nil {
+		return "None"
 	}
+	return getNodeText(valueNode)
+}
+
+// maxOpenPaths caps the number of paths followed through a function at once,
+// so that a sequence of branches does not multiply the paths without bound.
+const maxOpenPaths = 128
 
-	type branch struct {
-		kind string
-		idx  int
+// extendPaths returns copies of the paths with the markers appended.
+func extendPaths(paths [][]string, markers ...string) [][]string {
+	extended := make([][]string, len(paths))
+	for i, path := range paths {
+		extended[i] = append(append(make([]string, 0, len(path)+len(markers)), path...), markers...)
 	}
+	return extended
+}
 
-	branches := []branch{}
-	branchSet := map[branch]struct{}{}
+// limitPaths caps the paths at maxOpenPaths, keeping a set of paths that covers
+// every branch and outcome of them first (see MinimizePaths).
+func limitPaths(paths [][]string) [][]string {
+	if len(paths) <= maxOpenPaths {
+		return paths
+	}
+	limited, _ := MinimizePathsN(paths, maxOpenPaths)
+	kept := make(map[string]bool, len(limited))
+	for _, path := range limited {
+		kept[strings.Join(path, "\x00")] = true
+	}
 	for _, path := range paths {
-		for i, kind := range path {
-			if _, ok := branchKinds[kind]; ok {
-				b := branch{kind, i}
-				if _, exist := branchSet[b]; !exist {
-					branches = append(branches, b)
-					branchSet[b] = struct{}{}
-				}
+		if len(limited) >= maxOpenPaths {
+			break
+		}
+		if key := strings.Join(path, "\x00"); !kept[key] {
+			kept[key] = true
+			limited = append(limited, path)
+		}
+	}
+	return limited
+}
+
+// loopPaths continues the open paths through a loop with the given header
+// (see pythonLoopHeader): on one variant of every path the loop body never
+// runs, on the other it runs at least once, continued through the loop body by
+// body. Both variants continue after the loop. The variants are marked by the
+// loop header with the suffixes "-zero" and "-once", e.g. "for:items-zero", so
+// that the prompt asks for both an empty and a non-empty collection. A loop
+// without a header, such as an endless for loop, has no variants and its body
+// is always taken.
+func loopPaths(open [][]string, header string, body func(open [][]string) [][]string) [][]string {
+	if header == "" {
+		return body(open)
+	}
+	zero := extendPaths(open, header+"-zero")
+	once := body(extendPaths(open, header+"-once"))
+	return limitPaths(append(zero, once...))
+}
+
+// loopVariantCondition returns the condition of a loop variant marker, e.g.
+// "items is empty (the loop body never runs)" for "for:items-zero", and false
+// if the path node is no loop variant marker.
+func loopVariantCondition(kind string) (string, bool) {
+	var header, variant string
+	switch {
+	case strings.HasSuffix(kind, "-zero"):
+		header, variant = strings.TrimSuffix(kind, "-zero"), "zero"
+	case strings.HasSuffix(kind, "-once"):
+		header, variant = strings.TrimSuffix(kind, "-once"), "once"
+	default:
+		return "", false
+	}
+
+	switch {
+	case strings.HasPrefix(header, "for:") && variant == "zero":
+		return strings.TrimPrefix(header, "for:") + " is empty (the loop body never runs)", true
+	case strings.HasPrefix(header, "for:"):
+		return strings.TrimPrefix(header, "for:") + " is not empty (the loop body runs at least once)", true
+	case strings.HasPrefix(header, "while:") && variant == "zero":
+		return "not(" + strings.TrimPrefix(header, "while:") + ") when the loop is reached (the loop body never runs)", true
+	case strings.HasPrefix(header, "while:"):
+		return strings.TrimPrefix(header, "while:") + " when the loop is reached (the loop body runs at least once)", true
+	}
+	return "", false
+}
+
+// isPathBranch reports whether a path marker is the outcome of a branch: a
+// side of an if statement or elif clause, a case of a match or switch
+// statement, a variant of a loop, or the try or except (catch) branch of a try
+// statement.
+func isPathBranch(marker string) bool {
+	switch {
+	case strings.HasPrefix(marker, "if"), strings.HasPrefix(marker, "elif:"), strings.HasPrefix(marker, "case:"),
+		marker == "try", strings.HasPrefix(marker, "except:"), strings.HasPrefix(marker, "catch:"):
+		return true
+	}
+	_, ok := loopVariantCondition(marker)
+	return ok
+}
+
+// pathOutcome returns the marker of the return, raise or throw statement the
+// path ends in, or false if the path runs to the end of the function.
+func pathOutcome(path []string) (string, bool) {
+	if len(path) == 0 {
+		return "", false
+	}
+	terminal := path[len(path)-1]
+	for _, prefix := range []string{"return:", "raise:", "throw:"} {
+		if strings.HasPrefix(terminal, prefix) {
+			return terminal, true
+		}
+	}
+	return "", false
+}
+
+// newPathCover records the paths selected for a function, the distinct
+// branches they pass through, in the order they are first reached, and the
+// branches they leave uncovered.
+func newPathCover(paths [][]string, uncovered []string) *PathCover {
+	cover := &PathCover{Branches: []string{}, Paths: paths, Uncovered: uncovered}
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		for _, kind := range path {
+			if isPathBranch(kind) && !seen[kind] {
+				seen[kind] = true
+				cover.Branches = append(cover.Branches, kind)
 			}
 		}
 	}
+	return cover
+}
+
+// MinimizePaths selects a subset of the paths that takes every branch outcome
+// any of them takes, e.g. both sides of every if statement, and ends in every
+// distinct return value or exception, greedily taking the path covering the
+// most outcomes not covered yet next.
+func MinimizePaths(paths [][]string) [][]string {
+	minimized, _ := MinimizePathsN(paths, 0)
+	return minimized
+}
 
-	covered := map[branch]struct{}{}
+// MinimizePathsN selects paths like MinimizePaths, but at most maxPaths of them
+// (any number if maxPaths is 0 or less), so that the greedy choice covers as
+// many outcomes as the cap allows. It returns the selected paths and the
+// conditions of the branch outcomes none of them takes, e.g. "not(x > 0)", in
+// the order they are first reached, so the caller can tell the paths are
+// incomplete. Paths without any branch or outcome are represented by the first
+// of them.
+func MinimizePathsN(paths [][]string, maxPaths int) ([][]string, []string) {
+	goals := make([][]string, len(paths))
+	var branches []string
+	conditions := map[string]string{}
+	for i, path := range paths {
+		for j, marker := range path {
+			if !isPathBranch(marker) {
+				continue
+			}
+			goals[i] = append(goals[i], marker)
+			if _, seen := conditions[marker]; !seen {
+				conditions[marker] = branchCondition(path[:j+1])
+				branches = append(branches, marker)
+			}
+		}
+		if outcome, ok := pathOutcome(path); ok {
+			goals[i] = append(goals[i], outcome)
+		}
+	}
+
+	covered := map[string]bool{}
 	result := [][]string{}
 	used := make([]bool, len(paths))
-	for len(covered) < len(branches) {
+	for maxPaths <= 0 || len(result) < maxPaths {
 		maxCover, maxIdx := 0, -1
-		var maxNew map[branch]struct{}
-		for i, path := range paths {
+		for i := range paths {
 			if used[i] {
 				continue
 			}
-			newCover := map[branch]struct{}{}
-			for j, kind := range path {
-				b := branch{kind, j}
-				if _, ok := branchKinds[kind]; ok {
-					if _, already := covered[b]; !already {
-						newCover[b] = struct{}{}
-					}
+			newCover := map[string]bool{}
+			for _, goal := range goals[i] {
+				if !covered[goal] {
+					newCover[goal] = true
 				}
 			}
 			if len(newCover) > maxCover {
-				maxCover = len(newCover)
-				maxIdx = i
-				maxNew = newCover
+				maxCover, maxIdx = len(newCover), i
 			}
 		}
 		if maxIdx == -1 {
@@ -336,9 +1389,30 @@ func MinimizePaths(paths [][]string) [][]string {
 		}
 		result = append(result, paths[maxIdx])
 		used[maxIdx] = true
-		for b := range maxNew {
-			covered[b] = struct{}{}
+		for _, goal := range goals[maxIdx] {
+			covered[goal] = true
 		}
 	}
-	return result
+	if len(result) == 0 && len(paths) > 0 && maxPaths >= 0 {
+		result = append(result, paths[0])
+	}
+
+	var uncovered []string
+	for _, branch := range branches {
+		if !covered[branch] {
+			uncovered = append(uncovered, conditions[branch])
+		}
+	}
+	return result, uncovered
+}
+
+// branchCondition returns the condition of the branch the path ends in as it
+// reads in the source, e.g. "not(x > 0)" for "if:x > 0-else" or "command
+// matches the case pattern 1" for a case of `match command`.
+func branchCondition(path []string) string {
+	conds := symPathConditions(path)
+	if len(conds) == 0 {
+		return path[len(path)-1]
+	}
+	return conds[len(conds)-1]
 }
@@ -0,0 +1,1034 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Marksagittarius/pinguis/fileio"
+)
+
+func TestCollectSymPathsCoversEveryBranchOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		codeType string
+		code     string
+		function string
+		want     []string
+	}{
+		{
+			name:     "statements before a return keep the returned value",
+			codeType: "python",
+			code: `def h(x, y):
+    if x:
+        z = y + 1
+        return z
+    y = y * 2
+    return y
+`,
+			function: "h",
+			want:     []string{"x -> return:z", "not(x) -> return:y"},
+		},
+		{
+			name:     "elif clauses are conditions of their own",
+			codeType: "python",
+			code: `def sign(x):
+    if x > 0:
+        return 1
+    elif x < 0:
+        return -1
+    else:
+        return 0
+`,
+			function: "sign",
+			want: []string{
+				"x > 0 -> return:1",
+				"not(x > 0) and x < 0 -> return:-1",
+				"not(x > 0) and not(x < 0) -> return:0",
+			},
+		},
+		{
+			name:     "a loop that never runs continues after the loop",
+			codeType: "python",
+			code: `def total(items):
+    result = 0
+    for item in items:
+        result += item
+    return result
+`,
+			function: "total",
+			want: []string{
+				"items is empty (the loop body never runs) -> return:result",
+				"items is not empty (the loop body runs at least once) -> return:result",
+			},
+		},
+		{
+			name:     "JavaScript else-if chains and trailing returns",
+			codeType: "javascript",
+			code: `function grade(score) {
+  let bonus = 0;
+  if (score > 90) {
+    bonus = 10;
+  } else if (score > 50) {
+    return "pass";
+  }
+  return score + bonus;
+}
+`,
+			function: "grade",
+			want: []string{
+				"score > 90 -> return:score + bonus",
+				"not(score > 90) and score > 50 -> return:\"pass\"",
+				"not(score > 90) and not(score > 50) -> return:score + bonus",
+			},
+		},
+		{
+			name:     "Java try and catch branches",
+			codeType: "java",
+			code: `class Parser {
+    public int parse(String s) {
+        int value = 0;
+        try {
+            value = Integer.parseInt(s);
+        } catch (NumberFormatException e) {
+            return -1;
+        }
+        return value;
+    }
+}
+`,
+			function: "parse",
+			want: []string{
+				"the try block completes without an exception -> return:value",
+				"the try block throws NumberFormatException -> return:-1",
+			},
+		},
+		{
+			name:     "a match without a wildcard case can match no case",
+			codeType: "python",
+			code: `def describe(command):
+    match command:
+        case "start":
+            return 1
+        case "stop":
+            return 2
+    return 0
+`,
+			function: "describe",
+			want: []string{
+				"command matches the case pattern \"start\" -> return:1",
+				"command matches the case pattern \"stop\" -> return:2",
+				"command matches none of the cases -> return:0",
+			},
+		},
+		{
+			name:     "a match with a wildcard case always matches one",
+			codeType: "python",
+			code: `def describe(command):
+    match command:
+        case "start":
+            return 1
+        case _:
+            return 2
+`,
+			function: "describe",
+			want: []string{
+				"command matches the case pattern \"start\" -> return:1",
+				"command matches none of the other cases -> return:2",
+			},
+		},
+		{
+			name:     "a JavaScript switch without a default case",
+			codeType: "javascript",
+			code: `function code(op) {
+  switch (op) {
+    case "add":
+      return 1;
+  }
+  return 0;
+}
+`,
+			function: "code",
+			want: []string{
+				"op === \"add\" -> return:1",
+				"op matches none of the cases -> return:0",
+			},
+		},
+		{
+			name:     "a JavaScript case without a break falls through",
+			codeType: "javascript",
+			code: `function weight(size) {
+  let w = 0;
+  switch (size) {
+    case "large":
+      w += 2;
+    case "medium":
+      return w + 1;
+    case "small":
+      w = 1;
+      break;
+    default:
+      return -1;
+  }
+  return w;
+}
+`,
+			function: "weight",
+			want: []string{
+				"size === \"large\" -> return:w + 1",
+				"size === \"medium\" -> return:w + 1",
+				"size === \"small\" -> return:w",
+				"size equals none of the other cases -> return:-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := parseSymFunction(t, tt.code, tt.codeType, tt.function)
+			paths, uncovered, _ := collectSymPaths(fn, tt.code, tt.codeType, 0)
+			// The greedy cover decides the order of the paths
+			got := describedPaths(paths)
+			sort.Strings(got)
+			sort.Strings(tt.want)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths = %q, want %q", got, tt.want)
+			}
+			if len(uncovered) > 0 {
+				t.Errorf("uncovered = %q, want none", uncovered)
+			}
+		})
+	}
+}
+
+func TestMinimizePathsNReportsUncoveredConditions(t *testing.T) {
+	paths := [][]string{
+		{"if:a-then", "return:1"},
+		{"if:a-else", "if:b-then", "return:2"},
+		{"if:a-else", "if:b-else", "return:3"},
+	}
+
+	selected, uncovered := MinimizePathsN(paths, 1)
+	if len(selected) != 1 {
+		t.Fatalf("selected %d paths, want 1", len(selected))
+	}
+	if want := []string{"a", "not(b)"}; !reflect.DeepEqual(uncovered, want) {
+		t.Errorf("uncovered = %q, want %q", uncovered, want)
+	}
+}
+
+func TestMinimizePathsNCapsSelectedPaths(t *testing.T) {
+	paths := [][]string{
+		{"if:a-then", "return:1"},
+		{"if:a-else", "if:b-then", "return:2"},
+		{"if:a-else", "if:b-else", "return:3"},
+		{"if:a-then", "return:1"},
+	}
+	tests := []struct {
+		name      string
+		maxPaths  int
+		selected  int
+		uncovered []string
+	}{
+		{"no cap", 0, 3, nil},
+		{"cap above the paths needed", 10, 3, nil},
+		{"cap below the paths needed", 2, 2, []string{"not(b)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, uncovered := MinimizePathsN(paths, tt.maxPaths)
+			if len(selected) != tt.selected {
+				t.Errorf("selected %d paths, want %d", len(selected), tt.selected)
+			}
+			if !reflect.DeepEqual(uncovered, tt.uncovered) {
+				t.Errorf("uncovered = %q, want %q", uncovered, tt.uncovered)
+			}
+		})
+	}
+}
+
+func TestCollectSymPathsRendersUncoveredCases(t *testing.T) {
+	code := `def describe(command):
+    match command:
+        case "start":
+            return 1
+        case "stop":
+            return 2
+        case _:
+            return 0
+`
+	fn := parseSymFunction(t, code, "python", "describe")
+
+	paths, uncovered, _ := collectSymPaths(fn, code, "python", 1)
+	if len(paths) != 1 {
+		t.Fatalf("selected %d paths, want 1", len(paths))
+	}
+	want := []string{`command matches the case pattern "stop"`, "command matches none of the other cases"}
+	if !reflect.DeepEqual(uncovered, want) {
+		t.Errorf("uncovered = %q, want %q", uncovered, want)
+	}
+}
+
+func TestCollectSymPathsReportsSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		complete bool
+	}{
+		{"valid", "def f(x):\n    if x:\n        return 1\n    return 0\n", true},
+		{"broken", "def f(x):\n    if x:\n        return 1 +\n    return 0\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := parseSymFunction(t, tt.code, "python", "f")
+			if _, _, complete := collectSymPaths(fn, tt.code, "python", 0); complete != tt.complete {
+				t.Errorf("complete = %v, want %v", complete, tt.complete)
+			}
+		})
+	}
+}
+
+func TestSkipAbstractMethodsExcludesOnlyAbstractMethods(t *testing.T) {
+	code := "public abstract class Shape {\n" +
+		"    public static Shape unit() { return new Square(1); }\n" +
+		"    public abstract double area();\n" +
+		"}\n"
+	src := &symSource{Path: "Shape.java", Code: code}
+
+	for _, skipAbstract := range []bool{true, false} {
+		sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+			config.SkipAbstractMethods = skipAbstract
+			config.ForceGenerate = true
+		})
+
+		want := ""
+		if skipAbstract {
+			want = "method is abstract"
+		}
+		if got := sw.skipReason(src, parseSymFunction(t, code, "java", "area")); got != want {
+			t.Errorf("skipAbstract %v: skip reason of area = %q, want %q", skipAbstract, got, want)
+		}
+		if got := sw.skipReason(src, parseSymFunction(t, code, "java", "unit")); got != "" {
+			t.Errorf("skipAbstract %v: static method unit is skipped: %s", skipAbstract, got)
+		}
+	}
+}
+
+// countingFileIO reads and writes files like fileio.SimpleFileIO and counts the
+// reads of every path.
+type countingFileIO struct {
+	fileio.SimpleFileIO
+	mu    sync.Mutex
+	reads map[string]int
+}
+
+func (c *countingFileIO) Read(filePath string) ([]byte, error) {
+	c.mu.Lock()
+	c.reads[filePath]++
+	c.mu.Unlock()
+	return c.SimpleFileIO.Read(filePath)
+}
+
+func TestSymTaskReadsTemplateOnceFromAnotherDirectory(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "templates", "sym.txt")
+	if err := os.MkdirAll(filepath.Dir(templatePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, templatePath, "Custom template for {code}")
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n\ndef dec(x):\n    return x - 1\n")
+	// Neither the template nor a prompt.txt is in the working directory
+	t.Chdir(t.TempDir())
+
+	m := newFakeModel(pythonTestResponse)
+	files := &countingFileIO{reads: map[string]int{}}
+	sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+		config.PromptSource = nil
+		config.PromptTemplatePath = templatePath
+	})
+	sw.fileIO = files
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	prompts := m.recorded()
+	if len(prompts) != 2 {
+		t.Fatalf("model was prompted %d times, want once per function", len(prompts))
+	}
+	for _, prompt := range prompts {
+		if !strings.HasPrefix(prompt, "Custom template for ") {
+			t.Errorf("prompt %q does not use the configured template", prompt)
+		}
+	}
+	if reads := files.reads[templatePath]; reads != 1 {
+		t.Errorf("template read %d times, want once", reads)
+	}
+}
+
+func TestSymTaskIteratesOnCallbackFeedback(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+
+	var mu sync.Mutex
+	coverages := []float64{0.2, 1}
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+		config.MaxIterations = 3
+		config.CoverageThreshold = 0.9
+		config.Callback = func(sourceCode, testCode, testPath string) (float64, string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			coverage := coverages[0]
+			coverages = coverages[1:]
+			return coverage, fmt.Sprintf("coverage %.0f%%", coverage*100), nil
+		}
+	})
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	prompts := m.recorded()
+	if len(prompts) != 2 {
+		t.Fatalf("model was prompted %d times, want a second generation after the low coverage", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "coverage 20%") {
+		t.Errorf("second prompt does not include the report of the first test:\n%s", prompts[1])
+	}
+	if task := sw.Report().Tasks[0]; task.Status != TaskCompleted || task.BestCoverage != 1 {
+		t.Errorf("task finished %s with coverage %v, want completed with full coverage", task.Status, task.BestCoverage)
+	}
+}
+
+// failingWriteIO reads and writes files like fileio.SimpleFileIO, but fails to
+// write the files whose name contains fail.
+type failingWriteIO struct {
+	fileio.SimpleFileIO
+	fail string
+}
+
+func (f *failingWriteIO) Write(filePath string, data []byte) error {
+	if strings.Contains(filepath.Base(filePath), f.fail) {
+		return errors.New("disk full")
+	}
+	return f.SimpleFileIO.Write(filePath, data)
+}
+
+func TestSymTaskFailureDoesNotStopOtherFunctions(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n\ndef dec(x):\n    return x - 1\n")
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), nil)
+	sw.fileIO = &failingWriteIO{fail: "_inc_"}
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	statuses := map[string]string{}
+	for _, task := range sw.Report().Tasks {
+		statuses[task.FunctionName] = task.Status
+	}
+	if want := map[string]string{"inc": TaskFailed, "dec": TaskCompleted}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("task statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestSymTaskStopsWhenWorkerIsShutDown(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, nil)
+	sw.Shutdown()
+	if err := sw.SubmitSymTask(sourcePath); !errors.Is(err, context.Canceled) {
+		t.Errorf("SubmitSymTask() = %v, want %v", err, context.Canceled)
+	}
+	if prompts := m.recorded(); len(prompts) != 0 {
+		t.Errorf("model was prompted %d times after shutdown", len(prompts))
+	}
+}
+
+func TestSymTaskTimesOut(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+		config.MaxIterations = 1
+		config.TaskTimeout = 20 * time.Millisecond
+		config.Callback = func(sourceCode, testCode, testPath string) (float64, string, error) {
+			time.Sleep(100 * time.Millisecond)
+			return 1, "ok", nil
+		}
+	})
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	if task := sw.Report().Tasks[0]; task.Status != TaskTimedOut {
+		t.Errorf("task status = %s, want %s", task.Status, TaskTimedOut)
+	}
+	prompts := m.recorded()
+	if len(prompts) != 2 || !strings.Contains(prompts[1], "did not finish within the time limit") {
+		t.Errorf("prompts = %q, want a second prompt mentioning the timeout", prompts)
+	}
+}
+
+func TestSubmitDirectorySubmitsSourcesAndCollectsErrors(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"calc.py", "util/fmt.js", "calc_test.py", ".venv/lib.py", "notes.txt"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path, "def inc(x):\n    return x + 1\n")
+	}
+	writeFile(t, filepath.Join(root, "util", "fmt.js"), "function pad(s) {\n  return ' ' + s;\n}\n")
+	// A source that cannot be read fails on its own without stopping the walk
+	broken := filepath.Join(root, "broken.py")
+	if err := os.Symlink(filepath.Join(root, "missing.py"), broken); err != nil {
+		t.Fatal(err)
+	}
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), nil)
+	submitted, errs := sw.SubmitDirectory(root, nil)
+	if submitted != 2 {
+		t.Errorf("submitted %d files, want calc.py and util/fmt.js", submitted)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), broken) {
+		t.Errorf("errors = %v, want one for %s", errs, broken)
+	}
+
+	var sources []string
+	for _, task := range sw.Report().Tasks {
+		rel, _ := filepath.Rel(root, task.SourcePath)
+		sources = append(sources, filepath.ToSlash(rel))
+	}
+	sort.Strings(sources)
+	if want := []string{"calc.py", "util/fmt.js"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("tasks of %v, want %v", sources, want)
+	}
+}
+
+func TestSubmitDirectoryAppliesFilter(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "calc.py"), "def inc(x):\n    return x + 1\n")
+	writeFile(t, filepath.Join(root, "skip.py"), "def dec(x):\n    return x - 1\n")
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), nil)
+	submitted, errs := sw.SubmitDirectory(root, func(path string, info os.FileInfo) bool {
+		return info.Name() != "skip.py"
+	})
+	if submitted != 1 || len(errs) != 0 {
+		t.Errorf("submitted %d files with errors %v, want only calc.py", submitted, errs)
+	}
+}
+
+func TestSubmitSymTaskForRangeProcessesOverlappingFunctions(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n\n"+
+		"def dec(x):\n    return x - 1\n\n"+
+		"class Counter:\n    def reset(self):\n        self.n = 0\n")
+
+	tests := []struct {
+		name       string
+		start, end int
+		want       []string
+	}{
+		{"one function", 5, 5, []string{"dec"}},
+		{"across functions", 2, 4, []string{"inc", "dec"}},
+		{"method of a class", 9, 9, []string{"reset"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFakeModel(pythonTestResponse)
+			sw := newTestSymWorker(m, nil)
+			if err := sw.SubmitSymTaskForRange(sourcePath, tt.start, tt.end); err != nil {
+				t.Fatalf("SubmitSymTaskForRange: %v", err)
+			}
+
+			var processed []string
+			for _, task := range sw.Report().Tasks {
+				processed = append(processed, task.FunctionName)
+			}
+			if !reflect.DeepEqual(processed, tt.want) {
+				t.Errorf("processed %v, want %v", processed, tt.want)
+			}
+			if prompts := m.recorded(); len(prompts) != len(tt.want) {
+				t.Errorf("model was prompted %d times, want %d", len(prompts), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSubmitSymTaskForRangeRejectsRange(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "import math\n\ndef inc(x):\n    return x + 1\n")
+
+	tests := []struct {
+		name       string
+		start, end int
+		want       string
+	}{
+		{"start before the first line", 0, 2, "invalid line range 0-2"},
+		{"end before start", 4, 3, "invalid line range 4-3"},
+		{"no function overlaps", 1, 2, "no function in " + sourcePath + " overlaps lines 1-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFakeModel(pythonTestResponse)
+			sw := newTestSymWorker(m, nil)
+			err := sw.SubmitSymTaskForRange(sourcePath, tt.start, tt.end)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("SubmitSymTaskForRange(%d, %d) = %v, want %q", tt.start, tt.end, err, tt.want)
+			}
+			if prompts := m.recorded(); len(prompts) != 0 {
+				t.Errorf("model was prompted %d times for a rejected range", len(prompts))
+			}
+		})
+	}
+}
+
+func TestSymTaskGeneratesJavaTestClassForPublicMethods(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "Calc.java")
+	writeFile(t, sourcePath, `public class Calc {
+    public int sign(int x) {
+        if (x > 0) {
+            return 1;
+        }
+        return helper(x);
+    }
+
+    private int helper(int x) {
+        return x == 0 ? 0 : -1;
+    }
+}
+`)
+	testClass := "import org.junit.jupiter.api.Test;\n" +
+		"import static org.junit.jupiter.api.Assertions.assertEquals;\n\n" +
+		"class CalcTest {\n" +
+		"    @Test\n    void testSignPositive() {\n        assertEquals(1, new Calc().sign(5));\n    }\n\n" +
+		"    @Test\n    void testSignNotPositive() {\n        assertEquals(-1, new Calc().sign(-5));\n    }\n}\n"
+
+	m := newFakeModel("```java\n" + testClass + "```")
+	sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+		config.PromptSource = func() (string, error) { return "{path_constraints}\n{code}", nil }
+	})
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	// The private helper is an implementation detail and gets no test
+	tasks := sw.Report().Tasks
+	if len(tasks) != 1 || tasks[0].FunctionName != "sign" || tasks[0].Status != TaskCompleted {
+		t.Fatalf("tasks = %+v, want the completed task of sign", tasks)
+	}
+	prompts := m.recorded()
+	if len(prompts) != 1 {
+		t.Fatalf("model was prompted %d times, want once", len(prompts))
+	}
+	for _, want := range []string{
+		"Testcase 1 for int sign(int x):\ntest case where x > 0,\nreturns '1'",
+		"Testcase 2 for int sign(int x):\ntest case where not(x > 0),\nreturns 'helper(x)'",
+		"JUnit 5 test class named CalcTest",
+		"Only test the method sign",
+	} {
+		if !strings.Contains(prompts[0], want) {
+			t.Errorf("prompt %q does not contain %q", prompts[0], want)
+		}
+	}
+
+	testPath := filepath.Join(filepath.Dir(sourcePath), "CalcTest.java")
+	if tasks[0].TestPath != testPath {
+		t.Errorf("test path = %s, want %s", tasks[0].TestPath, testPath)
+	}
+	written, err := os.ReadFile(testPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"class CalcTest", "void testSignPositive()", "void testSignNotPositive()"} {
+		if !strings.Contains(string(written), want) {
+			t.Errorf("test file does not contain %q:\n%s", want, written)
+		}
+	}
+}
+
+func TestSymTaskExtractsFenceOfSourceLanguage(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "Calc.java")
+	writeFile(t, sourcePath, "public class Calc {\n    public int one() {\n        return 1;\n    }\n}\n")
+	javaTest := "class CalcTest {\n    @Test\n    void testOne() {\n        assertEquals(1, new Calc().one());\n    }\n}"
+
+	sw := newTestSymWorker(newFakeModel("```python\ndef test_one():\n    assert one() == 1\n```\n```java\n"+javaTest+"\n```"), nil)
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	task := sw.Report().Tasks[0]
+	if task.CodeType != "java" {
+		t.Errorf("code type = %s, want java", task.CodeType)
+	}
+	written, err := os.ReadFile(task.TestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(written), "void testOne()") || strings.Contains(string(written), "def test_one") {
+		t.Errorf("test file holds %q, want the java block only", written)
+	}
+}
+
+func TestSubmitSymTaskForFunctionWritesOnlyItsTest(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n\ndef dec(x):\n    return x - 1\n")
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), nil)
+	if err := sw.SubmitSymTaskForFunction(sourcePath, "dec"); err != nil {
+		t.Fatalf("SubmitSymTaskForFunction: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []string
+	for _, entry := range entries {
+		if entry.Name() != "calc.py" {
+			written = append(written, entry.Name())
+		}
+	}
+	if want := []string{"calc_dec_test_case_1.py"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("files written = %v, want %v", written, want)
+	}
+
+	err = sw.SubmitSymTaskForFunction(sourcePath, "mul")
+	if want := "function mul not found in " + sourcePath; err == nil || err.Error() != want {
+		t.Errorf("SubmitSymTaskForFunction(mul) = %v, want %q", err, want)
+	}
+}
+
+func TestCollectSymPathsCapturesPythonRaise(t *testing.T) {
+	code := `def parse(s):
+    if not s:
+        raise ValueError("empty input")
+    if s == "?":
+        raise
+    return int(s)
+`
+	fn := parseSymFunction(t, code, "python", "parse")
+
+	paths, _, _ := collectSymPaths(fn, code, "python", 0)
+	got := describedPaths(paths)
+	sort.Strings(got)
+	want := []string{
+		"not s -> raise:ValueError",
+		"not(not s) and not(s == \"?\") -> return:int(s)",
+		"not(not s) and s == \"?\" -> raise:the current exception",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("paths = %q, want %q", got, want)
+	}
+
+	desc := describeSymPath(0, "parse(s)", []string{"if:not s-then", "raise:ValueError"}, "python")
+	if want := "test case where not s,\nraises ValueError (assert it with pytest.raises)"; !strings.Contains(desc, want) {
+		t.Errorf("description = %q, want it to contain %q", desc, want)
+	}
+}
+
+func TestSubmitSymTaskWithContextAddsContextSummary(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+	contextPath := filepath.Join(dir, "Settings.java")
+	writeFile(t, contextPath, "public class Settings {\n    private int step;\n\n    public int step(int scale) {\n        return step * scale;\n    }\n}\n")
+
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, nil)
+	if err := sw.SubmitSymTaskWithContext(sourcePath, []string{contextPath}); err != nil {
+		t.Fatalf("SubmitSymTaskWithContext: %v", err)
+	}
+
+	prompts := m.recorded()
+	if len(prompts) != 1 {
+		t.Fatalf("model was prompted %d times, want once", len(prompts))
+	}
+	want := "The following files are relevant context for the code under test:\n\n" +
+		"File '" + filepath.ToSlash(contextPath) + "':\n" +
+		"- class Settings\n" +
+		"  - field step: int\n" +
+		"  - method step(scale: int) -> int\n"
+	if !strings.Contains(prompts[0], want) {
+		t.Errorf("prompt %q does not contain the context summary %q", prompts[0], want)
+	}
+
+	err := sw.SubmitSymTaskWithContext(sourcePath, []string{filepath.Join(dir, "notes.txt")})
+	if err == nil || !strings.Contains(err.Error(), "failed to summarize context file") {
+		t.Errorf("SubmitSymTaskWithContext(notes.txt) = %v, want a summary error", err)
+	}
+}
+
+func TestSymTaskStreamsJSONLineResults(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n\ndef dec(x):\n    return x - 1\n")
+
+	var results bytes.Buffer
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.ResultsWriter = &results
+	})
+	sw.fileIO = &failingWriteIO{fail: "_inc_"}
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(results.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("results = %q, want a line per function", results.String())
+	}
+	records := map[string]map[string]any{}
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("result %q is not JSON: %v", line, err)
+		}
+		records[fmt.Sprint(record["function_name"])] = record
+	}
+
+	// JSON numbers decode as float64; dec passed on its first iteration
+	want := map[string]any{
+		"source_path":   sourcePath,
+		"function_name": "dec",
+		"test_path":     filepath.Join(dir, "calc_dec_test_case_1.py"),
+		"coverage":      1.0,
+		"passed":        true,
+		"iterations":    0.0,
+	}
+	if dec := records["dec"]; !reflect.DeepEqual(dec, want) {
+		t.Errorf("record of dec = %v, want %v", dec, want)
+	}
+
+	inc := records["inc"]
+	if inc == nil || inc["passed"] != false || !strings.Contains(fmt.Sprint(inc["error"]), "disk full") {
+		t.Errorf("record of inc = %v, want a failed record with its error", inc)
+	}
+}
+
+func TestSymTaskSkipsStubFunctions(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def todo(x):\n    pass\n\n"+
+		"def later(x):\n    \"\"\"Not written yet.\"\"\"\n    ...\n\n"+
+		"def inc(x):\n    return x + 1\n")
+
+	tests := []struct {
+		name  string
+		force bool
+		want  []string
+	}{
+		{"stubs skipped", false, []string{"inc"}},
+		{"stubs forced", true, []string{"todo", "later", "inc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFakeModel(pythonTestResponse)
+			sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+				config.ForceGenerate = tt.force
+			})
+			if err := sw.SubmitSymTask(sourcePath); err != nil {
+				t.Fatalf("SubmitSymTask: %v", err)
+			}
+
+			var generated []string
+			for _, task := range sw.Report().Tasks {
+				generated = append(generated, task.FunctionName)
+			}
+			if !reflect.DeepEqual(generated, tt.want) {
+				t.Errorf("generated tests of %v, want %v", generated, tt.want)
+			}
+			if prompts := m.recorded(); len(prompts) != len(tt.want) {
+				t.Errorf("model was prompted %d times, want %d", len(prompts), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSymTaskFunctionOrder(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n\n"+
+		"def clamp(x):\n    if x < 0:\n        return 0\n    if x > 9:\n        return 9\n    return x\n\n"+
+		"def sign(x):\n    if x < 0:\n        return -1\n    return 1\n")
+
+	tests := []struct {
+		order FunctionOrder
+		want  []string
+	}{
+		{SourceOrder, []string{"inc", "clamp", "sign"}},
+		{NameSorted, []string{"clamp", "inc", "sign"}},
+		{ComplexityDesc, []string{"clamp", "sign", "inc"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+				config.FunctionOrder = tt.order
+			})
+			if err := sw.SubmitSymTask(sourcePath); err != nil {
+				t.Fatalf("SubmitSymTask: %v", err)
+			}
+
+			var processed []string
+			for _, task := range sw.Report().Tasks {
+				processed = append(processed, task.FunctionName)
+			}
+			if !reflect.DeepEqual(processed, tt.want) {
+				t.Errorf("processed %v, want %v", processed, tt.want)
+			}
+		})
+	}
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.FunctionOrder = "random"
+	})
+	if err := sw.SubmitSymTask(sourcePath); err == nil || !strings.Contains(err.Error(), `unknown function order "random"`) {
+		t.Errorf("SubmitSymTask with an unknown order = %v, want an error", err)
+	}
+}
+
+func TestSubmitDirectorySkipsFilesGitDoesNotTrack(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	for _, name := range []string{"calc.py", "lib/util.py", "scratch.py", "build/gen.py"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path, "def inc(x):\n    return x + 1\n")
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "calc.py", "lib/util.py"}} {
+		if output, err := exec.Command("git", append([]string{"-C", root}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+
+	submittedSources := func(gitTrackedOnly bool, dir string) []string {
+		sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+			config.GitTrackedOnly = gitTrackedOnly
+		})
+		if _, errs := sw.SubmitDirectory(dir, nil); len(errs) != 0 {
+			t.Fatalf("SubmitDirectory: %v", errs)
+		}
+		var sources []string
+		for _, task := range sw.Report().Tasks {
+			rel, _ := filepath.Rel(dir, task.SourcePath)
+			sources = append(sources, filepath.ToSlash(rel))
+		}
+		sort.Strings(sources)
+		return sources
+	}
+
+	if got, want := submittedSources(true, root), []string{"calc.py", "lib/util.py"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("git-tracked sources = %v, want %v", got, want)
+	}
+	// A directory below the repository root only submits its own tracked files
+	if got, want := submittedSources(true, filepath.Join(root, "lib")), []string{"util.py"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("git-tracked sources of lib = %v, want %v", got, want)
+	}
+	if got := submittedSources(false, root); len(got) != 4 {
+		t.Errorf("sources without GitTrackedOnly = %v, want every file", got)
+	}
+}
+
+func TestSubmitDirectoryOutsideGitSubmitsEveryFile(t *testing.T) {
+	root := t.TempDir()
+	if err := exec.Command("git", "-C", root, "rev-parse", "--is-inside-work-tree").Run(); err == nil {
+		t.Skip("the temp directory is inside a git working tree")
+	}
+	writeFile(t, filepath.Join(root, "calc.py"), "def inc(x):\n    return x + 1\n")
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.GitTrackedOnly = true
+	})
+	if submitted, errs := sw.SubmitDirectory(root, nil); submitted != 1 || len(errs) != 0 {
+		t.Errorf("submitted %d files with errors %v, want calc.py", submitted, errs)
+	}
+}
+
+func TestSymTaskReportsPathCover(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def sign(x):\n    if x > 0:\n        return 1\n    return -1\n")
+
+	sw := newTestSymWorker(newFakeModel(pythonTestResponse), nil)
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+	want := &PathCover{
+		Branches: []string{"if:x > 0-then", "if:x > 0-else"},
+		Paths:    [][]string{{"if:x > 0-then", "return:1"}, {"if:x > 0-else", "return:-1"}},
+	}
+	if cover := sw.Report().Tasks[0].PathCover; !reflect.DeepEqual(cover, want) {
+		t.Errorf("path cover = %+v, want %+v", cover, want)
+	}
+
+	// Capping the paths leaves one side of the branch uncovered
+	sw = newTestSymWorker(newFakeModel(pythonTestResponse), func(config *DeepWorkerConfig) {
+		config.MaxPathsPerFunction = 1
+	})
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+	cover := sw.Report().Tasks[0].PathCover
+	if cover == nil || len(cover.Paths) != 1 || len(cover.Uncovered) != 1 {
+		t.Fatalf("capped path cover = %+v, want one path and the uncovered branch", cover)
+	}
+	if !reflect.DeepEqual(cover.Branches, cover.Paths[0][:1]) {
+		t.Errorf("branches = %q, want the branch of the path %q", cover.Branches, cover.Paths[0])
+	}
+}
+
+func TestSubmitSymTaskRejectsUnsupportedLanguage(t *testing.T) {
+	dir := t.TempDir()
+	goSource := filepath.Join(dir, "calc.go")
+	writeFile(t, goSource, "package calc\n\nfunc Inc(x int) int { return x + 1 }\n")
+
+	// The language is rejected before the file is read, so a missing file
+	// fails the same way
+	for _, sourcePath := range []string{goSource, filepath.Join(dir, "missing.rb")} {
+		m := newFakeModel(pythonTestResponse)
+		sw := newTestSymWorker(m, nil)
+		err := sw.SubmitSymTask(sourcePath)
+		if !errors.Is(err, ErrUnsupportedLanguage) {
+			t.Errorf("SubmitSymTask(%s) = %v, want ErrUnsupportedLanguage", filepath.Base(sourcePath), err)
+		}
+		if prompts := m.recorded(); len(prompts) != 0 {
+			t.Errorf("model was prompted %d times for %s, want none", len(prompts), filepath.Base(sourcePath))
+		}
+	}
+}
+
+func TestSymTestFileNameFollowsLanguageConvention(t *testing.T) {
+	tests := []struct {
+		sourcePath string
+		codeType   string
+		want       string
+	}{
+		{"src/calc.py", "python", "calc_add_test_case_2.py"},
+		{"src/Calc.java", "java", "CalcTest.java"},
+		{"src/calc.js", "javascript", "calc_add_test_case_2.test.js"},
+	}
+	for _, tt := range tests {
+		if got := symTestFileName(tt.sourcePath, "add", 1, tt.codeType); got != tt.want {
+			t.Errorf("symTestFileName(%s) = %s, want %s", tt.sourcePath, got, tt.want)
+		}
+	}
+}
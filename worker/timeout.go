@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// errTaskTimeout is returned by iterateWithTimeout when an iteration takes
+// longer than the task timeout.
+var errTaskTimeout = errors.New("task iteration timed out")
+
+// iterateWithTimeout runs an iteration of the task like iterate, bounded by the
+// task timeout if one is set. The iteration's context is cancelled when the
+// timeout fires, which cancels its model call and keeps it from writing or
+// running the test, and the worker returns right away instead of waiting for
+// the iteration to end: a callback, which cannot be cancelled, may still be
+// running on the task. The returned channel is closed once the iteration has
+// ended; until then the task must not be used again.
+func (dw *DeepWorker) iterateWithTimeout(task *TestTask) (string, <-chan struct{}, error) {
+	ended := make(chan struct{})
+	if dw.taskTimeout <= 0 {
+		defer close(ended)
+		status, err := dw.iterate(dw.ctx, task)
+		return status, ended, err
+	}
+
+	ctx, cancel := context.WithTimeout(dw.ctx, dw.taskTimeout)
+	defer cancel()
+
+	type outcome struct {
+		status string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer close(ended)
+		status, err := dw.iterate(ctx, task)
+		done <- outcome{status, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.status, ended, result.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", ended, fmt.Errorf("%w after %s", errTaskTimeout, dw.taskTimeout)
+		}
+		// The worker was stopped, let the iteration end on its cancelled context
+		result := <-done
+		return result.status, ended, result.err
+	}
+}
+
+// timeoutTask handles an iteration of the task that timed out. The timeout is
+// recorded as the task's test report, so the prompt of the next iteration can
+// mention it, and the task is queued again, unless it used up its iterations,
+// in which case it is finished as timed out. Both happen only once the timed
+// out iteration has ended, signalled by running, so that it never writes or
+// runs the test at the task's test path while the next iteration does; the
+// worker is free for other tasks in the meantime.
+func (dw *DeepWorker) timeoutTask(task *TestTask, running <-chan struct{}) {
+	timedOut := dw.snapshotTask(task)
+	dw.reportTimeout(timedOut)
+
+	dw.timedOut.Add(1)
+	go func() {
+		defer dw.timedOut.Done()
+		<-running
+
+		if timedOut.Iterations >= dw.maxIterations {
+			dw.storeTask(task, timedOut)
+			dw.finishTask(timedOut, TaskTimedOut, fmt.Errorf("%w after %s", errTaskTimeout, dw.taskTimeout))
+			return
+		}
+
+		timedOut.Iterations++
+		dw.storeTask(task, timedOut)
+		if !dw.tasks.push(task) {
+			log.Printf("Failed to re-queue task for %s: queue full", task.SourcePath)
+			dw.failTask(task, fmt.Errorf("failed to re-queue task: queue full"))
+		}
+	}()
+}
+
+// reportTimeout records the timeout of the task's latest iteration as its test
+// report, so the prompt of the next iteration can mention it.
+func (dw *DeepWorker) reportTimeout(task *TestTask) {
+	task.TestReport = fmt.Sprintf("The previous attempt did not finish within the time limit of %s. "+
+		"Generate a test that runs quickly, without waiting, sleeping or looping indefinitely.", dw.taskTimeout)
+	log.Printf("Iteration %d of %s timed out after %s", task.Iterations, task.key(), dw.taskTimeout)
+}
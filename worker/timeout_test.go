@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimedOutIterationEndsBeforeTaskIsRequeued(t *testing.T) {
+	m := newFakeModel(pythonTestResponse)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var calls, running, overlapping atomic.Int32
+	callback := func(sourceCode, testCode, testPath string) (float64, string, error) {
+		if running.Add(1) > 1 {
+			overlapping.Add(1)
+		}
+		defer running.Add(-1)
+		if calls.Add(1) == 1 {
+			close(entered)
+			<-release
+		}
+		return 1, "ok", nil
+	}
+	dw := newTestWorker(m, func(config *DeepWorkerConfig) {
+		config.Callback = callback
+		config.MaxIterations = 3
+		config.TaskTimeout = 20 * time.Millisecond
+	})
+	dw.Run()
+	defer dw.Shutdown()
+
+	if err := dw.SubmitTask("", "slow.py"); err != nil {
+		t.Fatal(err)
+	}
+	waitForPrompt(t, m)
+	<-entered
+
+	// The first iteration timed out long ago, but its callback still runs
+	time.Sleep(200 * time.Millisecond)
+	if got := len(m.recorded()); got != 1 {
+		t.Fatalf("model called %d times while the timed out iteration was running, want 1", got)
+	}
+
+	close(release)
+	waitForPrompt(t, m)
+	select {
+	case <-dw.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not finish")
+	}
+	if overlapping.Load() > 0 {
+		t.Error("the callbacks of two iterations ran at the same time")
+	}
+}
@@ -0,0 +1,22 @@
+package worker
+
+import "unicode/utf8"
+
+// charsPerToken is the average number of characters per model token assumed
+// when no Tokenizer is configured.
+const charsPerToken = 4
+
+// Tokenizer counts the tokens a text takes up in the context of a model.
+// Integrations can wrap the tokenizer of the model they use, e.g. a
+// tiktoken-compatible one, to get exact prompt token counts.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// heuristicTokenizer estimates the token count of a text from its length, at
+// charsPerToken characters per token. It is used when no Tokenizer is configured.
+type heuristicTokenizer struct{}
+
+func (heuristicTokenizer) Count(text string) int {
+	return utf8.RuneCountInString(text) / charsPerToken
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wordTokenizer counts every whitespace-separated word as a token.
+type wordTokenizer struct{}
+
+func (wordTokenizer) Count(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestReportRecordsPromptTokensOfTokenizer(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "calc.py")
+	writeFile(t, sourcePath, "def inc(x):\n    return x + 1\n")
+
+	m := newFakeModel(pythonTestResponse)
+	sw := newTestSymWorker(m, func(config *DeepWorkerConfig) {
+		config.Tokenizer = wordTokenizer{}
+	})
+	if err := sw.SubmitSymTask(sourcePath); err != nil {
+		t.Fatalf("SubmitSymTask: %v", err)
+	}
+
+	want := 0
+	for _, prompt := range m.recorded() {
+		want += wordTokenizer{}.Count(prompt)
+	}
+	if want == 0 {
+		t.Fatal("model was not prompted")
+	}
+	if tokens := sw.Report().Tasks[0].PromptTokens; tokens != want {
+		t.Errorf("prompt tokens in the report = %d, want %d counted by the tokenizer", tokens, want)
+	}
+}
+
+func TestHeuristicTokenizerCountsCharacters(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 0},
+		{"abcdefgh", 2},
+		// Characters are counted, not bytes
+		{"äöüßäöüß", 2},
+	}
+	for _, tt := range tests {
+		if got := (heuristicTokenizer{}).Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}